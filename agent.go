@@ -18,6 +18,7 @@ package virtcontainers
 
 import (
 	"fmt"
+	"io"
 	"syscall"
 
 	"github.com/mitchellh/mapstructure"
@@ -42,6 +43,31 @@ type ProcessListOptions struct {
 // ProcessList represents the list of running processes inside the container
 type ProcessList []byte
 
+// ProbeOptions bounds the execution of an ExecProbe.
+type ProbeOptions struct {
+	// MaxOutputBytes bounds the amount of process output returned
+	// with the probe result, 0 meaning a default bound.
+	MaxOutputBytes int
+
+	// TimeoutSecs is the time in seconds the probe is allowed to
+	// run before it is given up on, 0 meaning a default timeout.
+	TimeoutSecs uint
+}
+
+// ProbeResult is the outcome of an ExecProbe.
+type ProbeResult struct {
+	// ExitCode is the exit status of the probe process.
+	ExitCode int32
+
+	// Output is the combined output of the probe process, bounded
+	// by the MaxOutputBytes probe option.
+	Output []byte
+
+	// Truncated is set when the output was cut at the configured
+	// bound.
+	Truncated bool
+}
+
 const (
 	// NoopAgentType is the No-Op agent.
 	NoopAgentType AgentType = "noop"
@@ -172,4 +198,36 @@ type agent interface {
 
 	// processListContainer will list the processes running inside the container
 	processListContainer(pod Pod, c Container, options ProcessListOptions) (ProcessList, error)
+
+	// execProbe runs a command inside the container and waits for
+	// its result, without setting up a full exec session. It is
+	// meant for high frequency liveness and readiness probes.
+	execProbe(pod *Pod, c Container, cmd Cmd, options ProbeOptions) (ProbeResult, error)
+
+	// attachStreams returns the stdio streams of a running container
+	// process, for callers implementing attach semantics on top of
+	// the library.
+	attachStreams(pod *Pod, c Container) (*ContainerStreams, error)
+
+	// addVolume exports a host directory to the running pod and
+	// returns the path at which the guest sees it.
+	addVolume(pod *Pod, volume Volume) (string, error)
+
+	// removeVolume withdraws a volume previously exported with
+	// addVolume.
+	removeVolume(pod *Pod, volume Volume) error
+}
+
+// ContainerStreams groups the stdio streams of a container process as
+// returned by an attach operation. Streams the agent cannot provide
+// are nil.
+type ContainerStreams struct {
+	// Stdin accepts input for the container process. Closing it
+	// signals end of input to the process.
+	Stdin io.WriteCloser
+
+	// Stdout and Stderr deliver the corresponding process output
+	// streams, without any multiplexing or framing.
+	Stdout io.Reader
+	Stderr io.Reader
 }
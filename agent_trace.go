@@ -0,0 +1,108 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AgentRPCRecord describes one completed agent RPC.
+type AgentRPCRecord struct {
+	// Name identifies the RPC, e.g. "CreateContainerRequest" for
+	// the kata agent or "newcontainer" for hyperstart.
+	Name string
+
+	// Duration is how long the call took, including the proxy and
+	// guest-side processing.
+	Duration time.Duration
+
+	// PayloadSize is the serialized size of the request payload in
+	// bytes.
+	PayloadSize int
+
+	// Error is the error the call returned, if any.
+	Error error
+}
+
+// AgentRPCTracer receives a record for every agent RPC, so callers
+// can feed their own tracing pipeline.
+type AgentRPCTracer func(record AgentRPCRecord)
+
+// defaultAgentRPCSlowThreshold is the duration after which an agent
+// RPC is logged as slow.
+const defaultAgentRPCSlowThreshold = 10 * time.Second
+
+var agentRPCLock sync.Mutex
+var agentRPCSlowThreshold = defaultAgentRPCSlowThreshold
+var agentRPCTracer AgentRPCTracer
+
+// SetAgentRPCSlowThreshold sets the duration after which an agent RPC
+// is logged as slow, to tell stalls in guest-side operations from
+// host-side ones.
+func SetAgentRPCSlowThreshold(threshold time.Duration) {
+	agentRPCLock.Lock()
+	defer agentRPCLock.Unlock()
+
+	agentRPCSlowThreshold = threshold
+}
+
+// SetAgentRPCTracer registers the tracer called with every agent RPC
+// record. A nil tracer disables tracing.
+func SetAgentRPCTracer(tracer AgentRPCTracer) {
+	agentRPCLock.Lock()
+	defer agentRPCLock.Unlock()
+
+	agentRPCTracer = tracer
+}
+
+// traceAgentRPC logs the completed agent RPC, warning when it crossed
+// the slow-call threshold, and hands it to the registered tracer.
+func traceAgentRPC(name string, payloadSize int, started time.Time, err error) {
+	duration := time.Since(started)
+
+	agentRPCLock.Lock()
+	threshold := agentRPCSlowThreshold
+	tracer := agentRPCTracer
+	agentRPCLock.Unlock()
+
+	agentLogger := virtLog.WithFields(logrus.Fields{
+		"rpc-name":         name,
+		"rpc-duration":     duration,
+		"rpc-payload-size": payloadSize,
+	})
+	if err != nil {
+		agentLogger = agentLogger.WithError(err)
+	}
+
+	if duration >= threshold {
+		agentLogger.Warn("Slow agent RPC")
+	} else {
+		agentLogger.Debug("Agent RPC")
+	}
+
+	if tracer != nil {
+		tracer(AgentRPCRecord{
+			Name:        name,
+			Duration:    duration,
+			PayloadSize: payloadSize,
+			Error:       err,
+		})
+	}
+}
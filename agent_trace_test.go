@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAgentRPCTracer(t *testing.T) {
+	var record AgentRPCRecord
+
+	SetAgentRPCTracer(func(r AgentRPCRecord) {
+		record = r
+	})
+	defer SetAgentRPCTracer(nil)
+
+	rpcErr := fmt.Errorf("guest stalled")
+	traceAgentRPC("CreateContainerRequest", 512, time.Now().Add(-time.Second), rpcErr)
+
+	if record.Name != "CreateContainerRequest" {
+		t.Fatalf("Got %s\nExpecting CreateContainerRequest", record.Name)
+	}
+
+	if record.PayloadSize != 512 {
+		t.Fatalf("Got %d\nExpecting 512", record.PayloadSize)
+	}
+
+	if record.Duration < time.Second {
+		t.Fatalf("Got %v\nExpecting at least one second", record.Duration)
+	}
+
+	if record.Error != rpcErr {
+		t.Fatalf("Got %v\nExpecting %v", record.Error, rpcErr)
+	}
+}
+
+func TestAgentRPCSlowThreshold(t *testing.T) {
+	SetAgentRPCSlowThreshold(time.Millisecond)
+	defer SetAgentRPCSlowThreshold(defaultAgentRPCSlowThreshold)
+
+	agentRPCLock.Lock()
+	threshold := agentRPCSlowThreshold
+	agentRPCLock.Unlock()
+
+	if threshold != time.Millisecond {
+		t.Fatalf("Got %v\nExpecting %v", threshold, time.Millisecond)
+	}
+
+	// A call over the threshold should not need a registered tracer.
+	traceAgentRPC("ping", 0, time.Now().Add(-time.Second), nil)
+}
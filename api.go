@@ -17,9 +17,12 @@
 package virtcontainers
 
 import (
+	"io"
 	"os"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -37,11 +40,18 @@ func SetLogger(logger logrus.FieldLogger) {
 
 // CreatePod is the virtcontainers pod creation entry point.
 // CreatePod creates a pod and its containers. It does not start them.
-func CreatePod(podConfig PodConfig) (VCPod, error) {
+func CreatePod(podConfig PodConfig) (p VCPod, err error) {
+	defer auditTrail.record("CreatePod", podConfig.ID, "", time.Now(), &err)
+
 	return createPodFromConfig(podConfig)
 }
 
 func createPodFromConfig(podConfig PodConfig) (*Pod, error) {
+	// The host memory may have changed since the last pod creation
+	// (memory hot-add, ballooning when running nested), make sure
+	// the VM gets sized against the current value.
+	hostMemory.refresh()
+
 	// Create the pod.
 	p, err := createPod(podConfig)
 	if err != nil {
@@ -90,12 +100,19 @@ func createPodFromConfig(podConfig PodConfig) (*Pod, error) {
 		return nil, err
 	}
 
+	// Record the pod claim in the host wide resource registry.
+	if err := registerPodResources(p, networkNS); err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
 // DeletePod is the virtcontainers pod deletion entry point.
 // DeletePod will stop an already running container and then delete it.
-func DeletePod(podID string) (VCPod, error) {
+func DeletePod(podID string) (_ VCPod, err error) {
+	defer auditTrail.record("DeletePod", podID, "", time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
@@ -154,7 +171,9 @@ func DeletePod(podID string) (VCPod, error) {
 // StartPod will talk to the given hypervisor to start an existing
 // pod and all its containers.
 // It returns the pod ID.
-func StartPod(podID string) (VCPod, error) {
+func StartPod(podID string) (_ VCPod, err error) {
+	defer auditTrail.record("StartPod", podID, "", time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
@@ -189,9 +208,43 @@ func startPod(p *Pod) (*Pod, error) {
 	return p, nil
 }
 
+// AdoptPod is the virtcontainers pod adoption entry point.
+// AdoptPod reconstructs the in-memory pod object of a running pod
+// purely from persisted state and re-establishes its communication
+// channels, so a restarted management process can keep managing pods
+// it did not start.
+func AdoptPod(podID string) (_ VCPod, err error) {
+	defer auditTrail.record("AdoptPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	// Fetch the pod from storage and create it.
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-establish the hypervisor monitor and the agent session.
+	if err := p.adopt(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
 // StopPod is the virtcontainers pod stopping entry point.
 // StopPod will talk to the given agent to stop an existing pod and destroy all containers within that pod.
-func StopPod(podID string) (VCPod, error) {
+func StopPod(podID string) (_ VCPod, err error) {
+	defer auditTrail.record("StopPod", podID, "", time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPod
 	}
@@ -218,9 +271,147 @@ func StopPod(podID string) (VCPod, error) {
 	return p, nil
 }
 
+// DrainPod is the virtcontainers deadline aware pod stopping entry
+// point. It behaves like StopPod, except that the containers are
+// stopped in the stop order configured for the pod and each workload
+// gets a bounded grace period to exit on its own before it is killed.
+func DrainPod(podID string, options DrainOptions) (_ VCPod, err error) {
+	defer auditTrail.record("DrainPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPod
+	}
+
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	// Fetch the pod from storage and create it.
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drain it.
+	err = p.drain(options)
+	if err != nil {
+		p.delete()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SnapshotPod is the virtcontainers pod storage snapshot entry point.
+// It takes a consistent snapshot of every block drive attached to the
+// pod containers and returns the snapshot handles for backup tooling.
+func SnapshotPod(podID string) (handles []SnapshotHandle, err error) {
+	defer auditTrail.record("SnapshotPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	// Snapshotting rewrites the active backing file of the pod
+	// drives, take the exclusive lock.
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.snapshotStorage()
+}
+
+// BackupPod is the virtcontainers pod storage backup entry point. It
+// exports the content of every block drive attached to the pod
+// containers. An incremental backup only exports the blocks written
+// since the last backup, which requires the hypervisor to track dirty
+// blocks.
+func BackupPod(podID string, incremental bool) (handles []BackupHandle, err error) {
+	defer auditTrail.record("BackupPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	// An incremental backup consumes and resets the drive dirty
+	// bitmaps, take the exclusive lock.
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.backupStorage(incremental)
+}
+
+// ExportPod is the virtcontainers NBD export entry point. It exports
+// the block devices of a stopped pod over per-drive unix socket NBD
+// servers for offline inspection or repair tooling. The pod cannot be
+// started again until UnexportPod is called.
+func ExportPod(podID string) (exports []NBDExport, err error) {
+	defer auditTrail.record("ExportPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.exportNBD()
+}
+
+// UnexportPod is the virtcontainers NBD export withdrawal entry point.
+// It stops the NBD servers started by ExportPod and allows the pod to
+// be started again.
+func UnexportPod(podID string) (err error) {
+	defer auditTrail.record("UnexportPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return errNeedPodID
+	}
+
+	lockFile, err := rwLockPod(podID)
+	if err != nil {
+		return err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return err
+	}
+
+	return p.unexportNBD()
+}
+
 // RunPod is the virtcontainers pod running entry point.
 // RunPod creates a pod and its containers and then it starts them.
-func RunPod(podConfig PodConfig) (VCPod, error) {
+func RunPod(podConfig PodConfig) (_ VCPod, err error) {
+	defer auditTrail.record("RunPod", podConfig.ID, "", time.Now(), &err)
+
 	p, err := createPodFromConfig(podConfig)
 	if err != nil {
 		return nil, err
@@ -236,7 +427,9 @@ func RunPod(podConfig PodConfig) (VCPod, error) {
 }
 
 // ListPod is the virtcontainers pod listing entry point.
-func ListPod() ([]PodStatus, error) {
+func ListPod() (list []PodStatus, err error) {
+	defer auditTrail.record("ListPod", "", "", time.Now(), &err)
+
 	dir, err := os.Open(configStoragePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -267,8 +460,18 @@ func ListPod() ([]PodStatus, error) {
 	return podStatusList, nil
 }
 
+// LeakCheck is the virtcontainers entry point listing the host
+// network artifacts still referencing deleted pods.
+func LeakCheck() (leaks []NetworkLeak, err error) {
+	defer auditTrail.record("LeakCheck", "", "", time.Now(), &err)
+
+	return leakCheck()
+}
+
 // StatusPod is the virtcontainers pod status entry point.
-func StatusPod(podID string) (PodStatus, error) {
+func StatusPod(podID string) (status PodStatus, err error) {
+	defer auditTrail.record("StatusPod", podID, "", time.Now(), &err)
+
 	if podID == "" {
 		return PodStatus{}, errNeedPodID
 	}
@@ -319,7 +522,9 @@ func StatusPod(podID string) (PodStatus, error) {
 
 // CreateContainer is the virtcontainers container creation entry point.
 // CreateContainer creates a container on a given pod.
-func CreateContainer(podID string, containerConfig ContainerConfig) (VCPod, VCContainer, error) {
+func CreateContainer(podID string, containerConfig ContainerConfig) (pod VCPod, container VCContainer, err error) {
+	defer auditTrail.record("CreateContainer", podID, containerConfig.ID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, nil, errNeedPodID
 	}
@@ -365,7 +570,9 @@ func CreateContainer(podID string, containerConfig ContainerConfig) (VCPod, VCCo
 // DeleteContainer is the virtcontainers container deletion entry point.
 // DeleteContainer deletes a Container from a Pod. If the container is running,
 // it needs to be stopped first.
-func DeleteContainer(podID, containerID string) (VCContainer, error) {
+func DeleteContainer(podID, containerID string) (container VCContainer, err error) {
+	defer auditTrail.record("DeleteContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
@@ -414,7 +621,9 @@ func DeleteContainer(podID, containerID string) (VCContainer, error) {
 
 // StartContainer is the virtcontainers container starting entry point.
 // StartContainer starts an already created container.
-func StartContainer(podID, containerID string) (VCContainer, error) {
+func StartContainer(podID, containerID string) (container VCContainer, err error) {
+	defer auditTrail.record("StartContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
@@ -452,7 +661,9 @@ func StartContainer(podID, containerID string) (VCContainer, error) {
 
 // StopContainer is the virtcontainers container stopping entry point.
 // StopContainer stops an already running container.
-func StopContainer(podID, containerID string) (VCContainer, error) {
+func StopContainer(podID, containerID string) (container VCContainer, err error) {
+	defer auditTrail.record("StopContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
@@ -490,7 +701,9 @@ func StopContainer(podID, containerID string) (VCContainer, error) {
 
 // EnterContainer is the virtcontainers container command execution entry point.
 // EnterContainer enters an already running container and runs a given command.
-func EnterContainer(podID, containerID string, cmd Cmd) (VCPod, VCContainer, *Process, error) {
+func EnterContainer(podID, containerID string, cmd Cmd) (_ VCPod, _ VCContainer, _ *Process, err error) {
+	defer auditTrail.record("EnterContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, nil, nil, errNeedPodID
 	}
@@ -525,9 +738,104 @@ func EnterContainer(podID, containerID string, cmd Cmd) (VCPod, VCContainer, *Pr
 	return p, c, process, nil
 }
 
+// ExecProbe is the virtcontainers probe execution entry point. It runs
+// a command inside a running container and returns its exit code and a
+// bounded amount of output, without the setup cost of a full exec
+// session. It is meant for high frequency liveness and readiness
+// probes.
+func ExecProbe(podID, containerID string, cmd Cmd, options ProbeOptions) (result ProbeResult, err error) {
+	defer auditTrail.record("ExecProbe", podID, containerID, time.Now(), &err)
+
+	if podID == "" {
+		return ProbeResult{}, errNeedPodID
+	}
+
+	if containerID == "" {
+		return ProbeResult{}, errNeedContainerID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	c, err := p.findContainer(containerID)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	return c.probe(cmd, options)
+}
+
+// ReadContainerLogs is the virtcontainers container logs reading entry
+// point. It returns a stream of the stdout and stderr collected for a
+// container, optionally limited to the last lines or following new
+// output. The caller owns the stream and must close it when done.
+func ReadContainerLogs(podID, containerID string, options ContainerLogsOptions) (logs io.ReadCloser, err error) {
+	defer auditTrail.record("ReadContainerLogs", podID, containerID, time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	if containerID == "" {
+		return nil, errNeedContainerID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.findContainer(containerID); err != nil {
+		return nil, err
+	}
+
+	return readContainerLogs(podID, containerID, options)
+}
+
+// AttestPod is the virtcontainers pod attestation entry point. It
+// retrieves the attestation evidence of a confidential pod, so that an
+// external verifier can attest the pod before secrets are released
+// into it.
+func AttestPod(podID string) (evidence AttestationEvidence, err error) {
+	defer auditTrail.record("AttestPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return AttestationEvidence{}, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return AttestationEvidence{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return AttestationEvidence{}, err
+	}
+
+	return p.AttestationEvidence()
+}
+
 // StatusContainer is the virtcontainers container status entry point.
 // StatusContainer returns a detailed container status.
-func StatusContainer(podID, containerID string) (ContainerStatus, error) {
+func StatusContainer(podID, containerID string) (status ContainerStatus, err error) {
+	defer auditTrail.record("StatusContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return ContainerStatus{}, errNeedPodID
 	}
@@ -602,6 +910,7 @@ func statusContainer(pod *Pod, containerID string) (ContainerStatus, error) {
 				StartTime:   container.process.StartTime,
 				RootFs:      container.config.RootFs,
 				Annotations: container.config.Annotations,
+				Devices:     container.deviceAddresses(),
 			}, nil
 		}
 	}
@@ -613,7 +922,9 @@ func statusContainer(pod *Pod, containerID string) (ContainerStatus, error) {
 // KillContainer is the virtcontainers entry point to send a signal
 // to a container running inside a pod. If all is true, all processes in
 // the container will be sent the signal.
-func KillContainer(podID, containerID string, signal syscall.Signal, all bool) error {
+func KillContainer(podID, containerID string, signal syscall.Signal, all bool) (err error) {
+	defer auditTrail.record("KillContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return errNeedPodID
 	}
@@ -648,21 +959,239 @@ func KillContainer(podID, containerID string, signal syscall.Signal, all bool) e
 	return nil
 }
 
+// AttachContainer is the virtcontainers entry point to attach to the
+// stdio streams of a running container, for callers implementing
+// attach semantics without an external shim.
+func AttachContainer(podID, containerID string) (streams *ContainerStreams, err error) {
+	defer auditTrail.record("AttachContainer", podID, containerID, time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	if containerID == "" {
+		return nil, errNeedContainerID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.findContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.agent.attachStreams(p, *c)
+}
+
 // PausePod is the virtcontainers pausing entry point which pauses an
 // already running pod.
-func PausePod(podID string) (VCPod, error) {
+func PausePod(podID string) (p VCPod, err error) {
+	defer auditTrail.record("PausePod", podID, "", time.Now(), &err)
+
 	return togglePausePod(podID, true)
 }
 
 // ResumePod is the virtcontainers resuming entry point which resumes
 // (or unpauses) and already paused pod.
-func ResumePod(podID string) (VCPod, error) {
+func ResumePod(podID string) (p VCPod, err error) {
+	defer auditTrail.record("ResumePod", podID, "", time.Now(), &err)
+
 	return togglePausePod(podID, false)
 }
 
+// UsagePod is the virtcontainers entry point to retrieve the host-side
+// resource consumption of the processes supporting a pod (hypervisor,
+// proxy and shims).
+func UsagePod(podID string) (usage PodResourceUsage, err error) {
+	defer auditTrail.record("UsagePod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return PodResourceUsage{}, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return PodResourceUsage{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return PodResourceUsage{}, err
+	}
+
+	return p.resourceUsage()
+}
+
+// WatermarksPod is the virtcontainers entry point to sample the guest
+// resource utilization of a pod and retrieve its high-water marks.
+// Each call takes a new sample, so the watermarks are as fresh as the
+// sampling frequency of the caller.
+func WatermarksPod(podID string) (watermarks PodWatermarks, err error) {
+	defer auditTrail.record("WatermarksPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return PodWatermarks{}, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return PodWatermarks{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return PodWatermarks{}, err
+	}
+
+	return p.sampleWatermarks()
+}
+
+// KSMPod is the virtcontainers entry point to report the memory
+// sharing KSM achieved on a pod.
+func KSMPod(podID string) (stats PodKSMStats, err error) {
+	defer auditTrail.record("KSMPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return PodKSMStats{}, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return PodKSMStats{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return PodKSMStats{}, err
+	}
+
+	return p.ksmStats()
+}
+
+// OverheadPod is the virtcontainers entry point to retrieve the fixed
+// per pod cost of VM isolation (VM memory size, hypervisor and proxy
+// resident set sizes), so schedulers can account for it.
+func OverheadPod(podID string) (overhead PodOverhead, err error) {
+	defer auditTrail.record("OverheadPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return PodOverhead{}, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return PodOverhead{}, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return PodOverhead{}, err
+	}
+
+	return p.overhead()
+}
+
+// isQueryCommand tells if a hypervisor monitor command is a read-only
+// query, the only kind HypervisorCommand accepts.
+func isQueryCommand(cmd string) bool {
+	return strings.HasPrefix(cmd, "query-")
+}
+
+// HypervisorCommand is the virtcontainers entry point to send a
+// diagnostic command (e.g. query-block, query-cpus) to a pod's
+// hypervisor monitor. The pod must have been created with
+// EnableDiagnosticCommands set, and only query commands are accepted,
+// so callers get diagnostics without holding the raw monitor socket.
+func HypervisorCommand(podID, cmd string, args map[string]interface{}) (response interface{}, err error) {
+	defer auditTrail.record("HypervisorCommand", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	if !isQueryCommand(cmd) {
+		return nil, errNotQueryCommand
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.config.HypervisorConfig.EnableDiagnosticCommands {
+		return nil, errDiagnosticsDisabled
+	}
+
+	return p.hypervisor.sendCommand(cmd, args)
+}
+
+// FetchConsoleLog is the virtcontainers entry point to retrieve the
+// guest console output collected for a pod. The console log persists
+// after the VM is gone, making boot failures diagnosable post-mortem.
+func FetchConsoleLog(podID string) (log []byte, err error) {
+	defer auditTrail.record("FetchConsoleLog", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	return fetchConsoleLog(podID)
+}
+
+// KernelLogPod is the virtcontainers entry point to stream the guest
+// kernel log of a running pod. It requires the kernel log console to be
+// attached (see HypervisorConfig.KernelLogConsole).
+func KernelLogPod(podID string) (stream io.ReadCloser, err error) {
+	defer auditTrail.record("KernelLogPod", podID, "", time.Now(), &err)
+
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	p, err := fetchPod(podID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.kernelLogStream()
+}
+
 // ProcessListContainer is the virtcontainers entry point to list
 // processes running inside a container
-func ProcessListContainer(podID, containerID string, options ProcessListOptions) (ProcessList, error) {
+func ProcessListContainer(podID, containerID string, options ProcessListOptions) (list ProcessList, err error) {
+	defer auditTrail.record("ProcessListContainer", podID, containerID, time.Now(), &err)
+
 	if podID == "" {
 		return nil, errNeedPodID
 	}
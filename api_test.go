@@ -589,6 +589,28 @@ func TestStopPodNoopAgentSuccessful(t *testing.T) {
 	}
 }
 
+func TestDrainPodNoopAgentSuccessful(t *testing.T) {
+	cleanUp()
+
+	config := newTestPodConfigNoop()
+
+	p, _, err := createAndStartPod(config)
+	if p == nil || err != nil {
+		t.Fatal(err)
+	}
+
+	vp, err := DrainPod(p.ID(), DrainOptions{GracePeriodSecs: 1})
+	if vp == nil || err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrainPodFailing(t *testing.T) {
+	if _, err := DrainPod("", DrainOptions{}); err != errNeedPod {
+		t.Fatalf("Expected errNeedPod, got %v", err)
+	}
+}
+
 func TestPauseThenResumePodNoopAgentSuccessful(t *testing.T) {
 	cleanUp()
 
@@ -920,6 +942,9 @@ func TestStatusPodSuccessfulStateReady(t *testing.T) {
 	// value will be.
 	expectedStatus.ContainersStatus[0].StartTime = status.ContainersStatus[0].StartTime
 
+	// Startup phase durations are measured, not predictable.
+	expectedStatus.State.StartupPhases = status.State.StartupPhases
+
 	if reflect.DeepEqual(status, expectedStatus) == false {
 		t.Fatalf("Got pod status %v\n expecting %v", status, expectedStatus)
 	}
@@ -979,6 +1004,9 @@ func TestStatusPodSuccessfulStateRunning(t *testing.T) {
 	// value will be.
 	expectedStatus.ContainersStatus[0].StartTime = status.ContainersStatus[0].StartTime
 
+	// Startup phase durations are measured, not predictable.
+	expectedStatus.State.StartupPhases = status.State.StartupPhases
+
 	if reflect.DeepEqual(status, expectedStatus) == false {
 		t.Fatalf("Got pod status %v\n expecting %v", status, expectedStatus)
 	}
@@ -2219,3 +2247,40 @@ func BenchmarkStartStop10ContainerQemuHypervisorHyperstartAgentNetworkNoop(b *te
 		createStartStopDeleteContainers(b, podConfig, contConfigs)
 	}
 }
+
+func TestIsQueryCommand(t *testing.T) {
+	for cmd, expected := range map[string]bool{
+		"query-block": true,
+		"query-cpus":  true,
+		"quit":        false,
+		"device_del":  false,
+		"":            false,
+		"queryblock":  false,
+	} {
+		if result := isQueryCommand(cmd); result != expected {
+			t.Fatalf("Got %t for %q\nExpecting %t", result, cmd, expected)
+		}
+	}
+}
+
+func TestAttestPodFailures(t *testing.T) {
+	if _, err := AttestPod(""); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	cleanUp()
+
+	if _, err := AttestPod(testPodID); err == nil {
+		t.Fatal("Attesting a non existing pod should fail")
+	}
+}
+
+func TestHypervisorCommandFailures(t *testing.T) {
+	if _, err := HypervisorCommand("", "query-block", nil); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	if _, err := HypervisorCommand(testPodID, "quit", nil); err != errNotQueryCommand {
+		t.Fatalf("Expected errNotQueryCommand, got %v", err)
+	}
+}
@@ -17,15 +17,51 @@
 package virtcontainers
 
 import (
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/containers/virtcontainers/pkg/annotations"
 )
 
+// HashMismatchError is returned when the computed hash for an asset
+// does not match the hash provided through the pod annotations.
+type HashMismatchError struct {
+	AssetType assetType
+	Path      string
+	Computed  string
+	Expected  string
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("Invalid hash for %s asset %s: computed %s, expecting %s", e.AssetType, e.Path, e.Computed, e.Expected)
+}
+
+// assetHashCacheEntry caches a computed asset hash, together with the
+// file attributes the hash was computed against.
+type assetHashCacheEntry struct {
+	modTime  time.Time
+	size     int64
+	hashType string
+	hash     string
+}
+
+// assetHashCache avoids re-reading large boot assets (kernels, images)
+// for every pod creation. Entries are keyed by asset path and
+// invalidated whenever the file modification time or size changes.
+var assetHashCache = struct {
+	sync.Mutex
+	entries map[string]assetHashCacheEntry
+}{
+	entries: make(map[string]assetHashCacheEntry),
+}
+
 type assetType string
 
 func (t assetType) annotations() (string, string, error) {
@@ -34,6 +70,8 @@ func (t assetType) annotations() (string, string, error) {
 		return annotations.KernelPath, annotations.KernelHash, nil
 	case imageAsset:
 		return annotations.ImagePath, annotations.ImageHash, nil
+	case initrdAsset:
+		return annotations.InitrdPath, annotations.InitrdHash, nil
 	case hypervisorAsset:
 		return annotations.HypervisorPath, annotations.HypervisorHash, nil
 	case firmwareAsset:
@@ -46,6 +84,7 @@ func (t assetType) annotations() (string, string, error) {
 const (
 	kernelAsset     assetType = "kernel"
 	imageAsset      assetType = "image"
+	initrdAsset     assetType = "initrd"
 	hypervisorAsset assetType = "hypervisor"
 	firmwareAsset   assetType = "firmware"
 )
@@ -66,6 +105,8 @@ func (a *asset) valid() bool {
 		return true
 	case imageAsset:
 		return true
+	case initrdAsset:
+		return true
 	case hypervisorAsset:
 		return true
 	case firmwareAsset:
@@ -80,6 +121,21 @@ func (a *asset) hash(hashType string) (string, error) {
 	var hashEncodedLen int
 	var hash string
 
+	fileInfo, err := os.Stat(a.path)
+	if err != nil {
+		return "", err
+	}
+
+	// A cached hash is only valid as long as the asset file
+	// has not been modified since we computed it.
+	assetHashCache.Lock()
+	cached, ok := assetHashCache.entries[a.path]
+	assetHashCache.Unlock()
+	if ok && cached.hashType == hashType && cached.modTime.Equal(fileInfo.ModTime()) && cached.size == fileInfo.Size() {
+		a.computedHash = cached.hash
+		return cached.hash, nil
+	}
+
 	// We read the actual asset content
 	bytes, err := ioutil.ReadFile(a.path)
 	if err != nil {
@@ -91,22 +147,39 @@ func (a *asset) hash(hashType string) (string, error) {
 	}
 
 	// Build the asset hash and convert it to a string.
-	// We only support SHA512 for now.
+	virtLog.Debugf("Computing %v hash", a.path)
+	var hashComputed []byte
 	switch hashType {
+	case annotations.SHA256:
+		h := sha256.Sum256(bytes)
+		hashComputed = h[:]
+	case annotations.SHA384:
+		h := sha512.Sum384(bytes)
+		hashComputed = h[:]
 	case annotations.SHA512:
-		virtLog.Debugf("Computing %v hash", a.path)
-		hashComputed := sha512.Sum512(bytes)
-		hashEncodedLen = hex.EncodedLen(len(hashComputed))
-		hashEncoded := make([]byte, hashEncodedLen)
-		hex.Encode(hashEncoded, hashComputed[:])
-		hash = string(hashEncoded[:])
-		virtLog.Debugf("%v hash: %s", a.path, hash)
+		h := sha512.Sum512(bytes)
+		hashComputed = h[:]
 	default:
 		return "", fmt.Errorf("Invalid hash type %s", hashType)
 	}
 
+	hashEncodedLen = hex.EncodedLen(len(hashComputed))
+	hashEncoded := make([]byte, hashEncodedLen)
+	hex.Encode(hashEncoded, hashComputed)
+	hash = string(hashEncoded[:])
+	virtLog.Debugf("%v hash: %s", a.path, hash)
+
 	a.computedHash = hash
 
+	assetHashCache.Lock()
+	assetHashCache.entries[a.path] = assetHashCacheEntry{
+		modTime:  fileInfo.ModTime(),
+		size:     fileInfo.Size(),
+		hashType: hashType,
+		hash:     hash,
+	}
+	assetHashCache.Unlock()
+
 	return hash, nil
 }
 
@@ -151,7 +224,12 @@ func newAsset(podConfig *PodConfig, t assetType) (*asset, error) {
 
 	// If our computed asset hash does not match the passed annotation, we must exit.
 	if hashComputed != hash {
-		return nil, fmt.Errorf("Invalid hash for %s: computed %s, expecting %s]", a.path, hashComputed, hash)
+		return nil, &HashMismatchError{
+			AssetType: t,
+			Path:      a.path,
+			Computed:  hashComputed,
+			Expected:  hash,
+		}
 	}
 
 	return a, nil
@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/containers/virtcontainers/pkg/annotations"
 	"github.com/stretchr/testify/assert"
@@ -76,6 +77,40 @@ func TestAssetHash(t *testing.T) {
 	assert.Equal(assetContentHash, a.computedHash)
 }
 
+func TestAssetHashAlgorithms(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	assert.Nil(err)
+
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name()) // clean up
+	}()
+
+	_, err = tmpfile.Write(assetContent)
+	assert.Nil(err)
+
+	data := []struct {
+		hashType string
+		expected string
+	}{
+		{annotations.SHA256, "d58d4016d9fec8e1db4e53548aa79cb08943f7d2f6b48283dab7d6e821deecb6"},
+		{annotations.SHA384, "75e7b5cce31ce6c9050b3c5126fc4bb549f5973ff76a8bef69232ccfcc1620e7974cb0fb417c6fdead5f3d47407c535c"},
+		{annotations.SHA512, assetContentHash},
+	}
+
+	for _, d := range data {
+		a := &asset{
+			path: tmpfile.Name(),
+		}
+
+		hash, err := a.hash(d.hashType)
+		assert.Nil(err)
+		assert.Equal(d.expected, hash)
+	}
+}
+
 func TestAssetNew(t *testing.T) {
 	assert := assert.New(t)
 
@@ -94,6 +129,8 @@ func TestAssetNew(t *testing.T) {
 		Annotations: map[string]string{
 			annotations.KernelPath: tmpfile.Name(),
 			annotations.KernelHash: assetContentHash,
+			annotations.InitrdPath: tmpfile.Name(),
+			annotations.InitrdHash: assetContentHash,
 		},
 	}
 
@@ -105,6 +142,11 @@ func TestAssetNew(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(assetContentHash, a.computedHash)
 
+	a, err = newAsset(p, initrdAsset)
+	assert.Nil(err)
+	assert.Equal(assetContentHash, a.computedHash)
+	assert.Equal(initrdAsset, a.kind)
+
 	p = &PodConfig{
 		Annotations: map[string]string{
 			annotations.KernelPath: tmpfile.Name(),
@@ -114,4 +156,57 @@ func TestAssetNew(t *testing.T) {
 
 	a, err = newAsset(p, kernelAsset)
 	assert.NotNil(err)
+
+	hashErr, ok := err.(*HashMismatchError)
+	assert.True(ok)
+	assert.Equal(kernelAsset, hashErr.AssetType)
+	assert.Equal(tmpfile.Name(), hashErr.Path)
+	assert.Equal(assetContentHash, hashErr.Computed)
+	assert.Equal(assetContentWrongHash, hashErr.Expected)
+}
+
+func TestAssetHashCache(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	assert.Nil(err)
+
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name()) // clean up
+	}()
+
+	_, err = tmpfile.Write(assetContent)
+	assert.Nil(err)
+
+	a := &asset{
+		path: tmpfile.Name(),
+	}
+
+	hash, err := a.hash(annotations.SHA512)
+	assert.Nil(err)
+	assert.Equal(assetContentHash, hash)
+
+	assetHashCache.Lock()
+	cached, ok := assetHashCache.entries[a.path]
+	assetHashCache.Unlock()
+	assert.True(ok)
+	assert.Equal(assetContentHash, cached.hash)
+
+	// A second computation should hit the cache.
+	hash, err = a.hash(annotations.SHA512)
+	assert.Nil(err)
+	assert.Equal(assetContentHash, hash)
+
+	// Modifying the asset must invalidate the cached hash.
+	err = ioutil.WriteFile(tmpfile.Name(), []byte("modified asset content"), 0644)
+	assert.Nil(err)
+
+	newModTime := cached.modTime.Add(time.Second)
+	err = os.Chtimes(tmpfile.Name(), newModTime, newModTime)
+	assert.Nil(err)
+
+	hash, err = a.hash(annotations.SHA512)
+	assert.Nil(err)
+	assert.NotEqual(assetContentHash, hash)
 }
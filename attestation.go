@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+// TEE types reported in attestation evidence.
+const (
+	// TeeSEV identifies an AMD SEV protected guest.
+	TeeSEV = "sev"
+
+	// TeeTDX identifies an Intel TDX protected guest.
+	TeeTDX = "tdx"
+)
+
+// AttestationEvidence is the evidence an external verifier needs to
+// attest a confidential pod before releasing secrets into it.
+type AttestationEvidence struct {
+	// TeeType identifies the trusted execution environment the pod
+	// runs in.
+	TeeType string
+
+	// LaunchMeasurement is the launch measurement retrieved from the
+	// VMM.
+	LaunchMeasurement []byte
+
+	// GuestReport is the attestation report generated inside the
+	// guest, when the agent is able to provide one.
+	GuestReport []byte
+}
+
+// guestAttester is implemented by agents able to retrieve an
+// attestation report from inside the guest.
+type guestAttester interface {
+	guestAttestationReport(pod *Pod) ([]byte, error)
+}
+
+// AttestationEvidence returns the attestation evidence for the pod,
+// combining the launch measurement from the VMM with a guest generated
+// report when the agent supports it.
+func (p *Pod) AttestationEvidence() (AttestationEvidence, error) {
+	evidence, err := p.hypervisor.attestationEvidence()
+	if err != nil {
+		return AttestationEvidence{}, err
+	}
+
+	if attester, ok := p.agent.(guestAttester); ok {
+		report, err := attester.guestAttestationReport(p)
+		if err != nil {
+			return AttestationEvidence{}, err
+		}
+
+		evidence.GuestReport = report
+	}
+
+	return evidence, nil
+}
@@ -0,0 +1,125 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditEntry is a single record of the audit trail. One JSON encoded
+// entry is appended to the audit log per public API call.
+type auditEntry struct {
+	Timestamp   time.Time         `json:"timestamp"`
+	Operation   string            `json:"operation"`
+	PodID       string            `json:"podID,omitempty"`
+	ContainerID string            `json:"containerID,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	DurationMs  int64             `json:"durationMs"`
+}
+
+// auditLogger appends one entry per public API call to an append-only
+// per-host audit log. It is disabled until EnableAuditLog is called.
+type auditLogger struct {
+	sync.Mutex
+
+	file     *os.File
+	metadata map[string]string
+}
+
+var auditTrail = &auditLogger{}
+
+// EnableAuditLog makes the package record every public API call
+// (operation, pod and container IDs, result and duration) to an
+// append-only audit log at the given path. The optional metadata is
+// attached to every record, allowing callers to identify the embedding
+// runtime or host.
+func EnableAuditLog(path string, metadata map[string]string) error {
+	if path == "" {
+		return errNeedFile
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	auditTrail.Lock()
+	defer auditTrail.Unlock()
+
+	if auditTrail.file != nil {
+		auditTrail.file.Close()
+	}
+
+	auditTrail.file = file
+	auditTrail.metadata = metadata
+
+	return nil
+}
+
+// DisableAuditLog stops the audit trail recording.
+func DisableAuditLog() {
+	auditTrail.Lock()
+	defer auditTrail.Unlock()
+
+	if auditTrail.file != nil {
+		auditTrail.file.Close()
+		auditTrail.file = nil
+	}
+
+	auditTrail.metadata = nil
+}
+
+// record appends an audit entry for the given operation. It is meant
+// to be deferred from public API functions, with errp pointing at their
+// named error return value.
+func (a *auditLogger) record(operation, podID, containerID string, start time.Time, errp *error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.file == nil {
+		return
+	}
+
+	entry := auditEntry{
+		Timestamp:   start,
+		Operation:   operation,
+		PodID:       podID,
+		ContainerID: containerID,
+		Metadata:    a.metadata,
+		DurationMs:  int64(time.Since(start) / time.Millisecond),
+	}
+
+	if errp != nil && *errp != nil {
+		entry.Error = (*errp).Error()
+	}
+
+	jsonOut, err := json.Marshal(entry)
+	if err != nil {
+		virtLog.WithError(err).Warn("Could not marshal audit entry")
+		return
+	}
+
+	jsonOut = append(jsonOut, '\n')
+
+	if _, err := a.file.Write(jsonOut); err != nil {
+		virtLog.WithError(err).Warn("Could not write audit entry")
+	}
+}
@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnableAuditLogEmptyPath(t *testing.T) {
+	if err := EnableAuditLog("", nil); err == nil {
+		t.Fatal("Expected error for empty audit log path")
+	}
+}
+
+func TestAuditLogRecordsAPICalls(t *testing.T) {
+	defer cleanUp()
+
+	auditPath := filepath.Join(testDir, "audit.log")
+
+	err := EnableAuditLog(auditPath, map[string]string{"host": "test-host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DisableAuditLog()
+
+	// This call fails but must still be audited.
+	if _, err := StatusPod(""); err == nil {
+		t.Fatal("StatusPod should have failed")
+	}
+
+	file, err := os.Open(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("Expected at least one audit entry")
+	}
+
+	var entry auditEntry
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Operation != "StatusPod" {
+		t.Fatalf("Got operation %q\nExpecting %q", entry.Operation, "StatusPod")
+	}
+
+	if entry.Error == "" {
+		t.Fatal("Expected the audit entry to record an error")
+	}
+
+	if entry.Metadata["host"] != "test-host" {
+		t.Fatalf("Got metadata %v\nExpecting host=test-host", entry.Metadata)
+	}
+}
+
+func TestDisableAuditLogStopsRecording(t *testing.T) {
+	defer cleanUp()
+
+	auditPath := filepath.Join(testDir, "audit.log")
+
+	if err := EnableAuditLog(auditPath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	DisableAuditLog()
+
+	if _, err := StatusPod(""); err == nil {
+		t.Fatal("StatusPod should have failed")
+	}
+
+	info, err := os.Stat(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != 0 {
+		t.Fatalf("Expected empty audit log, got %d bytes", info.Size())
+	}
+}
@@ -0,0 +1,209 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultBalloonReclaimInterval is the default time between two
+// balloon reclaim policy evaluations.
+const defaultBalloonReclaimInterval = 30 * time.Second
+
+// GuestMemoryStats describes the guest memory situation as reported
+// by the agent.
+type GuestMemoryStats struct {
+	// TotalBytes is the total guest memory.
+	TotalBytes uint64
+
+	// FreeBytes is the guest free memory.
+	FreeBytes uint64
+}
+
+// memoryStatsProvider is implemented by agents able to report guest
+// memory statistics, which the balloon reclaim policy needs.
+type memoryStatsProvider interface {
+	guestMemoryStats() (GuestMemoryStats, error)
+}
+
+// BalloonReclaimConfig configures the automatic balloon reclaim
+// policy. When enabled, idle guest memory is periodically reclaimed
+// by inflating the balloon, and returned under pressure by deflating
+// it, reducing the fleet memory footprint without caller
+// intervention.
+type BalloonReclaimConfig struct {
+	// Enabled turns the policy on.
+	Enabled bool
+
+	// Interval between two policy evaluations. A zero interval
+	// selects the default.
+	Interval time.Duration
+
+	// IdleFreePercent is the guest free memory percentage above
+	// which the balloon is inflated by one step.
+	IdleFreePercent uint64
+
+	// PressureFreePercent is the guest free memory percentage below
+	// which the balloon is deflated by one step.
+	PressureFreePercent uint64
+
+	// StepMiB is the balloon size adjustment applied per
+	// evaluation, in MiB.
+	StepMiB uint64
+}
+
+// balloonReclaimer periodically evaluates the balloon reclaim policy
+// for a pod. It lives alongside the pod VM, like the console logger.
+type balloonReclaimer struct {
+	pod    *Pod
+	config BalloonReclaimConfig
+	stopCh chan struct{}
+}
+
+// newBalloonReclaimer returns a balloon reclaimer for the given pod.
+func newBalloonReclaimer(pod *Pod, config BalloonReclaimConfig) *balloonReclaimer {
+	if config.Interval == 0 {
+		config.Interval = defaultBalloonReclaimInterval
+	}
+
+	return &balloonReclaimer{
+		pod:    pod,
+		config: config,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Logger returns a logrus logger appropriate for logging balloon
+// reclaim messages
+func (b *balloonReclaimer) Logger() *logrus.Entry {
+	return virtLog.WithFields(logrus.Fields{
+		"subsystem": "balloon-reclaim",
+		"pod-id":    b.pod.id,
+	})
+}
+
+// start validates the policy and launches the evaluation loop.
+func (b *balloonReclaimer) start() error {
+	if b.config.StepMiB == 0 {
+		return fmt.Errorf("Balloon reclaim step cannot be zero")
+	}
+
+	if b.config.IdleFreePercent <= b.config.PressureFreePercent {
+		return fmt.Errorf("Balloon reclaim idle threshold must be above the pressure threshold")
+	}
+
+	if _, ok := b.pod.agent.(memoryStatsProvider); !ok {
+		return fmt.Errorf("The pod agent cannot report guest memory statistics")
+	}
+
+	go func() {
+		ticker := time.NewTicker(b.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.stopCh:
+				return
+			case <-ticker.C:
+				if err := b.evaluate(); err != nil {
+					b.Logger().WithError(err).Warn("Balloon reclaim evaluation failed")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// evaluate takes one policy decision: inflate the balloon when the
+// guest sits on idle memory, deflate it when the guest is under
+// pressure, do nothing in between.
+func (b *balloonReclaimer) evaluate() error {
+	provider, ok := b.pod.agent.(memoryStatsProvider)
+	if !ok {
+		return fmt.Errorf("The pod agent cannot report guest memory statistics")
+	}
+
+	stats, err := provider.guestMemoryStats()
+	if err != nil {
+		return err
+	}
+
+	if stats.TotalBytes == 0 {
+		return fmt.Errorf("Guest reported no memory")
+	}
+
+	freePercent := stats.FreeBytes * 100 / stats.TotalBytes
+	step := b.config.StepMiB << 20
+
+	response, err := b.pod.hypervisor.sendCommand("query-balloon", nil)
+	if err != nil {
+		return err
+	}
+
+	balloon, ok := response.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Unexpected balloon query response %v", response)
+	}
+
+	actual, ok := balloon["actual"].(float64)
+	if !ok {
+		return fmt.Errorf("Unexpected balloon query response %v", response)
+	}
+
+	current := uint64(actual)
+	target := current
+
+	switch {
+	case freePercent >= b.config.IdleFreePercent:
+		// Inflating the balloon shrinks the guest memory.
+		if current > step {
+			target = current - step
+		}
+	case freePercent <= b.config.PressureFreePercent:
+		// Deflating gives memory back, up to the configured VM
+		// size.
+		limit := uint64(b.pod.config.VMConfig.Memory) << 20
+		target = current + step
+		if target > limit {
+			target = limit
+		}
+	}
+
+	if target == current {
+		return nil
+	}
+
+	b.Logger().WithFields(logrus.Fields{
+		"free-percent":   freePercent,
+		"balloon-target": target,
+	}).Info("Adjusting balloon")
+
+	_, err = b.pod.hypervisor.sendCommand("balloon", map[string]interface{}{
+		"value": target,
+	})
+
+	return err
+}
+
+// stop terminates the evaluation loop.
+func (b *balloonReclaimer) stop() {
+	close(b.stopCh)
+}
@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+// balloonTestAgent is a noop agent able to report canned guest memory
+// statistics.
+type balloonTestAgent struct {
+	noopAgent
+	stats GuestMemoryStats
+}
+
+func (a *balloonTestAgent) guestMemoryStats() (GuestMemoryStats, error) {
+	return a.stats, nil
+}
+
+// balloonTestHypervisor is a mock hypervisor with a balloon.
+type balloonTestHypervisor struct {
+	mockHypervisor
+	actual uint64
+	target uint64
+}
+
+func (h *balloonTestHypervisor) sendCommand(cmd string, args map[string]interface{}) (interface{}, error) {
+	switch cmd {
+	case "query-balloon":
+		return map[string]interface{}{"actual": float64(h.actual)}, nil
+	case "balloon":
+		h.target = args["value"].(uint64)
+	}
+
+	return nil, nil
+}
+
+func newBalloonTestPod(freeBytes, totalBytes, actual uint64) (*Pod, *balloonTestHypervisor) {
+	hypervisor := &balloonTestHypervisor{
+		actual: actual,
+	}
+
+	pod := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			VMConfig: Resources{
+				Memory: 2048,
+			},
+		},
+		agent: &balloonTestAgent{
+			stats: GuestMemoryStats{
+				TotalBytes: totalBytes,
+				FreeBytes:  freeBytes,
+			},
+		},
+		hypervisor: hypervisor,
+	}
+
+	return pod, hypervisor
+}
+
+func TestBalloonReclaimerStartInvalid(t *testing.T) {
+	pod, _ := newBalloonTestPod(0, 1, 1)
+
+	br := newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     80,
+		PressureFreePercent: 20,
+	})
+	if err := br.start(); err == nil {
+		t.Fatal("A zero reclaim step should be rejected")
+	}
+
+	br = newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     20,
+		PressureFreePercent: 80,
+		StepMiB:             128,
+	})
+	if err := br.start(); err == nil {
+		t.Fatal("An idle threshold below the pressure threshold should be rejected")
+	}
+
+	pod.agent = &noopAgent{}
+	br = newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     80,
+		PressureFreePercent: 20,
+		StepMiB:             128,
+	})
+	if err := br.start(); err == nil {
+		t.Fatal("An agent without memory statistics should be rejected")
+	}
+}
+
+func TestBalloonReclaimEvaluateIdle(t *testing.T) {
+	actual := uint64(2048) << 20
+	pod, hypervisor := newBalloonTestPod(900<<20, 1000<<20, actual)
+
+	br := newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     80,
+		PressureFreePercent: 20,
+		StepMiB:             128,
+	})
+
+	if err := br.evaluate(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := actual - (128 << 20)
+	if hypervisor.target != expected {
+		t.Fatalf("Got %d\nExpecting %d", hypervisor.target, expected)
+	}
+}
+
+func TestBalloonReclaimEvaluatePressure(t *testing.T) {
+	actual := uint64(2000) << 20
+	pod, hypervisor := newBalloonTestPod(100<<20, 1000<<20, actual)
+
+	br := newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     80,
+		PressureFreePercent: 20,
+		StepMiB:             128,
+	})
+
+	if err := br.evaluate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deflation is capped at the configured VM size.
+	expected := uint64(2048) << 20
+	if hypervisor.target != expected {
+		t.Fatalf("Got %d\nExpecting %d", hypervisor.target, expected)
+	}
+}
+
+func TestBalloonReclaimEvaluateSteady(t *testing.T) {
+	actual := uint64(2048) << 20
+	pod, hypervisor := newBalloonTestPod(500<<20, 1000<<20, actual)
+
+	br := newBalloonReclaimer(pod, BalloonReclaimConfig{
+		IdleFreePercent:     80,
+		PressureFreePercent: 20,
+		StepMiB:             128,
+	})
+
+	if err := br.evaluate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if hypervisor.target != 0 {
+		t.Fatalf("Got %d\nExpecting no balloon adjustment", hypervisor.target)
+	}
+}
@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "sync"
+
+// BootParams is the final VM boot configuration handed to boot policy
+// hooks just before the hypervisor is launched.
+type BootParams struct {
+	// PodID is the pod the VM belongs to.
+	PodID string
+
+	// HypervisorPath is the hypervisor binary about to be executed.
+	HypervisorPath string
+
+	// MachineType is the hypervisor machine type.
+	MachineType string
+
+	// KernelPath is the guest kernel being booted.
+	KernelPath string
+
+	// KernelParams is the guest kernel command line. Policy hooks may
+	// mutate it, and the mutated value is what the VM boots with.
+	KernelParams string
+
+	// Devices are the hypervisor device command line parameters, one
+	// string per device. They are informational: a policy rejects an
+	// unwanted device by returning an error.
+	Devices []string
+}
+
+// BootPolicy vets the final boot parameters of every pod VM before it is
+// launched. A policy can mutate BootParams.KernelParams or reject the
+// launch entirely by returning an error.
+type BootPolicy func(params *BootParams) error
+
+var bootPoliciesLock sync.Mutex
+var bootPolicies []BootPolicy
+
+// RegisterBootPolicy adds a policy hook invoked with the final boot
+// parameters of every pod VM before it is launched, so constraints like
+// forbidding filesystem sharing or requiring an IOMMU can be enforced
+// centrally. Hooks run in registration order.
+func RegisterBootPolicy(policy BootPolicy) {
+	bootPoliciesLock.Lock()
+	defer bootPoliciesLock.Unlock()
+
+	bootPolicies = append(bootPolicies, policy)
+}
+
+// applyBootPolicies runs every registered boot policy hook on the given
+// boot parameters, stopping at the first rejection.
+func applyBootPolicies(params *BootParams) error {
+	bootPoliciesLock.Lock()
+	policies := make([]BootPolicy, len(bootPolicies))
+	copy(policies, bootPolicies)
+	bootPoliciesLock.Unlock()
+
+	for _, policy := range policies {
+		if err := policy(params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func clearBootPolicies() {
+	bootPoliciesLock.Lock()
+	bootPolicies = nil
+	bootPoliciesLock.Unlock()
+}
+
+func TestBootPolicyMutation(t *testing.T) {
+	defer clearBootPolicies()
+
+	RegisterBootPolicy(func(params *BootParams) error {
+		params.KernelParams += " iommu=on"
+		return nil
+	})
+
+	params := &BootParams{
+		PodID:        testPodID,
+		KernelParams: "root=/dev/pmem0p1",
+	}
+
+	if err := applyBootPolicies(params); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "root=/dev/pmem0p1 iommu=on"
+	if params.KernelParams != expected {
+		t.Fatalf("Got %s\nExpecting %s", params.KernelParams, expected)
+	}
+}
+
+func TestBootPolicyRejection(t *testing.T) {
+	defer clearBootPolicies()
+
+	RegisterBootPolicy(func(params *BootParams) error {
+		for _, d := range params.Devices {
+			if strings.Contains(d, "virtio-9p-pci") {
+				return fmt.Errorf("Filesystem sharing is not allowed")
+			}
+		}
+
+		return nil
+	})
+
+	params := &BootParams{
+		PodID:   testPodID,
+		Devices: []string{"-device virtio-9p-pci,fsdev=shared9p"},
+	}
+
+	if err := applyBootPolicies(params); err == nil {
+		t.Fatal("A policy rejection should fail the launch")
+	}
+
+	params.Devices = []string{"-device virtio-serial-pci,id=serial0"}
+	if err := applyBootPolicies(params); err != nil {
+		t.Fatal(err)
+	}
+}
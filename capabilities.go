@@ -19,6 +19,9 @@ package virtcontainers
 const (
 	blockDeviceSupport = 1 << iota
 	blockDeviceHotplugSupport
+	netDeviceHotplugSupport
+	cpuHotplugSupport
+	memoryHotplugSupport
 )
 
 type capabilities struct {
@@ -46,3 +49,27 @@ func (caps *capabilities) isBlockDeviceHotplugSupported() bool {
 func (caps *capabilities) setBlockDeviceHotplugSupport() {
 	caps.flags |= blockDeviceHotplugSupport
 }
+
+func (caps *capabilities) isNetDeviceHotplugSupported() bool {
+	return caps.flags&netDeviceHotplugSupport != 0
+}
+
+func (caps *capabilities) setNetDeviceHotplugSupport() {
+	caps.flags |= netDeviceHotplugSupport
+}
+
+func (caps *capabilities) isCPUHotplugSupported() bool {
+	return caps.flags&cpuHotplugSupport != 0
+}
+
+func (caps *capabilities) setCPUHotplugSupport() {
+	caps.flags |= cpuHotplugSupport
+}
+
+func (caps *capabilities) isMemoryHotplugSupported() bool {
+	return caps.flags&memoryHotplugSupport != 0
+}
+
+func (caps *capabilities) setMemoryHotplugSupport() {
+	caps.flags |= memoryHotplugSupport
+}
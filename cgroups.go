@@ -0,0 +1,371 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupsConfig configures the host cgroup the pod host processes
+// (hypervisor, proxy and shims) are placed into.
+type CgroupsConfig struct {
+	// Path is the cgroup path relative to the cgroup root. An empty
+	// path disables host cgroup placement.
+	Path string
+
+	// Resources are the host resource limits applied to the cgroup.
+	Resources CgroupResources
+}
+
+// CgroupResources are the host resource limits applied to the pod
+// cgroup. They map to the unified cgroup v2 interface files, and are
+// translated to the equivalent v1 controller files on legacy
+// hierarchies.
+type CgroupResources struct {
+	// CPUQuota and CPUPeriod bound the CPU time of the cgroup, they
+	// map to cpu.max. A quota of 0 or less means no bound.
+	CPUQuota  int64
+	CPUPeriod uint64
+
+	// MemoryLimitBytes maps to memory.max, 0 means no limit.
+	MemoryLimitBytes int64
+
+	// IOMax are raw io.max entries ("major:minor rbps=... wbps=..."),
+	// only supported on cgroup v2.
+	IOMax []string
+}
+
+// PriorityClass expresses how strongly the pod host processes compete
+// for host CPU and IO against other pods, so best-effort pods cannot
+// starve guaranteed ones.
+type PriorityClass string
+
+const (
+	// PriorityClassGuaranteed gets the highest scheduling weights.
+	PriorityClassGuaranteed PriorityClass = "guaranteed"
+
+	// PriorityClassBurstable gets the default scheduling weights.
+	PriorityClassBurstable PriorityClass = "burstable"
+
+	// PriorityClassBestEffort gets the lowest scheduling weights.
+	PriorityClassBestEffort PriorityClass = "best-effort"
+)
+
+// priorityWeights holds the scheduler weights a priority class
+// translates to, on both cgroup hierarchies.
+type priorityWeights struct {
+	// cpuWeight maps to the v2 cpu.weight file (range 1-10000).
+	cpuWeight uint64
+
+	// ioWeight maps to the v2 io.weight file (range 1-10000).
+	ioWeight uint64
+
+	// cpuShares maps to the v1 cpu.shares file.
+	cpuShares uint64
+
+	// blkioWeight maps to the v1 blkio.weight file (range 100-1000).
+	blkioWeight uint64
+}
+
+// weights translates a priority class into scheduler weights.
+func (class PriorityClass) weights() (priorityWeights, error) {
+	switch class {
+	case PriorityClassGuaranteed:
+		return priorityWeights{cpuWeight: 1000, ioWeight: 1000, cpuShares: 10240, blkioWeight: 1000}, nil
+	case PriorityClassBurstable:
+		return priorityWeights{cpuWeight: 100, ioWeight: 100, cpuShares: 1024, blkioWeight: 500}, nil
+	case PriorityClassBestEffort:
+		return priorityWeights{cpuWeight: 10, ioWeight: 10, cpuShares: 102, blkioWeight: 100}, nil
+	default:
+		return priorityWeights{}, fmt.Errorf("Unknown priority class %s", class)
+	}
+}
+
+// cgroupRootPath is the host cgroup filesystem mount point.
+var cgroupRootPath = "/sys/fs/cgroup"
+
+// isCgroupV2 returns true when the host uses the unified cgroup v2
+// hierarchy.
+func isCgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRootPath, "cgroup.controllers"))
+	return err == nil
+}
+
+// hostCgroup handles the host cgroup of a pod on both cgroup v1 and
+// v2 hierarchies.
+type hostCgroup struct {
+	config   CgroupsConfig
+	priority PriorityClass
+	v2       bool
+}
+
+// newHostCgroup creates the pod host cgroup and applies the configured
+// resource limits to it.
+func newHostCgroup(config CgroupsConfig, priority PriorityClass) (*hostCgroup, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("Host cgroup requires a path")
+	}
+
+	cg := &hostCgroup{
+		config:   config,
+		priority: priority,
+		v2:       isCgroupV2(),
+	}
+
+	for _, path := range cg.paths() {
+		if err := os.MkdirAll(path, dirMode); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cg.applyResources(); err != nil {
+		return nil, err
+	}
+
+	return cg, nil
+}
+
+// paths returns the absolute directories backing the cgroup. The
+// unified hierarchy has a single directory, v1 has one per controller.
+func (cg *hostCgroup) paths() []string {
+	if cg.v2 {
+		return []string{filepath.Join(cgroupRootPath, cg.config.Path)}
+	}
+
+	paths := []string{
+		filepath.Join(cgroupRootPath, "cpu", cg.config.Path),
+		filepath.Join(cgroupRootPath, "memory", cg.config.Path),
+	}
+
+	// A priority class sets an IO weight, which lives in the blkio
+	// hierarchy on v1. The processes must join it for the weight to
+	// apply.
+	if cg.priority != "" {
+		paths = append(paths, filepath.Join(cgroupRootPath, "blkio", cg.config.Path))
+	}
+
+	return paths
+}
+
+// cpuMaxString formats the cgroup v2 cpu.max value.
+func (r CgroupResources) cpuMaxString() string {
+	period := r.CPUPeriod
+	if period == 0 {
+		period = 100000
+	}
+
+	if r.CPUQuota <= 0 {
+		return fmt.Sprintf("max %d", period)
+	}
+
+	return fmt.Sprintf("%d %d", r.CPUQuota, period)
+}
+
+// applyResources writes the configured limits to the cgroup interface
+// files.
+func (cg *hostCgroup) applyResources() error {
+	r := cg.config.Resources
+
+	if cg.v2 {
+		path := filepath.Join(cgroupRootPath, cg.config.Path)
+
+		if r.CPUQuota > 0 || r.CPUPeriod > 0 {
+			if err := writeCgroupFile(filepath.Join(path, "cpu.max"), r.cpuMaxString()); err != nil {
+				return err
+			}
+		}
+
+		if r.MemoryLimitBytes > 0 {
+			if err := writeCgroupFile(filepath.Join(path, "memory.max"), strconv.FormatInt(r.MemoryLimitBytes, 10)); err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range r.IOMax {
+			if err := writeCgroupFile(filepath.Join(path, "io.max"), entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(r.IOMax) > 0 {
+		return fmt.Errorf("io.max entries are only supported on cgroup v2")
+	}
+
+	cpuPath := filepath.Join(cgroupRootPath, "cpu", cg.config.Path)
+	if r.CPUQuota > 0 {
+		if err := writeCgroupFile(filepath.Join(cpuPath, "cpu.cfs_quota_us"), strconv.FormatInt(r.CPUQuota, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CPUPeriod > 0 {
+		if err := writeCgroupFile(filepath.Join(cpuPath, "cpu.cfs_period_us"), strconv.FormatUint(r.CPUPeriod, 10)); err != nil {
+			return err
+		}
+	}
+
+	if r.MemoryLimitBytes > 0 {
+		memoryPath := filepath.Join(cgroupRootPath, "memory", cg.config.Path)
+		if err := writeCgroupFile(filepath.Join(memoryPath, "memory.limit_in_bytes"), strconv.FormatInt(r.MemoryLimitBytes, 10)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyPriority writes the scheduler weights of the configured priority
+// class to the cgroup. The IO weight files only exist with an IO
+// scheduler supporting them, so their absence is tolerated.
+func (cg *hostCgroup) applyPriority() error {
+	if cg.priority == "" {
+		return nil
+	}
+
+	weights, err := cg.priority.weights()
+	if err != nil {
+		return err
+	}
+
+	if cg.v2 {
+		path := filepath.Join(cgroupRootPath, cg.config.Path)
+
+		if err := writeCgroupFile(filepath.Join(path, "cpu.weight"), strconv.FormatUint(weights.cpuWeight, 10)); err != nil {
+			return err
+		}
+
+		if err := writeCgroupFile(filepath.Join(path, "io.weight"), strconv.FormatUint(weights.ioWeight, 10)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	cpuPath := filepath.Join(cgroupRootPath, "cpu", cg.config.Path)
+	if err := writeCgroupFile(filepath.Join(cpuPath, "cpu.shares"), strconv.FormatUint(weights.cpuShares, 10)); err != nil {
+		return err
+	}
+
+	blkioPath := filepath.Join(cgroupRootPath, "blkio", cg.config.Path)
+	if err := writeCgroupFile(filepath.Join(blkioPath, "blkio.weight"), strconv.FormatUint(weights.blkioWeight, 10)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// addProcess moves the given process into the cgroup.
+func (cg *hostCgroup) addProcess(pid int) error {
+	for _, path := range cg.paths() {
+		if err := writeCgroupFile(filepath.Join(path, "cgroup.procs"), strconv.Itoa(pid)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// delete removes the cgroup directories. It fails if processes are
+// still inside the cgroup.
+func (cg *hostCgroup) delete() error {
+	for _, path := range cg.paths() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCgroupFile writes a value to a cgroup interface file.
+func writeCgroupFile(path, value string) error {
+	return ioutil.WriteFile(path, []byte(strings.TrimSpace(value)+"\n"), 0600)
+}
+
+// setupHostCgroup creates the pod host cgroup and moves the hypervisor
+// and proxy processes into it.
+func (p *Pod) setupHostCgroup() error {
+	if p.config.Cgroups.Path == "" {
+		return nil
+	}
+
+	cg, err := newHostCgroup(p.config.Cgroups, p.config.Priority)
+	if err != nil {
+		return err
+	}
+
+	if err := cg.applyPriority(); err != nil {
+		return err
+	}
+
+	if state, ok := p.hypervisor.getState().(QemuState); ok && state.UUID != "" {
+		if pid, err := findHypervisorPid(state.UUID); err == nil {
+			if err := cg.addProcess(pid); err != nil {
+				return err
+			}
+		}
+	}
+
+	var agentState struct {
+		ProxyPid int
+	}
+	if err := p.storage.fetchAgentState(p.id, &agentState); err == nil && agentState.ProxyPid > 0 {
+		if err := cg.addProcess(agentState.ProxyPid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addProcessToHostCgroup moves the container shim process into the pod
+// host cgroup.
+func (c *Container) addProcessToHostCgroup() error {
+	if c.pod.config.Cgroups.Path == "" || c.process.Pid <= 0 {
+		return nil
+	}
+
+	cg, err := newHostCgroup(c.pod.config.Cgroups, c.pod.config.Priority)
+	if err != nil {
+		return err
+	}
+
+	return cg.addProcess(c.process.Pid)
+}
+
+// deleteHostCgroup removes the pod host cgroup, once its processes are
+// gone.
+func (p *Pod) deleteHostCgroup() error {
+	if p.config.Cgroups.Path == "" {
+		return nil
+	}
+
+	cg := &hostCgroup{
+		config:   p.config.Cgroups,
+		priority: p.config.Priority,
+		v2:       isCgroupV2(),
+	}
+
+	return cg.delete()
+}
@@ -0,0 +1,261 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestCgroupRoot redirects the cgroup root to a temporary
+// directory, pretending it is a v2 unified hierarchy when v2 is set.
+func newTestCgroupRoot(t *testing.T, v2 bool) string {
+	root, err := ioutil.TempDir(testDir, "cgroup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v2 {
+		if err := ioutil.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory io\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedRoot := cgroupRootPath
+	cgroupRootPath = root
+
+	t.Cleanup(func() {
+		cgroupRootPath = savedRoot
+		os.RemoveAll(root)
+	})
+
+	return root
+}
+
+func checkCgroupFile(t *testing.T, path, expected string) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := strings.TrimSpace(string(content)); value != expected {
+		t.Fatalf("Got %q in %s\nExpecting %q", value, path, expected)
+	}
+}
+
+func TestCPUMaxString(t *testing.T) {
+	tests := []struct {
+		resources CgroupResources
+		expected  string
+	}{
+		{CgroupResources{}, "max 100000"},
+		{CgroupResources{CPUPeriod: 50000}, "max 50000"},
+		{CgroupResources{CPUQuota: 150000, CPUPeriod: 100000}, "150000 100000"},
+		{CgroupResources{CPUQuota: 50000}, "50000 100000"},
+	}
+
+	for _, test := range tests {
+		if s := test.resources.cpuMaxString(); s != test.expected {
+			t.Fatalf("Got %q for %+v\nExpecting %q", s, test.resources, test.expected)
+		}
+	}
+}
+
+func TestHostCgroupV2(t *testing.T) {
+	root := newTestCgroupRoot(t, true)
+
+	config := CgroupsConfig{
+		Path: "vc/test-pod",
+		Resources: CgroupResources{
+			CPUQuota:         150000,
+			CPUPeriod:        100000,
+			MemoryLimitBytes: 512 << 20,
+			IOMax:            []string{"8:0 rbps=1048576"},
+		},
+	}
+
+	cg, err := newHostCgroup(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(root, config.Path)
+	checkCgroupFile(t, filepath.Join(path, "cpu.max"), "150000 100000")
+	checkCgroupFile(t, filepath.Join(path, "memory.max"), "536870912")
+	checkCgroupFile(t, filepath.Join(path, "io.max"), "8:0 rbps=1048576")
+
+	if err := cg.addProcess(1234); err != nil {
+		t.Fatal(err)
+	}
+	checkCgroupFile(t, filepath.Join(path, "cgroup.procs"), "1234")
+
+	// On a real cgroup filesystem the interface files disappear with
+	// the directory, remove them to emulate that behavior here.
+	for _, file := range []string{"cpu.max", "memory.max", "io.max", "cgroup.procs"} {
+		if err := os.Remove(filepath.Join(path, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cg.delete(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Cgroup directory should be gone")
+	}
+}
+
+func TestHostCgroupV1(t *testing.T) {
+	root := newTestCgroupRoot(t, false)
+
+	config := CgroupsConfig{
+		Path: "vc/test-pod",
+		Resources: CgroupResources{
+			CPUQuota:         150000,
+			CPUPeriod:        100000,
+			MemoryLimitBytes: 512 << 20,
+		},
+	}
+
+	cg, err := newHostCgroup(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpuPath := filepath.Join(root, "cpu", config.Path)
+	checkCgroupFile(t, filepath.Join(cpuPath, "cpu.cfs_quota_us"), "150000")
+	checkCgroupFile(t, filepath.Join(cpuPath, "cpu.cfs_period_us"), "100000")
+
+	memoryPath := filepath.Join(root, "memory", config.Path)
+	checkCgroupFile(t, filepath.Join(memoryPath, "memory.limit_in_bytes"), "536870912")
+
+	if err := cg.addProcess(1234); err != nil {
+		t.Fatal(err)
+	}
+	checkCgroupFile(t, filepath.Join(cpuPath, "cgroup.procs"), "1234")
+	checkCgroupFile(t, filepath.Join(memoryPath, "cgroup.procs"), "1234")
+}
+
+func TestHostCgroupV1IOMax(t *testing.T) {
+	newTestCgroupRoot(t, false)
+
+	config := CgroupsConfig{
+		Path: "vc/test-pod",
+		Resources: CgroupResources{
+			IOMax: []string{"8:0 rbps=1048576"},
+		},
+	}
+
+	if _, err := newHostCgroup(config, ""); err == nil {
+		t.Fatal("io.max entries should fail on cgroup v1")
+	}
+}
+
+func TestHostCgroupNoPath(t *testing.T) {
+	if _, err := newHostCgroup(CgroupsConfig{}, ""); err == nil {
+		t.Fatal("Empty cgroup path should fail")
+	}
+}
+
+func TestPriorityClassWeights(t *testing.T) {
+	if _, err := PriorityClass("premium").weights(); err == nil {
+		t.Fatal("An unknown priority class should be rejected")
+	}
+
+	guaranteed, err := PriorityClassGuaranteed.weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bestEffort, err := PriorityClassBestEffort.weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if guaranteed.cpuWeight <= bestEffort.cpuWeight {
+		t.Fatal("Guaranteed pods should outweigh best-effort ones")
+	}
+}
+
+func TestHostCgroupPriorityV2(t *testing.T) {
+	root := newTestCgroupRoot(t, true)
+
+	config := CgroupsConfig{
+		Path: "vc/test-pod",
+	}
+
+	cg, err := newHostCgroup(config, PriorityClassBestEffort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cg.applyPriority(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(root, config.Path)
+	checkCgroupFile(t, filepath.Join(path, "cpu.weight"), "10")
+	checkCgroupFile(t, filepath.Join(path, "io.weight"), "10")
+
+	// An empty class keeps the host defaults.
+	cg, err = newHostCgroup(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cg.applyPriority(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHostCgroupPriorityV1(t *testing.T) {
+	root := newTestCgroupRoot(t, false)
+
+	config := CgroupsConfig{
+		Path: "vc/test-pod",
+	}
+
+	cg, err := newHostCgroup(config, PriorityClassGuaranteed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cg.applyPriority(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkCgroupFile(t, filepath.Join(root, "cpu", config.Path, "cpu.shares"), "10240")
+	checkCgroupFile(t, filepath.Join(root, "blkio", config.Path, "blkio.weight"), "1000")
+
+	// The processes join the blkio hierarchy too, otherwise the IO
+	// weight applies to an empty cgroup.
+	if err := cg.addProcess(4242); err != nil {
+		t.Fatal(err)
+	}
+
+	checkCgroupFile(t, filepath.Join(root, "blkio", config.Path, "cgroup.procs"), "4242")
+
+	// delete covers the blkio directory too, it would be leaked
+	// otherwise.
+	if len(cg.paths()) != 3 {
+		t.Fatalf("Got %v\nExpecting the blkio hierarchy to be included", cg.paths())
+	}
+}
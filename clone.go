@@ -0,0 +1,202 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/containers/virtcontainers/pkg/uuid"
+)
+
+// ioctlFIClone is the FICLONE ioctl, sharing the extents of a source
+// file with a destination file on filesystems that support reflinks.
+const ioctlFIClone = 0x40049409
+
+// CloneOptions overrides parts of the source pod configuration when
+// cloning a pod.
+type CloneOptions struct {
+	// ID is the clone pod ID. An empty ID gets one generated.
+	ID string
+
+	// Hostname overrides the pod hostname. An empty hostname keeps
+	// the source one.
+	Hostname string
+
+	// Annotations are merged over the source pod annotations.
+	Annotations map[string]string
+}
+
+// cloneFile duplicates a regular file, sharing its extents through a
+// reflink when the filesystem supports it and falling back to a full
+// copy otherwise.
+func cloneFile(src, dst string, mode os.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFile.Fd(), ioctlFIClone, srcFile.Fd()); errno == 0 {
+		return nil
+	}
+
+	_, err = io.Copy(dstFile, srcFile)
+
+	return err
+}
+
+// clonePath duplicates a file or a directory tree.
+func clonePath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode().IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+		entries, err := ioutil.ReadDir(src)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := clonePath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+
+		return os.Symlink(target, dst)
+
+	case info.Mode().IsRegular():
+		return cloneFile(src, dst, info.Mode().Perm())
+
+	default:
+		// Sockets, devices and fifos are recreated by the
+		// workload, not worth cloning.
+		return nil
+	}
+}
+
+// cloneRootFsPath returns the path the rootfs of a cloned container is
+// duplicated to, a sibling of the source rootfs named after the clone
+// pod.
+func cloneRootFsPath(srcRootFs, clonePodID string) string {
+	return filepath.Join(filepath.Dir(srcRootFs),
+		fmt.Sprintf("%s-%s", filepath.Base(srcRootFs), clonePodID))
+}
+
+// cloneConfig builds the configuration of a pod clone: the source
+// configuration with the options applied, and every container rootfs
+// duplicated so that the clone and the source do not share writable
+// disks.
+func (p *Pod) cloneConfig(options CloneOptions) (PodConfig, error) {
+	cloneConfig := *(p.config)
+
+	cloneConfig.ID = options.ID
+	if cloneConfig.ID == "" {
+		cloneConfig.ID = uuid.Generate().String()
+	}
+
+	if options.Hostname != "" {
+		cloneConfig.Hostname = options.Hostname
+	}
+
+	annotations := make(map[string]string)
+	for key, value := range p.config.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range options.Annotations {
+		annotations[key] = value
+	}
+	cloneConfig.Annotations = annotations
+
+	cloneConfig.Containers = append([]ContainerConfig{}, p.config.Containers...)
+
+	for idx, container := range cloneConfig.Containers {
+		if container.RootFs == "" {
+			continue
+		}
+
+		rootFs := cloneRootFsPath(container.RootFs, cloneConfig.ID)
+
+		if err := clonePath(container.RootFs, rootFs); err != nil {
+			return PodConfig{}, err
+		}
+
+		cloneConfig.Containers[idx].RootFs = rootFs
+	}
+
+	return cloneConfig, nil
+}
+
+// ClonePod duplicates a stopped pod: its disks are duplicated through
+// reflinks when the filesystem supports them, and its configuration is
+// reused with the given overrides applied. The clone is created but
+// not started.
+func ClonePod(srcPodID string, options CloneOptions) (_ VCPod, err error) {
+	defer auditTrail.record("ClonePod", srcPodID, "", time.Now(), &err)
+
+	if srcPodID == "" {
+		return nil, errNeedPodID
+	}
+
+	lockFile, err := rLockPod(srcPodID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockPod(lockFile)
+
+	src, err := fetchPod(srcPodID)
+	if err != nil {
+		return nil, err
+	}
+
+	if src.state.State != StateStopped {
+		return nil, fmt.Errorf("Pod not stopped, impossible to clone")
+	}
+
+	cloneConfig, err := src.cloneConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return createPodFromConfig(cloneConfig)
+}
@@ -0,0 +1,135 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClonePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vc-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("rootfs content")
+	if err := ioutil.WriteFile(filepath.Join(src, "subdir", "file"), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("subdir/file", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if err := clonePath(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	cloned, err := ioutil.ReadFile(filepath.Join(dst, "subdir", "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(cloned) != string(content) {
+		t.Fatalf("Got %q\nExpecting %q", cloned, content)
+	}
+
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if target != "subdir/file" {
+		t.Fatalf("Got symlink target %q\nExpecting subdir/file", target)
+	}
+}
+
+func TestCloneConfigOverrides(t *testing.T) {
+	pod := &Pod{
+		config: &PodConfig{
+			ID:       testPodID,
+			Hostname: "source-host",
+			Annotations: map[string]string{
+				"keep":     "source",
+				"override": "source",
+			},
+		},
+	}
+
+	cloneConfig, err := pod.cloneConfig(CloneOptions{
+		ID:       "clone-pod",
+		Hostname: "clone-host",
+		Annotations: map[string]string{
+			"override": "clone",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cloneConfig.ID != "clone-pod" || cloneConfig.Hostname != "clone-host" {
+		t.Fatalf("Got %s/%s\nExpecting clone-pod/clone-host", cloneConfig.ID, cloneConfig.Hostname)
+	}
+
+	if cloneConfig.Annotations["keep"] != "source" || cloneConfig.Annotations["override"] != "clone" {
+		t.Fatalf("Unexpected clone annotations %v", cloneConfig.Annotations)
+	}
+
+	// The source annotations are left untouched.
+	if pod.config.Annotations["override"] != "source" {
+		t.Fatal("The source pod annotations have been modified")
+	}
+}
+
+func TestCloneConfigGeneratesID(t *testing.T) {
+	pod := &Pod{
+		config: &PodConfig{
+			ID: testPodID,
+		},
+	}
+
+	cloneConfig, err := pod.cloneConfig(CloneOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cloneConfig.ID == "" || cloneConfig.ID == testPodID {
+		t.Fatalf("Got clone ID %q\nExpecting a fresh generated one", cloneConfig.ID)
+	}
+}
+
+func TestClonePodFailures(t *testing.T) {
+	if _, err := ClonePod("", CloneOptions{}); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	cleanUp()
+
+	if _, err := ClonePod(testPodID, CloneOptions{}); err == nil {
+		t.Fatal("Cloning a non existing pod should fail")
+	}
+}
@@ -0,0 +1,293 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// vc is a command-line tool built on the virtcontainers public API,
+// for managing pods directly when debugging appliance or runtime
+// deployments. Pod defaults come from a TOML runtime configuration
+// file handled by pkg/config.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	vc "github.com/containers/virtcontainers"
+	"github.com/containers/virtcontainers/pkg/config"
+	"github.com/containers/virtcontainers/pkg/uuid"
+	"github.com/urfave/cli"
+)
+
+func loadPodConfig(context *cli.Context) (vc.PodConfig, error) {
+	configPath := context.GlobalString("config")
+	if configPath == "" {
+		return vc.PodConfig{}, fmt.Errorf("Missing --config option")
+	}
+
+	podConfig, err := config.LoadConfig(configPath)
+	if err != nil {
+		return vc.PodConfig{}, err
+	}
+
+	podConfig.ID = context.String("id")
+	if podConfig.ID == "" {
+		podConfig.ID = uuid.Generate().String()
+	}
+
+	return podConfig, nil
+}
+
+func podID(context *cli.Context) (string, error) {
+	id := context.Args().First()
+	if id == "" {
+		return "", fmt.Errorf("Missing pod ID argument")
+	}
+
+	return id, nil
+}
+
+var createCommand = cli.Command{
+	Name:  "create",
+	Usage: "create a pod from the runtime configuration",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the pod identifier (default: auto-generated)",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		podConfig, err := loadPodConfig(context)
+		if err != nil {
+			return err
+		}
+
+		pod, err := vc.CreatePod(podConfig)
+		if err != nil {
+			return fmt.Errorf("Could not create pod: %s", err)
+		}
+
+		fmt.Println(pod.ID())
+
+		return nil
+	},
+}
+
+var startCommand = cli.Command{
+	Name:      "start",
+	Usage:     "start an existing pod",
+	ArgsUsage: "<pod-id>",
+	Action: func(context *cli.Context) error {
+		id, err := podID(context)
+		if err != nil {
+			return err
+		}
+
+		pod, err := vc.StartPod(id)
+		if err != nil {
+			return fmt.Errorf("Could not start pod: %s", err)
+		}
+
+		fmt.Println(pod.ID())
+
+		return nil
+	},
+}
+
+var stopCommand = cli.Command{
+	Name:      "stop",
+	Usage:     "stop a running pod",
+	ArgsUsage: "<pod-id>",
+	Action: func(context *cli.Context) error {
+		id, err := podID(context)
+		if err != nil {
+			return err
+		}
+
+		pod, err := vc.StopPod(id)
+		if err != nil {
+			return fmt.Errorf("Could not stop pod: %s", err)
+		}
+
+		fmt.Println(pod.ID())
+
+		return nil
+	},
+}
+
+var deleteCommand = cli.Command{
+	Name:      "delete",
+	Usage:     "delete an existing pod",
+	ArgsUsage: "<pod-id>",
+	Action: func(context *cli.Context) error {
+		id, err := podID(context)
+		if err != nil {
+			return err
+		}
+
+		pod, err := vc.DeletePod(id)
+		if err != nil {
+			return fmt.Errorf("Could not delete pod: %s", err)
+		}
+
+		fmt.Println(pod.ID())
+
+		return nil
+	},
+}
+
+var listCommand = cli.Command{
+	Name:  "list",
+	Usage: "list all existing pods",
+	Action: func(context *cli.Context) error {
+		podStatusList, err := vc.ListPod()
+		if err != nil {
+			return fmt.Errorf("Could not list pods: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 8, 1, '\t', 0)
+		fmt.Fprintln(w, "POD ID\tSTATE\tHYPERVISOR\tAGENT")
+
+		for _, podStatus := range podStatusList {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				podStatus.ID,
+				podStatus.State.State,
+				podStatus.Hypervisor,
+				podStatus.Agent)
+		}
+
+		return w.Flush()
+	},
+}
+
+var statusCommand = cli.Command{
+	Name:      "status",
+	Usage:     "return a detailed pod status",
+	ArgsUsage: "<pod-id>",
+	Action: func(context *cli.Context) error {
+		id, err := podID(context)
+		if err != nil {
+			return err
+		}
+
+		podStatus, err := vc.StatusPod(id)
+		if err != nil {
+			return fmt.Errorf("Could not get pod status: %s", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "POD ID\tSTATE\tHYPERVISOR\tAGENT\n")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			podStatus.ID,
+			podStatus.State.State,
+			podStatus.Hypervisor,
+			podStatus.Agent)
+
+		fmt.Fprintf(w, "\nCONTAINER ID\tSTATE\n")
+		for _, contStatus := range podStatus.ContainersStatus {
+			fmt.Fprintf(w, "%s\t%s\n", contStatus.ID, contStatus.State.State)
+		}
+
+		return w.Flush()
+	},
+}
+
+var execCommand = cli.Command{
+	Name:      "exec",
+	Usage:     "run a command in a running container",
+	ArgsUsage: "<pod-id> <container-id> <command>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "console",
+			Usage: "the process console path",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		args := context.Args()
+		if len(args) < 3 {
+			return fmt.Errorf("Missing pod ID, container ID or command argument")
+		}
+
+		console := context.String("console")
+
+		cmd := vc.Cmd{
+			Args: strings.Split(args[2], " "),
+			Envs: []vc.EnvVar{
+				{
+					Var:   "PATH",
+					Value: "/bin:/usr/bin:/sbin:/usr/sbin",
+				},
+			},
+			WorkDir:     "/",
+			Interactive: console != "",
+			Console:     console,
+		}
+
+		if _, _, _, err := vc.EnterContainer(args[0], args[1], cmd); err != nil {
+			return fmt.Errorf("Could not exec in container: %s", err)
+		}
+
+		return nil
+	},
+}
+
+var consoleCommand = cli.Command{
+	Name:      "console",
+	Usage:     "dump the pod console log",
+	ArgsUsage: "<pod-id>",
+	Action: func(context *cli.Context) error {
+		id, err := podID(context)
+		if err != nil {
+			return err
+		}
+
+		output, err := vc.FetchConsoleLog(id)
+		if err != nil {
+			return fmt.Errorf("Could not fetch console log: %s", err)
+		}
+
+		_, err = os.Stdout.Write(output)
+
+		return err
+	},
+}
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "vc"
+	app.Usage = "manage virtcontainers pods"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config",
+			Usage: "path to the runtime TOML configuration file",
+		},
+	}
+	app.Commands = []cli.Command{
+		createCommand,
+		startCommand,
+		stopCommand,
+		deleteCommand,
+		listCommand,
+		statusCommand,
+		execCommand,
+		consoleCommand,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
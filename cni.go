@@ -167,13 +167,22 @@ func (n *cni) add(pod Pod, config NetworkConfig, netNsPath string, netNsCreated
 // remove unbridges and deletes TAP interfaces. It also removes virtual network
 // interfaces and deletes the network namespace for the CNI network.
 func (n *cni) remove(pod Pod, networkNS NetworkNamespace) error {
-	if err := removeNetworkCommon(networkNS); err != nil {
-		return err
+	// The plugins and the namespace mount are still given a chance
+	// to clean up when an endpoint fails to detach, so an
+	// intermediate failure does not pin the namespace.
+	err := removeNetworkCommon(networkNS)
+
+	if pluginErr := n.invokePluginsDelete(pod, networkNS); pluginErr != nil && err == nil {
+		err = pluginErr
 	}
 
-	if err := n.invokePluginsDelete(pod, networkNS); err != nil {
+	if nsErr := deleteNetNS(networkNS.NetNsPath, true); nsErr != nil && err == nil {
+		err = nsErr
+	}
+
+	if err != nil {
 		return err
 	}
 
-	return deleteNetNS(networkNS.NetNsPath, true)
+	return clearNetworkArtifacts(pod.id)
 }
@@ -62,9 +62,17 @@ func (n *cnm) add(pod Pod, config NetworkConfig, netNsPath string, netNsCreated
 // remove unbridges and deletes TAP interfaces. It also removes virtual network
 // interfaces and deletes the network namespace for the CNM network.
 func (n *cnm) remove(pod Pod, networkNS NetworkNamespace) error {
-	if err := removeNetworkCommon(networkNS); err != nil {
+	// The namespace mount is removed even when an endpoint fails to
+	// detach, so an intermediate failure does not pin the namespace.
+	err := removeNetworkCommon(networkNS)
+
+	if nsErr := deleteNetNS(networkNS.NetNsPath, true); nsErr != nil && err == nil {
+		err = nsErr
+	}
+
+	if err != nil {
 		return err
 	}
 
-	return deleteNetNS(networkNS.NetNsPath, true)
+	return clearNetworkArtifacts(pod.id)
 }
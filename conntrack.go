@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// ConntrackBypass describes how the host connection tracker should
+// handle the traffic of one endpoint. High-connection-rate pods such
+// as VM-backed load balancers can exhaust the host conntrack table,
+// taking down every other pod on the node.
+type ConntrackBypass struct {
+	// NoTrack marks the endpoint traffic with NOTRACK so that it
+	// never enters the host connection tracker.
+	NoTrack bool
+
+	// Zone isolates the endpoint traffic into its own conntrack
+	// zone instead of bypassing tracking entirely. It is ignored
+	// when NoTrack is set.
+	Zone uint16
+}
+
+// conntrackTools lists the commands used to install the bypass rules,
+// one for each IP protocol version.
+var conntrackTools = []string{"iptables", "ip6tables"}
+
+func (c ConntrackBypass) enabled() bool {
+	return c.NoTrack || c.Zone != 0
+}
+
+// conntrackRules returns the raw table rules implementing the bypass
+// for the given host interface, without the action (append or delete)
+// so that the same rules can be installed and removed.
+func conntrackRules(ifaceName string, bypass ConntrackBypass) [][]string {
+	target := []string{"-j", "NOTRACK"}
+	if !bypass.NoTrack {
+		target = []string{"-j", "CT", "--zone", strconv.Itoa(int(bypass.Zone))}
+	}
+
+	var rules [][]string
+	for _, rule := range [][]string{
+		{"PREROUTING", "-i", ifaceName},
+		{"OUTPUT", "-o", ifaceName},
+	} {
+		rules = append(rules, append(rule, target...))
+	}
+
+	return rules
+}
+
+func applyConntrackRules(ifaceName string, bypass ConntrackBypass, action string) error {
+	for _, tool := range conntrackTools {
+		for _, rule := range conntrackRules(ifaceName, bypass) {
+			args := append([]string{"-t", "raw", action}, rule...)
+
+			cmd := exec.Command(tool, args...)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("Could not modify the conntrack rules for %s: %s: %s",
+					ifaceName, err, output)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setupConntrackBypass installs the raw table rules keeping the
+// traffic of the given host interface out of the shared conntrack
+// table.
+func setupConntrackBypass(ifaceName string, bypass ConntrackBypass) error {
+	return applyConntrackRules(ifaceName, bypass, "-A")
+}
+
+// clearConntrackBypass removes the rules installed by
+// setupConntrackBypass.
+func clearConntrackBypass(ifaceName string, bypass ConntrackBypass) error {
+	return applyConntrackRules(ifaceName, bypass, "-D")
+}
@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConntrackRulesNoTrack(t *testing.T) {
+	rules := conntrackRules("veth0", ConntrackBypass{NoTrack: true})
+
+	expected := [][]string{
+		{"PREROUTING", "-i", "veth0", "-j", "NOTRACK"},
+		{"OUTPUT", "-o", "veth0", "-j", "NOTRACK"},
+	}
+
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Got %v\nExpecting %v", rules, expected)
+	}
+}
+
+func TestConntrackRulesZone(t *testing.T) {
+	rules := conntrackRules("veth0", ConntrackBypass{Zone: 42})
+
+	expected := [][]string{
+		{"PREROUTING", "-i", "veth0", "-j", "CT", "--zone", "42"},
+		{"OUTPUT", "-o", "veth0", "-j", "CT", "--zone", "42"},
+	}
+
+	if !reflect.DeepEqual(rules, expected) {
+		t.Fatalf("Got %v\nExpecting %v", rules, expected)
+	}
+}
+
+func TestConntrackBypassEnabled(t *testing.T) {
+	if (ConntrackBypass{}).enabled() {
+		t.Fatal("An empty conntrack bypass should be disabled")
+	}
+
+	if !(ConntrackBypass{NoTrack: true}).enabled() {
+		t.Fatal("A NOTRACK conntrack bypass should be enabled")
+	}
+
+	if !(ConntrackBypass{Zone: 1}).enabled() {
+		t.Fatal("A zoned conntrack bypass should be enabled")
+	}
+}
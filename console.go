@@ -0,0 +1,354 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// consoleLogFile is the file name storing the guest console output.
+// It lives in the pod runtime directory and survives the VM, allowing
+// post-mortem analysis of boot failures.
+const consoleLogFile = "console.log"
+
+// consoleLogRotateFile is the file name the console log gets rotated to
+// once it reaches consoleLogMaxSize bytes. Only one rotated generation
+// is kept.
+const consoleLogRotateFile = consoleLogFile + ".1"
+
+// consoleLogMaxSize is the size in bytes above which the console log
+// file gets rotated.
+const consoleLogMaxSize = int64(1 << 20)
+
+// consoleLogger reads the guest console from the hypervisor console
+// socket and appends it to a size-capped, rotated log file in the pod
+// runtime directory.
+type consoleLogger struct {
+	podID       string
+	consolePath string
+	logPath     string
+	maxSize     int64
+
+	conn net.Conn
+	file *os.File
+}
+
+// newConsoleLogger returns a console logger for the given pod.
+func newConsoleLogger(podID, consolePath string) *consoleLogger {
+	return &consoleLogger{
+		podID:       podID,
+		consolePath: consolePath,
+		logPath:     filepath.Join(runStoragePath, podID, consoleLogFile),
+		maxSize:     consoleLogMaxSize,
+	}
+}
+
+// Logger returns a logrus logger appropriate for logging console messages
+func (cl *consoleLogger) Logger() *logrus.Entry {
+	return virtLog.WithFields(logrus.Fields{
+		"subsystem": "console",
+		"pod-id":    cl.podID,
+	})
+}
+
+// rotate moves the current log file out of the way, dropping any
+// previously rotated generation.
+func (cl *consoleLogger) rotate() error {
+	if cl.file != nil {
+		cl.file.Close()
+		cl.file = nil
+	}
+
+	rotatePath := filepath.Join(filepath.Dir(cl.logPath), consoleLogRotateFile)
+	if err := os.Rename(cl.logPath, rotatePath); err != nil {
+		return err
+	}
+
+	return cl.openLog()
+}
+
+func (cl *consoleLogger) openLog() error {
+	file, err := os.OpenFile(cl.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+
+	cl.file = file
+
+	return nil
+}
+
+// write appends data to the console log file, rotating it whenever it
+// grows beyond the configured size cap.
+func (cl *consoleLogger) write(data []byte) error {
+	if cl.file == nil {
+		if err := cl.openLog(); err != nil {
+			return err
+		}
+	}
+
+	info, err := cl.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size()+int64(len(data)) > cl.maxSize {
+		if err := cl.rotate(); err != nil {
+			return err
+		}
+	}
+
+	_, err = cl.file.Write(data)
+
+	return err
+}
+
+// start connects to the console socket and copies any guest output to
+// the pod console log until the connection gets closed.
+func (cl *consoleLogger) start() error {
+	if cl.consolePath == "" {
+		return fmt.Errorf("Missing console path for pod %s", cl.podID)
+	}
+
+	conn, err := net.Dial("unix", cl.consolePath)
+	if err != nil {
+		return err
+	}
+
+	cl.conn = conn
+
+	go func() {
+		buf := make([]byte, 4096)
+
+		for {
+			n, err := cl.conn.Read(buf)
+			if n > 0 {
+				if err := cl.write(buf[:n]); err != nil {
+					cl.Logger().WithError(err).Warn("Could not write console log")
+					break
+				}
+			}
+
+			if err != nil {
+				break
+			}
+		}
+
+		cl.stop()
+	}()
+
+	return nil
+}
+
+// stop terminates the console logging.
+func (cl *consoleLogger) stop() {
+	if cl.conn != nil {
+		cl.conn.Close()
+		cl.conn = nil
+	}
+
+	if cl.file != nil {
+		cl.file.Close()
+		cl.file = nil
+	}
+}
+
+// consolePtyFile is the file name storing the slave path of the pod
+// console terminal, for pods exposing their console through a PTY.
+const consolePtyFile = "console.pty"
+
+// consolePty bridges the guest console socket to a pseudo terminal,
+// making the raw VM interactively usable from the host. It is meant
+// for pods running without an agent, where no proxy provides an
+// interactive channel into the guest.
+type consolePty struct {
+	podID       string
+	consolePath string
+	slavePath   string
+
+	master *os.File
+	conn   net.Conn
+}
+
+// newConsolePty returns a console PTY bridge for the given pod.
+func newConsolePty(podID, consolePath string) *consolePty {
+	return &consolePty{
+		podID:       podID,
+		consolePath: consolePath,
+	}
+}
+
+// Logger returns a logrus logger appropriate for logging console PTY
+// messages
+func (cp *consolePty) Logger() *logrus.Entry {
+	return virtLog.WithFields(logrus.Fields{
+		"subsystem": "console-pty",
+		"pod-id":    cp.podID,
+	})
+}
+
+// start allocates the terminal, connects to the console socket and
+// copies the data both ways until either side gets closed. The slave
+// path is stored in the pod runtime directory so that any process can
+// find the terminal to attach to.
+func (cp *consolePty) start() error {
+	if cp.consolePath == "" {
+		return fmt.Errorf("Missing console path for pod %s", cp.podID)
+	}
+
+	master, slavePath, err := newConsolePTY()
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("unix", cp.consolePath)
+	if err != nil {
+		master.Close()
+		return err
+	}
+
+	ptyFilePath := filepath.Join(runStoragePath, cp.podID, consolePtyFile)
+	if err := ioutil.WriteFile(ptyFilePath, []byte(slavePath), 0640); err != nil {
+		master.Close()
+		conn.Close()
+		return err
+	}
+
+	cp.master = master
+	cp.slavePath = slavePath
+	cp.conn = conn
+
+	// The guest output goes through a bounded buffer so a stalled
+	// terminal reader cannot make the host accumulate unbounded
+	// memory. The buffer policy decides whether the guest gets
+	// backpressure or the oldest output is dropped.
+	buf := newBoundedBuffer(ioBufferConfig())
+
+	go func() {
+		if _, err := io.Copy(buf, conn); err != nil {
+			cp.Logger().WithError(err).Debug("Console to buffer copy terminated")
+		}
+		buf.Close()
+	}()
+
+	go func() {
+		if _, err := io.Copy(master, buf); err != nil {
+			cp.Logger().WithError(err).Debug("Buffer to terminal copy terminated")
+		}
+	}()
+
+	go func() {
+		if _, err := io.Copy(conn, master); err != nil {
+			cp.Logger().WithError(err).Debug("Terminal to console copy terminated")
+		}
+	}()
+
+	return nil
+}
+
+// stop terminates the console PTY bridge.
+func (cp *consolePty) stop() {
+	if cp.conn != nil {
+		cp.conn.Close()
+		cp.conn = nil
+	}
+
+	if cp.master != nil {
+		cp.master.Close()
+		cp.master = nil
+	}
+
+	os.Remove(filepath.Join(runStoragePath, cp.podID, consolePtyFile))
+}
+
+// ConsolePty returns the path of the terminal attached to the pod
+// console, for pods exposing their console through a PTY.
+func (p *Pod) ConsolePty() (string, error) {
+	slavePath, err := ioutil.ReadFile(filepath.Join(runStoragePath, p.id, consolePtyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("No console PTY for pod %s, the console is consumed by the proxy or the console logger", p.id)
+		}
+		return "", err
+	}
+
+	return string(slavePath), nil
+}
+
+// consoleConsumedByProxy tells if the pod proxy holds the console
+// socket. In that case the console output goes through the proxy and
+// cannot be consumed from here, the console chardev socket accepting a
+// single connection.
+func consoleConsumedByProxy(proxyType ProxyType) bool {
+	switch proxyType {
+	case CCProxyType, KataProxyType:
+		return true
+	}
+
+	return false
+}
+
+// fetchConsoleLog returns the console output collected for a pod,
+// including the rotated generation if present.
+func fetchConsoleLog(podID string) ([]byte, error) {
+	if podID == "" {
+		return nil, errNeedPodID
+	}
+
+	var output []byte
+
+	podRunPath := filepath.Join(runStoragePath, podID)
+
+	for _, file := range []string{consoleLogRotateFile, consoleLogFile} {
+		path := filepath.Join(podRunPath, file)
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		output = append(output, data...)
+	}
+
+	return output, nil
+}
+
+// kernelLogStream connects to the kernel log console socket of the pod
+// and returns a stream of the guest kernel messages. The caller owns
+// the stream and must close it when done.
+func (p *Pod) kernelLogStream() (io.ReadCloser, error) {
+	if !p.config.HypervisorConfig.KernelLogConsole {
+		return nil, fmt.Errorf("Kernel log streaming requires the kernel log console to be attached")
+	}
+
+	conn, err := net.Dial("unix", filepath.Join(runStoragePath, p.id, kmsgConsoleSock))
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to the kernel log console: %v", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,314 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestConsoleLogger(t *testing.T) *consoleLogger {
+	podRunPath := filepath.Join(runStoragePath, testPodID)
+	if err := os.MkdirAll(podRunPath, dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	return newConsoleLogger(testPodID, filepath.Join(podRunPath, defaultConsole))
+}
+
+func TestConsoleLoggerWrite(t *testing.T) {
+	defer cleanUp()
+
+	cl := newTestConsoleLogger(t)
+	defer cl.stop()
+
+	data := []byte("console output\n")
+
+	if err := cl.write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(cl.logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(content, data) {
+		t.Fatalf("Got %q\nExpecting %q", content, data)
+	}
+}
+
+func TestConsoleLoggerRotate(t *testing.T) {
+	defer cleanUp()
+
+	cl := newTestConsoleLogger(t)
+	defer cl.stop()
+
+	cl.maxSize = 8
+
+	if err := cl.write([]byte("0123456\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// This write should trigger a rotation.
+	if err := cl.write([]byte("789\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	rotatePath := filepath.Join(filepath.Dir(cl.logPath), consoleLogRotateFile)
+	content, err := ioutil.ReadFile(rotatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(content, []byte("0123456\n")) {
+		t.Fatalf("Unexpected rotated content %q", content)
+	}
+
+	content, err = ioutil.ReadFile(cl.logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(content, []byte("789\n")) {
+		t.Fatalf("Unexpected current content %q", content)
+	}
+}
+
+func TestConsoleLoggerStartMissingSocket(t *testing.T) {
+	defer cleanUp()
+
+	cl := newTestConsoleLogger(t)
+
+	if err := cl.start(); err == nil {
+		t.Fatal("Console logger start should have failed")
+	}
+}
+
+func TestConsoleLoggerStartMissingConsolePath(t *testing.T) {
+	cl := newConsoleLogger(testPodID, "")
+
+	if err := cl.start(); err == nil {
+		t.Fatal("Console logger start should have failed")
+	}
+}
+
+func TestConsoleConsumedByProxy(t *testing.T) {
+	for proxyType, expected := range map[ProxyType]bool{
+		NoopProxyType: false,
+		NoProxyType:   false,
+		CCProxyType:   true,
+		KataProxyType: true,
+	} {
+		if result := consoleConsumedByProxy(proxyType); result != expected {
+			t.Fatalf("Got %t for %s\nExpecting %t", result, proxyType, expected)
+		}
+	}
+}
+
+func TestFetchConsoleLog(t *testing.T) {
+	defer cleanUp()
+
+	cl := newTestConsoleLogger(t)
+	defer cl.stop()
+
+	cl.maxSize = 8
+
+	for _, data := range []string{"0123456\n", "789\n"} {
+		if err := cl.write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := fetchConsoleLog(testPodID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("0123456\n789\n")
+	if !bytes.Equal(content, expected) {
+		t.Fatalf("Got %q\nExpecting %q", content, expected)
+	}
+}
+
+func TestFetchConsoleLogEmptyPodID(t *testing.T) {
+	if _, err := fetchConsoleLog(""); err == nil {
+		t.Fatal("Expected error for empty pod ID")
+	}
+}
+
+func TestKernelLogStreamRequiresConsole(t *testing.T) {
+	p := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			HypervisorConfig: HypervisorConfig{},
+		},
+	}
+
+	if _, err := p.kernelLogStream(); err == nil {
+		t.Fatal("Expected an error when the kernel log console is not attached")
+	}
+}
+
+func TestKernelLogStream(t *testing.T) {
+	// Keep the pod ID short so the socket path stays below the unix
+	// socket path size limit.
+	podID := "kmsg-test-pod"
+
+	podDir := filepath.Join(runStoragePath, podID)
+	if err := os.MkdirAll(podDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(podDir)
+
+	sockPath := filepath.Join(podDir, kmsgConsoleSock)
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	msg := []byte("[    0.000000] Linux version test\n")
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write(msg)
+		conn.Close()
+	}()
+
+	p := &Pod{
+		id: podID,
+		config: &PodConfig{
+			HypervisorConfig: HypervisorConfig{
+				KernelLogConsole: true,
+			},
+		},
+	}
+
+	stream, err := p.kernelLogStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(msg) {
+		t.Fatalf("Got kernel log %q\nExpecting %q", string(data), string(msg))
+	}
+}
+
+func TestConsolePtyStartMissingConsolePath(t *testing.T) {
+	cp := newConsolePty(testPodID, "")
+
+	if err := cp.start(); err == nil {
+		t.Fatal("Console PTY start should have failed")
+	}
+}
+
+func TestConsolePty(t *testing.T) {
+	defer cleanUp()
+
+	podRunPath := filepath.Join(runStoragePath, testPodID)
+	if err := os.MkdirAll(podRunPath, dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the socket path short enough for a unix socket.
+	sockDir, err := ioutil.TempDir("", "vc-console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sockDir)
+
+	consolePath := filepath.Join(sockDir, defaultConsole)
+
+	listener, err := net.Listen("unix", consolePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	cp := newConsolePty(testPodID, consolePath)
+
+	if err := cp.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cp.stop()
+
+	pod := &Pod{id: testPodID}
+
+	slavePath, err := pod.ConsolePty()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if slavePath != cp.slavePath {
+		t.Fatalf("Got slave path %s\nExpecting %s", slavePath, cp.slavePath)
+	}
+
+	if _, err := os.Stat(slavePath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsolePtyStopRemovesPtyFile(t *testing.T) {
+	defer cleanUp()
+
+	podRunPath := filepath.Join(runStoragePath, testPodID)
+	if err := os.MkdirAll(podRunPath, dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep the socket path short enough for a unix socket.
+	sockDir, err := ioutil.TempDir("", "vc-console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sockDir)
+
+	consolePath := filepath.Join(sockDir, defaultConsole)
+
+	listener, err := net.Listen("unix", consolePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	cp := newConsolePty(testPodID, consolePath)
+
+	if err := cp.start(); err != nil {
+		t.Fatal(err)
+	}
+
+	cp.stop()
+
+	pod := &Pod{id: testPodID}
+
+	if _, err := pod.ConsolePty(); err == nil {
+		t.Fatal("ConsolePty should have failed after the bridge was stopped")
+	}
+}
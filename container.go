@@ -17,7 +17,6 @@
 package virtcontainers
 
 import (
-	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -56,6 +55,33 @@ type ContainerStatus struct {
 	// for example to add additional status values required
 	// to support particular specifications.
 	Annotations map[string]string
+
+	// Devices are the guest addresses of the devices attached to
+	// the container.
+	Devices []DeviceAddress
+}
+
+// IDMap describes a mapping between container and host user or group
+// IDs, as defined by the OCI user namespace mappings.
+type IDMap struct {
+	// ContainerID is the first ID inside the container.
+	ContainerID uint32
+	// HostID is the first ID on the host the mapping starts from.
+	HostID uint32
+	// Size is the number of consecutive IDs being mapped.
+	Size uint32
+}
+
+// hostID returns the host ID a container ID maps to, following the
+// user namespace mappings.
+func hostID(mappings []IDMap, containerID uint32) (uint32, bool) {
+	for _, m := range mappings {
+		if containerID >= m.ContainerID && containerID-m.ContainerID < m.Size {
+			return m.HostID + (containerID - m.ContainerID), true
+		}
+	}
+
+	return 0, false
 }
 
 // ContainerConfig describes one container runtime configuration.
@@ -65,6 +91,22 @@ type ContainerConfig struct {
 	// RootFs is the container workload image on the host.
 	RootFs string
 
+	// RootfsLayers lists the read-only image layers the container
+	// rootfs is assembled from, ordered lowest layer first. When set,
+	// the layers are shared with the guest and the agent mounts them
+	// as an overlayfs rootfs, so no merged rootfs needs to be
+	// prepared on the host. RootFs is ignored in that case.
+	RootfsLayers []string
+
+	// DeviceReadiness declares the guest devices the container must
+	// not start before.
+	DeviceReadiness DeviceReadiness
+
+	// OOMScoreAdj is the OOM score adjustment the agent applies to
+	// the container process inside the guest. Zero keeps the guest
+	// default.
+	OOMScoreAdj int
+
 	// ReadOnlyRootfs indicates if the rootfs should be mounted readonly
 	ReadonlyRootfs bool
 
@@ -80,6 +122,52 @@ type ContainerConfig struct {
 
 	// Device configuration for devices that must be available within the container.
 	DeviceInfos []DeviceInfo
+
+	// UIDMappings is the user namespace uid mapping for the container.
+	UIDMappings []IDMap
+
+	// GIDMappings is the user namespace gid mapping for the container.
+	GIDMappings []IDMap
+
+	// Sysctls is the list of kernel parameters to apply inside the
+	// guest for this container.
+	Sysctls map[string]string
+
+	// MountLabel is the SELinux context applied to the container
+	// mounts, for agents supporting it.
+	MountLabel string
+
+	// Image is the container image reference, required when the
+	// image is pulled inside the guest.
+	Image string
+
+	// GuestImagePull requests that the agent pulls and unpacks Image
+	// inside the guest instead of sharing the rootfs from the host,
+	// so the host never handles the plaintext image.
+	GuestImagePull bool
+
+	// Resources are the container resource limits used for VM
+	// sizing.
+	Resources ContainerResources
+
+	// DedicatedVCPUs is the number of vCPUs to hotplug into the VM
+	// and reserve exclusively for this container.
+	DedicatedVCPUs uint32
+
+	// HostCPUs is the set of host CPUs the dedicated vCPU threads
+	// are pinned to. It is only used when DedicatedVCPUs is set.
+	HostCPUs []int
+
+	// BlockVolumes lists the mount destinations that should be
+	// attached as block devices rather than shared through the
+	// filesystem, when their source is backed by a block device.
+	BlockVolumes []string
+
+	// DirectVolumes lists the mount destinations that should be
+	// copied onto a freshly created disk image and attached as block
+	// devices, for workloads needing POSIX semantics that filesystem
+	// sharing cannot provide.
+	DirectVolumes []string
 }
 
 // valid checks that the container configuration is valid.
@@ -111,7 +199,8 @@ type Container struct {
 	id    string
 	podID string
 
-	rootFs string
+	rootFs       string
+	rootfsLayers []string
 
 	config *ContainerConfig
 
@@ -137,6 +226,12 @@ func (c *Container) ID() string {
 	return c.id
 }
 
+// State returns the container lifecycle state, so consumers can
+// observe it without reaching for the internal fields.
+func (c *Container) State() State {
+	return c.state
+}
+
 // Logger returns a logrus logger appropriate for logging Container messages
 func (c *Container) Logger() *logrus.Entry {
 	return virtLog.WithFields(logrus.Fields{
@@ -262,6 +357,8 @@ func (c *Container) setContainerState(state stateString) error {
 		return err
 	}
 
+	watchers.publish(c.pod.id, c.id, state)
+
 	return nil
 }
 
@@ -292,13 +389,35 @@ func (c *Container) mountSharedDirMounts(hostSharedDir, guestSharedDir string) (
 			continue
 		}
 
-		randBytes, err := generateRandomBytes(8)
-		if err != nil {
-			return nil, err
+		if c.isDirectVolume(m.Destination) {
+			directMount, err := c.attachDirectVolume(idx, m)
+			if err != nil {
+				return nil, err
+			}
+
+			sharedDirMounts = append(sharedDirMounts, directMount)
+			continue
 		}
 
-		// These mounts are created in the shared dir
-		filename := fmt.Sprintf("%s-%s-%s", c.id, hex.EncodeToString(randBytes), filepath.Base(m.Destination))
+		if c.isBlockVolume(m.Destination) {
+			blockMount, err := c.attachBlockVolume(idx, m)
+			if err != nil {
+				return nil, err
+			}
+
+			if blockMount.BlockDevice != "" {
+				sharedDirMounts = append(sharedDirMounts, blockMount)
+				continue
+			}
+
+			// The source turned out not to be block-backed, fall
+			// back to sharing it through the filesystem.
+		}
+
+		// These mounts are created in the shared dir. The name is
+		// derived from the mount destination so that it is stable
+		// across restarts of the management process.
+		filename := fmt.Sprintf("%s-%s-%s", c.id, contentID(c.id, m.Destination), filepath.Base(m.Destination))
 		mountDest := filepath.Join(hostSharedDir, c.pod.id, filename)
 
 		if err := bindMount(m.Source, mountDest, false); err != nil {
@@ -335,6 +454,154 @@ func (c *Container) mountSharedDirMounts(hostSharedDir, guestSharedDir string) (
 	return sharedDirMounts, nil
 }
 
+// isBlockVolume tells if a mount destination has been requested to be
+// attached as a block device.
+func (c *Container) isBlockVolume(destination string) bool {
+	for _, dest := range c.config.BlockVolumes {
+		if dest == destination {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDirectVolume tells if a mount destination has been requested to be
+// copied onto a dedicated disk image.
+func (c *Container) isDirectVolume(destination string) bool {
+	for _, dest := range c.config.DirectVolumes {
+		if dest == destination {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attachDirectVolume copies a host directory onto a freshly created
+// disk image, hotplugs the image into the VM and returns the mount
+// describing the device as seen from the guest. Unlike block volumes
+// there is no fallback to filesystem sharing, since direct volumes are
+// requested precisely for semantics sharing cannot provide.
+func (c *Container) attachDirectVolume(idx int, m Mount) (Mount, error) {
+	caps := c.pod.hypervisor.capabilities()
+	if !caps.isBlockDeviceHotplugSupported() {
+		return Mount{}, fmt.Errorf("Direct volume %s requires block device hotplug support from the hypervisor", m.Destination)
+	}
+
+	imagePath := filepath.Join(c.runPath, fmt.Sprintf("%s.img", contentID(c.id, m.Destination)))
+	if err := createDirectVolumeImage(imagePath, m.Source); err != nil {
+		return Mount{}, err
+	}
+
+	index, err := c.pod.getAndSetPodBlockIndex()
+	if err != nil {
+		return Mount{}, err
+	}
+
+	driveName, err := getVirtDriveName(index)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	drive := Drive{
+		File:   imagePath,
+		Format: "raw",
+		ID:     makeNameID("drive", contentID(c.id, m.Destination)),
+	}
+
+	if err := c.pod.hypervisor.hotplugAddDevice(drive, blockDev); err != nil {
+		return Mount{}, err
+	}
+
+	c.mounts[idx].BlockDevice = imagePath
+
+	c.Logger().WithFields(logrus.Fields{
+		"image-path": imagePath,
+		"mount":      m.Destination,
+	}).Info("Attached direct volume")
+
+	readonly := false
+	for _, flag := range m.Options {
+		if flag == "ro" {
+			readonly = true
+		}
+	}
+
+	return Mount{
+		Source:      filepath.Join("/dev", driveName),
+		Destination: m.Destination,
+		Type:        "ext4",
+		Options:     m.Options,
+		ReadOnly:    readonly,
+		BlockDevice: imagePath,
+	}, nil
+}
+
+// attachBlockVolume hotplugs the block device backing a container
+// volume into the VM and returns the mount describing the device as
+// seen from the guest. The returned mount is empty when the source is
+// not backed by a block device.
+func (c *Container) attachBlockVolume(idx int, m Mount) (Mount, error) {
+	devicePath, fsType, err := blockMountSource(m.Source)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	if devicePath == "" {
+		return Mount{}, nil
+	}
+
+	caps := c.pod.hypervisor.capabilities()
+	if !caps.isBlockDeviceHotplugSupported() {
+		c.Logger().WithField("mount", m.Destination).Warn("Block volume requested but the hypervisor does not support block device hotplug, falling back to filesystem sharing")
+		return Mount{}, nil
+	}
+
+	index, err := c.pod.getAndSetPodBlockIndex()
+	if err != nil {
+		return Mount{}, err
+	}
+
+	driveName, err := getVirtDriveName(index)
+	if err != nil {
+		return Mount{}, err
+	}
+
+	drive := Drive{
+		File:   devicePath,
+		Format: "raw",
+		ID:     makeNameID("drive", contentID(c.id, m.Destination)),
+	}
+
+	if err := c.pod.hypervisor.hotplugAddDevice(drive, blockDev); err != nil {
+		return Mount{}, err
+	}
+
+	c.mounts[idx].BlockDevice = devicePath
+
+	c.Logger().WithFields(logrus.Fields{
+		"device-path": devicePath,
+		"mount":       m.Destination,
+	}).Info("Attached block volume")
+
+	readonly := false
+	for _, flag := range m.Options {
+		if flag == "ro" {
+			readonly = true
+		}
+	}
+
+	return Mount{
+		Source:      filepath.Join("/dev", driveName),
+		Destination: m.Destination,
+		Type:        fsType,
+		Options:     m.Options,
+		ReadOnly:    readonly,
+		BlockDevice: devicePath,
+	}, nil
+}
+
 func (c *Container) unmountHostMounts() error {
 	for _, m := range c.mounts {
 		if m.HostPath != "" {
@@ -361,6 +628,7 @@ func newContainer(pod *Pod, contConfig ContainerConfig) (*Container, error) {
 		id:            contConfig.ID,
 		podID:         pod.id,
 		rootFs:        contConfig.RootFs,
+		rootfsLayers:  contConfig.RootfsLayers,
 		config:        &contConfig,
 		pod:           pod,
 		runPath:       filepath.Join(runStoragePath, pod.id, contConfig.ID),
@@ -429,6 +697,13 @@ func createContainer(pod *Pod, contConfig ContainerConfig) (*Container, error) {
 		}
 	}
 
+	// Hotplug the dedicated vCPUs before the agent creates the
+	// container, so the guest can build an exclusive cpuset from
+	// them.
+	if err := c.hotplugVCPUs(); err != nil {
+		return nil, err
+	}
+
 	// Attach devices
 	if err := c.attachDevices(); err != nil {
 		return nil, err
@@ -438,6 +713,12 @@ func createContainer(pod *Pod, contConfig ContainerConfig) (*Container, error) {
 	// inside the VM
 	c.getSystemMountInfo()
 
+	// Give the container the pod hosts file, so the VM hostname does
+	// not leak into it.
+	if err := c.addHostsMount(); err != nil {
+		return nil, err
+	}
+
 	if err := c.storeDevices(); err != nil {
 		return nil, err
 	}
@@ -453,6 +734,12 @@ func createContainer(pod *Pod, contConfig ContainerConfig) (*Container, error) {
 		return nil, err
 	}
 
+	// The shim belongs to the pod host cgroup, like the hypervisor
+	// and the proxy.
+	if err := c.addProcessToHostCgroup(); err != nil {
+		return nil, err
+	}
+
 	if err := c.setContainerState(StateReady); err != nil {
 		return nil, err
 	}
@@ -517,6 +804,18 @@ func (c *Container) start() error {
 		return err
 	}
 
+	// Hotplugged devices show up in the guest asynchronously, give
+	// the declared ones a chance to appear before the workload runs.
+	if err := c.waitForDeviceReadiness(); err != nil {
+		return err
+	}
+
+	// Pod-wide environment variables and secret files are injected
+	// right before the workload runs.
+	if err := c.applyPodInjection(); err != nil {
+		return err
+	}
+
 	if err := c.pod.agent.startContainer(*(c.pod), c); err != nil {
 		c.Logger().WithError(err).Error("Failed to start container")
 
@@ -526,9 +825,33 @@ func (c *Container) start() error {
 		return err
 	}
 
+	// Best effort, not every agent exposes the container output
+	// streams.
+	if err := c.startLogPipeline(); err != nil {
+		c.Logger().WithError(err).Debug("Container log pipeline not available")
+	}
+
 	return c.setContainerState(StateRunning)
 }
 
+// startLogPipeline copies the container output to the container log
+// file. The pipeline lives as long as the streams it is plugged into,
+// it terminates when the container process exits.
+func (c *Container) startLogPipeline() error {
+	streams, err := c.pod.agent.attachStreams(c.pod, *c)
+	if err != nil {
+		return err
+	}
+
+	if streams.Stdout == nil && streams.Stderr == nil {
+		return nil
+	}
+
+	newContainerLogger(c.pod.id, c.id).start(streams)
+
+	return nil
+}
+
 func (c *Container) stop() error {
 	// In case the container status has been updated implicitly because
 	// the container process has terminated, it might be possible that
@@ -596,9 +919,66 @@ func (c *Container) stop() error {
 		return err
 	}
 
+	if err := c.removeVCPUs(); err != nil {
+		return err
+	}
+
 	return c.setContainerState(StateStopped)
 }
 
+// drain stops the container with a bounded grace period: a running
+// workload first gets a SIGTERM and gracePeriodSecs seconds to exit on
+// its own, and goes through the regular stop path afterwards, which
+// kills it forcefully if it is still alive.
+func (c *Container) drain(gracePeriodSecs uint32) error {
+	if c.state.State == StateRunning && gracePeriodSecs > 0 {
+		if err := c.pod.agent.killContainer(*(c.pod), *c, syscall.SIGTERM, false); err != nil {
+			c.Logger().WithError(err).Warn("Could not signal the container workload")
+		} else {
+			deadline := time.Now().Add(time.Duration(gracePeriodSecs) * time.Second)
+
+			for time.Now().Before(deadline) {
+				if running, _ := isShimRunning(c.process.Pid); !running {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}
+
+	return c.stop()
+}
+
+// applyExecDefaults completes an exec command with the settings of the
+// container workload: a process entered with EnterContainer runs as
+// the container user and groups, in the container working directory
+// and with the container environment, unless the command specifies its
+// own. This mirrors `docker exec -u` and CRI ExecSync semantics.
+func (c *Container) applyExecDefaults(cmd Cmd) Cmd {
+	if cmd.User == "" {
+		cmd.User = c.config.Cmd.User
+	}
+
+	if cmd.PrimaryGroup == "" {
+		cmd.PrimaryGroup = c.config.Cmd.PrimaryGroup
+	}
+
+	if cmd.SupplementaryGroups == nil {
+		cmd.SupplementaryGroups = c.config.Cmd.SupplementaryGroups
+	}
+
+	if cmd.WorkDir == "" {
+		cmd.WorkDir = c.config.Cmd.WorkDir
+	}
+
+	// The container environment is inherited, with the variables of
+	// the exec command appended so that they take precedence.
+	cmd.Envs = append(append([]EnvVar{}, c.config.Cmd.Envs...), cmd.Envs...)
+
+	return cmd
+}
+
 func (c *Container) enter(cmd Cmd) (*Process, error) {
 	if err := c.checkPodRunning("enter"); err != nil {
 		return nil, err
@@ -608,7 +988,7 @@ func (c *Container) enter(cmd Cmd) (*Process, error) {
 		return nil, fmt.Errorf("Container not running, impossible to enter")
 	}
 
-	process, err := c.pod.agent.exec(c.pod, *c, cmd)
+	process, err := c.pod.agent.exec(c.pod, *c, c.applyExecDefaults(cmd))
 	if err != nil {
 		return nil, err
 	}
@@ -616,6 +996,20 @@ func (c *Container) enter(cmd Cmd) (*Process, error) {
 	return process, nil
 }
 
+// probe runs a probe command inside the container without a full exec
+// session, see ExecProbe.
+func (c *Container) probe(cmd Cmd, options ProbeOptions) (ProbeResult, error) {
+	if err := c.checkPodRunning("probe"); err != nil {
+		return ProbeResult{}, err
+	}
+
+	if c.state.State != StateRunning {
+		return ProbeResult{}, fmt.Errorf("Container not running, impossible to probe")
+	}
+
+	return c.pod.agent.execProbe(c.pod, *c, c.applyExecDefaults(cmd), options)
+}
+
 func (c *Container) kill(signal syscall.Signal, all bool) error {
 	if c.pod.state.State != StateReady && c.pod.state.State != StateRunning {
 		return fmt.Errorf("Pod not ready or running, impossible to signal the container")
@@ -640,7 +1034,43 @@ func (c *Container) processList(options ProcessListOptions) (ProcessList, error)
 	return c.pod.agent.processListContainer(*(c.pod), *c, options)
 }
 
+// blockDevicePath returns the host block device backing the container
+// rootfs, or an empty string when the rootfs is not backed by a
+// device mapper block device.
+func (c *Container) blockDevicePath() (string, error) {
+	dev, err := getDeviceForPath(c.rootFs)
+	if err == errMountPointNotFound {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	isDM, err := checkStorageDriver(dev.major, dev.minor)
+	if err != nil {
+		return "", err
+	}
+
+	if !isDM {
+		return "", nil
+	}
+
+	devicePath, _, err := getDevicePathAndFsType(dev.mountPoint)
+	if err != nil {
+		return "", err
+	}
+
+	return devicePath, nil
+}
+
 func (c *Container) hotplugDrive() error {
+	// A guest assembled overlay rootfs has no host side rootfs to
+	// attach as a block device.
+	if len(c.rootfsLayers) > 0 {
+		return nil
+	}
+
 	dev, err := getDeviceForPath(c.rootFs)
 
 	if err == errMountPointNotFound {
@@ -731,6 +1161,48 @@ func (c *Container) removeDrive() (err error) {
 	return nil
 }
 
+// hotplugVCPUs hotplugs the dedicated vCPUs requested by the container
+// and pins their threads on the host.
+func (c *Container) hotplugVCPUs() error {
+	if c.config.DedicatedVCPUs == 0 {
+		return nil
+	}
+
+	dev := CPUDevice{
+		ID:       c.id,
+		Count:    c.config.DedicatedVCPUs,
+		HostCPUs: c.config.HostCPUs,
+	}
+
+	return c.pod.hypervisor.hotplugAddDevice(dev, cpuDev)
+}
+
+// removeVCPUs unplugs the dedicated vCPUs hotplugged for the container.
+func (c *Container) removeVCPUs() error {
+	if c.config.DedicatedVCPUs == 0 {
+		return nil
+	}
+
+	dev := CPUDevice{
+		ID:    c.id,
+		Count: c.config.DedicatedVCPUs,
+	}
+
+	return c.pod.hypervisor.hotplugRemoveDevice(dev, cpuDev)
+}
+
+// deviceAddresses returns the guest addresses of the devices attached
+// to the container.
+func (c *Container) deviceAddresses() []DeviceAddress {
+	var addresses []DeviceAddress
+
+	for _, device := range c.devices {
+		addresses = append(addresses, device.guestAddress())
+	}
+
+	return addresses
+}
+
 func (c *Container) attachDevices() error {
 	for _, device := range c.devices {
 		if err := device.attach(c.pod.hypervisor, c); err != nil {
@@ -281,3 +281,88 @@ func TestCheckPodRunningSuccessful(t *testing.T) {
 	err := c.checkPodRunning("test_cmd")
 	assert.Nil(t, err, "%v", err)
 }
+
+func TestHostID(t *testing.T) {
+	mappings := []IDMap{
+		{ContainerID: 0, HostID: 100000, Size: 65536},
+		{ContainerID: 65536, HostID: 300000, Size: 1000},
+	}
+
+	data := []struct {
+		containerID uint32
+		expected    uint32
+		found       bool
+	}{
+		{0, 100000, true},
+		{1000, 101000, true},
+		{65536, 300000, true},
+		{66000, 300464, true},
+		{70000, 0, false},
+	}
+
+	for _, d := range data {
+		id, ok := hostID(mappings, d.containerID)
+		if ok != d.found {
+			t.Fatalf("Got found %v for container ID %d, expecting %v", ok, d.containerID, d.found)
+		}
+
+		if id != d.expected {
+			t.Fatalf("Got host ID %d for container ID %d, expecting %d", id, d.containerID, d.expected)
+		}
+	}
+
+	if _, ok := hostID(nil, 0); ok {
+		t.Fatal("Expecting no mapping without user namespace mappings")
+	}
+}
+
+func TestApplyExecDefaults(t *testing.T) {
+	container := Container{
+		config: &ContainerConfig{
+			Cmd: Cmd{
+				User:                "1000",
+				PrimaryGroup:        "1000",
+				SupplementaryGroups: []string{"10"},
+				WorkDir:             "/workdir",
+				Envs: []EnvVar{
+					{Var: "PATH", Value: "/usr/bin"},
+				},
+			},
+		},
+	}
+
+	cmd := container.applyExecDefaults(Cmd{Args: []string{"ps"}})
+
+	if cmd.User != "1000" || cmd.PrimaryGroup != "1000" || cmd.WorkDir != "/workdir" {
+		t.Fatalf("Expecting the container settings to be inherited, got %+v", cmd)
+	}
+
+	if !reflect.DeepEqual(cmd.SupplementaryGroups, []string{"10"}) {
+		t.Fatalf("Expecting the container supplementary groups to be inherited, got %+v", cmd.SupplementaryGroups)
+	}
+
+	if !reflect.DeepEqual(cmd.Envs, container.config.Cmd.Envs) {
+		t.Fatalf("Expecting the container environment to be inherited, got %+v", cmd.Envs)
+	}
+
+	cmd = container.applyExecDefaults(Cmd{
+		Args:    []string{"ps"},
+		User:    "0",
+		WorkDir: "/",
+		Envs: []EnvVar{
+			{Var: "DEBUG", Value: "1"},
+		},
+	})
+
+	if cmd.User != "0" || cmd.WorkDir != "/" {
+		t.Fatalf("Expecting the command settings to take precedence, got %+v", cmd)
+	}
+
+	expectedEnvs := []EnvVar{
+		{Var: "PATH", Value: "/usr/bin"},
+		{Var: "DEBUG", Value: "1"},
+	}
+	if !reflect.DeepEqual(cmd.Envs, expectedEnvs) {
+		t.Fatalf("Expecting the command environment to be appended, got %+v", cmd.Envs)
+	}
+}
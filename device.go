@@ -17,11 +17,13 @@
 package virtcontainers
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -62,6 +64,21 @@ type Device interface {
 	attach(hypervisor, *Container) error
 	detach(hypervisor) error
 	deviceType() string
+	guestAddress() DeviceAddress
+}
+
+// DeviceAddress reports where a device landed inside the guest.
+type DeviceAddress struct {
+	// ID is the device identifier passed to the hypervisor.
+	ID string
+
+	// Type is the device type (block, vfio, generic, ...).
+	Type string
+
+	// Address is the guest-visible address of the device: a /dev
+	// node path for virtio-blk devices, a PCI BDF for passthrough
+	// devices. It is empty when the address is not known.
+	Address string
 }
 
 // DeviceInfo is an embedded type that contains device data common to all types of devices.
@@ -104,12 +121,27 @@ func deviceLogger() *logrus.Entry {
 	return virtLog.WithField("subsystem", "device")
 }
 
+// contentID derives a deterministic, content-addressed identifier from
+// the given fields. Devices named this way get the same ID and hypervisor
+// command line across restarts of the management process, which keeps VM
+// templates and snapshots compatible with the pods they were created from.
+func contentID(fields ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(fields, "/")))
+	return hex.EncodeToString(hash[:8])
+}
+
 // VFIODevice is a vfio device meant to be passed to the hypervisor
 // to be used by the Virtual Machine.
 type VFIODevice struct {
 	DeviceType string
 	DeviceInfo DeviceInfo
 	BDF        string
+
+	// Functions lists the BDFs of all the functions of a
+	// multi-function device, function 0 first. The functions are
+	// attached as a unit and share one guest slot, as several GPU
+	// drivers require. BDF holds function 0 in that case.
+	Functions []string
 }
 
 func newVFIODevice(devInfo DeviceInfo) *VFIODevice {
@@ -128,7 +160,11 @@ func (device *VFIODevice) attach(h hypervisor, c *Container) error {
 		return err
 	}
 
-	// Pass all devices in iommu group
+	// Pass all devices in iommu group, grouping the functions of a
+	// multi-function device so they are attached as a unit.
+	functionsBySlot := make(map[string][]string)
+	var slots []string
+
 	for _, deviceFile := range deviceFiles {
 
 		//Get bdf of device eg 0000:00:1c.0
@@ -137,7 +173,22 @@ func (device *VFIODevice) attach(h hypervisor, c *Container) error {
 			return err
 		}
 
-		device.BDF = deviceBDF
+		slot := deviceBDF[:strings.LastIndex(deviceBDF, ".")]
+		if _, ok := functionsBySlot[slot]; !ok {
+			slots = append(slots, slot)
+		}
+		functionsBySlot[slot] = append(functionsBySlot[slot], deviceBDF)
+	}
+
+	for _, slot := range slots {
+		functions := functionsBySlot[slot]
+		sort.Strings(functions)
+
+		device.BDF = functions[0]
+		device.Functions = nil
+		if len(functions) > 1 {
+			device.Functions = functions
+		}
 
 		if err := h.addDevice(*device, vfioDev); err != nil {
 			deviceLogger().WithError(err).Error("Failed to add device")
@@ -157,6 +208,14 @@ func (device *VFIODevice) detach(h hypervisor) error {
 	return nil
 }
 
+func (device *VFIODevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:      device.DeviceInfo.ID,
+		Type:    device.DeviceType,
+		Address: device.BDF,
+	}
+}
+
 func (device *VFIODevice) deviceType() string {
 	return device.DeviceType
 }
@@ -240,14 +299,8 @@ func (vhostUserBlkDevice *VhostUserBlkDevice) Type() string {
 // vhostUserAttach handles the common logic among all of the vhost-user device's
 // attach functions
 func vhostUserAttach(device VhostUserDevice, h hypervisor, c *Container) (err error) {
-	// generate a unique ID to be used for hypervisor commandline fields
-	randBytes, err := generateRandomBytes(8)
-	if err != nil {
-		return err
-	}
-	id := hex.EncodeToString(randBytes)
-
-	device.Attrs().ID = id
+	// derive a stable ID to be used for hypervisor commandline fields
+	device.Attrs().ID = contentID(c.id, device.Attrs().SocketPath)
 
 	return h.addDevice(device, vhostuserDev)
 }
@@ -267,6 +320,13 @@ func (vhostUserNetDevice *VhostUserNetDevice) deviceType() string {
 	return vhostUserNetDevice.DeviceType
 }
 
+func (vhostUserNetDevice *VhostUserNetDevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:   vhostUserNetDevice.ID,
+		Type: vhostUserNetDevice.DeviceType,
+	}
+}
+
 //
 // VhostUserBlkDevice's implementation of the device interface:
 //
@@ -282,6 +342,13 @@ func (vhostUserBlkDevice *VhostUserBlkDevice) deviceType() string {
 	return vhostUserBlkDevice.DeviceType
 }
 
+func (vhostUserBlkDevice *VhostUserBlkDevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:   vhostUserBlkDevice.ID,
+		Type: vhostUserBlkDevice.DeviceType,
+	}
+}
+
 //
 // VhostUserSCSIDevice's implementation of the device interface:
 //
@@ -297,6 +364,13 @@ func (vhostUserSCSIDevice *VhostUserSCSIDevice) deviceType() string {
 	return vhostUserSCSIDevice.DeviceType
 }
 
+func (vhostUserSCSIDevice *VhostUserSCSIDevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:   vhostUserSCSIDevice.ID,
+		Type: vhostUserSCSIDevice.DeviceType,
+	}
+}
+
 // Long term, this should be made more configurable.  For now matching path
 // provided by CNM VPP and OVS-DPDK plugins, available at github.com/clearcontainers/vpp and
 // github.com/clearcontainers/ovsdpdk.  The plugins create the socket on the host system
@@ -352,12 +426,7 @@ func newBlockDevice(devInfo DeviceInfo) *BlockDevice {
 }
 
 func (device *BlockDevice) attach(h hypervisor, c *Container) (err error) {
-	randBytes, err := generateRandomBytes(8)
-	if err != nil {
-		return err
-	}
-
-	device.DeviceInfo.ID = hex.EncodeToString(randBytes)
+	device.DeviceInfo.ID = contentID(c.id, device.DeviceInfo.HostPath)
 
 	drive := Drive{
 		File:   device.DeviceInfo.HostPath,
@@ -418,6 +487,14 @@ func (device *BlockDevice) deviceType() string {
 	return device.DeviceType
 }
 
+func (device *BlockDevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:      device.DeviceInfo.ID,
+		Type:    device.DeviceType,
+		Address: device.VirtPath,
+	}
+}
+
 // GenericDevice refers to a device that is neither a VFIO device or block device.
 type GenericDevice struct {
 	DeviceType string
@@ -443,6 +520,14 @@ func (device *GenericDevice) deviceType() string {
 	return device.DeviceType
 }
 
+func (device *GenericDevice) guestAddress() DeviceAddress {
+	return DeviceAddress{
+		ID:      device.DeviceInfo.ID,
+		Type:    device.DeviceType,
+		Address: device.DeviceInfo.ContainerPath,
+	}
+}
+
 // isVFIO checks if the device provided is a vfio group.
 func isVFIO(hostPath string) bool {
 	// Ignore /dev/vfio/vfio character device
@@ -0,0 +1,63 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "fmt"
+
+// defaultDeviceReadinessTimeout is the number of seconds a container
+// start waits for its declared devices before giving up.
+const defaultDeviceReadinessTimeout = 10
+
+// DeviceReadiness declares the guest devices a container must not
+// start before. Hotplugged devices become visible in the guest
+// asynchronously, so GPU and block-backed workloads can otherwise
+// race their own devices.
+type DeviceReadiness struct {
+	// Devices lists the guest device paths to wait for, e.g.
+	// "/dev/nvidia0" or "/dev/vdb".
+	Devices []string
+
+	// TimeoutSeconds bounds the wait. Zero selects the default.
+	TimeoutSeconds uint
+}
+
+// deviceWaiter is implemented by agents able to wait inside the guest
+// until hotplugged devices have shown up and udev has settled.
+type deviceWaiter interface {
+	waitForDevices(pod *Pod, c *Container, devices []string, timeoutSeconds uint) error
+}
+
+// waitForDeviceReadiness blocks until every device the container
+// declared is visible in the guest.
+func (c *Container) waitForDeviceReadiness() error {
+	readiness := c.config.DeviceReadiness
+	if len(readiness.Devices) == 0 {
+		return nil
+	}
+
+	waiter, ok := c.pod.agent.(deviceWaiter)
+	if !ok {
+		return fmt.Errorf("The pod agent cannot wait for device readiness")
+	}
+
+	timeout := readiness.TimeoutSeconds
+	if timeout == 0 {
+		timeout = defaultDeviceReadinessTimeout
+	}
+
+	return waiter.waitForDevices(c.pod, c, readiness.Devices, timeout)
+}
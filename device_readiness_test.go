@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+// deviceWaitTestAgent is a noop agent recording the devices it was
+// asked to wait for.
+type deviceWaitTestAgent struct {
+	noopAgent
+	devices []string
+	timeout uint
+}
+
+func (a *deviceWaitTestAgent) waitForDevices(pod *Pod, c *Container, devices []string, timeoutSeconds uint) error {
+	a.devices = devices
+	a.timeout = timeoutSeconds
+	return nil
+}
+
+func TestWaitForDeviceReadinessNoDevices(t *testing.T) {
+	c := &Container{
+		config: &ContainerConfig{},
+		pod: &Pod{
+			agent: &noopAgent{},
+		},
+	}
+
+	if err := c.waitForDeviceReadiness(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForDeviceReadinessUnsupportedAgent(t *testing.T) {
+	c := &Container{
+		config: &ContainerConfig{
+			DeviceReadiness: DeviceReadiness{
+				Devices: []string{"/dev/vdb"},
+			},
+		},
+		pod: &Pod{
+			agent: &noopAgent{},
+		},
+	}
+
+	if err := c.waitForDeviceReadiness(); err == nil {
+		t.Fatal("An agent unable to wait for devices should be rejected")
+	}
+}
+
+func TestWaitForDeviceReadiness(t *testing.T) {
+	agent := &deviceWaitTestAgent{}
+
+	c := &Container{
+		config: &ContainerConfig{
+			DeviceReadiness: DeviceReadiness{
+				Devices: []string{"/dev/nvidia0", "/dev/vdb"},
+			},
+		},
+		pod: &Pod{
+			agent: agent,
+		},
+	}
+
+	if err := c.waitForDeviceReadiness(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(agent.devices) != 2 || agent.devices[0] != "/dev/nvidia0" {
+		t.Fatalf("Got %v\nExpecting the declared devices", agent.devices)
+	}
+
+	if agent.timeout != defaultDeviceReadinessTimeout {
+		t.Fatalf("Got %d\nExpecting the default timeout", agent.timeout)
+	}
+}
@@ -377,3 +377,52 @@ func TestAttachBlockDevice(t *testing.T) {
 	err = device.detach(hypervisor)
 	assert.Nil(t, err)
 }
+
+func TestDeviceGuestAddress(t *testing.T) {
+	block := &BlockDevice{
+		DeviceType: DeviceBlock,
+		DeviceInfo: DeviceInfo{ID: "block-id"},
+		VirtPath:   "/dev/vda",
+	}
+
+	addr := block.guestAddress()
+	if addr.ID != "block-id" || addr.Type != DeviceBlock || addr.Address != "/dev/vda" {
+		t.Fatalf("Got unexpected block device address %+v", addr)
+	}
+
+	vfio := &VFIODevice{
+		DeviceType: DeviceVFIO,
+		BDF:        "0000:00:1c.0",
+	}
+
+	addr = vfio.guestAddress()
+	if addr.Type != DeviceVFIO || addr.Address != "0000:00:1c.0" {
+		t.Fatalf("Got unexpected VFIO device address %+v", addr)
+	}
+
+	generic := &GenericDevice{
+		DeviceType: DeviceGeneric,
+		DeviceInfo: DeviceInfo{ContainerPath: "/dev/random"},
+	}
+
+	addr = generic.guestAddress()
+	if addr.Type != DeviceGeneric || addr.Address != "/dev/random" {
+		t.Fatalf("Got unexpected generic device address %+v", addr)
+	}
+}
+
+func TestContentID(t *testing.T) {
+	id := contentID("pod", "/dev/sda")
+
+	if id != contentID("pod", "/dev/sda") {
+		t.Fatal("Expected content IDs to be stable across calls")
+	}
+
+	if len(id) != 16 {
+		t.Fatalf("Got content ID length %d\nExpecting 16", len(id))
+	}
+
+	if id == contentID("pod", "/dev/sdb") {
+		t.Fatal("Expected different inputs to produce different content IDs")
+	}
+}
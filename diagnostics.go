@@ -0,0 +1,237 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diagnosticsDirName is the name of the directory, below the pod
+// runtime directory, holding the boot failure diagnostics bundle.
+const diagnosticsDirName = "diagnostics"
+
+// diagnosticsSummaryFile is the name of the diagnostics file describing
+// the boot failure itself.
+const diagnosticsSummaryFile = "summary.txt"
+
+// qmpStatusFile is the name of the diagnostics file holding the QMP
+// status of the VM at the time the boot failed.
+const qmpStatusFile = "qmp-status.json"
+
+// qemuStderrFile is the name of the file, below the pod runtime
+// directory, holding the hypervisor standard error output when the
+// hypervisor failed to launch.
+const qemuStderrFile = "hypervisor-stderr.log"
+
+// collectBootDiagnostics gathers everything useful to understand a pod
+// boot failure (guest console output, hypervisor standard error, QMP
+// status) into the pod diagnostics directory, and returns its path.
+func (p *Pod) collectBootDiagnostics(bootErr error) (string, error) {
+	dir := filepath.Join(runStoragePath, p.id, diagnosticsDirName)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return "", err
+	}
+
+	summary := fmt.Sprintf("pod: %s\ntime: %s\nerror: %v\n", p.id, time.Now().Format(time.RFC3339), bootErr)
+	if err := ioutil.WriteFile(filepath.Join(dir, diagnosticsSummaryFile), []byte(summary), 0640); err != nil {
+		return "", err
+	}
+
+	// Stop the console logger first so that all the guest output
+	// received so far reaches the console log file.
+	if p.consoleLog != nil {
+		p.consoleLog.stop()
+		p.consoleLog = nil
+	}
+
+	for _, name := range []string{consoleLogFile, consoleLogRotateFile, qemuStderrFile} {
+		src := filepath.Join(runStoragePath, p.id, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		if err := copyDiagnosticsFile(src, filepath.Join(dir, name)); err != nil {
+			p.Logger().WithError(err).WithField("file", src).Warn("Could not copy diagnostics file")
+		}
+	}
+
+	// Query the VM status over QMP, when the monitor still answers.
+	if status, err := p.hypervisor.sendCommand("query-status", nil); err == nil {
+		if data, err := json.Marshal(status); err == nil {
+			if err := ioutil.WriteFile(filepath.Join(dir, qmpStatusFile), data, 0640); err != nil {
+				p.Logger().WithError(err).Warn("Could not store the QMP status")
+			}
+		}
+	}
+
+	return dir, nil
+}
+
+func copyDiagnosticsFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+
+	return err
+}
+
+// bootFailure turns a pod boot error into a diagnosed one: the VM gets
+// killed, a diagnostics bundle is collected and the returned error
+// references it.
+func (p *Pod) bootFailure(bootErr error) error {
+	dir, err := p.collectBootDiagnostics(bootErr)
+
+	if stopErr := p.hypervisor.stopPod(); stopErr != nil {
+		p.Logger().WithError(stopErr).Warn("Could not stop the VM after a boot failure")
+	}
+
+	if err != nil {
+		p.Logger().WithError(err).Warn("Could not collect the boot diagnostics")
+		return bootErr
+	}
+
+	return fmt.Errorf("Pod boot failed: %v (diagnostics collected in %s)", bootErr, dir)
+}
+
+// guestMemoryDumpScratchFile is the name of the file, below the pod
+// runtime directory, the guest memory is captured into before being
+// copied to its destination.
+const guestMemoryDumpScratchFile = "memory-dump.tmp"
+
+// GuestMemoryDumpOptions tunes DumpGuestMemory.
+type GuestMemoryDumpOptions struct {
+	// Paging makes the hypervisor walk the guest page tables while
+	// dumping, producing an image virtual memory tools can consume
+	// directly.
+	Paging bool
+
+	// RateLimitBytesPerSec bounds the throughput of the copy to the
+	// dump destination, so that capturing a large guest does not
+	// starve the host I/O. 0 means unbounded.
+	RateLimitBytesPerSec uint64
+
+	// Progress, when set, is called repeatedly with the number of
+	// bytes copied to the destination so far and the total size of
+	// the dump.
+	Progress func(completed, total uint64)
+}
+
+// DumpGuestMemory captures an image of the guest memory into path, for
+// forensic analysis of suspicious workloads. format is the dump format
+// the hypervisor should produce, e.g. "elf", an empty format meaning
+// the hypervisor default.
+//
+// The hypervisor cannot throttle dump-guest-memory itself, so the
+// guest memory is captured into the pod runtime directory first, then
+// copied to its destination at the configured rate while reporting
+// progress.
+func (p *Pod) DumpGuestMemory(path, format string, options GuestMemoryDumpOptions) error {
+	if p.state.State != StateRunning && p.state.State != StatePaused {
+		return fmt.Errorf("Pod not running or paused, impossible to dump the guest memory")
+	}
+
+	scratch := filepath.Join(runStoragePath, p.id, guestMemoryDumpScratchFile)
+	defer os.Remove(scratch)
+
+	args := map[string]interface{}{
+		"protocol": fmt.Sprintf("file:%s", scratch),
+		"paging":   options.Paging,
+	}
+	if format != "" {
+		args["format"] = format
+	}
+
+	if _, err := p.hypervisor.sendCommand("dump-guest-memory", args); err != nil {
+		return fmt.Errorf("Could not dump the guest memory: %v", err)
+	}
+
+	return copyGuestMemoryDump(scratch, path, options)
+}
+
+// copyGuestMemoryDump copies a captured dump to its destination,
+// honoring the rate limit and progress reporting of the dump options.
+func copyGuestMemoryDump(srcPath, dstPath string, options GuestMemoryDumpOptions) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	total := uint64(info.Size())
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	chunk := make([]byte, 1<<20)
+	var completed uint64
+	start := time.Now()
+
+	for {
+		n, readErr := src.Read(chunk)
+		if n > 0 {
+			if _, err := dst.Write(chunk[:n]); err != nil {
+				return err
+			}
+
+			completed += uint64(n)
+
+			if options.Progress != nil {
+				options.Progress(completed, total)
+			}
+
+			if options.RateLimitBytesPerSec > 0 {
+				// Sleep until the amount copied so far
+				// complies with the configured rate.
+				expected := time.Duration(completed) * time.Second / time.Duration(options.RateLimitBytesPerSec)
+				if elapsed := time.Since(start); elapsed < expected {
+					time.Sleep(expected - elapsed)
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
@@ -0,0 +1,75 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyGuestMemoryDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "guest-memory-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("dump"), 1024)
+	srcPath := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(srcPath, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastCompleted, lastTotal uint64
+	options := GuestMemoryDumpOptions{
+		Progress: func(completed, total uint64) {
+			lastCompleted = completed
+			lastTotal = total
+		},
+	}
+
+	dstPath := filepath.Join(dir, "dst")
+	if err := copyGuestMemoryDump(srcPath, dstPath, options); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := ioutil.ReadFile(dstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(copied, content) {
+		t.Fatal("The copied dump does not match the captured one")
+	}
+
+	if lastCompleted != uint64(len(content)) || lastTotal != uint64(len(content)) {
+		t.Fatalf("Wrong progress report: %d/%d", lastCompleted, lastTotal)
+	}
+}
+
+func TestDumpGuestMemoryNotRunning(t *testing.T) {
+	pod := &Pod{
+		state: State{State: StateReady},
+	}
+
+	if err := pod.DumpGuestMemory("/tmp/dump", "elf", GuestMemoryDumpOptions{}); err == nil {
+		t.Fatal("Expecting an error when the pod is not running")
+	}
+}
@@ -18,6 +18,8 @@ package virtcontainers
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 // common error objects used for argument checking
@@ -28,4 +30,34 @@ var (
 	errNeedFile        = errors.New("File cannot be empty")
 	errNeedState       = errors.New("State cannot be empty")
 	errInvalidResource = errors.New("Invalid pod resource")
+
+	// errDiagnosticsDisabled is returned by HypervisorCommand when the
+	// pod has not been configured to accept diagnostic commands.
+	errDiagnosticsDisabled = errors.New("Hypervisor diagnostic commands are disabled for this pod")
+
+	// errNotQueryCommand is returned by HypervisorCommand for commands
+	// that could alter the VM state.
+	errNotQueryCommand = errors.New("Only query hypervisor commands are allowed")
+
+	// ErrHypervisorUnresponsive is returned when the hypervisor
+	// monitor does not answer within the configured QMP timeout.
+	// Callers can match on it to decide to kill the hypervisor
+	// process instead of retrying.
+	ErrHypervisorUnresponsive = errors.New("Hypervisor monitor is unresponsive")
 )
+
+// ConfigValidationError gathers all the problems found while validating
+// a configuration, so that they can be fixed in one iteration instead
+// of being discovered one at a time.
+type ConfigValidationError struct {
+	Errors []error
+}
+
+func (e *ConfigValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("Invalid configuration: %s", strings.Join(messages, "; "))
+}
@@ -0,0 +1,196 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Host paths probed by CheckHostRequirements. They are variables so
+// tests can redirect them.
+var (
+	kvmDevicePath  = "/dev/kvm"
+	sysModulePath  = "/sys/module"
+	hugePagesField = "HugePages_Total"
+)
+
+// HostRequirement is the result of a single host capability check.
+type HostRequirement struct {
+	// Name identifies the check.
+	Name string
+
+	// Satisfied is true when the host meets the requirement.
+	Satisfied bool
+
+	// Details explains the result of the check.
+	Details string
+
+	// Fatal marks requirements without which pods cannot run at
+	// all. Non-fatal requirements only disable optional features.
+	Fatal bool
+}
+
+// HostRequirementsReport is the structured outcome of
+// CheckHostRequirements.
+type HostRequirementsReport struct {
+	// Requirements is the list of individual check results.
+	Requirements []HostRequirement
+}
+
+// Satisfied returns true when every fatal requirement is met.
+func (r HostRequirementsReport) Satisfied() bool {
+	for _, req := range r.Requirements {
+		if req.Fatal && !req.Satisfied {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkKVMDevice verifies that the KVM device exists and can be
+// opened read-write.
+func checkKVMDevice() HostRequirement {
+	req := HostRequirement{
+		Name:  "kvm",
+		Fatal: true,
+	}
+
+	f, err := os.OpenFile(kvmDevicePath, os.O_RDWR, 0)
+	if err != nil {
+		req.Details = fmt.Sprintf("Could not open %s: %v", kvmDevicePath, err)
+		return req
+	}
+	f.Close()
+
+	req.Satisfied = true
+	req.Details = fmt.Sprintf("%s is accessible", kvmDevicePath)
+
+	return req
+}
+
+// checkNestedVirtualization verifies that the KVM module allows
+// nested guests, which is required when the host itself is a VM.
+func checkNestedVirtualization() HostRequirement {
+	req := HostRequirement{
+		Name: "nested-virtualization",
+	}
+
+	for _, module := range []string{"kvm_intel", "kvm_amd"} {
+		nested, err := ioutil.ReadFile(filepath.Join(sysModulePath, module, "parameters", "nested"))
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(nested))
+		if value == "Y" || value == "1" {
+			req.Satisfied = true
+			req.Details = fmt.Sprintf("%s supports nested guests", module)
+			return req
+		}
+
+		req.Details = fmt.Sprintf("%s does not support nested guests", module)
+		return req
+	}
+
+	req.Details = "No KVM module exposes a nested parameter"
+
+	return req
+}
+
+// checkVhostModules verifies that the vhost modules backing
+// accelerated networking are loaded.
+func checkVhostModules() HostRequirement {
+	req := HostRequirement{
+		Name: "vhost",
+	}
+
+	var missing []string
+	for _, module := range []string{"vhost", "vhost_net"} {
+		if _, err := os.Stat(filepath.Join(sysModulePath, module)); err != nil {
+			missing = append(missing, module)
+		}
+	}
+
+	if len(missing) > 0 {
+		req.Details = fmt.Sprintf("Missing modules: %s", strings.Join(missing, ", "))
+		return req
+	}
+
+	req.Satisfied = true
+	req.Details = "vhost and vhost_net are loaded"
+
+	return req
+}
+
+// checkHugePages verifies that the host reserved hugepages, required
+// when the hypervisor is configured with HugePages.
+func checkHugePages(memInfoPath string) HostRequirement {
+	req := HostRequirement{
+		Name: "hugepages",
+	}
+
+	f, err := os.Open(memInfoPath)
+	if err != nil {
+		req.Details = fmt.Sprintf("Could not open %s: %v", memInfoPath, err)
+		return req
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], hugePagesField) {
+			continue
+		}
+
+		total, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			break
+		}
+
+		if total > 0 {
+			req.Satisfied = true
+		}
+		req.Details = fmt.Sprintf("%d hugepages reserved", total)
+
+		return req
+	}
+
+	req.Details = fmt.Sprintf("No %s entry in %s", hugePagesField, memInfoPath)
+
+	return req
+}
+
+// CheckHostRequirements probes the host for the capabilities pod VMs
+// rely on, so configuration problems surface before CreatePod fails.
+func CheckHostRequirements() HostRequirementsReport {
+	return HostRequirementsReport{
+		Requirements: []HostRequirement{
+			checkKVMDevice(),
+			checkNestedVirtualization(),
+			checkVhostModules(),
+			checkHugePages(procMemInfo),
+		},
+	}
+}
@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostRequirementsReportSatisfied(t *testing.T) {
+	report := HostRequirementsReport{
+		Requirements: []HostRequirement{
+			{Name: "kvm", Satisfied: true, Fatal: true},
+			{Name: "hugepages", Satisfied: false},
+		},
+	}
+
+	if !report.Satisfied() {
+		t.Fatal("Report with only non-fatal failures should be satisfied")
+	}
+
+	report.Requirements[0].Satisfied = false
+	if report.Satisfied() {
+		t.Fatal("Report with a fatal failure should not be satisfied")
+	}
+}
+
+func TestCheckNestedVirtualization(t *testing.T) {
+	sysModule, err := ioutil.TempDir(testDir, "module-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sysModule)
+
+	savedPath := sysModulePath
+	sysModulePath = sysModule
+	defer func() {
+		sysModulePath = savedPath
+	}()
+
+	req := checkNestedVirtualization()
+	if req.Satisfied {
+		t.Fatal("Nested check should fail without KVM modules")
+	}
+
+	paramsDir := filepath.Join(sysModule, "kvm_intel", "parameters")
+	if err := os.MkdirAll(paramsDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(paramsDir, "nested"), []byte("Y\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	req = checkNestedVirtualization()
+	if !req.Satisfied {
+		t.Fatalf("Nested check should succeed: %s", req.Details)
+	}
+}
+
+func TestCheckHugePages(t *testing.T) {
+	memInfo, err := ioutil.TempFile(testDir, "meminfo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(memInfo.Name())
+
+	content := "MemTotal:       16384000 kB\nHugePages_Total:     512\n"
+	if err := ioutil.WriteFile(memInfo.Name(), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	req := checkHugePages(memInfo.Name())
+	if !req.Satisfied {
+		t.Fatalf("Hugepages check should succeed: %s", req.Details)
+	}
+
+	content = "MemTotal:       16384000 kB\nHugePages_Total:       0\n"
+	if err := ioutil.WriteFile(memInfo.Name(), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	req = checkHugePages(memInfo.Name())
+	if req.Satisfied {
+		t.Fatal("Hugepages check should fail with no reserved pages")
+	}
+}
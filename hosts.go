@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hostsFileName is the name of the generated hosts file in the pod
+// run directory.
+const hostsFileName = "hosts"
+
+// hostsFilePath is the location of the hosts file inside containers.
+const hostsFilePath = "/etc/hosts"
+
+// createHostsFile generates the pod hosts file in the pod run
+// directory and returns its path. The file holds the loopback
+// entries, the pod hostname and the configured host aliases, so that
+// containers do not see the VM's default hostname.
+func (p *Pod) createHostsFile() (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString("127.0.0.1\tlocalhost\n")
+	buf.WriteString("::1\tlocalhost ip6-localhost ip6-loopback\n")
+
+	if p.config.Hostname != "" {
+		fmt.Fprintf(&buf, "127.0.1.1\t%s\n", p.config.Hostname)
+	}
+
+	for _, alias := range p.config.HostAliases {
+		if alias.IP == "" || len(alias.Hostnames) == 0 {
+			return "", fmt.Errorf("Invalid host alias %+v", alias)
+		}
+
+		fmt.Fprintf(&buf, "%s\t%s\n", alias.IP, strings.Join(alias.Hostnames, " "))
+	}
+
+	if err := os.MkdirAll(p.runPath, dirMode); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(p.runPath, hostsFileName)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// addHostsMount appends a bind mount placing the pod hosts file at
+// /etc/hosts inside the container, unless the configuration already
+// provides one.
+func (c *Container) addHostsMount() error {
+	for _, m := range c.mounts {
+		if m.Destination == hostsFilePath {
+			return nil
+		}
+	}
+
+	path, err := c.pod.createHostsFile()
+	if err != nil {
+		return err
+	}
+
+	c.mounts = append(c.mounts, Mount{
+		Source:      path,
+		Destination: hostsFilePath,
+		Type:        "bind",
+		Options:     []string{"bind"},
+	})
+
+	return nil
+}
@@ -0,0 +1,129 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestHostsPod(t *testing.T, config *PodConfig) *Pod {
+	runPath, err := ioutil.TempDir(testDir, "hosts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Pod{
+		id:      "hosts-test-pod",
+		config:  config,
+		runPath: runPath,
+	}
+}
+
+func TestCreateHostsFile(t *testing.T) {
+	config := &PodConfig{
+		Hostname: "testhost",
+		HostAliases: []HostAlias{
+			{
+				IP:        "192.168.0.1",
+				Hostnames: []string{"gateway", "gw"},
+			},
+		},
+	}
+
+	pod := newTestHostsPod(t, config)
+	defer os.RemoveAll(pod.runPath)
+
+	path, err := pod.createHostsFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != filepath.Join(pod.runPath, hostsFileName) {
+		t.Fatalf("Got unexpected hosts path %q", path)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"127.0.0.1\tlocalhost",
+		"127.0.1.1\ttesthost",
+		"192.168.0.1\tgateway gw",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("Got %q\nExpecting it to contain %q", string(content), expected)
+		}
+	}
+}
+
+func TestCreateHostsFileInvalidAlias(t *testing.T) {
+	config := &PodConfig{
+		HostAliases: []HostAlias{
+			{
+				IP: "192.168.0.1",
+			},
+		},
+	}
+
+	pod := newTestHostsPod(t, config)
+	defer os.RemoveAll(pod.runPath)
+
+	if _, err := pod.createHostsFile(); err == nil {
+		t.Fatal("Expecting an error with an alias missing hostnames")
+	}
+}
+
+func TestAddHostsMount(t *testing.T) {
+	pod := newTestHostsPod(t, &PodConfig{Hostname: "testhost"})
+	defer os.RemoveAll(pod.runPath)
+
+	c := &Container{
+		id:  "hosts-test-container",
+		pod: pod,
+	}
+
+	if err := c.addHostsMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.mounts) != 1 || c.mounts[0].Destination != hostsFilePath {
+		t.Fatalf("Got mounts %+v\nExpecting a single %s mount", c.mounts, hostsFilePath)
+	}
+
+	// A hosts mount provided by the configuration takes precedence.
+	c.mounts = []Mount{
+		{
+			Source:      "/tmp/hosts",
+			Destination: hostsFilePath,
+			Type:        "bind",
+		},
+	}
+
+	if err := c.addHostsMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.mounts) != 1 || c.mounts[0].Source != "/tmp/hosts" {
+		t.Fatalf("Got mounts %+v\nExpecting the configured hosts mount only", c.mounts)
+	}
+}
@@ -17,6 +17,7 @@
 package virtcontainers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
@@ -135,6 +136,14 @@ func (h *hyper) buildHyperContainerProcess(cmd Cmd) (*hyperstart.Process, error)
 		Ambient:     cmd.Capabilities.Ambient,
 	}
 
+	for _, rlimit := range cmd.Rlimits {
+		process.Rlimits = append(process.Rlimits, hyperstart.Rlimit{
+			Type: rlimit.Type,
+			Hard: rlimit.Hard,
+			Soft: rlimit.Soft,
+		})
+	}
+
 	return process, nil
 }
 
@@ -202,7 +211,7 @@ func (h *hyper) buildNetworkInterfacesAndRoutes(pod Pod) ([]hyperstart.NetworkIf
 		}
 
 		iface := hyperstart.NetworkIface{
-			NewDevice:   endpoint.Name(),
+			NewDevice:   endpoint.GuestName(),
 			IPAddresses: ipAddresses,
 			MTU:         endpoint.Properties().Iface.MTU,
 			MACAddr:     endpoint.HardwareAddr(),
@@ -211,7 +220,7 @@ func (h *hyper) buildNetworkInterfacesAndRoutes(pod Pod) ([]hyperstart.NetworkIf
 		ifaces = append(ifaces, iface)
 
 		for _, r := range endpoint.Properties().Routes {
-			route := h.processHyperRoute(r, endpoint.Name())
+			route := h.processHyperRoute(r, endpoint.GuestName())
 			if route == nil {
 				continue
 			}
@@ -272,6 +281,18 @@ func (h *hyper) init(pod *Pod, config interface{}) (err error) {
 }
 
 func (h *hyper) createPod(pod *Pod) (err error) {
+	if pod.config.GuestSwap.Enabled {
+		return fmt.Errorf("Guest swap is not supported by the hyperstart agent")
+	}
+
+	if pod.config.MemorySoftLimitMiB > 0 {
+		return fmt.Errorf("Memory soft limits are not supported by the hyperstart agent")
+	}
+
+	if pod.config.SharePidNs {
+		return fmt.Errorf("A shared PID namespace is not supported by the hyperstart agent")
+	}
+
 	for _, socket := range h.sockets {
 		err := pod.hypervisor.addDevice(socket, serialPortDev)
 		if err != nil {
@@ -358,6 +379,18 @@ func (h *hyper) startPod(pod Pod) error {
 		return err
 	}
 
+	// Make sure the guest agent answers the version command before any
+	// other request goes through, so that an incompatible hyperstart
+	// binary in the guest image fails early and clearly.
+	versionCmd := hyperstartProxyCmd{
+		cmd:     hyperstart.Version,
+		message: nil,
+	}
+
+	if _, err := h.sendCmd(versionCmd); err != nil {
+		return fmt.Errorf("Could not negotiate the hyperstart API version: %v", err)
+	}
+
 	ifaces, routes, err := h.buildNetworkInterfacesAndRoutes(pod)
 	if err != nil {
 		return err
@@ -404,6 +437,14 @@ func (h *hyper) stopPod(pod Pod) error {
 }
 
 func (h *hyper) startOneContainer(pod Pod, c *Container) error {
+	if len(c.rootfsLayers) > 0 {
+		return fmt.Errorf("Overlay rootfs layers are not supported by the hyperstart agent")
+	}
+
+	if c.config.OOMScoreAdj != 0 {
+		return fmt.Errorf("OOM score adjustments are not supported by the hyperstart agent")
+	}
+
 	process, err := h.buildHyperContainerProcess(c.config.Cmd)
 	if err != nil {
 		return err
@@ -414,6 +455,7 @@ func (h *hyper) startOneContainer(pod Pod, c *Container) error {
 		Image:   c.id,
 		Rootfs:  rootfsDir,
 		Process: process,
+		Sysctl:  c.config.Sysctls,
 	}
 
 	container.SystemMountsInfo.BindMountDev = c.systemMountsInfo.BindMountDev
@@ -443,6 +485,12 @@ func (h *hyper) startOneContainer(pod Pod, c *Container) error {
 		return err
 	}
 
+	for _, m := range newMounts {
+		if m.BlockDevice != "" {
+			return fmt.Errorf("Block device volumes are not supported by the hyperstart agent")
+		}
+	}
+
 	fsmap := fsMapFromMounts(newMounts)
 
 	// Append container mounts for block devices passed with --device.
@@ -478,6 +526,26 @@ func (h *hyper) startOneContainer(pod Pod, c *Container) error {
 
 // createContainer is the agent Container creation implementation for hyperstart.
 func (h *hyper) createContainer(pod *Pod, c *Container) (*Process, error) {
+	// hyperstart has no support for user namespaces, fail early
+	// instead of silently dropping the requested isolation.
+	if len(c.config.UIDMappings) > 0 || len(c.config.GIDMappings) > 0 {
+		return nil, fmt.Errorf("User namespace mappings are not supported by the hyperstart agent")
+	}
+
+	if c.config.GuestImagePull {
+		return nil, fmt.Errorf("Guest image pull is not supported by the hyperstart agent")
+	}
+
+	if c.config.DedicatedVCPUs > 0 {
+		return nil, fmt.Errorf("Dedicated vCPUs are not supported by the hyperstart agent")
+	}
+
+	// hyperstart cannot apply SELinux or AppArmor labels inside the
+	// guest, warn so the requested confinement is not lost silently.
+	if c.config.Cmd.SelinuxLabel != "" || c.config.Cmd.ApparmorProfile != "" || c.config.MountLabel != "" {
+		h.Logger().WithField("container", c.id).Warn("Security labels are not supported by the hyperstart agent")
+	}
+
 	token, err := h.attach()
 	if err != nil {
 		return nil, err
@@ -558,6 +626,44 @@ func (h *hyper) killOneContainer(cID string, signal syscall.Signal, all bool) er
 	return nil
 }
 
+// attachStreams is not supported by the hyperstart agent, the
+// container stdio goes through the shim and proxy processes.
+func (h *hyper) attachStreams(pod *Pod, c Container) (*ContainerStreams, error) {
+	return nil, fmt.Errorf("Stream attachment is not supported by the hyperstart agent")
+}
+
+// addVolume exports a host directory to the running pod by bind
+// mounting it below the pod shared directory. The returned guest path
+// is relative to the shared 9p mount, which is how the hyperstart
+// agent addresses shared content.
+func (h *hyper) addVolume(pod *Pod, volume Volume) (string, error) {
+	if volume.MountTag == "" || volume.HostPath == "" {
+		return "", fmt.Errorf("Volume needs both a mount tag and a host path")
+	}
+
+	mountDest := filepath.Join(defaultSharedDir, pod.id, volume.MountTag)
+	if err := bindMount(volume.HostPath, mountDest, false); err != nil {
+		return "", err
+	}
+
+	return volume.MountTag, nil
+}
+
+// removeVolume withdraws a volume previously exported with addVolume.
+func (h *hyper) removeVolume(pod *Pod, volume Volume) error {
+	if volume.MountTag == "" {
+		return fmt.Errorf("Volume needs a mount tag")
+	}
+
+	return syscall.Unmount(filepath.Join(defaultSharedDir, pod.id, volume.MountTag), 0)
+}
+
+// execProbe is not supported by the hyperstart agent, probe commands
+// have to go through the regular exec path.
+func (h *hyper) execProbe(pod *Pod, c Container, cmd Cmd, options ProbeOptions) (ProbeResult, error) {
+	return ProbeResult{}, fmt.Errorf("Exec probes are not supported by the hyperstart agent")
+}
+
 func (h *hyper) processListContainer(pod Pod, c Container, options ProcessListOptions) (ProcessList, error) {
 	return h.processListOneContainer(pod.id, c.id, options)
 }
@@ -708,6 +814,25 @@ func (h *hyper) unregister() error {
 	return nil
 }
 
+// keepAlive checks the hyperstart agent still answers by sending a
+// ping through the proxy.
+func (h *hyper) keepAlive(pod *Pod) error {
+	pingCmd := hyperstartProxyCmd{
+		cmd:     hyperstart.Ping,
+		message: nil,
+	}
+
+	_, err := h.sendCmd(pingCmd)
+	return err
+}
+
+// resumeSession re-registers the VM and its IO streams with the
+// proxy, so a restarted proxy or management process can keep managing
+// the running containers.
+func (h *hyper) resumeSession(pod *Pod) error {
+	return h.register()
+}
+
 func (h *hyper) attach() (string, error) {
 	if err := h.connect(); err != nil {
 		return "", err
@@ -732,7 +857,17 @@ func (h *hyper) attach() (string, error) {
 	return attachVMReturn.IO.Tokens[0], nil
 }
 
-func (h *hyper) sendCmd(proxyCmd hyperstartProxyCmd) (interface{}, error) {
+func (h *hyper) sendCmd(proxyCmd hyperstartProxyCmd) (response interface{}, err error) {
+	payloadSize := 0
+	if payload, err := json.Marshal(proxyCmd.message); err == nil {
+		payloadSize = len(payload)
+	}
+
+	started := time.Now()
+	defer func() {
+		traceAgentRPC(proxyCmd.cmd, payloadSize, started, err)
+	}()
+
 	if err := h.connect(); err != nil {
 		return nil, err
 	}
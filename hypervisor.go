@@ -19,9 +19,15 @@ package virtcontainers
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/containers/virtcontainers/pkg/annotations"
 )
 
 // HypervisorType describes an hypervisor type.
@@ -46,6 +52,8 @@ const (
 	defaultMemSzMiB = 2048
 
 	defaultBridges = 1
+
+	maxDefaultBridges = 5
 )
 
 // deviceType describes a virtualized device type.
@@ -78,6 +86,9 @@ const (
 
 	// vhostuserDev is a Vhost-user device type
 	vhostuserDev
+
+	// cpuDev is the CPU device type
+	cpuDev
 )
 
 // Set sets an hypervisor type based on the input string.
@@ -129,11 +140,74 @@ func makeNameID(namedType string, id string) string {
 }
 
 // Param is a key/value representation for hypervisor and kernel parameters.
+// GuestOOMConfig configures how the guest kernel handles memory
+// pressure, instead of relying on its defaults. The knobs are applied
+// as sysctl kernel parameters at boot.
+type GuestOOMConfig struct {
+	// PanicOnOOM makes the guest kernel panic on an out of memory
+	// condition instead of killing tasks (vm.panic_on_oom).
+	PanicOnOOM bool
+
+	// OvercommitMemory sets the guest memory overcommit policy
+	// (vm.overcommit_memory: 0 heuristic, 1 always, 2 never). Nil
+	// keeps the guest default.
+	OvercommitMemory *int
+
+	// OvercommitRatio sets the percentage of memory considered
+	// allocatable with vm.overcommit_memory=2 (vm.overcommit_ratio).
+	// Nil keeps the guest default.
+	OvercommitRatio *int
+}
+
+// kernelParams translates the OOM configuration into guest kernel
+// parameters.
+func (c GuestOOMConfig) kernelParams() []Param {
+	var params []Param
+
+	if c.PanicOnOOM {
+		params = append(params, Param{"sysctl.vm.panic_on_oom", "1"})
+	}
+
+	if c.OvercommitMemory != nil {
+		params = append(params, Param{"sysctl.vm.overcommit_memory", strconv.Itoa(*c.OvercommitMemory)})
+	}
+
+	if c.OvercommitRatio != nil {
+		params = append(params, Param{"sysctl.vm.overcommit_ratio", strconv.Itoa(*c.OvercommitRatio)})
+	}
+
+	return params
+}
+
 type Param struct {
 	Key   string
 	Value string
 }
 
+// FwCfgBlob is a named blob passed to the guest through the hypervisor
+// firmware configuration (fw_cfg) device, e.g. for agent configuration,
+// policy documents or measured boot payloads.
+type FwCfgBlob struct {
+	// Name is the fw_cfg entry name the guest looks the blob up by.
+	// Guest visible entries must live under the "opt/" namespace.
+	Name string
+
+	// Path is the host path of the file holding the blob content.
+	Path string
+}
+
+// TrustedHypervisor is one entry of the host-wide hypervisor allow-list.
+// A per pod hypervisor override must match an entry by path, and by
+// content when a digest is provided.
+type TrustedHypervisor struct {
+	// Path is the trusted hypervisor binary path.
+	Path string
+
+	// Digest is the expected hex encoded SHA-512 digest of the
+	// binary. An empty digest trusts the path alone.
+	Digest string
+}
+
 // HypervisorConfig is the hypervisor configuration.
 type HypervisorConfig struct {
 	// KernelPath is the guest kernel host path.
@@ -142,6 +216,9 @@ type HypervisorConfig struct {
 	// ImagePath is the guest image host path.
 	ImagePath string
 
+	// InitrdPath is the guest initrd image host path.
+	InitrdPath string
+
 	// FirmwarePath is the bios host path
 	FirmwarePath string
 
@@ -151,6 +228,21 @@ type HypervisorConfig struct {
 	// HypervisorPath is the hypervisor executable host path.
 	HypervisorPath string
 
+	// SharedImage makes the hypervisor attach the guest image as a
+	// read-only device, so that a single image file can back many
+	// pods at the same time. The guest is expected to build a per-pod
+	// writable overlay on top of the shared rootfs.
+	SharedImage bool
+
+	// DebugConsole attaches an extra hvc console, backed by its own
+	// host socket, meant for an interactive debug shell in the guest.
+	DebugConsole bool
+
+	// KernelLogConsole attaches an extra hvc console, backed by its
+	// own host socket, and directs the guest kernel messages to it so
+	// they do not interleave with application output.
+	KernelLogConsole bool
+
 	// DisableBlockDeviceUse disallows a block device from being used.
 	DisableBlockDeviceUse bool
 
@@ -160,6 +252,10 @@ type HypervisorConfig struct {
 	// HypervisorParams are additional hypervisor parameters.
 	HypervisorParams []Param
 
+	// FwCfgBlobs are named blobs injected into the guest through the
+	// hypervisor firmware configuration (fw_cfg) device.
+	FwCfgBlobs []FwCfgBlob
+
 	// HypervisorMachineType specifies the type of machine being
 	// emulated.
 	HypervisorMachineType string
@@ -180,12 +276,31 @@ type HypervisorConfig struct {
 	// Bridges can be used to hot plug devices
 	DefaultBridges uint32
 
+	// MemSlots specifies the number of memory slots left available
+	// for memory hotplug. A value of 0 means the default.
+	MemSlots uint8
+
+	// MemMaxMiB specifies the maximum amount of memory in MiB the
+	// guest can grow to through memory hotplug. A value of 0 derives
+	// the maximum from the host memory size.
+	MemMaxMiB uint32
+
+	// MemOffsetMiB specifies the memory space in MiB reserved on top
+	// of the maximum memory for devices backed by guest memory
+	// space, such as the NVDIMM rootfs image. A value of 0 means the
+	// default.
+	MemOffsetMiB uint32
+
 	// MemPrealloc specifies if the memory should be pre-allocated
 	MemPrealloc bool
 
 	// HugePages specifies if the memory should be pre-allocated from huge pages
 	HugePages bool
 
+	// MemMergeable marks the pod memory as mergeable (MADV_MERGEABLE),
+	// allowing KSM to deduplicate identical pages across similar pods.
+	MemMergeable bool
+
 	// Realtime Used to enable/disable realtime
 	Realtime bool
 
@@ -198,6 +313,74 @@ type HypervisorConfig struct {
 	// when running on top of another VMM.
 	DisableNestingChecks bool
 
+	// EnableDiagnosticCommands allows privileged callers to send
+	// diagnostic (query) commands to the hypervisor monitor through
+	// the HypervisorCommand API.
+	EnableDiagnosticCommands bool
+
+	// QMPTimeoutSecs is the time in seconds an interaction with the
+	// hypervisor monitor may take before the hypervisor is declared
+	// unresponsive. A value of 0 means the default.
+	QMPTimeoutSecs uint32
+
+	// QMPRetries is the number of times a failed connection to the
+	// hypervisor monitor gets retried. A value of 0 means the
+	// default.
+	QMPRetries uint32
+
+	// TrackDirtyBlocks attaches a persistent dirty bitmap to every
+	// hotplugged drive, so that incremental backups only copy the
+	// blocks written since the last backup.
+	TrackDirtyBlocks bool
+
+	// GuestMemoryDump enables capturing a guest memory dump in the pod
+	// runtime directory whenever the guest kernel panics.
+	GuestMemoryDump bool
+
+	// GuestMemoryDumpSizeMiB caps the guest memory size above which no
+	// crash dump will be captured. A value of 0 means no cap.
+	GuestMemoryDumpSizeMiB uint32
+
+	// AssetTrustRootPath is the path to a PEM encoded file holding the
+	// public keys trusted to sign custom boot assets. When set, custom
+	// kernel and image assets must carry a valid detached signature.
+	AssetTrustRootPath string
+
+	// TrustedHypervisors is the host-wide allow-list a per pod
+	// hypervisor override is validated against. An empty list keeps
+	// the historical behaviour of accepting any override.
+	TrustedHypervisors []TrustedHypervisor
+
+	// ProcessLabel is the SELinux label or AppArmor profile the
+	// hypervisor process is started with, for confinement on the
+	// host side.
+	ProcessLabel string
+
+	// Nice is the nice value applied to the hypervisor process,
+	// 0 keeps the inherited priority.
+	Nice int
+
+	// IOClass is the IO scheduling class of the hypervisor process
+	// (1 realtime, 2 best-effort, 3 idle), 0 keeps the kernel
+	// default.
+	IOClass int
+
+	// IOPriority is the priority level within IOClass, from 0
+	// (highest) to 7 (lowest).
+	IOPriority int
+
+	// OOMScoreAdj adjusts the host OOM killer score of the
+	// hypervisor process, from -1000 (never kill) to 1000 (kill
+	// first). 0 keeps the inherited score.
+	OOMScoreAdj int
+
+	// Tuning groups the guest time and entropy tuning knobs.
+	Tuning TuningConfig
+
+	// GuestOOM configures how the guest kernel reacts to memory
+	// pressure.
+	GuestOOM GuestOOMConfig
+
 	// customAssets is a map of assets.
 	// Each value in that map takes precedence over the configured assets.
 	// For example, if there is a value for the "kernel" key in this map,
@@ -205,13 +388,85 @@ type HypervisorConfig struct {
 	customAssets map[assetType]*asset
 }
 
-func (conf *HypervisorConfig) valid() (bool, error) {
+// validate runs every check on the hypervisor configuration and
+// returns the complete list of problems found, so that a user can fix
+// a broken configuration in a single iteration.
+func (conf *HypervisorConfig) validate() []error {
+	var errs []error
+
 	if conf.KernelPath == "" {
-		return false, fmt.Errorf("Missing kernel path")
+		errs = append(errs, fmt.Errorf("Missing kernel path"))
 	}
 
 	if conf.ImagePath == "" {
-		return false, fmt.Errorf("Missing image path")
+		errs = append(errs, fmt.Errorf("Missing image path"))
+	}
+
+	// Boot assets must be readable when configured.
+	assetPaths := map[string]string{
+		"kernel":     conf.KernelPath,
+		"image":      conf.ImagePath,
+		"initrd":     conf.InitrdPath,
+		"firmware":   conf.FirmwarePath,
+		"hypervisor": conf.HypervisorPath,
+	}
+
+	for kind, path := range assetPaths {
+		if path == "" {
+			continue
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("Could not access %s path %s: %v", kind, path, err))
+		}
+	}
+
+	if hostCPUs := runtime.NumCPU(); conf.DefaultVCPUs > uint32(hostCPUs) {
+		errs = append(errs, fmt.Errorf("%d vCPUs requested but the host only has %d CPUs", conf.DefaultVCPUs, hostCPUs))
+	}
+
+	if conf.DefaultBridges > maxDefaultBridges {
+		errs = append(errs, fmt.Errorf("%d bridges requested but a maximum of %d is supported", conf.DefaultBridges, maxDefaultBridges))
+	}
+
+	if conf.MemMaxMiB != 0 {
+		memMiB := conf.DefaultMemSz
+		if memMiB == 0 {
+			memMiB = defaultMemSzMiB
+		}
+
+		if conf.MemMaxMiB < memMiB {
+			errs = append(errs, fmt.Errorf("Maximum memory of %dMiB is smaller than the %dMiB the guest boots with", conf.MemMaxMiB, memMiB))
+		}
+	}
+
+	for _, blob := range conf.FwCfgBlobs {
+		if blob.Name == "" {
+			errs = append(errs, fmt.Errorf("Missing fw_cfg blob name"))
+			continue
+		}
+
+		if !strings.HasPrefix(blob.Name, "opt/") {
+			errs = append(errs, fmt.Errorf("fw_cfg blob name %s must be under the opt/ namespace", blob.Name))
+		}
+
+		if blob.Path == "" {
+			errs = append(errs, fmt.Errorf("Missing path for fw_cfg blob %s", blob.Name))
+		} else if _, err := os.Stat(blob.Path); err != nil {
+			errs = append(errs, fmt.Errorf("Could not access fw_cfg blob %s path %s: %v", blob.Name, blob.Path, err))
+		}
+	}
+
+	if err := conf.Tuning.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func (conf *HypervisorConfig) valid() (bool, error) {
+	if errs := conf.validate(); len(errs) > 0 {
+		return false, &ConfigValidationError{Errors: errs}
 	}
 
 	if conf.DefaultVCPUs == 0 {
@@ -241,6 +496,59 @@ func (conf *HypervisorConfig) AddKernelParam(p Param) error {
 	return nil
 }
 
+// applyAnnotations overrides hypervisor tunables from the pod
+// annotations, so that a single pod can be tuned without changing the
+// global runtime configuration.
+func (conf *HypervisorConfig) applyAnnotations(podAnnotations map[string]string) error {
+	if value, ok := podAnnotations[annotations.MachineType]; ok && value != "" {
+		conf.HypervisorMachineType = value
+	}
+
+	if value, ok := podAnnotations[annotations.DefaultVCPUs]; ok && value != "" {
+		vcpus, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || vcpus == 0 {
+			return fmt.Errorf("Invalid default vCPUs annotation %s", value)
+		}
+
+		conf.DefaultVCPUs = uint32(vcpus)
+	}
+
+	if value, ok := podAnnotations[annotations.DefaultMemory]; ok && value != "" {
+		memSz, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || memSz == 0 {
+			return fmt.Errorf("Invalid default memory annotation %s", value)
+		}
+
+		conf.DefaultMemSz = uint32(memSz)
+	}
+
+	if value, ok := podAnnotations[annotations.KernelParams]; ok && value != "" {
+		for _, field := range strings.Fields(value) {
+			param := strings.SplitN(field, "=", 2)
+
+			p := Param{Key: param[0]}
+			if len(param) == 2 {
+				p.Value = param[1]
+			}
+
+			if err := conf.AddKernelParam(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if value, ok := podAnnotations[annotations.HypervisorDebug]; ok && value != "" {
+		debug, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Invalid hypervisor debug annotation %s", value)
+		}
+
+		conf.Debug = debug
+	}
+
+	return nil
+}
+
 func (conf *HypervisorConfig) addCustomAsset(a *asset) error {
 	if a == nil || a.path == "" {
 		// We did not get a custom asset, we will use the default one.
@@ -262,6 +570,38 @@ func (conf *HypervisorConfig) addCustomAsset(a *asset) error {
 	return nil
 }
 
+// validateTrustedHypervisor checks a per pod hypervisor override against
+// the host-wide allow-list. An entry carrying a digest additionally pins
+// the binary content, so a trusted path cannot be swapped underneath.
+func (conf *HypervisorConfig) validateTrustedHypervisor(a *asset) error {
+	if len(conf.TrustedHypervisors) == 0 {
+		return nil
+	}
+
+	for _, trusted := range conf.TrustedHypervisors {
+		if trusted.Path != a.path {
+			continue
+		}
+
+		if trusted.Digest == "" {
+			return nil
+		}
+
+		digest, err := a.hash(annotations.SHA512)
+		if err != nil {
+			return err
+		}
+
+		if digest == trusted.Digest {
+			return nil
+		}
+
+		return fmt.Errorf("Hypervisor %s does not match its trusted digest", a.path)
+	}
+
+	return fmt.Errorf("Hypervisor %s is not in the trusted hypervisors list", a.path)
+}
+
 func (conf *HypervisorConfig) assetPath(t assetType) (string, error) {
 	// Custom assets take precedence over the configured ones
 	a, ok := conf.customAssets[t]
@@ -276,6 +616,8 @@ func (conf *HypervisorConfig) assetPath(t assetType) (string, error) {
 		return conf.KernelPath, nil
 	case imageAsset:
 		return conf.ImagePath, nil
+	case initrdAsset:
+		return conf.InitrdPath, nil
 	case hypervisorAsset:
 		return conf.HypervisorPath, nil
 	case firmwareAsset:
@@ -314,6 +656,16 @@ func (conf *HypervisorConfig) CustomImageAsset() bool {
 	return conf.isCustomAsset(imageAsset)
 }
 
+// InitrdAssetPath returns the guest initrd path
+func (conf *HypervisorConfig) InitrdAssetPath() (string, error) {
+	return conf.assetPath(initrdAsset)
+}
+
+// CustomInitrdAsset returns true if the initrd asset is a custom one, false otherwise.
+func (conf *HypervisorConfig) CustomInitrdAsset() bool {
+	return conf.isCustomAsset(initrdAsset)
+}
+
 // HypervisorAssetPath returns the VM hypervisor path
 func (conf *HypervisorConfig) HypervisorAssetPath() (string, error) {
 	return conf.assetPath(hypervisorAsset)
@@ -379,6 +731,48 @@ func DeserializeParams(parameters []string) []Param {
 	return params
 }
 
+// hostMemoryCache caches the host memory size between pod creations.
+// The host memory is not set in stone: when running nested or on a
+// host supporting memory hot-add it can change, so the cache gets
+// refreshed before each pod creation.
+type hostMemoryCache struct {
+	sync.Mutex
+	sizeKb uint64
+	valid  bool
+}
+
+var hostMemory = &hostMemoryCache{}
+
+// get returns the cached host memory size, reading it from the host
+// on first use or after a refresh.
+func (h *hostMemoryCache) get() (uint64, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.valid {
+		return h.sizeKb, nil
+	}
+
+	sizeKb, err := getHostMemorySizeKb(procMemInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	h.sizeKb = sizeKb
+	h.valid = true
+
+	return sizeKb, nil
+}
+
+// refresh drops the cached host memory size so the next query re-reads
+// it from the host.
+func (h *hostMemoryCache) refresh() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.valid = false
+}
+
 func getHostMemorySizeKb(memInfoPath string) (uint64, error) {
 	f, err := os.Open(memInfoPath)
 	if err != nil {
@@ -412,6 +806,53 @@ func getHostMemorySizeKb(memInfoPath string) (uint64, error) {
 	return 0, fmt.Errorf("unable get MemTotal from %s", memInfoPath)
 }
 
+// dmiIDPath is the DMI identification sysfs directory.
+var dmiIDPath = "/sys/class/dmi/id"
+
+// vmmDMIPrefixes are DMI vendor and product strings betraying that
+// the host is itself a VM.
+var vmmDMIPrefixes = []string{
+	"QEMU",
+	"KVM",
+	"VMware",
+	"VirtualBox",
+	"innotek GmbH",
+	"Xen",
+	"Microsoft Corporation",
+	"Bochs",
+}
+
+// RunningOnVMMDMI checks the DMI identification strings for a
+// virtual machine signature. It complements RunningOnVMM for VMMs
+// that do not expose the CPUID hypervisor flag.
+func RunningOnVMMDMI(dmiPath string) bool {
+	for _, file := range []string{"product_name", "sys_vendor", "board_vendor"} {
+		content, err := ioutil.ReadFile(filepath.Join(dmiPath, file))
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(content))
+		for _, prefix := range vmmDMIPrefixes {
+			if strings.HasPrefix(value, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// runningOnVMM combines the CPUID and DMI probes to decide whether
+// the host is itself a VM.
+func runningOnVMM() bool {
+	if nested, err := RunningOnVMM(procCPUInfo); err == nil && nested {
+		return true
+	}
+
+	return RunningOnVMMDMI(dmiIDPath)
+}
+
 // RunningOnVMM checks if the system is running inside a VM.
 func RunningOnVMM(cpuInfoPath string) (bool, error) {
 	flagsField := "flags"
@@ -468,6 +909,11 @@ type hypervisor interface {
 	hotplugAddDevice(devInfo interface{}, devType deviceType) error
 	hotplugRemoveDevice(devInfo interface{}, devType deviceType) error
 	getPodConsole(podID string) string
+	sendCommand(cmd string, args map[string]interface{}) (interface{}, error)
+	attestationEvidence() (AttestationEvidence, error)
+	snapshotBlockDevice(deviceID, snapshotFile string) error
+	backupBlockDevice(deviceID, target string, incremental bool) error
+	saveState(stateFile string) error
 	capabilities() capabilities
 	getState() interface{}
 }
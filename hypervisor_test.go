@@ -23,6 +23,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	vcAnnotations "github.com/containers/virtcontainers/pkg/annotations"
 )
 
 func testSetHypervisorType(t *testing.T, value string, expected HypervisorType) {
@@ -155,6 +157,94 @@ func TestHypervisorConfigNoHypervisorPath(t *testing.T) {
 	testHypervisorConfigValid(t, hypervisorConfig, true)
 }
 
+func TestHypervisorConfigMemMaxTooSmall(t *testing.T) {
+	hypervisorConfig := &HypervisorConfig{
+		KernelPath:     fmt.Sprintf("%s/%s", testDir, testKernel),
+		ImagePath:      fmt.Sprintf("%s/%s", testDir, testImage),
+		HypervisorPath: fmt.Sprintf("%s/%s", testDir, testHypervisor),
+		DefaultMemSz:   2048,
+		MemMaxMiB:      1024,
+	}
+
+	testHypervisorConfigValid(t, hypervisorConfig, false)
+}
+
+func TestHypervisorConfigFwCfgBlobs(t *testing.T) {
+	hypervisorConfig := &HypervisorConfig{
+		KernelPath:     fmt.Sprintf("%s/%s", testDir, testKernel),
+		ImagePath:      fmt.Sprintf("%s/%s", testDir, testImage),
+		HypervisorPath: fmt.Sprintf("%s/%s", testDir, testHypervisor),
+		FwCfgBlobs: []FwCfgBlob{
+			{
+				Name: "opt/org.test/config",
+				Path: fmt.Sprintf("%s/%s", testDir, testImage),
+			},
+		},
+	}
+
+	testHypervisorConfigValid(t, hypervisorConfig, true)
+
+	// A blob outside the opt/ namespace is rejected.
+	hypervisorConfig.FwCfgBlobs[0].Name = "etc/config"
+	testHypervisorConfigValid(t, hypervisorConfig, false)
+
+	// A blob whose backing file cannot be read is rejected.
+	hypervisorConfig.FwCfgBlobs[0].Name = "opt/org.test/config"
+	hypervisorConfig.FwCfgBlobs[0].Path = "/nonexistent/blob"
+	testHypervisorConfigValid(t, hypervisorConfig, false)
+}
+
+func TestValidateTrustedHypervisor(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write(assetContent); err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+
+	a := &asset{
+		path: tmpfile.Name(),
+		kind: hypervisorAsset,
+	}
+
+	// An empty allow-list accepts any override.
+	hypervisorConfig := &HypervisorConfig{}
+	if err := hypervisorConfig.validateTrustedHypervisor(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// An override missing from the allow-list is rejected.
+	hypervisorConfig.TrustedHypervisors = []TrustedHypervisor{
+		{Path: "/usr/bin/qemu-trusted"},
+	}
+	if err := hypervisorConfig.validateTrustedHypervisor(a); err == nil {
+		t.Fatal("An untrusted hypervisor override should be rejected")
+	}
+
+	// A path only entry trusts the binary path.
+	hypervisorConfig.TrustedHypervisors = []TrustedHypervisor{
+		{Path: tmpfile.Name()},
+	}
+	if err := hypervisorConfig.validateTrustedHypervisor(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// A digest entry pins the binary content.
+	hypervisorConfig.TrustedHypervisors[0].Digest = assetContentHash
+	if err := hypervisorConfig.validateTrustedHypervisor(a); err != nil {
+		t.Fatal(err)
+	}
+
+	hypervisorConfig.TrustedHypervisors[0].Digest = assetContentWrongHash
+	if err := hypervisorConfig.validateTrustedHypervisor(a); err == nil {
+		t.Fatal("A hypervisor with the wrong digest should be rejected")
+	}
+}
+
 func TestHypervisorConfigIsValid(t *testing.T) {
 	hypervisorConfig := &HypervisorConfig{
 		KernelPath:     fmt.Sprintf("%s/%s", testDir, testKernel),
@@ -186,6 +276,93 @@ func TestHypervisorConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestHypervisorConfigValidateMultipleErrors(t *testing.T) {
+	hypervisorConfig := &HypervisorConfig{
+		KernelPath:     "",
+		ImagePath:      "",
+		DefaultBridges: maxDefaultBridges + 1,
+	}
+
+	errs := hypervisorConfig.validate()
+	if len(errs) != 3 {
+		t.Fatalf("Got %d errors, expecting 3: %v", len(errs), errs)
+	}
+
+	_, err := hypervisorConfig.valid()
+	if err == nil {
+		t.Fatal()
+	}
+
+	validationErr, ok := err.(*ConfigValidationError)
+	if !ok {
+		t.Fatalf("Expecting a ConfigValidationError, got %T", err)
+	}
+
+	if len(validationErr.Errors) != 3 {
+		t.Fatalf("Got %d errors, expecting 3: %v", len(validationErr.Errors), validationErr.Errors)
+	}
+}
+
+func TestHypervisorConfigValidateUnreadableAssets(t *testing.T) {
+	hypervisorConfig := &HypervisorConfig{
+		KernelPath: "/this/kernel/does/not/exist",
+		ImagePath:  fmt.Sprintf("%s/%s", testDir, testImage),
+	}
+
+	errs := hypervisorConfig.validate()
+	if len(errs) != 1 {
+		t.Fatalf("Got %d errors, expecting 1: %v", len(errs), errs)
+	}
+}
+
+func TestHypervisorConfigApplyAnnotations(t *testing.T) {
+	hypervisorConfig := &HypervisorConfig{}
+
+	podAnnotations := map[string]string{
+		vcAnnotations.MachineType:     "q35",
+		vcAnnotations.DefaultVCPUs:    "4",
+		vcAnnotations.DefaultMemory:   "4096",
+		vcAnnotations.KernelParams:    "quiet nosmap console=hvc0",
+		vcAnnotations.HypervisorDebug: "true",
+	}
+
+	if err := hypervisorConfig.applyAnnotations(podAnnotations); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedKernelParams := []Param{
+		{Key: "quiet"},
+		{Key: "nosmap"},
+		{Key: "console", Value: "hvc0"},
+	}
+
+	hypervisorConfigExpected := &HypervisorConfig{
+		HypervisorMachineType: "q35",
+		DefaultVCPUs:          4,
+		DefaultMemSz:          4096,
+		KernelParams:          expectedKernelParams,
+		Debug:                 true,
+	}
+
+	if reflect.DeepEqual(hypervisorConfig, hypervisorConfigExpected) == false {
+		t.Fatalf("Got %+v\nExpecting %+v", hypervisorConfig, hypervisorConfigExpected)
+	}
+}
+
+func TestHypervisorConfigApplyAnnotationsFailures(t *testing.T) {
+	for _, podAnnotations := range []map[string]string{
+		{vcAnnotations.DefaultVCPUs: "notanumber"},
+		{vcAnnotations.DefaultVCPUs: "0"},
+		{vcAnnotations.DefaultMemory: "-1"},
+		{vcAnnotations.HypervisorDebug: "maybe"},
+	} {
+		hypervisorConfig := &HypervisorConfig{}
+		if err := hypervisorConfig.applyAnnotations(podAnnotations); err == nil {
+			t.Fatalf("Expecting failure with annotations %v", podAnnotations)
+		}
+	}
+}
+
 func TestAppendParams(t *testing.T) {
 	paramList := []Param{
 		{
@@ -498,3 +675,89 @@ func TestRunningOnVMMNotExistingCPUInfoPathFailure(t *testing.T) {
 		t.Fatalf("Should fail because %q file path does not exist", filePath)
 	}
 }
+
+func TestRunningOnVMMDMI(t *testing.T) {
+	dmiPath, err := ioutil.TempDir("", "dmi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dmiPath)
+
+	if RunningOnVMMDMI(dmiPath) {
+		t.Fatal("Empty DMI directory should not look like a VM")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dmiPath, "sys_vendor"), []byte("Dell Inc.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if RunningOnVMMDMI(dmiPath) {
+		t.Fatal("Physical DMI vendor should not look like a VM")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dmiPath, "product_name"), []byte("KVM Virtual Machine\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !RunningOnVMMDMI(dmiPath) {
+		t.Fatal("KVM DMI product name should look like a VM")
+	}
+}
+
+func TestHostMemoryCache(t *testing.T) {
+	hostMemory.refresh()
+
+	sizeKb, err := hostMemory.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := getHostMemorySizeKb(procMemInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sizeKb != expected {
+		t.Fatalf("Got %dkB\nExpecting %dkB", sizeKb, expected)
+	}
+
+	// A stale value survives until the next refresh.
+	hostMemory.Lock()
+	hostMemory.sizeKb = 42
+	hostMemory.Unlock()
+
+	if sizeKb, _ := hostMemory.get(); sizeKb != 42 {
+		t.Fatalf("Got %dkB\nExpecting the cached 42kB", sizeKb)
+	}
+
+	hostMemory.refresh()
+
+	if sizeKb, _ := hostMemory.get(); sizeKb != expected {
+		t.Fatalf("Got %dkB\nExpecting %dkB after a refresh", sizeKb, expected)
+	}
+}
+
+func TestGuestOOMConfigKernelParams(t *testing.T) {
+	if params := (GuestOOMConfig{}).kernelParams(); len(params) != 0 {
+		t.Fatalf("Got %v\nExpecting no kernel parameters", params)
+	}
+
+	overcommitMemory := 2
+	overcommitRatio := 80
+
+	config := GuestOOMConfig{
+		PanicOnOOM:       true,
+		OvercommitMemory: &overcommitMemory,
+		OvercommitRatio:  &overcommitRatio,
+	}
+
+	expected := []Param{
+		{"sysctl.vm.panic_on_oom", "1"},
+		{"sysctl.vm.overcommit_memory", "2"},
+		{"sysctl.vm.overcommit_ratio", "80"},
+	}
+
+	if params := config.kernelParams(); !reflect.DeepEqual(params, expected) {
+		t.Fatalf("Got %v\nExpecting %v", params, expected)
+	}
+}
@@ -19,6 +19,7 @@
 package virtcontainers
 
 import (
+	"io"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
@@ -48,11 +49,56 @@ func (impl *VCImpl) StartPod(podID string) (VCPod, error) {
 	return StartPod(podID)
 }
 
+// AdoptPod implements the VC function of the same name.
+func (impl *VCImpl) AdoptPod(podID string) (VCPod, error) {
+	return AdoptPod(podID)
+}
+
 // StopPod implements the VC function of the same name.
 func (impl *VCImpl) StopPod(podID string) (VCPod, error) {
 	return StopPod(podID)
 }
 
+// DrainPod implements the VC function of the same name.
+func (impl *VCImpl) DrainPod(podID string, options DrainOptions) (VCPod, error) {
+	return DrainPod(podID, options)
+}
+
+// SnapshotPod implements the VC function of the same name.
+func (impl *VCImpl) SnapshotPod(podID string) ([]SnapshotHandle, error) {
+	return SnapshotPod(podID)
+}
+
+// BackupPod implements the VC function of the same name.
+func (impl *VCImpl) BackupPod(podID string, incremental bool) ([]BackupHandle, error) {
+	return BackupPod(podID, incremental)
+}
+
+// ExportPod implements the VC function of the same name.
+func (impl *VCImpl) ExportPod(podID string) ([]NBDExport, error) {
+	return ExportPod(podID)
+}
+
+// UnexportPod implements the VC function of the same name.
+func (impl *VCImpl) UnexportPod(podID string) error {
+	return UnexportPod(podID)
+}
+
+// ClonePod implements the VC function of the same name.
+func (impl *VCImpl) ClonePod(srcPodID string, options CloneOptions) (VCPod, error) {
+	return ClonePod(srcPodID, options)
+}
+
+// CreateVMTemplate implements the VC function of the same name.
+func (impl *VCImpl) CreateVMTemplate(templateDir string, podConfig PodConfig) (*VMTemplate, error) {
+	return CreateVMTemplate(templateDir, podConfig)
+}
+
+// LoadVMTemplate implements the VC function of the same name.
+func (impl *VCImpl) LoadVMTemplate(templateDir string) (*VMTemplate, error) {
+	return LoadVMTemplate(templateDir)
+}
+
 // RunPod implements the VC function of the same name.
 func (impl *VCImpl) RunPod(podConfig PodConfig) (VCPod, error) {
 	return RunPod(podConfig)
@@ -63,6 +109,11 @@ func (impl *VCImpl) ListPod() ([]PodStatus, error) {
 	return ListPod()
 }
 
+// LeakCheck implements the VC function of the same name.
+func (impl *VCImpl) LeakCheck() ([]NetworkLeak, error) {
+	return LeakCheck()
+}
+
 // StatusPod implements the VC function of the same name.
 func (impl *VCImpl) StatusPod(podID string) (PodStatus, error) {
 	return StatusPod(podID)
@@ -78,6 +129,46 @@ func (impl *VCImpl) ResumePod(podID string) (VCPod, error) {
 	return ResumePod(podID)
 }
 
+// HypervisorCommand implements the VC function of the same name.
+func (impl *VCImpl) HypervisorCommand(podID, cmd string, args map[string]interface{}) (interface{}, error) {
+	return HypervisorCommand(podID, cmd, args)
+}
+
+// AttestPod implements the VC function of the same name.
+func (impl *VCImpl) AttestPod(podID string) (AttestationEvidence, error) {
+	return AttestPod(podID)
+}
+
+// KernelLogPod implements the VC function of the same name.
+func (impl *VCImpl) KernelLogPod(podID string) (io.ReadCloser, error) {
+	return KernelLogPod(podID)
+}
+
+// UsagePod implements the VC function of the same name.
+func (impl *VCImpl) UsagePod(podID string) (PodResourceUsage, error) {
+	return UsagePod(podID)
+}
+
+// WatermarksPod implements the VC function of the same name.
+func (impl *VCImpl) WatermarksPod(podID string) (PodWatermarks, error) {
+	return WatermarksPod(podID)
+}
+
+// KSMPod implements the VC function of the same name.
+func (impl *VCImpl) KSMPod(podID string) (PodKSMStats, error) {
+	return KSMPod(podID)
+}
+
+// OverheadPod implements the VC function of the same name.
+func (impl *VCImpl) OverheadPod(podID string) (PodOverhead, error) {
+	return OverheadPod(podID)
+}
+
+// FetchConsoleLog implements the VC function of the same name.
+func (impl *VCImpl) FetchConsoleLog(podID string) ([]byte, error) {
+	return FetchConsoleLog(podID)
+}
+
 // CreateContainer implements the VC function of the same name.
 func (impl *VCImpl) CreateContainer(podID string, containerConfig ContainerConfig) (VCPod, VCContainer, error) {
 	return CreateContainer(podID, containerConfig)
@@ -103,6 +194,16 @@ func (impl *VCImpl) EnterContainer(podID, containerID string, cmd Cmd) (VCPod, V
 	return EnterContainer(podID, containerID, cmd)
 }
 
+// ExecProbe implements the VC function of the same name.
+func (impl *VCImpl) ExecProbe(podID, containerID string, cmd Cmd, options ProbeOptions) (ProbeResult, error) {
+	return ExecProbe(podID, containerID, cmd, options)
+}
+
+// ReadContainerLogs implements the VC function of the same name.
+func (impl *VCImpl) ReadContainerLogs(podID, containerID string, options ContainerLogsOptions) (io.ReadCloser, error) {
+	return ReadContainerLogs(podID, containerID, options)
+}
+
 // StatusContainer implements the VC function of the same name.
 func (impl *VCImpl) StatusContainer(podID, containerID string) (ContainerStatus, error) {
 	return StatusContainer(podID, containerID)
@@ -117,3 +218,8 @@ func (impl *VCImpl) KillContainer(podID, containerID string, signal syscall.Sign
 func (impl *VCImpl) ProcessListContainer(podID, containerID string, options ProcessListOptions) (ProcessList, error) {
 	return ProcessListContainer(podID, containerID, options)
 }
+
+// AttachContainer implements the VC function of the same name.
+func (impl *VCImpl) AttachContainer(podID, containerID string) (*ContainerStreams, error) {
+	return AttachContainer(podID, containerID)
+}
@@ -0,0 +1,112 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"os"
+)
+
+// maxSecretFileSize bounds the size of an injected secret file. The
+// agent channel is a control channel, not a data path.
+const maxSecretFileSize = 64 * 1024
+
+// SecretFile is a small secret delivered to the guest over the agent
+// channel, so its content never touches a host-shared filesystem.
+type SecretFile struct {
+	// Path is the destination path inside the container.
+	Path string
+
+	// Data is the secret content.
+	Data []byte
+
+	// Mode is the destination file mode. Zero selects 0400.
+	Mode os.FileMode
+}
+
+// PodInjection defines environment variables and secret files injected
+// into every container of a pod.
+type PodInjection struct {
+	// Envs is appended to each container command environment. A
+	// variable already set by the container keeps its value.
+	Envs []EnvVar
+
+	// Secrets lists the secret files written into each container.
+	Secrets []SecretFile
+}
+
+// secretInjector is implemented by agents able to write a file inside
+// a container through their control channel.
+type secretInjector interface {
+	injectFile(pod *Pod, c *Container, path string, data []byte, mode os.FileMode) error
+}
+
+// mergeEnvs returns the container environment extended with the pod
+// environment variables it does not already define.
+func mergeEnvs(containerEnvs, podEnvs []EnvVar) []EnvVar {
+	envs := containerEnvs
+
+	for _, podEnv := range podEnvs {
+		defined := false
+		for _, env := range containerEnvs {
+			if env.Var == podEnv.Var {
+				defined = true
+				break
+			}
+		}
+
+		if !defined {
+			envs = append(envs, podEnv)
+		}
+	}
+
+	return envs
+}
+
+// applyPodInjection injects the pod-wide environment variables and
+// secret files into the container before it starts.
+func (c *Container) applyPodInjection() error {
+	injection := c.pod.config.Injection
+
+	c.config.Cmd.Envs = mergeEnvs(c.config.Cmd.Envs, injection.Envs)
+
+	if len(injection.Secrets) == 0 {
+		return nil
+	}
+
+	injector, ok := c.pod.agent.(secretInjector)
+	if !ok {
+		return fmt.Errorf("The pod agent cannot inject secret files")
+	}
+
+	for _, secret := range injection.Secrets {
+		if len(secret.Data) > maxSecretFileSize {
+			return fmt.Errorf("Secret file %s is larger than %d bytes", secret.Path, maxSecretFileSize)
+		}
+
+		mode := secret.Mode
+		if mode == 0 {
+			mode = 0400
+		}
+
+		if err := injector.injectFile(c.pod, c, secret.Path, secret.Data, mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
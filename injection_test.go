@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// injectTestAgent is a noop agent recording the files it was asked to
+// inject.
+type injectTestAgent struct {
+	noopAgent
+	paths []string
+	data  [][]byte
+	modes []os.FileMode
+}
+
+func (a *injectTestAgent) injectFile(pod *Pod, c *Container, path string, data []byte, mode os.FileMode) error {
+	a.paths = append(a.paths, path)
+	a.data = append(a.data, data)
+	a.modes = append(a.modes, mode)
+	return nil
+}
+
+func TestMergeEnvs(t *testing.T) {
+	containerEnvs := []EnvVar{
+		{Var: "PATH", Value: "/usr/bin"},
+		{Var: "TOKEN", Value: "container"},
+	}
+
+	podEnvs := []EnvVar{
+		{Var: "TOKEN", Value: "pod"},
+		{Var: "REGION", Value: "eu-west-1"},
+	}
+
+	envs := mergeEnvs(containerEnvs, podEnvs)
+
+	if len(envs) != 3 {
+		t.Fatalf("Got %v\nExpecting 3 environment variables", envs)
+	}
+
+	for _, env := range envs {
+		if env.Var == "TOKEN" && env.Value != "container" {
+			t.Fatalf("Got %s\nExpecting the container value to win", env.Value)
+		}
+	}
+
+	if envs[2].Var != "REGION" {
+		t.Fatalf("Got %v\nExpecting the pod variable appended", envs)
+	}
+}
+
+func TestApplyPodInjectionSecrets(t *testing.T) {
+	agent := &injectTestAgent{}
+
+	c := &Container{
+		config: &ContainerConfig{},
+		pod: &Pod{
+			agent: agent,
+			config: &PodConfig{
+				Injection: PodInjection{
+					Secrets: []SecretFile{
+						{
+							Path: "/run/secrets/token",
+							Data: []byte("s3cr3t"),
+						},
+						{
+							Path: "/run/secrets/cert",
+							Data: []byte("cert"),
+							Mode: 0440,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := c.applyPodInjection(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(agent.paths) != 2 || agent.paths[0] != "/run/secrets/token" {
+		t.Fatalf("Got %v\nExpecting the declared secret files", agent.paths)
+	}
+
+	if !bytes.Equal(agent.data[0], []byte("s3cr3t")) {
+		t.Fatalf("Got %s\nExpecting s3cr3t", agent.data[0])
+	}
+
+	if agent.modes[0] != 0400 || agent.modes[1] != 0440 {
+		t.Fatalf("Got %v\nExpecting the default 0400 and the declared 0440", agent.modes)
+	}
+}
+
+func TestApplyPodInjectionUnsupportedAgent(t *testing.T) {
+	c := &Container{
+		config: &ContainerConfig{},
+		pod: &Pod{
+			agent: &noopAgent{},
+			config: &PodConfig{
+				Injection: PodInjection{
+					Secrets: []SecretFile{
+						{Path: "/run/secrets/token"},
+					},
+				},
+			},
+		},
+	}
+
+	if err := c.applyPodInjection(); err == nil {
+		t.Fatal("An agent unable to inject files should be rejected")
+	}
+}
+
+func TestApplyPodInjectionOversizedSecret(t *testing.T) {
+	c := &Container{
+		config: &ContainerConfig{},
+		pod: &Pod{
+			agent: &injectTestAgent{},
+			config: &PodConfig{
+				Injection: PodInjection{
+					Secrets: []SecretFile{
+						{
+							Path: "/run/secrets/blob",
+							Data: make([]byte, maxSecretFileSize+1),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := c.applyPodInjection(); err == nil {
+		t.Fatal("An oversized secret file should be rejected")
+	}
+}
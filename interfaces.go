@@ -15,6 +15,7 @@
 package virtcontainers
 
 import (
+	"io"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
@@ -24,24 +25,45 @@ import (
 type VC interface {
 	SetLogger(logger logrus.FieldLogger)
 
+	AdoptPod(podID string) (VCPod, error)
 	CreatePod(podConfig PodConfig) (VCPod, error)
 	DeletePod(podID string) (VCPod, error)
 	ListPod() ([]PodStatus, error)
+	LeakCheck() ([]NetworkLeak, error)
 	PausePod(podID string) (VCPod, error)
 	ResumePod(podID string) (VCPod, error)
 	RunPod(podConfig PodConfig) (VCPod, error)
 	StartPod(podID string) (VCPod, error)
 	StatusPod(podID string) (PodStatus, error)
 	StopPod(podID string) (VCPod, error)
+	DrainPod(podID string, options DrainOptions) (VCPod, error)
+	SnapshotPod(podID string) ([]SnapshotHandle, error)
+	BackupPod(podID string, incremental bool) ([]BackupHandle, error)
+	ExportPod(podID string) ([]NBDExport, error)
+	UnexportPod(podID string) error
+	ClonePod(srcPodID string, options CloneOptions) (VCPod, error)
+	CreateVMTemplate(templateDir string, podConfig PodConfig) (*VMTemplate, error)
+	LoadVMTemplate(templateDir string) (*VMTemplate, error)
+	FetchConsoleLog(podID string) ([]byte, error)
+	KernelLogPod(podID string) (io.ReadCloser, error)
+	UsagePod(podID string) (PodResourceUsage, error)
+	WatermarksPod(podID string) (PodWatermarks, error)
+	KSMPod(podID string) (PodKSMStats, error)
+	OverheadPod(podID string) (PodOverhead, error)
+	HypervisorCommand(podID, cmd string, args map[string]interface{}) (interface{}, error)
+	AttestPod(podID string) (AttestationEvidence, error)
 
 	CreateContainer(podID string, containerConfig ContainerConfig) (VCPod, VCContainer, error)
 	DeleteContainer(podID, containerID string) (VCContainer, error)
 	EnterContainer(podID, containerID string, cmd Cmd) (VCPod, VCContainer, *Process, error)
+	ExecProbe(podID, containerID string, cmd Cmd, options ProbeOptions) (ProbeResult, error)
+	ReadContainerLogs(podID, containerID string, options ContainerLogsOptions) (io.ReadCloser, error)
 	KillContainer(podID, containerID string, signal syscall.Signal, all bool) error
 	StartContainer(podID, containerID string) (VCContainer, error)
 	StatusContainer(podID, containerID string) (ContainerStatus, error)
 	StopContainer(podID, containerID string) (VCContainer, error)
 	ProcessListContainer(podID, containerID string, options ProcessListOptions) (ProcessList, error)
+	AttachContainer(podID, containerID string) (*ContainerStreams, error)
 }
 
 // VCPod is the Pod interface
@@ -53,6 +75,7 @@ type VCPod interface {
 	GetContainer(containerID string) VCContainer
 	ID() string
 	SetAnnotations(annotations map[string]string) error
+	State() State
 }
 
 // VCContainer is the Container interface
@@ -65,4 +88,5 @@ type VCContainer interface {
 	Pod() VCPod
 	Process() Process
 	SetPid(pid int) error
+	State() State
 }
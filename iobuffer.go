@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// IOBufferPolicy defines what happens when a bounded IO buffer is
+// full and more data arrives.
+type IOBufferPolicy int
+
+const (
+	// IOBufferBlock blocks the writer until the reader drains the
+	// buffer, applying backpressure to the data source.
+	IOBufferBlock IOBufferPolicy = iota
+
+	// IOBufferDropOldest evicts the oldest buffered data to make
+	// room, keeping the most recent output.
+	IOBufferDropOldest
+
+	// IOBufferDropNewest discards the incoming data, keeping the
+	// oldest buffered output.
+	IOBufferDropNewest
+)
+
+// defaultIOBufferSize is the default capacity of a bounded IO buffer.
+const defaultIOBufferSize = 1 << 16
+
+var ioBufferLock sync.Mutex
+var ioBufferSize = defaultIOBufferSize
+var ioBufferPolicy = IOBufferBlock
+
+// SetIOBufferConfig sets the capacity and full-buffer policy applied
+// to container and console IO buffering, so a guest spewing output
+// cannot consume unbounded host memory.
+func SetIOBufferConfig(size int, policy IOBufferPolicy) error {
+	if size <= 0 {
+		return fmt.Errorf("Invalid IO buffer size %d", size)
+	}
+
+	switch policy {
+	case IOBufferBlock, IOBufferDropOldest, IOBufferDropNewest:
+	default:
+		return fmt.Errorf("Invalid IO buffer policy %d", policy)
+	}
+
+	ioBufferLock.Lock()
+	defer ioBufferLock.Unlock()
+
+	ioBufferSize = size
+	ioBufferPolicy = policy
+
+	return nil
+}
+
+func ioBufferConfig() (int, IOBufferPolicy) {
+	ioBufferLock.Lock()
+	defer ioBufferLock.Unlock()
+
+	return ioBufferSize, ioBufferPolicy
+}
+
+// boundedBuffer is a size-capped FIFO pipe between an IO producer and
+// a consumer. Reads block until data is available or the buffer is
+// closed, writes follow the configured full-buffer policy.
+type boundedBuffer struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	data     []byte
+	capacity int
+	policy   IOBufferPolicy
+	closed   bool
+}
+
+func newBoundedBuffer(capacity int, policy IOBufferPolicy) *boundedBuffer {
+	b := &boundedBuffer{
+		capacity: capacity,
+		policy:   policy,
+	}
+	b.cond = sync.NewCond(&b.mutex)
+
+	return b
+}
+
+// Write appends data to the buffer. When the buffer is full it
+// blocks, evicts the oldest data or drops the incoming data, as per
+// the buffer policy.
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	total := len(p)
+
+	for len(p) > 0 {
+		if b.closed {
+			return total - len(p), io.ErrClosedPipe
+		}
+
+		free := b.capacity - len(b.data)
+		if free == 0 {
+			switch b.policy {
+			case IOBufferBlock:
+				b.cond.Wait()
+			case IOBufferDropOldest:
+				drop := len(p)
+				if drop > len(b.data) {
+					drop = len(b.data)
+				}
+				b.data = b.data[drop:]
+			case IOBufferDropNewest:
+				return total, nil
+			}
+			continue
+		}
+
+		chunk := len(p)
+		if chunk > free {
+			chunk = free
+		}
+
+		b.data = append(b.data, p[:chunk]...)
+		p = p[chunk:]
+		b.cond.Broadcast()
+	}
+
+	return total, nil
+}
+
+// Read drains buffered data, blocking until some is available or the
+// buffer gets closed.
+func (b *boundedBuffer) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for len(b.data) == 0 {
+		if b.closed {
+			return 0, io.EOF
+		}
+
+		b.cond.Wait()
+	}
+
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	b.cond.Broadcast()
+
+	return n, nil
+}
+
+// Close unblocks any pending reader and writer. The remaining
+// buffered data can still be read before EOF is reported.
+func (b *boundedBuffer) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	b.cond.Broadcast()
+
+	return nil
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBoundedBufferRoundTrip(t *testing.T) {
+	buf := newBoundedBuffer(8, IOBufferBlock)
+
+	if _, err := buf.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Close()
+
+	data, err := ioutil.ReadAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "hello" {
+		t.Fatalf("Got %s\nExpecting hello", data)
+	}
+}
+
+func TestBoundedBufferBlock(t *testing.T) {
+	buf := newBoundedBuffer(4, IOBufferBlock)
+
+	done := make(chan error)
+	go func() {
+		_, err := buf.Write([]byte("12345678"))
+		done <- err
+	}()
+
+	data := make([]byte, 8)
+	read := 0
+	for read < 8 {
+		n, err := buf.Read(data[read:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		read += n
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "12345678" {
+		t.Fatalf("Got %s\nExpecting 12345678", data)
+	}
+}
+
+func TestBoundedBufferDropOldest(t *testing.T) {
+	buf := newBoundedBuffer(4, IOBufferDropOldest)
+
+	if _, err := buf.Write([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Close()
+
+	data, err := ioutil.ReadAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "5678" {
+		t.Fatalf("Got %s\nExpecting 5678", data)
+	}
+}
+
+func TestBoundedBufferDropNewest(t *testing.T) {
+	buf := newBoundedBuffer(4, IOBufferDropNewest)
+
+	if _, err := buf.Write([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Close()
+
+	data, err := ioutil.ReadAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "1234" {
+		t.Fatalf("Got %s\nExpecting 1234", data)
+	}
+}
+
+func TestBoundedBufferClosed(t *testing.T) {
+	buf := newBoundedBuffer(4, IOBufferBlock)
+	buf.Close()
+
+	if _, err := buf.Write([]byte("1")); err != io.ErrClosedPipe {
+		t.Fatalf("Got %v\nExpecting %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestSetIOBufferConfig(t *testing.T) {
+	savedSize, savedPolicy := ioBufferConfig()
+	defer SetIOBufferConfig(savedSize, savedPolicy)
+
+	if err := SetIOBufferConfig(0, IOBufferBlock); err == nil {
+		t.Fatal("A zero IO buffer size should be rejected")
+	}
+
+	if err := SetIOBufferConfig(1024, IOBufferPolicy(42)); err == nil {
+		t.Fatal("An unknown IO buffer policy should be rejected")
+	}
+
+	if err := SetIOBufferConfig(1024, IOBufferDropOldest); err != nil {
+		t.Fatal(err)
+	}
+
+	size, policy := ioBufferConfig()
+	if size != 1024 || policy != IOBufferDropOldest {
+		t.Fatalf("Got %d, %d\nExpecting 1024, %d", size, policy, IOBufferDropOldest)
+	}
+}
@@ -21,18 +21,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	vcAnnotations "github.com/containers/virtcontainers/pkg/annotations"
 	"github.com/containers/virtcontainers/pkg/uuid"
+	proto "github.com/gogo/protobuf/proto"
 	kataclient "github.com/kata-containers/agent/protocols/client"
 	"github.com/kata-containers/agent/protocols/grpc"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -44,8 +48,11 @@ var (
 	errorMissingOCISpec         = errors.New("Missing OCI specification")
 	kataHostSharedDir           = "/tmp/kata-containers/shared/pods/"
 	kataGuestSharedDir          = "/tmp/kata-containers/shared/pods/"
+	kataGuestBlockVolumeDir     = "/tmp/kata-containers/blockvolumes/"
+	kataGuestOverlayDir         = "/run/kata-containers/overlay/"
 	mountGuest9pTag             = "kataShared"
 	type9pFs                    = "9p"
+	typeOverlayFs               = "overlay"
 	devPath                     = "/dev"
 	vsockSocketScheme           = "vsock"
 )
@@ -54,6 +61,15 @@ var (
 // to reach the Kata Containers agent.
 type KataAgentConfig struct {
 	GRPCSocket string
+
+	// DisableGuestSeccomp strips the OCI seccomp profile from the
+	// specification sent to the agent, for guest images built
+	// without libseccomp support.
+	DisableGuestSeccomp bool
+
+	// TLS secures the agent gRPC channel with mutual
+	// authentication when enabled.
+	TLS TLSConfig
 }
 
 type kataVSOCK struct {
@@ -78,7 +94,9 @@ type kataAgent struct {
 	client *kataclient.AgentClient
 	state  KataAgentState
 
-	vmSocket interface{}
+	vmSocket            interface{}
+	disableGuestSeccomp bool
+	tls                 TLSConfig
 }
 
 func (k *kataAgent) Logger() *logrus.Entry {
@@ -133,6 +151,8 @@ func (k *kataAgent) init(pod *Pod, config interface{}) (err error) {
 		if err := k.generateVMSocket(*pod, c); err != nil {
 			return err
 		}
+		k.disableGuestSeccomp = c.DisableGuestSeccomp
+		k.tls = c.TLS
 	default:
 		return fmt.Errorf("Invalid config type")
 	}
@@ -210,27 +230,32 @@ func cmdToKataProcess(cmd Cmd) (process *grpc.Process, err error) {
 	// the gRPC "User" type.
 	const grpcUserBits = 32
 
-	// User can contain only the "uid" or it can contain "uid:gid".
-	parsedUser := strings.Split(cmd.User, ":")
-	if len(parsedUser) > 2 {
-		return nil, fmt.Errorf("cmd.User %q format is wrong", cmd.User)
-	}
-
-	i, err = strconv.ParseUint(parsedUser[0], 10, grpcUserBits)
-	if err != nil {
-		return nil, err
-	}
+	var uid, gid uint32
 
-	uid := uint32(i)
+	// User can contain only the "uid" or it can contain "uid:gid".
+	// An empty user runs the process as root, like the OCI runtimes
+	// do.
+	if cmd.User != "" {
+		parsedUser := strings.Split(cmd.User, ":")
+		if len(parsedUser) > 2 {
+			return nil, fmt.Errorf("cmd.User %q format is wrong", cmd.User)
+		}
 
-	var gid uint32
-	if len(parsedUser) > 1 {
-		i, err = strconv.ParseUint(parsedUser[1], 10, grpcUserBits)
+		i, err = strconv.ParseUint(parsedUser[0], 10, grpcUserBits)
 		if err != nil {
 			return nil, err
 		}
 
-		gid = uint32(i)
+		uid = uint32(i)
+
+		if len(parsedUser) > 1 {
+			i, err = strconv.ParseUint(parsedUser[1], 10, grpcUserBits)
+			if err != nil {
+				return nil, err
+			}
+
+			gid = uint32(i)
+		}
 	}
 
 	if cmd.PrimaryGroup != "" {
@@ -263,6 +288,24 @@ func cmdToKataProcess(cmd Cmd) (process *grpc.Process, err error) {
 		Args: cmd.Args,
 		Env:  cmdEnvsToStringSlice(cmd.Envs),
 		Cwd:  cmd.WorkDir,
+		Capabilities: &grpc.LinuxCapabilities{
+			Bounding:    cmd.Capabilities.Bounding,
+			Effective:   cmd.Capabilities.Effective,
+			Inheritable: cmd.Capabilities.Inheritable,
+			Permitted:   cmd.Capabilities.Permitted,
+			Ambient:     cmd.Capabilities.Ambient,
+		},
+		NoNewPrivileges: cmd.NoNewPrivileges,
+		ApparmorProfile: cmd.ApparmorProfile,
+		SelinuxLabel:    cmd.SelinuxLabel,
+	}
+
+	for _, rlimit := range cmd.Rlimits {
+		process.Rlimits = append(process.Rlimits, grpc.POSIXRlimit{
+			Type: rlimit.Type,
+			Hard: rlimit.Hard,
+			Soft: rlimit.Soft,
+		})
 	}
 
 	return process, nil
@@ -300,6 +343,180 @@ func (k *kataAgent) exec(pod *Pod, c Container, cmd Cmd) (*Process, error) {
 	return prepareAndStartShim(pod, k.shim, c.id, req.ExecId, k.state.URL, cmd)
 }
 
+// Default bounds applied to exec probes.
+const (
+	probeDefaultTimeout   = 10 * time.Second
+	probeDefaultMaxOutput = 4 * 1024
+)
+
+// execProbe runs a probe command inside the container over a single
+// agent connection, without spawning a shim, and returns its exit code
+// and a bounded amount of output. This keeps the cost of high
+// frequency liveness and readiness probes low.
+func (k *kataAgent) execProbe(pod *Pod, c Container, cmd Cmd, options ProbeOptions) (ProbeResult, error) {
+	timeout := probeDefaultTimeout
+	if options.TimeoutSecs > 0 {
+		timeout = time.Duration(options.TimeoutSecs) * time.Second
+	}
+
+	maxOutput := probeDefaultMaxOutput
+	if options.MaxOutputBytes > 0 {
+		maxOutput = options.MaxOutputBytes
+	}
+
+	// Probes never get a terminal, their output is collected below.
+	cmd.Interactive = false
+
+	kataProcess, err := cmdToKataProcess(cmd)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	if err := k.connect(); err != nil {
+		return ProbeResult{}, err
+	}
+	defer k.disconnect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req := &grpc.ExecProcessRequest{
+		ContainerId: c.id,
+		ExecId:      uuid.Generate().String(),
+		Process:     kataProcess,
+	}
+
+	if _, err := k.client.ExecProcess(ctx, req); err != nil {
+		return ProbeResult{}, fmt.Errorf("Could not execute the probe: %v", err)
+	}
+
+	waitReq := &grpc.WaitProcessRequest{
+		ContainerId: c.id,
+		ExecId:      req.ExecId,
+	}
+
+	waitResp, err := k.client.WaitProcess(ctx, waitReq)
+	if err != nil {
+		// The probe process is signalled so that it does not
+		// outlive a timed out probe.
+		signalReq := &grpc.SignalProcessRequest{
+			ContainerId: c.id,
+			ExecId:      req.ExecId,
+			Signal:      uint32(syscall.SIGKILL),
+		}
+		k.client.SignalProcess(context.Background(), signalReq)
+
+		return ProbeResult{}, fmt.Errorf("Probe did not complete: %v", err)
+	}
+
+	result := ProbeResult{
+		ExitCode: waitResp.Status,
+	}
+
+	for len(result.Output) < maxOutput {
+		readReq := &grpc.ReadStreamRequest{
+			ContainerId: c.id,
+			ExecId:      req.ExecId,
+			Len:         uint32(maxOutput - len(result.Output)),
+		}
+
+		resp, err := k.client.ReadStdout(ctx, readReq)
+		if err != nil || len(resp.Data) == 0 {
+			// The stream ends with an error once the process
+			// is gone and its output drained.
+			break
+		}
+
+		result.Output = append(result.Output, resp.Data...)
+	}
+
+	result.Truncated = len(result.Output) >= maxOutput
+
+	return result, nil
+}
+
+// addVolume exports a host directory to the running pod by bind
+// mounting it below the pod shared directory, where the guest already
+// has it mounted over 9p. Content updated on the host after pod start,
+// like refreshed secrets, becomes visible in the guest without a
+// restart.
+func (k *kataAgent) addVolume(pod *Pod, volume Volume) (string, error) {
+	if volume.MountTag == "" || volume.HostPath == "" {
+		return "", fmt.Errorf("Volume needs both a mount tag and a host path")
+	}
+
+	mountDest := filepath.Join(kataHostSharedDir, pod.id, volume.MountTag)
+	if err := bindMount(volume.HostPath, mountDest, false); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(kataGuestSharedDir, volume.MountTag), nil
+}
+
+// removeVolume withdraws a volume previously exported with addVolume.
+func (k *kataAgent) removeVolume(pod *Pod, volume Volume) error {
+	if volume.MountTag == "" {
+		return fmt.Errorf("Volume needs a mount tag")
+	}
+
+	return syscall.Unmount(filepath.Join(kataHostSharedDir, pod.id, volume.MountTag), 0)
+}
+
+// checkAgentAPIVersion verifies that the agent running in the guest
+// implements an API version the host can talk to. Failing here gives a
+// clear incompatibility error instead of deserialization failures later
+// in the pod lifecycle.
+func (k *kataAgent) checkAgentAPIVersion() error {
+	if err := k.connect(); err != nil {
+		return err
+	}
+	defer k.disconnect()
+
+	resp, err := k.client.Version(context.Background(), &grpc.CheckRequest{})
+	if err != nil {
+		return fmt.Errorf("Could not query the agent API version: %v", err)
+	}
+
+	k.Logger().WithFields(logrus.Fields{
+		"agent-version":     resp.AgentVersion,
+		"agent-api-version": resp.GrpcVersion,
+		"host-api-version":  grpc.APIVersion,
+	}).Info("agent API version negotiated")
+
+	return checkAPIVersionCompatible(grpc.APIVersion, resp.GrpcVersion)
+}
+
+// checkAPIVersionCompatible compares the host and agent API versions.
+// A different major version means the wire protocol is not compatible.
+func checkAPIVersionCompatible(hostVersion, agentVersion string) error {
+	hostMajor, err := apiMajorVersion(hostVersion)
+	if err != nil {
+		return err
+	}
+
+	agentMajor, err := apiMajorVersion(agentVersion)
+	if err != nil {
+		return err
+	}
+
+	if hostMajor != agentMajor {
+		return fmt.Errorf("Agent API version %s is not compatible with host API version %s", agentVersion, hostVersion)
+	}
+
+	return nil
+}
+
+func apiMajorVersion(version string) (int, error) {
+	fields := strings.Split(version, ".")
+
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, fmt.Errorf("Malformed API version %s", version)
+	}
+
+	return major, nil
+}
+
 func (k *kataAgent) startPod(pod Pod) error {
 	if k.proxy == nil {
 		return errorMissingProxy
@@ -330,6 +547,10 @@ func (k *kataAgent) startPod(pod Pod) error {
 
 	k.Logger().WithField("proxy-pid", pid).Info("proxy started")
 
+	if err := k.checkAgentAPIVersion(); err != nil {
+		return err
+	}
+
 	hostname := pod.config.Hostname
 	if len(hostname) > maxHostnameLen {
 		hostname = hostname[:maxHostnameLen]
@@ -350,7 +571,17 @@ func (k *kataAgent) startPod(pod Pod) error {
 	req := &grpc.CreateSandboxRequest{
 		Hostname:     hostname,
 		Storages:     []*grpc.Storage{sharedVolume},
-		SandboxPidns: false,
+		SandboxPidns: pod.config.SharePidNs,
+	}
+
+	if pod.config.GuestSwap.Enabled {
+		// The agent formats the device and enables it as guest
+		// swap space.
+		req.GuestSwapDevice = pod.state.GuestSwapDevice
+	}
+
+	if pod.config.MemorySoftLimitMiB > 0 {
+		req.MemorySoftLimitMB = uint64(pod.config.MemorySoftLimitMiB)
 	}
 
 	_, err = k.sendReq(req)
@@ -403,19 +634,25 @@ func (k *kataAgent) replaceOCIMountSource(spec *specs.Spec, guestMounts []Mount)
 	return nil
 }
 
-func constraintGRPCSpec(grpcSpec *grpc.Spec) {
+func (k *kataAgent) constraintGRPCSpec(grpcSpec *grpc.Spec, sharePidNs bool) {
 	// Disable Hooks since they have been handled on the host and there is
 	// no reason to send them to the agent. It would make no sense to try
 	// to apply them on the guest.
 	grpcSpec.Hooks = nil
 
-	// Disable Seccomp since they cannot be handled properly by the agent
-	// until we provide a guest image with libseccomp support. More details
-	// here: https://github.com/kata-containers/agent/issues/104
-	grpcSpec.Linux.Seccomp = nil
+	// The OCI seccomp profile is sent to the agent so that syscall
+	// filtering requested by the user is applied inside the guest as
+	// well. It can be stripped for guest images built without
+	// libseccomp support. More details here:
+	// https://github.com/kata-containers/agent/issues/104
+	if k.disableGuestSeccomp {
+		grpcSpec.Linux.Seccomp = nil
+	}
 
-	// TODO: Remove this constraint as soon as the agent properly handles
-	// resources provided through the specification.
+	// The resources of the host spec may reference host specific
+	// cgroup configuration. They are replaced with the subset
+	// mirrored in the container configuration, which the agent
+	// enforces inside the guest. See applyGuestResources().
 	grpcSpec.Linux.Resources = nil
 
 	// Disable network namespace since it is already handled on the host by
@@ -428,6 +665,16 @@ func constraintGRPCSpec(grpcSpec *grpc.Spec) {
 	for _, ns := range grpcSpec.Linux.Namespaces {
 		switch ns.Type {
 		case specs.NetworkNamespace:
+		case string(specs.PIDNamespace):
+			// When the pod shares a single PID namespace across
+			// its containers, dropping the entry makes the agent
+			// create the container in the sandbox PID namespace.
+			if sharePidNs {
+				continue
+			}
+
+			ns.Path = ""
+			tmpNamespaces = append(tmpNamespaces, ns)
 		default:
 			ns.Path = ""
 			tmpNamespaces = append(tmpNamespaces, ns)
@@ -447,6 +694,56 @@ func constraintGRPCSpec(grpcSpec *grpc.Spec) {
 	}
 }
 
+// applyGuestResources translates the container resource limits into
+// cgroup constraints the agent applies to the container inside the
+// guest, so that containers sharing the pod VM cannot starve each
+// other even though the host only accounts for one VM.
+func (k *kataAgent) applyGuestResources(grpcSpec *grpc.Spec, resources ContainerResources) {
+	linuxResources := &grpc.LinuxResources{}
+	constrained := false
+
+	if resources.MemoryLimitBytes > 0 {
+		linuxResources.Memory = &grpc.LinuxMemory{
+			Limit: resources.MemoryLimitBytes,
+		}
+		constrained = true
+	}
+
+	cpu := &grpc.LinuxCPU{}
+	if resources.CPUQuota > 0 && resources.CPUPeriod > 0 {
+		cpu.Quota = resources.CPUQuota
+		cpu.Period = resources.CPUPeriod
+	}
+	if resources.CPUShares > 0 {
+		cpu.Shares = resources.CPUShares
+	}
+	if *cpu != (grpc.LinuxCPU{}) {
+		linuxResources.CPU = cpu
+		constrained = true
+	}
+
+	if !constrained {
+		return
+	}
+
+	grpcSpec.Linux.Resources = linuxResources
+}
+
+// remapSharedDir gives the shared directory entry of a container to
+// the host IDs that map to root inside its user namespace.
+func remapSharedDir(podID string, c *Container) error {
+	uid, okUID := hostID(c.config.UIDMappings, 0)
+	gid, okGID := hostID(c.config.GIDMappings, 0)
+
+	if !okUID && !okGID {
+		return nil
+	}
+
+	sharedDir := filepath.Join(kataHostSharedDir, podID, c.id)
+
+	return os.Chown(sharedDir, int(uid), int(gid))
+}
+
 func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 	ociSpecJSON, ok := c.config.Annotations[vcAnnotations.ConfigJSONKey]
 	if !ok {
@@ -465,7 +762,47 @@ func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 	// This is the guest absolute root path for that container.
 	rootPath := filepath.Join(kataGuestSharedDir, c.id, rootfsDir)
 
-	if c.state.Fstype != "" {
+	if c.config.GuestImagePull {
+		// The image is pulled and unpacked by the agent inside the
+		// guest, nothing is shared from the host. The image
+		// reference is passed through the spec annotations.
+		if c.config.Image == "" {
+			return nil, fmt.Errorf("Guest image pull requires an image reference")
+		}
+	} else if len(c.rootfsLayers) > 0 {
+		// The rootfs is assembled by the agent inside the guest, as
+		// an overlayfs of the shared read-only image layers with a
+		// guest local writable layer on top. No merged rootfs is
+		// prepared on the host and identical layers can be shared
+		// across pods.
+		if err := bindMountContainerLayers(kataHostSharedDir, pod.id, c.id, c.rootfsLayers); err != nil {
+			bindUnmountAllRootfs(kataHostSharedDir, *pod)
+			return nil, err
+		}
+
+		if err := remapSharedDir(pod.id, c); err != nil {
+			bindUnmountAllRootfs(kataHostSharedDir, *pod)
+			return nil, err
+		}
+
+		// overlayfs lists lower directories top-most first while the
+		// container configuration orders layers lowest first.
+		var lowerDirs []string
+		for i := len(c.rootfsLayers) - 1; i >= 0; i-- {
+			lowerDirs = append(lowerDirs, filepath.Join(kataGuestSharedDir, c.id, rootfsLayersDir, fmt.Sprintf("%d", i)))
+		}
+
+		rootfs.Source = typeOverlayFs
+		rootfs.MountPoint = rootPath
+		rootfs.Fstype = typeOverlayFs
+		rootfs.Options = []string{
+			fmt.Sprintf("lowerdir=%s", strings.Join(lowerDirs, ":")),
+			fmt.Sprintf("upperdir=%s", filepath.Join(kataGuestOverlayDir, c.id, "upper")),
+			fmt.Sprintf("workdir=%s", filepath.Join(kataGuestOverlayDir, c.id, "work")),
+		}
+
+		containerStorage = append(containerStorage, rootfs)
+	} else if c.state.Fstype != "" {
 		// This is a block based device rootfs.
 		// driveName is the predicted virtio-block guest name (the vd* in /dev/vd*).
 		driveName, err := getVirtDriveName(c.state.BlockIndex)
@@ -496,6 +833,14 @@ func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 			bindUnmountAllRootfs(kataHostSharedDir, *pod)
 			return nil, err
 		}
+
+		// When the container runs in a user namespace, the shared
+		// directory has to belong to the host IDs root is mapped
+		// to, otherwise the guest cannot access its 9p share.
+		if err := remapSharedDir(pod.id, c); err != nil {
+			bindUnmountAllRootfs(kataHostSharedDir, *pod)
+			return nil, err
+		}
 	}
 
 	ociSpec := &specs.Spec{}
@@ -503,6 +848,23 @@ func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 		return nil, err
 	}
 
+	if c.config.GuestImagePull {
+		if ociSpec.Annotations == nil {
+			ociSpec.Annotations = make(map[string]string)
+		}
+		ociSpec.Annotations[vcAnnotations.ImageName] = c.config.Image
+		ociSpec.Annotations[vcAnnotations.GuestImagePull] = "true"
+	}
+
+	if c.config.DedicatedVCPUs > 0 {
+		// The agent builds an exclusive cpuset cgroup out of the
+		// vCPUs hotplugged for this container.
+		if ociSpec.Annotations == nil {
+			ociSpec.Annotations = make(map[string]string)
+		}
+		ociSpec.Annotations[vcAnnotations.DedicatedVCPUs] = fmt.Sprintf("%d", c.config.DedicatedVCPUs)
+	}
+
 	// Handle container mounts
 	newMounts, err := c.mountSharedDirMounts(kataHostSharedDir, kataGuestSharedDir)
 	if err != nil {
@@ -510,6 +872,30 @@ func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 		return nil, err
 	}
 
+	// Block backed volumes get mounted from their device by the agent,
+	// and the container mount becomes a bind mount from there.
+	for i, m := range newMounts {
+		if m.BlockDevice == "" {
+			continue
+		}
+
+		guestPath := filepath.Join(kataGuestBlockVolumeDir, c.id, contentID(c.id, m.Destination))
+
+		var options []string
+		if m.ReadOnly {
+			options = []string{"ro"}
+		}
+
+		containerStorage = append(containerStorage, &grpc.Storage{
+			Source:     m.Source,
+			MountPoint: guestPath,
+			Fstype:     m.Type,
+			Options:    options,
+		})
+
+		newMounts[i].Source = guestPath
+	}
+
 	// We replace all OCI mount sources that match our container mount
 	// with the right source path (The guest one).
 	if err := k.replaceOCIMountSource(ociSpec, newMounts); err != nil {
@@ -526,7 +912,17 @@ func (k *kataAgent) createContainer(pod *Pod, c *Container) (*Process, error) {
 
 	// We need to constraint the spec to make sure we're not passing
 	// irrelevant information to the agent.
-	constraintGRPCSpec(grpcSpec)
+	k.constraintGRPCSpec(grpcSpec, pod.config.SharePidNs)
+
+	// The agent creates a cgroup per container inside the guest and
+	// applies the container limits to it.
+	k.applyGuestResources(grpcSpec, c.config.Resources)
+
+	// The agent sets the OOM score adjustment on the container
+	// process inside the guest.
+	if c.config.OOMScoreAdj != 0 && grpcSpec.Process != nil {
+		grpcSpec.Process.OOMScoreAdj = int64(c.config.OOMScoreAdj)
+	}
 
 	// Append container mounts for block devices passed with --device.
 	for _, device := range c.devices {
@@ -580,7 +976,9 @@ func (k *kataAgent) stopContainer(pod Pod, c Container) error {
 		return err
 	}
 
-	if err := bindUnmountContainerRootfs(kataHostSharedDir, pod.id, c.id); err != nil {
+	if len(c.rootfsLayers) > 0 {
+		bindUnmountContainerLayers(kataHostSharedDir, pod.id, c.id, c.rootfsLayers)
+	} else if err := bindUnmountContainerRootfs(kataHostSharedDir, pod.id, c.id); err != nil {
 		return err
 	}
 
@@ -602,14 +1000,134 @@ func (k *kataAgent) processListContainer(pod Pod, c Container, options ProcessLi
 	return nil, nil
 }
 
+// kataStdinWriter forwards caller data to the stdin of a container
+// process through the agent.
+type kataStdinWriter struct {
+	agent       *kataAgent
+	containerID string
+	execID      string
+}
+
+func (w *kataStdinWriter) Write(data []byte) (int, error) {
+	if err := w.agent.connect(); err != nil {
+		return 0, err
+	}
+	defer w.agent.disconnect()
+
+	resp, err := w.agent.client.WriteStdin(context.Background(), &grpc.WriteStreamRequest{
+		ContainerId: w.containerID,
+		ExecId:      w.execID,
+		Data:        data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Len), nil
+}
+
+func (w *kataStdinWriter) Close() error {
+	if err := w.agent.connect(); err != nil {
+		return err
+	}
+	defer w.agent.disconnect()
+
+	_, err := w.agent.client.CloseStdin(context.Background(), &grpc.CloseStdinRequest{
+		ContainerId: w.containerID,
+		ExecId:      w.execID,
+	})
+	return err
+}
+
+// kataStreamReader reads a container process output stream through
+// the agent.
+type kataStreamReader struct {
+	agent       *kataAgent
+	containerID string
+	execID      string
+	stderr      bool
+}
+
+func (r *kataStreamReader) Read(data []byte) (int, error) {
+	if err := r.agent.connect(); err != nil {
+		return 0, err
+	}
+	defer r.agent.disconnect()
+
+	req := &grpc.ReadStreamRequest{
+		ContainerId: r.containerID,
+		ExecId:      r.execID,
+		Len:         uint32(len(data)),
+	}
+
+	var resp *grpc.ReadStreamResponse
+	var err error
+	if r.stderr {
+		resp, err = r.agent.client.ReadStderr(context.Background(), req)
+	} else {
+		resp, err = r.agent.client.ReadStdout(context.Background(), req)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if len(resp.Data) == 0 {
+		return 0, io.EOF
+	}
+
+	return copy(data, resp.Data), nil
+}
+
+// attachStreams returns streams plugged into the container stdio
+// through the agent stream RPCs, without any extra framing.
+func (k *kataAgent) attachStreams(pod *Pod, c Container) (*ContainerStreams, error) {
+	execID := c.process.Token
+	if execID == "" {
+		execID = c.id
+	}
+
+	return &ContainerStreams{
+		Stdin: &kataStdinWriter{
+			agent:       k,
+			containerID: c.id,
+			execID:      execID,
+		},
+		Stdout: &kataStreamReader{
+			agent:       k,
+			containerID: c.id,
+			execID:      execID,
+		},
+		Stderr: &kataStreamReader{
+			agent:       k,
+			containerID: c.id,
+			execID:      execID,
+			stderr:      true,
+		},
+	}, nil
+}
+
 func (k *kataAgent) connect() error {
 	if k.client != nil {
 		return nil
 	}
 
-	client, err := kataclient.NewAgentClient(k.state.URL)
-	if err != nil {
-		return err
+	var client *kataclient.AgentClient
+	if k.tls.Enabled {
+		tlsConfig, err := k.tls.clientTLSConfig()
+		if err != nil {
+			return err
+		}
+
+		client, err = kataclient.NewAgentClientWithTransportCredentials(k.state.URL, credentials.NewTLS(tlsConfig))
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		client, err = kataclient.NewAgentClient(k.state.URL)
+		if err != nil {
+			return err
+		}
 	}
 
 	k.client = client
@@ -631,7 +1149,18 @@ func (k *kataAgent) disconnect() error {
 	return nil
 }
 
-func (k *kataAgent) sendReq(request interface{}) (interface{}, error) {
+func (k *kataAgent) sendReq(request interface{}) (response interface{}, err error) {
+	payloadSize := 0
+	if msg, ok := request.(proto.Message); ok {
+		payloadSize = proto.Size(msg)
+	}
+
+	rpcName := strings.TrimPrefix(fmt.Sprintf("%T", request), "*grpc.")
+	started := time.Now()
+	defer func() {
+		traceAgentRPC(rpcName, payloadSize, started, err)
+	}()
+
 	if err := k.connect(); err != nil {
 		return nil, err
 	}
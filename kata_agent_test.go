@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/containers/virtcontainers/pkg/mock"
@@ -194,10 +195,19 @@ func (p *gRPCProxy) OnlineCPUMem(ctx context.Context, req *pb.OnlineCPUMemReques
 	return emptyResp, nil
 }
 
+func (p *gRPCProxy) Check(ctx context.Context, req *pb.CheckRequest) (*pb.HealthCheckResponse, error) {
+	return &pb.HealthCheckResponse{}, nil
+}
+
+func (p *gRPCProxy) Version(ctx context.Context, req *pb.CheckRequest) (*pb.VersionCheckResponse, error) {
+	return &pb.VersionCheckResponse{GrpcVersion: pb.APIVersion}, nil
+}
+
 func gRPCRegister(s *grpc.Server, srv interface{}) {
 	switch g := srv.(type) {
 	case *gRPCProxy:
 		pb.RegisterAgentServiceServer(s, g)
+		pb.RegisterHealthServer(s, g)
 	}
 }
 
@@ -243,3 +253,221 @@ func TestKataAgentSendReq(t *testing.T) {
 		}
 	}
 }
+
+func TestKataAgentExecProbe(t *testing.T) {
+	impl := &gRPCProxy{}
+
+	proxy := mock.ProxyGRPCMock{
+		GRPCImplementer: impl,
+		GRPCRegister:    gRPCRegister,
+	}
+
+	sockDir, err := testGenerateKataProxySockDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sockDir)
+
+	testKataProxyURL := fmt.Sprintf(testKataProxyURLTempl, sockDir)
+	if err := proxy.Start(testKataProxyURL); err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Stop()
+
+	k := &kataAgent{
+		state: KataAgentState{
+			URL: testKataProxyURL,
+		},
+	}
+
+	container := Container{
+		id: "probed-container",
+		config: &ContainerConfig{
+			Cmd: Cmd{},
+		},
+	}
+
+	cmd := Cmd{
+		Args: []string{"/bin/true"},
+	}
+
+	result, err := k.execProbe(nil, container, cmd, ProbeOptions{TimeoutSecs: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Fatalf("Expecting a zero exit code, got %d", result.ExitCode)
+	}
+
+	if result.Truncated {
+		t.Fatal("Expecting the empty probe output not to be truncated")
+	}
+}
+
+func TestConstraintGRPCSpecSeccomp(t *testing.T) {
+	seccomp := &pb.LinuxSeccomp{
+		DefaultAction: "SCMP_ACT_ERRNO",
+	}
+
+	newSpec := func() *pb.Spec {
+		return &pb.Spec{
+			Linux: &pb.Linux{
+				Seccomp: seccomp,
+			},
+		}
+	}
+
+	k := &kataAgent{}
+
+	spec := newSpec()
+	k.constraintGRPCSpec(spec, false)
+	if spec.Linux.Seccomp != seccomp {
+		t.Fatal("Expecting the seccomp profile to be passed to the agent")
+	}
+
+	k = &kataAgent{disableGuestSeccomp: true}
+
+	spec = newSpec()
+	k.constraintGRPCSpec(spec, false)
+	if spec.Linux.Seccomp != nil {
+		t.Fatal("Expecting the seccomp profile to be stripped")
+	}
+}
+
+func TestConstraintGRPCSpecSharePidNs(t *testing.T) {
+	newSpec := func() *pb.Spec {
+		return &pb.Spec{
+			Linux: &pb.Linux{
+				Namespaces: []pb.LinuxNamespace{
+					{Type: "pid", Path: "/proc/112/ns/pid"},
+					{Type: "ipc"},
+				},
+			},
+		}
+	}
+
+	k := &kataAgent{}
+
+	spec := newSpec()
+	k.constraintGRPCSpec(spec, false)
+	if len(spec.Linux.Namespaces) != 2 {
+		t.Fatalf("Expecting the PID namespace to be kept, got %+v", spec.Linux.Namespaces)
+	}
+	if spec.Linux.Namespaces[0].Path != "" {
+		t.Fatal("Expecting the PID namespace path to be emptied")
+	}
+
+	spec = newSpec()
+	k.constraintGRPCSpec(spec, true)
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == "pid" {
+			t.Fatal("Expecting the PID namespace to be dropped when shared")
+		}
+	}
+}
+
+func TestApplyGuestResources(t *testing.T) {
+	k := &kataAgent{}
+
+	spec := &pb.Spec{Linux: &pb.Linux{}}
+	k.applyGuestResources(spec, ContainerResources{})
+	if spec.Linux.Resources != nil {
+		t.Fatal("Expecting no guest resources for an unconstrained container")
+	}
+
+	resources := ContainerResources{
+		CPUQuota:         50000,
+		CPUPeriod:        100000,
+		CPUShares:        512,
+		MemoryLimitBytes: 128 << 20,
+	}
+
+	spec = &pb.Spec{Linux: &pb.Linux{}}
+	k.applyGuestResources(spec, resources)
+
+	if spec.Linux.Resources == nil {
+		t.Fatal("Expecting guest resources to be set")
+	}
+
+	if spec.Linux.Resources.Memory == nil || spec.Linux.Resources.Memory.Limit != resources.MemoryLimitBytes {
+		t.Fatalf("Wrong guest memory limit: %+v", spec.Linux.Resources.Memory)
+	}
+
+	cpu := spec.Linux.Resources.CPU
+	if cpu == nil || cpu.Quota != resources.CPUQuota || cpu.Period != resources.CPUPeriod || cpu.Shares != resources.CPUShares {
+		t.Fatalf("Wrong guest CPU constraints: %+v", cpu)
+	}
+}
+
+func TestCmdToKataProcess(t *testing.T) {
+	cmd := Cmd{
+		Args:         []string{"echo"},
+		WorkDir:      "/",
+		User:         "1000",
+		PrimaryGroup: "1000",
+		Capabilities: LinuxCapabilities{
+			Bounding: []string{"CAP_NET_ADMIN"},
+		},
+		NoNewPrivileges: true,
+		Rlimits: []Rlimit{
+			{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 512},
+		},
+		SelinuxLabel:    "system_u:system_r:container_t:s0",
+		ApparmorProfile: "virtcontainers-default",
+	}
+
+	process, err := cmdToKataProcess(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !process.NoNewPrivileges {
+		t.Fatal("Expecting NoNewPrivileges to be set")
+	}
+
+	if process.Capabilities == nil || len(process.Capabilities.Bounding) != 1 {
+		t.Fatalf("Got capabilities %+v", process.Capabilities)
+	}
+
+	expectedRlimits := []pb.POSIXRlimit{
+		{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 512},
+	}
+
+	if !reflect.DeepEqual(process.Rlimits, expectedRlimits) {
+		t.Fatalf("Got %+v\nExpecting %+v", process.Rlimits, expectedRlimits)
+	}
+
+	if process.SelinuxLabel != cmd.SelinuxLabel {
+		t.Fatalf("Got SELinux label %q\nExpecting %q", process.SelinuxLabel, cmd.SelinuxLabel)
+	}
+
+	if process.ApparmorProfile != cmd.ApparmorProfile {
+		t.Fatalf("Got AppArmor profile %q\nExpecting %q", process.ApparmorProfile, cmd.ApparmorProfile)
+	}
+}
+
+func TestCheckAPIVersionCompatible(t *testing.T) {
+	tests := []struct {
+		hostVersion  string
+		agentVersion string
+		expectError  bool
+	}{
+		{"0.0.1", "0.0.1", false},
+		{"0.0.1", "0.1.0", false},
+		{"0.0.1", "1.0.0", true},
+		{"1.2.0", "0.0.1", true},
+		{"0.0.1", "invalid", true},
+	}
+
+	for _, test := range tests {
+		err := checkAPIVersionCompatible(test.hostVersion, test.agentVersion)
+		if test.expectError && err == nil {
+			t.Fatalf("Expected an error comparing %s with %s", test.hostVersion, test.agentVersion)
+		}
+
+		if !test.expectError && err != nil {
+			t.Fatalf("Unexpected error comparing %s with %s: %v", test.hostVersion, test.agentVersion, err)
+		}
+	}
+}
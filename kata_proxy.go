@@ -50,6 +50,13 @@ func (p *kataProxy) start(pod Pod, params proxyParams) (int, string, error) {
 	}
 
 	args := []string{config.Path, "-listen-socket", proxyURL, "-mux-socket", params.agentURL}
+	if config.TLS.Enabled {
+		// The proxy binary terminates TLS on its control socket and
+		// requires a valid client certificate from its callers.
+		args = append(args, "-tls-ca-cert", config.TLS.CACertPath)
+		args = append(args, "-tls-cert", config.TLS.CertPath)
+		args = append(args, "-tls-key", config.TLS.KeyPath)
+	}
 	if config.Debug {
 		args = append(args, "-log", "debug")
 		args = append(args, "-agent-logs-socket", pod.hypervisor.getPodConsole(pod.id))
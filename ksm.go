@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ksmSysfsPath is the KSM control directory. It is a variable so the
+// tests can point it at a fake sysfs tree.
+var ksmSysfsPath = "/sys/kernel/mm/ksm"
+
+// KSMConfig describes the host KSM tuning to apply when many similar
+// pods run on the same host.
+type KSMConfig struct {
+	// Enabled starts or stops the KSM scanning thread.
+	Enabled bool
+
+	// PagesToScan is the number of pages scanned per scan interval.
+	// Zero keeps the current host setting.
+	PagesToScan uint64
+
+	// SleepMillisec is the interval between two scans. Zero keeps
+	// the current host setting.
+	SleepMillisec uint64
+}
+
+// KSMHostStats reports the host-wide sharing KSM achieved.
+type KSMHostStats struct {
+	// PagesShared is the number of shared pages in use.
+	PagesShared uint64
+
+	// PagesSharing is the number of pages deduplicated onto the
+	// shared pages; the achieved saving.
+	PagesSharing uint64
+
+	// FullScans is the number of times all mergeable areas have
+	// been scanned.
+	FullScans uint64
+}
+
+// PodKSMStats reports the sharing KSM achieved on one pod.
+type PodKSMStats struct {
+	// MergedPages is the number of pod pages currently merged.
+	MergedPages uint64
+
+	// MergedBytes is the same saving expressed in bytes.
+	MergedBytes uint64
+}
+
+func writeKSMControl(name string, value uint64) error {
+	path := filepath.Join(ksmSysfsPath, name)
+	if err := ioutil.WriteFile(path, []byte(strconv.FormatUint(value, 10)), 0200); err != nil {
+		return fmt.Errorf("Could not set KSM control %s: %v", name, err)
+	}
+
+	return nil
+}
+
+func readKSMControl(name string) (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(ksmSysfsPath, name))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// TuneKSM applies the given KSM tuning to the host.
+func TuneKSM(config KSMConfig) error {
+	if config.PagesToScan != 0 {
+		if err := writeKSMControl("pages_to_scan", config.PagesToScan); err != nil {
+			return err
+		}
+	}
+
+	if config.SleepMillisec != 0 {
+		if err := writeKSMControl("sleep_millisecs", config.SleepMillisec); err != nil {
+			return err
+		}
+	}
+
+	run := uint64(0)
+	if config.Enabled {
+		run = 1
+	}
+
+	return writeKSMControl("run", run)
+}
+
+// KSMHostInfo returns the host-wide sharing KSM achieved.
+func KSMHostInfo() (KSMHostStats, error) {
+	stats := KSMHostStats{}
+
+	fields := []struct {
+		name  string
+		value *uint64
+	}{
+		{"pages_shared", &stats.PagesShared},
+		{"pages_sharing", &stats.PagesSharing},
+		{"full_scans", &stats.FullScans},
+	}
+
+	for _, field := range fields {
+		value, err := readKSMControl(field.name)
+		if err != nil {
+			return KSMHostStats{}, err
+		}
+
+		*field.value = value
+	}
+
+	return stats, nil
+}
+
+// ksmStats returns the sharing KSM achieved on the pod, read from the
+// hypervisor process KSM accounting in procfs.
+func (p *Pod) ksmStats() (PodKSMStats, error) {
+	state, ok := p.hypervisor.getState().(QemuState)
+	if !ok {
+		return PodKSMStats{}, fmt.Errorf("The pod hypervisor does not expose a process to inspect")
+	}
+
+	pid, err := findHypervisorPid(state.UUID)
+	if err != nil {
+		return PodKSMStats{}, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(procPath, strconv.Itoa(pid), "ksm_stat"))
+	if err != nil {
+		return PodKSMStats{}, err
+	}
+
+	stats := PodKSMStats{}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// Expected format: ["ksm_merging_pages", "1234"]
+		parts := strings.Fields(line)
+		if len(parts) != 2 || parts[0] != "ksm_merging_pages" {
+			continue
+		}
+
+		pages, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return PodKSMStats{}, err
+		}
+
+		stats.MergedPages = pages
+		stats.MergedBytes = pages * uint64(os.Getpagesize())
+		break
+	}
+
+	return stats, nil
+}
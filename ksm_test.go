@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fakeKSMSysfs(t *testing.T) (string, func()) {
+	ksmDir, err := ioutil.TempDir("", "vc-ksm-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"run":             "0",
+		"pages_to_scan":   "100",
+		"sleep_millisecs": "20",
+		"pages_shared":    "10",
+		"pages_sharing":   "50",
+		"full_scans":      "3",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(ksmDir, name), []byte(value+"\n"), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedSysfsPath := ksmSysfsPath
+	ksmSysfsPath = ksmDir
+
+	return ksmDir, func() {
+		ksmSysfsPath = savedSysfsPath
+		os.RemoveAll(ksmDir)
+	}
+}
+
+func TestTuneKSM(t *testing.T) {
+	ksmDir, cleanup := fakeKSMSysfs(t)
+	defer cleanup()
+
+	config := KSMConfig{
+		Enabled:       true,
+		PagesToScan:   500,
+		SleepMillisec: 10,
+	}
+
+	if err := TuneKSM(config); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"run":             "1",
+		"pages_to_scan":   "500",
+		"sleep_millisecs": "10",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(ksmDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != expected {
+			t.Fatalf("Got %s\nExpecting %s for %s", data, expected, name)
+		}
+	}
+
+	if err := TuneKSM(KSMConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(ksmDir, "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "0" {
+		t.Fatalf("Got %s\nExpecting 0", data)
+	}
+}
+
+func TestTuneKSMMissingSysfs(t *testing.T) {
+	savedSysfsPath := ksmSysfsPath
+	ksmSysfsPath = filepath.Join(os.TempDir(), "nonexistent-ksm")
+	defer func() {
+		ksmSysfsPath = savedSysfsPath
+	}()
+
+	if err := TuneKSM(KSMConfig{Enabled: true}); err == nil {
+		t.Fatal("Tuning KSM without the sysfs controls should fail")
+	}
+}
+
+func TestKSMHostInfo(t *testing.T) {
+	_, cleanup := fakeKSMSysfs(t)
+	defer cleanup()
+
+	stats, err := KSMHostInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := KSMHostStats{
+		PagesShared:  10,
+		PagesSharing: 50,
+		FullScans:    3,
+	}
+
+	if stats != expected {
+		t.Fatalf("Got %+v\nExpecting %+v", stats, expected)
+	}
+}
@@ -0,0 +1,58 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// procAttrExecPath is the LSM attribute applied to the next process
+// exec'd by the writing thread. It is honored by both SELinux (a
+// context) and AppArmor (an "exec <profile>" directive).
+const procAttrExecPath = "/proc/thread-self/attr/exec"
+
+// setExecSecurityLabel asks the kernel to start the next process exec'd
+// by the current thread with the provided SELinux or AppArmor label.
+// Callers must be locked to their OS thread and should clear the label
+// with clearExecSecurityLabel once the process has been started.
+func setExecSecurityLabel(label string) error {
+	if label == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(procAttrExecPath); err != nil {
+		return fmt.Errorf("Security label %q requested but no LSM is available: %s", label, err)
+	}
+
+	if err := ioutil.WriteFile(procAttrExecPath, []byte(label), 0); err != nil {
+		return fmt.Errorf("Could not set security label %q: %s", label, err)
+	}
+
+	return nil
+}
+
+// clearExecSecurityLabel resets the exec label of the current thread so
+// that processes started afterwards are not confined unexpectedly.
+func clearExecSecurityLabel() error {
+	if _, err := os.Stat(procAttrExecPath); err != nil {
+		return nil
+	}
+
+	return ioutil.WriteFile(procAttrExecPath, nil, 0)
+}
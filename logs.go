@@ -0,0 +1,361 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// containerLogFile is the file name storing the stdout and stderr of a
+// container process. It lives in the container runtime directory.
+const containerLogFile = "container.log"
+
+// containerLogRotateFile is the file name the container log gets
+// rotated to once it reaches containerLogMaxSize bytes. Only one
+// rotated generation is kept.
+const containerLogRotateFile = containerLogFile + ".1"
+
+// containerLogMaxSize is the size in bytes above which the container
+// log file gets rotated.
+const containerLogMaxSize = int64(1 << 20)
+
+// containerLogRateLimit is the amount of container output in bytes per
+// second written to the log files. A container flooding its stdio gets
+// throttled instead of monopolizing the host disk bandwidth.
+const containerLogRateLimit = int64(1 << 20)
+
+// containerLogPollInterval is how often a followed container log gets
+// checked for new output.
+const containerLogPollInterval = 100 * time.Millisecond
+
+// containerLogger copies the stdio streams of a container process to a
+// size-capped, rotated log file in the container runtime directory.
+// The copy is rate limited so a log-flooding container cannot starve
+// the host.
+type containerLogger struct {
+	sync.Mutex
+
+	podID       string
+	containerID string
+	logPath     string
+	maxSize     int64
+	rateLimit   int64
+
+	file    *os.File
+	started time.Time
+	written int64
+}
+
+// newContainerLogger returns a container logger for the given container.
+func newContainerLogger(podID, containerID string) *containerLogger {
+	return &containerLogger{
+		podID:       podID,
+		containerID: containerID,
+		logPath:     filepath.Join(runStoragePath, podID, containerID, containerLogFile),
+		maxSize:     containerLogMaxSize,
+		rateLimit:   containerLogRateLimit,
+	}
+}
+
+// Logger returns a logrus logger appropriate for logging container log
+// pipeline messages
+func (cl *containerLogger) Logger() *logrus.Entry {
+	return virtLog.WithFields(logrus.Fields{
+		"subsystem":    "container-logs",
+		"pod-id":       cl.podID,
+		"container-id": cl.containerID,
+	})
+}
+
+// rotate moves the current log file out of the way, dropping any
+// previously rotated generation.
+func (cl *containerLogger) rotate() error {
+	if cl.file != nil {
+		cl.file.Close()
+		cl.file = nil
+	}
+
+	rotatePath := filepath.Join(filepath.Dir(cl.logPath), containerLogRotateFile)
+	if err := os.Rename(cl.logPath, rotatePath); err != nil {
+		return err
+	}
+
+	return cl.openLog()
+}
+
+func (cl *containerLogger) openLog() error {
+	file, err := os.OpenFile(cl.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+
+	cl.file = file
+
+	return nil
+}
+
+// throttle delays the caller so that the overall write rate stays
+// below the configured limit.
+func (cl *containerLogger) throttle(length int) {
+	if cl.rateLimit == 0 {
+		return
+	}
+
+	if cl.started.IsZero() {
+		cl.started = time.Now()
+	}
+
+	cl.written += int64(length)
+
+	deadline := cl.started.Add(time.Duration(cl.written) * time.Second / time.Duration(cl.rateLimit))
+	time.Sleep(time.Until(deadline))
+}
+
+// write appends data to the container log file, rotating it whenever
+// it grows beyond the configured size cap.
+func (cl *containerLogger) write(data []byte) error {
+	cl.Lock()
+	defer cl.Unlock()
+
+	cl.throttle(len(data))
+
+	if cl.file == nil {
+		if err := cl.openLog(); err != nil {
+			return err
+		}
+	}
+
+	info, err := cl.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size()+int64(len(data)) > cl.maxSize {
+		if err := cl.rotate(); err != nil {
+			return err
+		}
+	}
+
+	_, err = cl.file.Write(data)
+
+	return err
+}
+
+// start copies the given container streams to the container log file
+// until they get closed. Streams the agent could not provide are
+// skipped.
+func (cl *containerLogger) start(streams *ContainerStreams) {
+	for _, stream := range []io.Reader{streams.Stdout, streams.Stderr} {
+		if stream == nil {
+			continue
+		}
+
+		go cl.copyStream(stream)
+	}
+}
+
+// copyStream forwards one output stream to the container log file.
+func (cl *containerLogger) copyStream(stream io.Reader) {
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if err := cl.write(buf[:n]); err != nil {
+				cl.Logger().WithError(err).Warn("Could not write container log")
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ContainerLogsOptions describes what part of the container logs a
+// ReadContainerLogs caller is interested in.
+type ContainerLogsOptions struct {
+	// Follow keeps the returned stream open and delivers new log
+	// data as the container produces it.
+	Follow bool
+
+	// TailLines limits the returned logs to the last TailLines
+	// lines. Zero means the complete logs.
+	TailLines int
+}
+
+// tailLines returns the last requested number of lines of data.
+func tailLines(data []byte, lines int) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	end := len(data)
+	if data[end-1] == '\n' {
+		end--
+	}
+
+	count := 0
+	for idx := end - 1; idx >= 0; idx-- {
+		if data[idx] != '\n' {
+			continue
+		}
+
+		count++
+		if count == lines {
+			return data[idx+1:]
+		}
+	}
+
+	return data
+}
+
+// logFollower streams a container log file, first delivering the
+// snapshot taken when the follow started and then polling the file for
+// new output. A rotation while following makes it restart from the
+// beginning of the new file.
+type logFollower struct {
+	path    string
+	initial *bytes.Reader
+	offset  int64
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newLogFollower(path string, initial []byte, offset int64) *logFollower {
+	return &logFollower{
+		path:    path,
+		initial: bytes.NewReader(initial),
+		offset:  offset,
+		closed:  make(chan struct{}),
+	}
+}
+
+func (f *logFollower) Read(p []byte) (int, error) {
+	if f.initial.Len() > 0 {
+		return f.initial.Read(p)
+	}
+
+	for {
+		select {
+		case <-f.closed:
+			return 0, io.EOF
+		default:
+		}
+
+		n, err := f.readNew(p)
+		if n > 0 || err != nil {
+			return n, err
+		}
+
+		time.Sleep(containerLogPollInterval)
+	}
+}
+
+// readNew returns any log data appended since the last read.
+func (f *logFollower) readNew(p []byte) (int, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Size() < f.offset {
+		f.offset = 0
+	}
+
+	if info.Size() == f.offset {
+		return 0, nil
+	}
+
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := file.Read(p)
+	f.offset += int64(n)
+
+	if err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+// Close terminates the follow, any blocked Read returns io.EOF.
+func (f *logFollower) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.closed)
+	})
+
+	return nil
+}
+
+// readContainerLogs returns the logs collected for a container,
+// including the rotated generation if present.
+func readContainerLogs(podID, containerID string, options ContainerLogsOptions) (io.ReadCloser, error) {
+	logDir := filepath.Join(runStoragePath, podID, containerID)
+
+	var output []byte
+	var curSize int64
+
+	for _, file := range []string{containerLogRotateFile, containerLogFile} {
+		path := filepath.Join(logDir, file)
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if file == containerLogFile {
+			curSize = int64(len(data))
+		}
+
+		output = append(output, data...)
+	}
+
+	if options.TailLines > 0 {
+		output = tailLines(output, options.TailLines)
+	}
+
+	if !options.Follow {
+		return ioutil.NopCloser(bytes.NewReader(output)), nil
+	}
+
+	return newLogFollower(filepath.Join(logDir, containerLogFile), output, curSize), nil
+}
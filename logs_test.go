@@ -0,0 +1,171 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContainerLoggerRotate(t *testing.T) {
+	podID := "logger-pod"
+	containerID := "logger-container"
+
+	logDir := filepath.Join(runStoragePath, podID, containerID)
+	if err := os.MkdirAll(logDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(runStoragePath, podID))
+
+	logger := newContainerLogger(podID, containerID)
+	logger.maxSize = 16
+	logger.rateLimit = 0
+
+	if err := logger.write([]byte("first-dozen-byte")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := logger.write([]byte("overflow")); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := ioutil.ReadFile(filepath.Join(logDir, containerLogRotateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(rotated) != "first-dozen-byte" {
+		t.Fatalf("Unexpected rotated log content %q", string(rotated))
+	}
+
+	current, err := ioutil.ReadFile(logger.logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(current) != "overflow" {
+		t.Fatalf("Unexpected log content %q", string(current))
+	}
+}
+
+func TestReadContainerLogsTail(t *testing.T) {
+	podID := "tail-pod"
+	containerID := "tail-container"
+
+	logDir := filepath.Join(runStoragePath, podID, containerID)
+	if err := os.MkdirAll(logDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(runStoragePath, podID))
+
+	err := ioutil.WriteFile(filepath.Join(logDir, containerLogRotateFile), []byte("line1\nline2\n"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(logDir, containerLogFile), []byte("line3\nline4\n"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := readContainerLogs(podID, containerID, ContainerLogsOptions{TailLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	logs, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(logs) != "line2\nline3\nline4\n" {
+		t.Fatalf("Unexpected tailed logs %q", string(logs))
+	}
+}
+
+func TestReadContainerLogsFollow(t *testing.T) {
+	podID := "follow-pod"
+	containerID := "follow-container"
+
+	logDir := filepath.Join(runStoragePath, podID, containerID)
+	if err := os.MkdirAll(logDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(runStoragePath, podID))
+
+	logPath := filepath.Join(logDir, containerLogFile)
+
+	if err := ioutil.WriteFile(logPath, []byte("before\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := readContainerLogs(podID, containerID, ContainerLogsOptions{Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	buf := make([]byte, 32)
+
+	n, err := stream.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "before\n" {
+		t.Fatalf("Unexpected log snapshot %q", string(buf[:n]))
+	}
+
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	var followed []byte
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !bytes.Contains(followed, []byte("after\n")) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Followed logs %q miss the appended data", string(followed))
+		}
+
+		n, err := stream.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		followed = append(followed, buf[:n]...)
+	}
+}
+
+func TestTailLinesIncompleteLastLine(t *testing.T) {
+	logs := tailLines([]byte("line1\nline2\nline3"), 2)
+
+	if string(logs) != "line2\nline3" {
+		t.Fatalf("Unexpected tailed logs %q", string(logs))
+	}
+}
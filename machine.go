@@ -0,0 +1,187 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	govmmQemu "github.com/intel/govmm/qemu"
+)
+
+// machineRegistry holds the known qemu machine types, their aliases
+// and the binary providing them. User registered types come on top of
+// the built-in ones.
+type machineRegistry struct {
+	sync.Mutex
+	machines map[string]govmmQemu.Machine
+	aliases  map[string]string
+	paths    map[string]string
+}
+
+var qemuMachines = &machineRegistry{
+	machines: map[string]govmmQemu.Machine{},
+	aliases:  map[string]string{},
+	paths:    map[string]string{},
+}
+
+// defaultMachineTypes is the default machine type of each supported
+// architecture.
+var defaultMachineTypes = map[string]string{
+	"amd64": defaultQemuMachineType,
+	"arm64": "virt",
+}
+
+func init() {
+	for _, m := range []struct {
+		machine govmmQemu.Machine
+		path    string
+		aliases []string
+	}{
+		{govmmQemu.Machine{Type: QemuPCLite, Acceleration: defaultQemuMachineAccelerators}, "/usr/bin/qemu-lite-system-x86_64", nil},
+		{govmmQemu.Machine{Type: QemuPC, Acceleration: defaultQemuMachineAccelerators}, defaultQemuPath, []string{"pc-i440fx"}},
+		{govmmQemu.Machine{Type: QemuQ35, Acceleration: defaultQemuMachineAccelerators}, "/usr/bin/qemu-35-system-x86_64", []string{"pc-q35"}},
+		{govmmQemu.Machine{Type: "virt", Acceleration: "kvm"}, "/usr/bin/qemu-system-aarch64", nil},
+	} {
+		qemuMachines.register(m.machine, m.path, m.aliases...)
+	}
+}
+
+func (r *machineRegistry) register(machine govmmQemu.Machine, binPath string, aliases ...string) error {
+	if machine.Type == "" {
+		return fmt.Errorf("Machine type cannot be empty")
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.machines[machine.Type] = machine
+
+	if binPath != "" {
+		r.paths[machine.Type] = binPath
+	}
+
+	for _, alias := range aliases {
+		r.aliases[alias] = machine.Type
+	}
+
+	return nil
+}
+
+// canonicalMachineType resolves an alias to the name the machine type
+// is registered under. Must be called with the registry locked.
+func (r *machineRegistry) canonicalMachineType(name string) string {
+	if target, ok := r.aliases[name]; ok {
+		return target
+	}
+
+	return name
+}
+
+// baseMachineType strips a trailing version from a versioned machine
+// type name, "pc-q35-2.9" gives "pc-q35".
+func baseMachineType(name string) string {
+	idx := strings.LastIndex(name, "-")
+	if idx <= 0 || idx == len(name)-1 {
+		return name
+	}
+
+	for _, c := range name[idx+1:] {
+		if c != '.' && (c < '0' || c > '9') {
+			return name
+		}
+	}
+
+	return name[:idx]
+}
+
+// resolve maps a machine type name, alias or versioned variant of a
+// registered type to its machine definition. Versioned variants keep
+// their full name, that is what the hypervisor needs on its command
+// line.
+func (r *machineRegistry) resolve(name string) (govmmQemu.Machine, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if m, ok := r.machines[r.canonicalMachineType(name)]; ok {
+		return m, nil
+	}
+
+	if m, ok := r.machines[r.canonicalMachineType(baseMachineType(name))]; ok {
+		m.Type = name
+		return m, nil
+	}
+
+	return govmmQemu.Machine{}, fmt.Errorf("unrecognised machine type: %v", name)
+}
+
+// binaryPath returns the hypervisor binary registered for a machine
+// type, alias or versioned variant.
+func (r *machineRegistry) binaryPath(name string) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if p, ok := r.paths[r.canonicalMachineType(name)]; ok {
+		return p, true
+	}
+
+	p, ok := r.paths[r.canonicalMachineType(baseMachineType(name))]
+
+	return p, ok
+}
+
+// RegisterMachineType makes a custom machine type available on top of
+// the built-in pc, pc-lite and q35 ones. binPath optionally maps the
+// type to the hypervisor binary providing it, aliases are alternative
+// names resolving to it.
+func RegisterMachineType(name, acceleration, binPath string, aliases ...string) error {
+	return qemuMachines.register(govmmQemu.Machine{
+		Type:         name,
+		Acceleration: acceleration,
+	}, binPath, aliases...)
+}
+
+// defaultMachineType returns the default machine type of the host
+// architecture.
+func defaultMachineType() string {
+	if machineType, ok := defaultMachineTypes[runtime.GOARCH]; ok {
+		return machineType
+	}
+
+	return defaultQemuMachineType
+}
+
+// validateMachineType checks a machine type against the ones the
+// hypervisor binary actually supports. A binary that cannot be probed
+// does not fail the validation, the registry is then the only gate.
+func validateMachineType(binPath, machineType string) error {
+	output, err := exec.Command(binPath, "-machine", "help").CombinedOutput()
+	if err != nil {
+		return nil
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == machineType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Machine type %s is not supported by %s", machineType, binPath)
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"testing"
+)
+
+func TestMachineRegistryAlias(t *testing.T) {
+	machine, err := qemuMachines.resolve("pc-q35")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if machine.Type != QemuQ35 {
+		t.Fatalf("Got machine type %s\nExpecting %s", machine.Type, QemuQ35)
+	}
+}
+
+func TestMachineRegistryVersionedType(t *testing.T) {
+	machine, err := qemuMachines.resolve("pc-q35-2.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The versioned name is what the hypervisor needs on its
+	// command line.
+	if machine.Type != "pc-q35-2.9" {
+		t.Fatalf("Got machine type %s\nExpecting pc-q35-2.9", machine.Type)
+	}
+
+	if machine.Acceleration != defaultQemuMachineAccelerators {
+		t.Fatalf("Got acceleration %s\nExpecting %s", machine.Acceleration, defaultQemuMachineAccelerators)
+	}
+
+	if _, err := qemuMachines.resolve("virt-2.9-foo"); err == nil {
+		t.Fatal("An unknown versioned machine type should not resolve")
+	}
+}
+
+func TestRegisterMachineType(t *testing.T) {
+	err := RegisterMachineType("test-machine", "kvm", "/usr/bin/test-qemu", "test-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machine, err := qemuMachines.resolve("test-alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if machine.Type != "test-machine" {
+		t.Fatalf("Got machine type %s\nExpecting test-machine", machine.Type)
+	}
+
+	path, ok := qemuMachines.binaryPath("test-machine-2.11")
+	if !ok || path != "/usr/bin/test-qemu" {
+		t.Fatalf("Got binary path %s\nExpecting /usr/bin/test-qemu", path)
+	}
+
+	if err := RegisterMachineType("", "", ""); err == nil {
+		t.Fatal("Registering an empty machine type should fail")
+	}
+}
+
+func TestDefaultMachineType(t *testing.T) {
+	machineType := defaultMachineType()
+
+	if machineType == "" {
+		t.Fatal("The default machine type cannot be empty")
+	}
+
+	if _, err := qemuMachines.resolve(machineType); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateMachineTypeUnprobeableBinary(t *testing.T) {
+	// A binary that cannot be probed leaves the registry as the
+	// only gate.
+	if err := validateMachineType("/nonexistent/qemu", QemuPC); err != nil {
+		t.Fatal(err)
+	}
+}
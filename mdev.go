@@ -0,0 +1,158 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/virtcontainers/pkg/uuid"
+)
+
+// Mediated device sysfs entry points. They are variables so the tests
+// can point them at a fake sysfs tree.
+var mdevBusPath = "/sys/class/mdev_bus"
+var mdevDevicesPath = "/sys/bus/mdev/devices"
+
+// mdevManifestFile lists the mediated device instances created for a
+// pod, so they can be cleaned up after a management process crash.
+const mdevManifestFile = "mdev.json"
+
+// MDevDevice describes one mediated device instance to carve out of a
+// parent host device for the pod lifetime, e.g. a vGPU slice of a
+// physical GPU.
+type MDevDevice struct {
+	// ParentBDF is the parent host device the instance is created
+	// from, e.g. "0000:3d:00.0".
+	ParentBDF string
+
+	// Type is the mdev type to instantiate, picked from the parent's
+	// mdev_supported_types directory (e.g. "nvidia-222").
+	Type string
+}
+
+// mdevInstance is one mediated device created for a pod.
+type mdevInstance struct {
+	UUID      string `json:"uuid"`
+	ParentBDF string `json:"parent-bdf"`
+	Type      string `json:"type"`
+}
+
+func mdevManifestPath(podID string) string {
+	return filepath.Join(runStoragePath, podID, mdevManifestFile)
+}
+
+// loadMDevInstances returns the mediated device instances created for
+// the given pod.
+func loadMDevInstances(podID string) ([]mdevInstance, error) {
+	manifest, err := ioutil.ReadFile(mdevManifestPath(podID))
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []mdevInstance
+	if err := json.Unmarshal(manifest, &instances); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// removeMDevInstances destroys the given mediated device instances.
+// Instances that no longer exist are skipped, so a cleanup can be
+// retried safely.
+func removeMDevInstances(instances []mdevInstance) error {
+	var firstErr error
+
+	for _, instance := range instances {
+		removePath := filepath.Join(mdevDevicesPath, instance.UUID, "remove")
+
+		err := ioutil.WriteFile(removePath, []byte("1"), 0200)
+		if err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = fmt.Errorf("Could not remove mdev instance %s: %v", instance.UUID, err)
+		}
+	}
+
+	return firstErr
+}
+
+// createMDevInstances creates the mediated device instances configured
+// for the pod and persists them so they can be cleaned up on deletion
+// or after a crash. Instances created by a previous incarnation of the
+// pod are reused.
+func (p *Pod) createMDevInstances() error {
+	if len(p.config.MDevDevices) == 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(mdevManifestPath(p.id)); err == nil {
+		return nil
+	}
+
+	var instances []mdevInstance
+
+	for _, d := range p.config.MDevDevices {
+		instance := mdevInstance{
+			UUID:      uuid.Generate().String(),
+			ParentBDF: d.ParentBDF,
+			Type:      d.Type,
+		}
+
+		createPath := filepath.Join(mdevBusPath, d.ParentBDF, "mdev_supported_types", d.Type, "create")
+		if err := ioutil.WriteFile(createPath, []byte(instance.UUID), 0200); err != nil {
+			removeMDevInstances(instances)
+			return fmt.Errorf("Could not create a %s mdev instance on %s: %v", d.Type, d.ParentBDF, err)
+		}
+
+		instances = append(instances, instance)
+	}
+
+	manifest, err := json.Marshal(instances)
+	if err != nil {
+		removeMDevInstances(instances)
+		return err
+	}
+
+	if err := ioutil.WriteFile(mdevManifestPath(p.id), manifest, 0640); err != nil {
+		removeMDevInstances(instances)
+		return err
+	}
+
+	return nil
+}
+
+// destroyMDevInstances destroys the mediated device instances created
+// for the pod. The manifest is only removed once every instance is
+// gone, so a failed cleanup can be retried.
+func (p *Pod) destroyMDevInstances() error {
+	instances, err := loadMDevInstances(p.id)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := removeMDevInstances(instances); err != nil {
+		return err
+	}
+
+	return os.Remove(mdevManifestPath(p.id))
+}
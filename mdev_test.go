@@ -0,0 +1,168 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMDevInstancesLifecycle(t *testing.T) {
+	busDir, err := ioutil.TempDir("", "mdev-bus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(busDir)
+
+	devicesDir, err := ioutil.TempDir("", "mdev-devices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(devicesDir)
+
+	savedBusPath := mdevBusPath
+	savedDevicesPath := mdevDevicesPath
+	mdevBusPath = busDir
+	mdevDevicesPath = devicesDir
+	defer func() {
+		mdevBusPath = savedBusPath
+		mdevDevicesPath = savedDevicesPath
+	}()
+
+	parentBDF := "0000:3d:00.0"
+	mdevType := "nvidia-222"
+
+	typeDir := filepath.Join(busDir, parentBDF, "mdev_supported_types", mdevType)
+	if err := os.MkdirAll(typeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(typeDir, "create"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(runStoragePath, testPodID), dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Join(runStoragePath, testPodID))
+
+	pod := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			MDevDevices: []MDevDevice{
+				{
+					ParentBDF: parentBDF,
+					Type:      mdevType,
+				},
+			},
+		},
+	}
+
+	if err := pod.createMDevInstances(); err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := loadMDevInstances(testPodID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(instances) != 1 || instances[0].UUID == "" {
+		t.Fatalf("Got %v\nExpecting one instance with a UUID", instances)
+	}
+
+	created, err := ioutil.ReadFile(filepath.Join(typeDir, "create"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(created) != instances[0].UUID {
+		t.Fatalf("Got %s\nExpecting %s", created, instances[0].UUID)
+	}
+
+	// A second creation reuses the persisted instances.
+	if err := pod.createMDevInstances(); err != nil {
+		t.Fatal(err)
+	}
+
+	removeDir := filepath.Join(devicesDir, instances[0].UUID)
+	if err := os.MkdirAll(removeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(removeDir, "remove"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pod.destroyMDevInstances(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(mdevManifestPath(testPodID)); !os.IsNotExist(err) {
+		t.Fatal("The mdev manifest should be removed with the instances")
+	}
+
+	removed, err := ioutil.ReadFile(filepath.Join(removeDir, "remove"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(removed) != "1" {
+		t.Fatalf("Got %s\nExpecting 1", removed)
+	}
+}
+
+func TestCreateMDevInstancesUnknownType(t *testing.T) {
+	busDir, err := ioutil.TempDir("", "mdev-bus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(busDir)
+
+	savedBusPath := mdevBusPath
+	mdevBusPath = busDir
+	defer func() {
+		mdevBusPath = savedBusPath
+	}()
+
+	pod := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			MDevDevices: []MDevDevice{
+				{
+					ParentBDF: "0000:3d:00.0",
+					Type:      "unknown-type",
+				},
+			},
+		},
+	}
+
+	if err := pod.createMDevInstances(); err == nil {
+		t.Fatal("Creating an mdev instance of an unknown type should fail")
+	}
+}
+
+func TestDestroyMDevInstancesNoManifest(t *testing.T) {
+	pod := &Pod{
+		id:     testPodID,
+		config: &PodConfig{},
+	}
+
+	if err := pod.destroyMDevInstances(); err != nil {
+		t.Fatal(err)
+	}
+}
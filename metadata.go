@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// guestMetadataFile is the file name of the guest metadata image. It
+// lives in the pod runtime directory and is attached to the VM as a
+// read-only NVDIMM device, so that guest images can self-configure
+// before the agent connects.
+const guestMetadataFile = "metadata.img"
+
+// guestMetadataAlign is the size boundary the metadata image is padded
+// to, memory backed devices need page aligned backing files.
+const guestMetadataAlign = 4096
+
+// PodMetadata is the structured metadata exposed to the guest through
+// the metadata image. The image holds its JSON serialization, zero
+// padded to the alignment boundary.
+type PodMetadata struct {
+	// PodID is the pod identifier.
+	PodID string `json:"pod-id"`
+
+	// Hostname is the pod hostname, empty when none is configured.
+	Hostname string `json:"hostname,omitempty"`
+
+	// Containers lists the IDs of the containers the pod was created
+	// with.
+	Containers []string `json:"containers,omitempty"`
+
+	// UserData is the arbitrary user provided metadata.
+	UserData map[string]string `json:"user-data,omitempty"`
+}
+
+// buildGuestMetadata gathers the metadata the guest gets to see for a
+// pod.
+func buildGuestMetadata(podConfig PodConfig) PodMetadata {
+	metadata := PodMetadata{
+		PodID:    podConfig.ID,
+		Hostname: podConfig.Hostname,
+		UserData: podConfig.GuestMetadata,
+	}
+
+	for _, container := range podConfig.Containers {
+		metadata.Containers = append(metadata.Containers, container.ID)
+	}
+
+	return metadata
+}
+
+// writeGuestMetadataImage serializes the metadata into a page aligned
+// image file the hypervisor can expose to the guest, and returns the
+// image size.
+func writeGuestMetadataImage(path string, metadata PodMetadata) (int64, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, err
+	}
+
+	size := (int64(len(data)) + guestMetadataAlign - 1) & ^int64(guestMetadataAlign-1)
+
+	image := make([]byte, size)
+	copy(image, data)
+
+	if err := ioutil.WriteFile(path, image, 0640); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildGuestMetadata(t *testing.T) {
+	podConfig := PodConfig{
+		ID:       testPodID,
+		Hostname: "test-host",
+		Containers: []ContainerConfig{
+			{ID: "100"},
+			{ID: "200"},
+		},
+		GuestMetadata: map[string]string{
+			"key": "value",
+		},
+	}
+
+	expected := PodMetadata{
+		PodID:      testPodID,
+		Hostname:   "test-host",
+		Containers: []string{"100", "200"},
+		UserData: map[string]string{
+			"key": "value",
+		},
+	}
+
+	metadata := buildGuestMetadata(podConfig)
+
+	if reflect.DeepEqual(metadata, expected) == false {
+		t.Fatalf("Got %+v\nExpecting %+v", metadata, expected)
+	}
+}
+
+func TestWriteGuestMetadataImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vc-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metadata := PodMetadata{
+		PodID:      testPodID,
+		Containers: []string{"100"},
+	}
+
+	path := filepath.Join(dir, guestMetadataFile)
+
+	size, err := writeGuestMetadataImage(path, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size%guestMetadataAlign != 0 {
+		t.Fatalf("Image size %d is not %d bytes aligned", size, guestMetadataAlign)
+	}
+
+	image, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int64(len(image)) != size {
+		t.Fatalf("Got image of %d bytes\nExpecting %d bytes", len(image), size)
+	}
+
+	var decoded PodMetadata
+	if err := json.Unmarshal(bytes.TrimRight(image, "\x00"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(decoded, metadata) == false {
+		t.Fatalf("Got %+v\nExpecting %+v", decoded, metadata)
+	}
+}
@@ -72,6 +72,26 @@ func (m *mockHypervisor) getPodConsole(podID string) string {
 	return ""
 }
 
+func (m *mockHypervisor) sendCommand(cmd string, args map[string]interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (m *mockHypervisor) attestationEvidence() (AttestationEvidence, error) {
+	return AttestationEvidence{}, nil
+}
+
+func (m *mockHypervisor) snapshotBlockDevice(deviceID, snapshotFile string) error {
+	return nil
+}
+
+func (m *mockHypervisor) backupBlockDevice(deviceID, target string, incremental bool) error {
+	return nil
+}
+
+func (m *mockHypervisor) saveState(stateFile string) error {
+	return nil
+}
+
 func (m *mockHypervisor) getState() interface{} {
 	return nil
 }
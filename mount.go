@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -31,6 +32,10 @@ import (
 
 var rootfsDir = "rootfs"
 
+// rootfsLayersDir is the shared directory entry holding the read-only
+// image layers an overlay assembled container rootfs is built from.
+var rootfsLayersDir = "rootfs-layers"
+
 func mountLogger() *logrus.Entry {
 	return virtLog.WithField("subsystem", "mount")
 }
@@ -286,6 +291,29 @@ func bindMountContainerRootfs(sharedDir, podID, cID, cRootFs string, readonly bo
 	return bindMount(cRootFs, rootfsDest, readonly)
 }
 
+// bindMountContainerLayers bind mounts the read-only image layers of a
+// container into a 9pfs shared directory between the guest and the host,
+// so the agent can assemble them into an overlayfs rootfs inside the
+// guest. Each layer is shared under its index in the configured order.
+func bindMountContainerLayers(sharedDir, podID, cID string, layers []string) error {
+	for i, layer := range layers {
+		layerDest := filepath.Join(sharedDir, podID, cID, rootfsLayersDir, fmt.Sprintf("%d", i))
+		if err := bindMount(layer, layerDest, true); err != nil {
+			bindUnmountContainerLayers(sharedDir, podID, cID, layers)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bindUnmountContainerLayers(sharedDir, podID, cID string, layers []string) {
+	for i := range layers {
+		layerDest := filepath.Join(sharedDir, podID, cID, rootfsLayersDir, fmt.Sprintf("%d", i))
+		syscall.Unmount(layerDest, 0)
+	}
+}
+
 // Mount describes a container mount.
 type Mount struct {
 	Source      string
@@ -302,6 +330,11 @@ type Mount struct {
 
 	// ReadOnly specifies if the mount should be read only or not
 	ReadOnly bool
+
+	// BlockDevice is the host block device backing this mount, when
+	// the mount is attached as a block device instead of being
+	// shared through the filesystem.
+	BlockDevice string
 }
 
 func bindUnmountContainerRootfs(sharedDir, podID, cID string) error {
@@ -314,10 +347,134 @@ func bindUnmountContainerRootfs(sharedDir, podID, cID string) error {
 func bindUnmountAllRootfs(sharedDir string, pod Pod) {
 	for _, c := range pod.containers {
 		c.unmountHostMounts()
-		if c.state.Fstype == "" {
+		if len(c.rootfsLayers) > 0 {
+			bindUnmountContainerLayers(sharedDir, pod.id, c.id, c.rootfsLayers)
+		} else if c.state.Fstype == "" {
 			// Need to check for error returned by this call.
 			// See: https://github.com/containers/virtcontainers/issues/295
 			bindUnmountContainerRootfs(sharedDir, pod.id, c.id)
 		}
 	}
 }
+
+// blockMountSource checks whether a mount source is backed by a host
+// block device that can be attached to the VM directly. It returns the
+// device path and filesystem type, or an empty device path when the
+// source is not block-backed.
+func blockMountSource(source string) (string, string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The source can be a block device node itself.
+	if info.Mode()&os.ModeDevice != 0 && info.Mode()&os.ModeCharDevice == 0 {
+		return source, "", nil
+	}
+
+	dev, err := getDeviceForPath(source)
+	if err == errMountPointNotFound {
+		return "", "", nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	// Only a source that is its own mount point can be attached as a
+	// device, otherwise the device would expose more than the volume.
+	if dev.mountPoint != source {
+		return "", "", nil
+	}
+
+	isDM, err := checkStorageDriver(dev.major, dev.minor)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !isDM {
+		return "", "", nil
+	}
+
+	return getDevicePathAndFsType(dev.mountPoint)
+}
+
+const (
+	mkfsExt4BinaryName = "mkfs.ext4"
+
+	// directVolumeMinSize is the minimum size of a direct volume
+	// image, so that small directories still leave the workload some
+	// room to write.
+	directVolumeMinSize = 32 * 1024 * 1024
+)
+
+// directVolumeSize returns the size of the disk image to create for a
+// direct volume populated from dir: the space used by the directory
+// plus some headroom.
+func directVolumeSize(dir string) (int64, error) {
+	var used int64
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		used += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	size := used + used/4
+	if size < directVolumeMinSize {
+		size = directVolumeMinSize
+	}
+
+	return size, nil
+}
+
+// createDirectVolumeImage creates a disk image at imagePath holding an
+// ext4 filesystem populated with the contents of sourceDir.
+func createDirectVolumeImage(imagePath, sourceDir string) error {
+	info, err := os.Stat(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("Direct volume source %s is not a directory", sourceDir)
+	}
+
+	size, err := directVolumeSize(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	image, err := os.Create(imagePath)
+	if err != nil {
+		return err
+	}
+
+	if err := image.Truncate(size); err != nil {
+		image.Close()
+		return err
+	}
+
+	if err := image.Close(); err != nil {
+		return err
+	}
+
+	binPath, err := exec.LookPath(mkfsExt4BinaryName)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binPath, "-F", "-q", "-d", sourceDir, imagePath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Could not create the direct volume image %s: %v: %s", imagePath, err, output)
+	}
+
+	return nil
+}
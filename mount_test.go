@@ -19,6 +19,7 @@ package virtcontainers
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -282,3 +283,119 @@ func TestGetVirtDriveName(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockMountSourceNotBlockBacked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "block-mount-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	devicePath, _, err := blockMountSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if devicePath != "" {
+		t.Fatalf("Expected no device path for %s, got %s", dir, devicePath)
+	}
+}
+
+func TestBlockMountSourceNonExistent(t *testing.T) {
+	_, _, err := blockMountSource("/this/path/does/not/exist")
+	if err == nil {
+		t.Fatal("Expected an error for a non existent mount source")
+	}
+}
+
+func TestDirectVolumeSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "direct-volume-size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	size, err := directVolumeSize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != directVolumeMinSize {
+		t.Fatalf("Expected the minimum size %d for an empty directory, got %d", directVolumeMinSize, size)
+	}
+
+	data := make([]byte, 2*directVolumeMinSize)
+	if err := ioutil.WriteFile(filepath.Join(dir, "data"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err = directVolumeSize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size <= 2*directVolumeMinSize {
+		t.Fatalf("Expected the directory contents plus headroom, got %d", size)
+	}
+}
+
+func TestCreateDirectVolumeImageNotADirectory(t *testing.T) {
+	file, err := ioutil.TempFile("", "direct-volume-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	err = createDirectVolumeImage(filepath.Join(os.TempDir(), "direct-volume.img"), file.Name())
+	if err == nil {
+		t.Fatal("Expected an error for a source that is not a directory")
+	}
+}
+
+func TestBindMountContainerLayers(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip(testDisabledAsNonRoot)
+	}
+
+	sharedDir, err := ioutil.TempDir("", "shared-layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sharedDir)
+
+	var layers []string
+	for i := 0; i < 2; i++ {
+		layer, err := ioutil.TempDir("", "layer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(layer)
+
+		marker := filepath.Join(layer, fmt.Sprintf("layer-%d", i))
+		if err := ioutil.WriteFile(marker, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if err := bindMountContainerLayers(sharedDir, testPodID, testContainerID, layers); err != nil {
+		t.Fatal(err)
+	}
+	defer bindUnmountContainerLayers(sharedDir, testPodID, testContainerID, layers)
+
+	for i := range layers {
+		layerDest := filepath.Join(sharedDir, testPodID, testContainerID, rootfsLayersDir, fmt.Sprintf("%d", i))
+
+		marker := filepath.Join(layerDest, fmt.Sprintf("layer-%d", i))
+		if _, err := os.Stat(marker); err != nil {
+			t.Fatal(err)
+		}
+
+		// Layers must be shared read-only.
+		if err := ioutil.WriteFile(filepath.Join(layerDest, "scratch"), []byte{}, 0644); err == nil {
+			t.Fatalf("Layer %d is not mounted read-only", i)
+		}
+	}
+}
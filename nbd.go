@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// nbdServerBinary is the NBD server binary exporting pod drives for
+// offline inspection. It is looked up in the regular binary search
+// path.
+const nbdServerBinary = "qemu-nbd"
+
+// nbdExportsFile is the file recording the active NBD exports of a
+// pod. Its presence prevents the pod from being started.
+const nbdExportsFile = "nbd-exports.json"
+
+// NBDExport describes one pod drive exported over a unix socket NBD
+// server.
+type NBDExport struct {
+	// ContainerID is the container the exported drive belongs to.
+	ContainerID string `json:"containerID"`
+
+	// Device is the host block device or image file exported.
+	Device string `json:"device"`
+
+	// Socket is the unix socket the NBD server listens on.
+	Socket string `json:"socket"`
+
+	// Pid is the process ID of the NBD server.
+	Pid int `json:"pid"`
+}
+
+func nbdExportsPath(podID string) string {
+	return filepath.Join(runStoragePath, podID, nbdExportsFile)
+}
+
+// nbdExportActive tells whether the pod storage is currently exported
+// over NBD.
+func nbdExportActive(podID string) bool {
+	_, err := os.Stat(nbdExportsPath(podID))
+	return err == nil
+}
+
+// exportNBD exports the block devices backing the pod containers over
+// per-drive unix socket NBD servers, for offline inspection and repair
+// tooling. The pod cannot be started again until the exports are
+// withdrawn with unexportNBD.
+func (p *Pod) exportNBD() ([]NBDExport, error) {
+	if p.state.State != StateStopped {
+		return nil, fmt.Errorf("Pod not stopped, impossible to export its storage")
+	}
+
+	if nbdExportActive(p.id) {
+		return nil, fmt.Errorf("Pod %s storage is already exported", p.id)
+	}
+
+	binPath, err := exec.LookPath(nbdServerBinary)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find the NBD server %s: %v", nbdServerBinary, err)
+	}
+
+	var exports []NBDExport
+
+	for _, c := range p.containers {
+		device, err := c.blockDevicePath()
+		if err != nil {
+			stopNBDExports(exports)
+			return nil, err
+		}
+
+		if device == "" {
+			continue
+		}
+
+		socket := filepath.Join(runStoragePath, p.id, fmt.Sprintf("nbd-%s.sock", c.id))
+
+		cmd := exec.Command(binPath, "--socket", socket, "--format", "raw", device)
+		if err := cmd.Start(); err != nil {
+			stopNBDExports(exports)
+			return nil, err
+		}
+
+		// The server is reparented to init, the export outlives
+		// this process.
+		cmd.Process.Release()
+
+		exports = append(exports, NBDExport{
+			ContainerID: c.id,
+			Device:      device,
+			Socket:      socket,
+			Pid:         cmd.Process.Pid,
+		})
+	}
+
+	if len(exports) == 0 {
+		return nil, fmt.Errorf("Pod %s has no block device to export", p.id)
+	}
+
+	data, err := json.Marshal(exports)
+	if err != nil {
+		stopNBDExports(exports)
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(nbdExportsPath(p.id), data, 0640); err != nil {
+		stopNBDExports(exports)
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// unexportNBD stops the NBD servers exporting the pod storage and
+// lifts the start lock.
+func (p *Pod) unexportNBD() error {
+	data, err := ioutil.ReadFile(nbdExportsPath(p.id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("Pod %s storage is not exported", p.id)
+		}
+
+		return err
+	}
+
+	var exports []NBDExport
+	if err := json.Unmarshal(data, &exports); err != nil {
+		return err
+	}
+
+	stopNBDExports(exports)
+
+	return os.Remove(nbdExportsPath(p.id))
+}
+
+// stopNBDExports terminates the NBD servers backing the given exports
+// and removes their sockets.
+func stopNBDExports(exports []NBDExport) {
+	for _, export := range exports {
+		if export.Pid > 0 {
+			syscall.Kill(export.Pid, syscall.SIGTERM)
+		}
+
+		os.Remove(export.Socket)
+	}
+}
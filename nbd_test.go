@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportNBDNotStopped(t *testing.T) {
+	pod := &Pod{
+		id:    testPodID,
+		state: State{State: StateRunning},
+	}
+
+	if _, err := pod.exportNBD(); err == nil {
+		t.Fatal("Exporting a running pod should fail")
+	}
+}
+
+func TestPodStartExportedFails(t *testing.T) {
+	podDir := filepath.Join(runStoragePath, testPodID)
+	if err := os.MkdirAll(podDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(podDir)
+
+	if err := ioutil.WriteFile(nbdExportsPath(testPodID), []byte("[]"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &Pod{
+		id:    testPodID,
+		state: State{State: StateStopped},
+	}
+
+	if err := pod.start(); err == nil {
+		t.Fatal("Starting an exported pod should fail")
+	}
+}
+
+func TestUnexportNBDNotExported(t *testing.T) {
+	pod := &Pod{
+		id:    testPodID,
+		state: State{State: StateStopped},
+	}
+
+	if err := pod.unexportNBD(); err == nil {
+		t.Fatal("Withdrawing a non existing export should fail")
+	}
+}
+
+func TestExportPodFailures(t *testing.T) {
+	if _, err := ExportPod(""); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	if err := UnexportPod(""); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	cleanUp()
+
+	if _, err := ExportPod(testPodID); err == nil {
+		t.Fatal("Exporting a non existing pod should fail")
+	}
+}
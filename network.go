@@ -17,7 +17,6 @@
 package virtcontainers
 
 import (
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -143,6 +142,18 @@ type NetworkInterfacePair struct {
 	NetInterworkingModel
 	VMFds    []*os.File
 	VhostFds []*os.File
+
+	// ProxyArpNdp enables ARP and NDP proxying on the host side of
+	// the pair, for fabrics that filter on the original veth MAC.
+	ProxyArpNdp bool
+
+	// TapMACInheritance makes the TAP device take over the original
+	// veth MAC, while the veth keeps the generated one.
+	TapMACInheritance bool
+
+	// Conntrack describes how the host connection tracker should
+	// handle the traffic going through the pair.
+	Conntrack ConntrackBypass
 }
 
 // NetworkConfig is the network configuration related to a network.
@@ -150,16 +161,43 @@ type NetworkConfig struct {
 	NetNSPath         string
 	NumInterfaces     int
 	InterworkingModel NetInterworkingModel
+
+	// VFConfigs lists the SR-IOV virtual functions to program on
+	// their physical functions before passthrough.
+	VFConfigs []VFConfig
+
+	// GuestInterfaceNames maps an endpoint host interface name to
+	// the name it should carry inside the guest, since the default
+	// eth0/eth1 ordering is nondeterministic with several NICs.
+	GuestInterfaceNames map[string]string
+
+	// ProxyArpNdp enables ARP and NDP proxying on the host side of
+	// every bridged endpoint. The guest answers with its own MAC,
+	// which MAC-filtering fabrics (e.g. cloud VPCs) would otherwise
+	// drop.
+	ProxyArpNdp bool
+
+	// TapMACInheritance makes the TAP device of every bridged
+	// endpoint take over the original veth MAC, while the veth keeps
+	// the generated one, so cloud provider anti-spoofing does not
+	// drop pod traffic.
+	TapMACInheritance bool
+
+	// ConntrackBypass maps an endpoint host interface name to the
+	// way its traffic should bypass the host connection tracker.
+	ConntrackBypass map[string]ConntrackBypass
 }
 
 // Endpoint represents a physical or virtual network interface.
 type Endpoint interface {
 	Properties() NetworkInfo
 	Name() string
+	GuestName() string
 	HardwareAddr() string
 	Type() EndpointType
 
 	SetProperties(NetworkInfo)
+	SetGuestName(string)
 	Attach(hypervisor) error
 	Detach() error
 }
@@ -170,6 +208,7 @@ type VirtualEndpoint struct {
 	EndpointProperties NetworkInfo
 	Physical           bool
 	EndpointType       EndpointType
+	GuestIfaceName     string
 }
 
 // PhysicalEndpoint gathers a physical network interface and its properties
@@ -181,6 +220,7 @@ type PhysicalEndpoint struct {
 	BDF                string
 	Driver             string
 	VendorDeviceID     string
+	GuestIfaceName     string
 }
 
 // VhostUserEndpoint represents a vhost-user socket based network interface
@@ -192,6 +232,7 @@ type VhostUserEndpoint struct {
 	IfaceName          string
 	EndpointProperties NetworkInfo
 	EndpointType       EndpointType
+	GuestIfaceName     string
 }
 
 // Properties returns properties for the veth interface in the network pair.
@@ -220,6 +261,20 @@ func (endpoint *VirtualEndpoint) SetProperties(properties NetworkInfo) {
 	endpoint.EndpointProperties = properties
 }
 
+// GuestName returns the name the interface should have inside the
+// guest, defaulting to the host side name.
+func (endpoint *VirtualEndpoint) GuestName() string {
+	if endpoint.GuestIfaceName != "" {
+		return endpoint.GuestIfaceName
+	}
+	return endpoint.Name()
+}
+
+// SetGuestName sets the name the interface should have inside the guest.
+func (endpoint *VirtualEndpoint) SetGuestName(name string) {
+	endpoint.GuestIfaceName = name
+}
+
 func networkLogger() *logrus.Entry {
 	return virtLog.WithField("subsystem", "network")
 }
@@ -268,22 +323,30 @@ func (endpoint *VhostUserEndpoint) SetProperties(properties NetworkInfo) {
 	endpoint.EndpointProperties = properties
 }
 
+// GuestName returns the name the interface should have inside the
+// guest, defaulting to the host side name.
+func (endpoint *VhostUserEndpoint) GuestName() string {
+	if endpoint.GuestIfaceName != "" {
+		return endpoint.GuestIfaceName
+	}
+	return endpoint.Name()
+}
+
+// SetGuestName sets the name the interface should have inside the guest.
+func (endpoint *VhostUserEndpoint) SetGuestName(name string) {
+	endpoint.GuestIfaceName = name
+}
+
 // Attach for vhostuser endpoint
 func (endpoint *VhostUserEndpoint) Attach(h hypervisor) error {
 	networkLogger().Info("Attaching vhostuser based endpoint")
 
-	// generate a unique ID to be used for hypervisor commandline fields
-	randBytes, err := generateRandomBytes(8)
-	if err != nil {
-		return err
-	}
-	id := hex.EncodeToString(randBytes)
-
+	// derive a stable ID to be used for hypervisor commandline fields
 	d := VhostUserNetDevice{
 		MacAddress: endpoint.HardAddr,
 	}
 	d.SocketPath = endpoint.SocketPath
-	d.ID = id
+	d.ID = contentID(endpoint.IfaceName, endpoint.SocketPath)
 
 	return h.addDevice(d, vhostuserDev)
 }
@@ -331,6 +394,20 @@ func (endpoint *PhysicalEndpoint) SetProperties(properties NetworkInfo) {
 	endpoint.EndpointProperties = properties
 }
 
+// GuestName returns the name the interface should have inside the
+// guest, defaulting to the host side name.
+func (endpoint *PhysicalEndpoint) GuestName() string {
+	if endpoint.GuestIfaceName != "" {
+		return endpoint.GuestIfaceName
+	}
+	return endpoint.Name()
+}
+
+// SetGuestName sets the name the interface should have inside the guest.
+func (endpoint *PhysicalEndpoint) SetGuestName(name string) {
+	endpoint.GuestIfaceName = name
+}
+
 // Attach for physical endpoint binds the physical network interface to
 // vfio-pci and adds device to the hypervisor with vfio-passthrough.
 func (endpoint *PhysicalEndpoint) Attach(h hypervisor) error {
@@ -542,6 +619,10 @@ func (networkType *NetworkModel) Set(value string) error {
 		*networkType = CNMNetworkModel
 		return nil
 	default:
+		if _, ok := registeredNetworkModel(NetworkModel(value)); ok {
+			*networkType = NetworkModel(value)
+			return nil
+		}
 		return fmt.Errorf("Unknown network type %s", value)
 	}
 }
@@ -556,6 +637,9 @@ func (networkType *NetworkModel) String() string {
 	case CNMNetworkModel:
 		return string(CNMNetworkModel)
 	default:
+		if _, ok := registeredNetworkModel(*networkType); ok {
+			return string(*networkType)
+		}
 		return ""
 	}
 }
@@ -570,6 +654,9 @@ func newNetwork(networkType NetworkModel) network {
 	case CNMNetworkModel:
 		return &cnm{}
 	default:
+		if implementation, ok := registeredNetworkModel(networkType); ok {
+			return &pluggableNetwork{impl: implementation}
+		}
 		return &noopNetwork{}
 	}
 }
@@ -611,19 +698,29 @@ func addNetworkCommon(pod Pod, networkNS *NetworkNamespace) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	return recordNetworkArtifacts(pod.id, *networkNS)
 }
 
 func removeNetworkCommon(networkNS NetworkNamespace) error {
 	return doNetNS(networkNS.NetNsPath, func(_ ns.NetNS) error {
+		// Detach every endpoint even when one of them fails, so
+		// that a single broken endpoint does not leak the taps
+		// and bridges of the others.
+		var firstErr error
 		for _, endpoint := range networkNS.Endpoints {
 			if err := endpoint.Detach(); err != nil {
-				return err
+				networkLogger().WithError(err).Warn("Could not detach endpoint")
+				if firstErr == nil {
+					firstErr = err
+				}
 			}
 		}
 
-		return nil
+		return firstErr
 	})
 }
 
@@ -707,6 +804,22 @@ func getLinkByName(netHandle *netlink.Handle, name string, expectedLink netlink.
 	return nil, fmt.Errorf("Incorrect link type %s, expecting %s", link.Type(), expectedLink.Type())
 }
 
+// enableProxyArpNdp turns on ARP and NDP proxying on the given
+// interface, so the host answers on behalf of the guest when the
+// fabric filters on the expected veth MAC.
+func enableProxyArpNdp(ifaceName string) error {
+	for _, sysctlPath := range []string{
+		fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/proxy_arp", ifaceName),
+		fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/proxy_ndp", ifaceName),
+	} {
+		if err := ioutil.WriteFile(sysctlPath, []byte("1"), 0644); err != nil {
+			return fmt.Errorf("Could not enable ARP/NDP proxying on %s: %v", ifaceName, err)
+		}
+	}
+
+	return nil
+}
+
 // The endpoint type should dictate how the connection needs to be made
 func xconnectVMNetwork(netPair *NetworkInterfacePair, connect bool) error {
 	if netPair.NetInterworkingModel == NetXConnectDefaultModel {
@@ -848,6 +961,16 @@ func tapNetworkPair(netPair *NetworkInterfacePair) error {
 	tapHardAddr := vethLinkAttrs.HardwareAddr
 	netPair.TAPIface.HardAddr = vethLinkAttrs.HardwareAddr.String()
 
+	// With MAC inheritance the TAP device itself takes over the
+	// original veth MAC, so every frame leaving the pod carries the
+	// address the fabric expects.
+	if netPair.TapMACInheritance {
+		if err := netHandle.LinkSetHardwareAddr(tapLink, vethLinkAttrs.HardwareAddr); err != nil {
+			return fmt.Errorf("Could not set TAP MAC address %s: %s",
+				vethLinkAttrs.HardwareAddr, err)
+		}
+	}
+
 	if err := netHandle.LinkSetMTU(tapLink, vethLinkAttrs.MTU); err != nil {
 		return fmt.Errorf("Could not set TAP MTU %d: %s", vethLinkAttrs.MTU, err)
 	}
@@ -884,6 +1007,18 @@ func tapNetworkPair(netPair *NetworkInterfacePair) error {
 		return fmt.Errorf("Could not enable veth %s: %s", netPair.VirtIface.Name, err)
 	}
 
+	if netPair.ProxyArpNdp {
+		if err := enableProxyArpNdp(netPair.TAPIface.Name); err != nil {
+			return err
+		}
+	}
+
+	if netPair.Conntrack.enabled() {
+		if err := setupConntrackBypass(netPair.VirtIface.Name, netPair.Conntrack); err != nil {
+			return err
+		}
+	}
+
 	// Note: The underlying interfaces need to be up prior to fd creation.
 
 	// Setup the multiqueue fds to be consumed by QEMU as macvtap cannot
@@ -942,6 +1077,16 @@ func bridgeNetworkPair(netPair *NetworkInterfacePair) error {
 	// to see traffic from this MAC address and not another one.
 	netPair.TAPIface.HardAddr = vethLinkAttrs.HardwareAddr.String()
 
+	// With MAC inheritance the TAP device itself takes over the
+	// original veth MAC, so every frame leaving the pod carries the
+	// address the fabric expects.
+	if netPair.TapMACInheritance {
+		if err := netHandle.LinkSetHardwareAddr(tapLink, vethLinkAttrs.HardwareAddr); err != nil {
+			return fmt.Errorf("Could not set TAP MAC address %s: %s",
+				vethLinkAttrs.HardwareAddr, err)
+		}
+	}
+
 	if err := netHandle.LinkSetMTU(tapLink, vethLinkAttrs.MTU); err != nil {
 		return fmt.Errorf("Could not set TAP MTU %d: %s", vethLinkAttrs.MTU, err)
 	}
@@ -983,23 +1128,45 @@ func bridgeNetworkPair(netPair *NetworkInterfacePair) error {
 		return fmt.Errorf("Could not enable bridge %s: %s", netPair.Name, err)
 	}
 
+	if netPair.ProxyArpNdp {
+		if err := enableProxyArpNdp(netPair.Name); err != nil {
+			return err
+		}
+	}
+
+	if netPair.Conntrack.enabled() {
+		if err := setupConntrackBypass(netPair.VirtIface.Name, netPair.Conntrack); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func untapNetworkPair(netPair NetworkInterfacePair) error {
+	if netPair.Conntrack.enabled() {
+		if err := clearConntrackBypass(netPair.VirtIface.Name, netPair.Conntrack); err != nil {
+			virtLog.Warnf("Could not remove the conntrack bypass rules for %s: %s",
+				netPair.VirtIface.Name, err)
+		}
+	}
+
 	netHandle, err := netlink.NewHandle()
 	if err != nil {
 		return err
 	}
 	defer netHandle.Delete()
 
+	// Remove every artifact even when an intermediate step fails,
+	// recording the first error, so that a broken TAP does not
+	// leave the veth in its pre-start state.
+	var firstErr error
+
 	tapLink, err := getLinkByName(netHandle, netPair.TAPIface.Name, &netlink.Macvtap{})
 	if err != nil {
-		return fmt.Errorf("Could not get TAP interface %s: %s", netPair.TAPIface.Name, err)
-	}
-
-	if err := netHandle.LinkDel(tapLink); err != nil {
-		return fmt.Errorf("Could not remove TAP %s: %s", netPair.TAPIface.Name, err)
+		firstErr = fmt.Errorf("Could not get TAP interface %s: %s", netPair.TAPIface.Name, err)
+	} else if err := netHandle.LinkDel(tapLink); err != nil {
+		firstErr = fmt.Errorf("Could not remove TAP %s: %s", netPair.TAPIface.Name, err)
 	}
 
 	vethLink, err := getLinkByName(netHandle, netPair.VirtIface.Name, &netlink.Veth{})
@@ -1007,51 +1174,80 @@ func untapNetworkPair(netPair NetworkInterfacePair) error {
 		// The veth pair is not totally managed by virtcontainers
 		virtLog.Warnf("Could not get veth interface %s: %s", netPair.VirtIface.Name, err)
 	} else {
-		if err := netHandle.LinkSetDown(vethLink); err != nil {
-			return fmt.Errorf("Could not disable veth %s: %s", netPair.VirtIface.Name, err)
+		if err := netHandle.LinkSetDown(vethLink); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("Could not disable veth %s: %s", netPair.VirtIface.Name, err)
 		}
 	}
 
 	// Restore the IPs that were cleared
-	err = setIPs(vethLink, netPair.VirtIface.Addrs)
-	return err
+	if err := setIPs(vethLink, netPair.VirtIface.Addrs); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
 }
 
 func unBridgeNetworkPair(netPair NetworkInterfacePair) error {
+	if netPair.Conntrack.enabled() {
+		if err := clearConntrackBypass(netPair.VirtIface.Name, netPair.Conntrack); err != nil {
+			virtLog.Warnf("Could not remove the conntrack bypass rules for %s: %s",
+				netPair.VirtIface.Name, err)
+		}
+	}
+
 	netHandle, err := netlink.NewHandle()
 	if err != nil {
 		return err
 	}
 	defer netHandle.Delete()
 
-	tapLink, err := getLinkByName(netHandle, netPair.TAPIface.Name, &netlink.Tuntap{})
-	if err != nil {
-		return fmt.Errorf("Could not get TAP interface: %s", err)
+	// Remove every artifact even when an intermediate step fails,
+	// recording the first error, so that a broken bridge does not
+	// leak the TAP device or the other way around.
+	var firstErr error
+
+	recordErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
 
-	bridgeLink, err := getLinkByName(netHandle, netPair.Name, &netlink.Bridge{})
-	if err != nil {
-		return fmt.Errorf("Could not get bridge interface: %s", err)
+	tapLink, tapErr := getLinkByName(netHandle, netPair.TAPIface.Name, &netlink.Tuntap{})
+	if tapErr != nil {
+		recordErr(fmt.Errorf("Could not get TAP interface: %s", tapErr))
 	}
 
-	if err := netHandle.LinkSetDown(bridgeLink); err != nil {
-		return fmt.Errorf("Could not disable bridge %s: %s", netPair.Name, err)
+	bridgeLink, bridgeErr := getLinkByName(netHandle, netPair.Name, &netlink.Bridge{})
+	if bridgeErr != nil {
+		recordErr(fmt.Errorf("Could not get bridge interface: %s", bridgeErr))
 	}
 
-	if err := netHandle.LinkSetDown(tapLink); err != nil {
-		return fmt.Errorf("Could not disable TAP %s: %s", netPair.TAPIface.Name, err)
+	if bridgeErr == nil {
+		if err := netHandle.LinkSetDown(bridgeLink); err != nil {
+			recordErr(fmt.Errorf("Could not disable bridge %s: %s", netPair.Name, err))
+		}
 	}
 
-	if err := netHandle.LinkSetNoMaster(tapLink); err != nil {
-		return fmt.Errorf("Could not detach TAP %s: %s", netPair.TAPIface.Name, err)
+	if tapErr == nil {
+		if err := netHandle.LinkSetDown(tapLink); err != nil {
+			recordErr(fmt.Errorf("Could not disable TAP %s: %s", netPair.TAPIface.Name, err))
+		}
+
+		if err := netHandle.LinkSetNoMaster(tapLink); err != nil {
+			recordErr(fmt.Errorf("Could not detach TAP %s: %s", netPair.TAPIface.Name, err))
+		}
 	}
 
-	if err := netHandle.LinkDel(bridgeLink); err != nil {
-		return fmt.Errorf("Could not remove bridge %s: %s", netPair.Name, err)
+	if bridgeErr == nil {
+		if err := netHandle.LinkDel(bridgeLink); err != nil {
+			recordErr(fmt.Errorf("Could not remove bridge %s: %s", netPair.Name, err))
+		}
 	}
 
-	if err := netHandle.LinkDel(tapLink); err != nil {
-		return fmt.Errorf("Could not remove TAP %s: %s", netPair.TAPIface.Name, err)
+	if tapErr == nil {
+		if err := netHandle.LinkDel(tapLink); err != nil {
+			recordErr(fmt.Errorf("Could not remove TAP %s: %s", netPair.TAPIface.Name, err))
+		}
 	}
 
 	vethLink, err := getLinkByName(netHandle, netPair.VirtIface.Name, &netlink.Veth{})
@@ -1060,16 +1256,16 @@ func unBridgeNetworkPair(netPair NetworkInterfacePair) error {
 		virtLog.WithError(err).Warn("Could not get veth interface")
 	} else {
 		if err := netHandle.LinkSetDown(vethLink); err != nil {
-			return fmt.Errorf("Could not disable veth %s: %s", netPair.VirtIface.Name, err)
+			recordErr(fmt.Errorf("Could not disable veth %s: %s", netPair.VirtIface.Name, err))
 		}
 
 		if err := netHandle.LinkSetNoMaster(vethLink); err != nil {
-			return fmt.Errorf("Could not detach veth %s: %s", netPair.VirtIface.Name, err)
+			recordErr(fmt.Errorf("Could not detach veth %s: %s", netPair.VirtIface.Name, err))
 		}
 
 	}
 
-	return nil
+	return firstErr
 }
 
 func createNetNS() (string, error) {
@@ -1277,6 +1473,16 @@ func createEndpointsFromScan(networkNSPath string, config NetworkConfig) ([]Endp
 		}
 
 		endpoint.SetProperties(netInfo)
+		if guestName, ok := config.GuestInterfaceNames[netInfo.Iface.Name]; ok {
+			endpoint.SetGuestName(guestName)
+		}
+		if virtualEndpoint, ok := endpoint.(*VirtualEndpoint); ok {
+			virtualEndpoint.NetPair.ProxyArpNdp = config.ProxyArpNdp
+			virtualEndpoint.NetPair.TapMACInheritance = config.TapMACInheritance
+			if bypass, ok := config.ConntrackBypass[netInfo.Iface.Name]; ok {
+				virtualEndpoint.NetPair.Conntrack = bypass
+			}
+		}
 		endpoints = append(endpoints, endpoint)
 
 		idx++
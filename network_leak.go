@@ -0,0 +1,184 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+)
+
+// networkArtifactsPath is where the per-pod network artifacts
+// manifests are kept. It is a variable so the tests can point it at a
+// temporary directory.
+var networkArtifactsPath = "/run/virtcontainers/network-artifacts"
+
+// NetworkLeak describes the host network artifacts still referencing
+// a deleted pod.
+type NetworkLeak struct {
+	// PodID is the deleted pod the artifacts were created for.
+	PodID string
+
+	// NetNsPath is the leaked network namespace mount, if it still
+	// exists.
+	NetNsPath string
+
+	// Links lists the interfaces (taps, bridges) still present in
+	// the leaked network namespace.
+	Links []string
+}
+
+// networkArtifacts lists the host network artifacts created for a
+// pod. It is persisted outside the pod storage so it survives the pod
+// deletion and lets LeakCheck attribute leftovers.
+type networkArtifacts struct {
+	PodID     string   `json:"pod-id"`
+	NetNsPath string   `json:"netns-path"`
+	Links     []string `json:"links"`
+}
+
+func networkArtifactsManifestPath(podID string) string {
+	return filepath.Join(networkArtifactsPath, podID+".json")
+}
+
+// recordNetworkArtifacts persists the list of host network artifacts
+// created for the pod, so a leak can be attributed to it after the
+// pod storage is gone.
+func recordNetworkArtifacts(podID string, networkNS NetworkNamespace) error {
+	var links []string
+	for _, endpoint := range networkNS.Endpoints {
+		if virtualEndpoint, ok := endpoint.(*VirtualEndpoint); ok {
+			links = append(links, virtualEndpoint.NetPair.Name,
+				virtualEndpoint.NetPair.TAPIface.Name)
+		}
+	}
+
+	artifacts := networkArtifacts{
+		PodID:     podID,
+		NetNsPath: networkNS.NetNsPath,
+		Links:     links,
+	}
+
+	manifest, err := json.Marshal(artifacts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(networkArtifactsPath, dirMode); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(networkArtifactsManifestPath(podID), manifest, 0640)
+}
+
+// clearNetworkArtifacts drops the artifacts manifest of a pod whose
+// network has been fully torn down.
+func clearNetworkArtifacts(podID string) error {
+	if err := os.Remove(networkArtifactsManifestPath(podID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// checkNetworkArtifacts returns the artifacts of the manifest that
+// still exist on the host.
+func checkNetworkArtifacts(artifacts networkArtifacts) NetworkLeak {
+	leak := NetworkLeak{
+		PodID: artifacts.PodID,
+	}
+
+	if _, err := os.Stat(artifacts.NetNsPath); err != nil {
+		return leak
+	}
+
+	leak.NetNsPath = artifacts.NetNsPath
+
+	// The links only need to be checked when the namespace can
+	// still be entered.
+	doNetNS(artifacts.NetNsPath, func(_ ns.NetNS) error {
+		netHandle, err := netlink.NewHandle()
+		if err != nil {
+			return err
+		}
+		defer netHandle.Delete()
+
+		for _, link := range artifacts.Links {
+			if _, err := netHandle.LinkByName(link); err == nil {
+				leak.Links = append(leak.Links, link)
+			}
+		}
+
+		return nil
+	})
+
+	return leak
+}
+
+// leakCheck lists the host network artifacts still referencing
+// deleted pods. Manifests whose artifacts are all gone are dropped
+// along the way.
+func leakCheck() ([]NetworkLeak, error) {
+	manifests, err := ioutil.ReadDir(networkArtifactsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []NetworkLeak{}, nil
+		}
+		return []NetworkLeak{}, err
+	}
+
+	var leaks []NetworkLeak
+
+	for _, entry := range manifests {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		manifest, err := ioutil.ReadFile(filepath.Join(networkArtifactsPath, entry.Name()))
+		if err != nil {
+			return []NetworkLeak{}, err
+		}
+
+		var artifacts networkArtifacts
+		if err := json.Unmarshal(manifest, &artifacts); err != nil {
+			return []NetworkLeak{}, fmt.Errorf("Could not parse the network artifacts manifest %s: %s",
+				entry.Name(), err)
+		}
+
+		// A pod still present in the store is not a leak.
+		if _, err := os.Stat(filepath.Join(configStoragePath, artifacts.PodID)); err == nil {
+			continue
+		}
+
+		leak := checkNetworkArtifacts(artifacts)
+		if leak.NetNsPath == "" && len(leak.Links) == 0 {
+			if err := clearNetworkArtifacts(artifacts.PodID); err != nil {
+				return []NetworkLeak{}, err
+			}
+			continue
+		}
+
+		leaks = append(leaks, leak)
+	}
+
+	return leaks, nil
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLeakCheckNoManifests(t *testing.T) {
+	savedArtifactsPath := networkArtifactsPath
+	networkArtifactsPath = filepath.Join(os.TempDir(), "nonexistent-artifacts")
+	defer func() {
+		networkArtifactsPath = savedArtifactsPath
+	}()
+
+	leaks, err := LeakCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(leaks) != 0 {
+		t.Fatalf("Got %v\nExpecting no leaks", leaks)
+	}
+}
+
+func TestRecordAndClearNetworkArtifacts(t *testing.T) {
+	artifactsDir, err := ioutil.TempDir("", "vc-artifacts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(artifactsDir)
+
+	savedArtifactsPath := networkArtifactsPath
+	networkArtifactsPath = artifactsDir
+	defer func() {
+		networkArtifactsPath = savedArtifactsPath
+	}()
+
+	networkNS := NetworkNamespace{
+		NetNsPath: "/var/run/netns/test",
+		Endpoints: []Endpoint{
+			&VirtualEndpoint{
+				NetPair: NetworkInterfacePair{
+					Name: "br0",
+					TAPIface: NetworkInterface{
+						Name: "tap0",
+					},
+				},
+			},
+		},
+	}
+
+	if err := recordNetworkArtifacts(testPodID, networkNS); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(networkArtifactsManifestPath(testPodID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clearNetworkArtifacts(testPodID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(networkArtifactsManifestPath(testPodID)); err == nil {
+		t.Fatal("The artifacts manifest should be gone")
+	}
+
+	// Clearing artifacts that were already cleared should not fail.
+	if err := clearNetworkArtifacts(testPodID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeakCheckDeletedPod(t *testing.T) {
+	artifactsDir, err := ioutil.TempDir("", "vc-artifacts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(artifactsDir)
+
+	configDir, err := ioutil.TempDir("", "vc-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configDir)
+
+	savedArtifactsPath := networkArtifactsPath
+	savedConfigPath := configStoragePath
+	networkArtifactsPath = artifactsDir
+	configStoragePath = configDir
+	defer func() {
+		networkArtifactsPath = savedArtifactsPath
+		configStoragePath = savedConfigPath
+	}()
+
+	// A leftover namespace mount is simulated with a plain file.
+	netNsFile := filepath.Join(artifactsDir, "netns")
+	if err := ioutil.WriteFile(netNsFile, []byte{}, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	leakedNS := NetworkNamespace{
+		NetNsPath: netNsFile,
+	}
+
+	if err := recordNetworkArtifacts("leaked-pod", leakedNS); err != nil {
+		t.Fatal(err)
+	}
+
+	cleanNS := NetworkNamespace{
+		NetNsPath: filepath.Join(artifactsDir, "nonexistent-netns"),
+	}
+
+	if err := recordNetworkArtifacts("clean-pod", cleanNS); err != nil {
+		t.Fatal(err)
+	}
+
+	leaks, err := LeakCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(leaks) != 1 {
+		t.Fatalf("Got %v\nExpecting one leak", leaks)
+	}
+
+	if leaks[0].PodID != "leaked-pod" || leaks[0].NetNsPath != netNsFile {
+		t.Fatalf("Got %+v\nExpecting the leaked-pod namespace", leaks[0])
+	}
+
+	// The manifest of the fully cleaned up pod should be dropped.
+	if _, err := os.Stat(networkArtifactsManifestPath("clean-pod")); err == nil {
+		t.Fatal("The clean-pod artifacts manifest should be gone")
+	}
+}
@@ -0,0 +1,109 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Network is the interface a pluggable network model implements, so
+// downstream users can provide custom endpoint plumbing (e.g. vendor
+// SmartNIC representors) without modifying virtcontainers.
+type Network interface {
+	// Init initializes the network, setting a new network
+	// namespace. It returns the namespace path, and whether the
+	// namespace was created by the implementation.
+	Init(config NetworkConfig) (string, bool, error)
+
+	// Run runs a callback function in the specified network
+	// namespace.
+	Run(networkNSPath string, cb func() error) error
+
+	// Add adds all needed interfaces inside the network namespace.
+	Add(pod Pod, config NetworkConfig, netNsPath string, netNsCreated bool) (NetworkNamespace, error)
+
+	// Remove removes the interfaces created by Add and deletes the
+	// network namespace.
+	Remove(pod Pod, networkNS NetworkNamespace) error
+}
+
+var networkModelsLock sync.Mutex
+var networkModels = make(map[NetworkModel]Network)
+
+// RegisterNetworkModel registers a custom network model under the
+// given name. The name becomes a valid PodConfig NetworkModel value.
+// Registering over a built-in model or a name already registered is
+// an error.
+func RegisterNetworkModel(name string, implementation Network) error {
+	if name == "" {
+		return fmt.Errorf("Network model name cannot be empty")
+	}
+
+	if implementation == nil {
+		return fmt.Errorf("Network model implementation cannot be nil")
+	}
+
+	model := NetworkModel(name)
+	switch model {
+	case NoopNetworkModel, CNINetworkModel, CNMNetworkModel:
+		return fmt.Errorf("Network model %s is already provided by virtcontainers", name)
+	}
+
+	networkModelsLock.Lock()
+	defer networkModelsLock.Unlock()
+
+	if _, ok := networkModels[model]; ok {
+		return fmt.Errorf("Network model %s is already registered", name)
+	}
+
+	networkModels[model] = implementation
+
+	return nil
+}
+
+// registeredNetworkModel returns the implementation registered under
+// the given model, if any.
+func registeredNetworkModel(model NetworkModel) (Network, bool) {
+	networkModelsLock.Lock()
+	defer networkModelsLock.Unlock()
+
+	implementation, ok := networkModels[model]
+	return implementation, ok
+}
+
+// pluggableNetwork adapts a registered Network implementation to the
+// internal network interface.
+type pluggableNetwork struct {
+	impl Network
+}
+
+func (n *pluggableNetwork) init(config NetworkConfig) (string, bool, error) {
+	return n.impl.Init(config)
+}
+
+func (n *pluggableNetwork) run(networkNSPath string, cb func() error) error {
+	return n.impl.Run(networkNSPath, cb)
+}
+
+func (n *pluggableNetwork) add(pod Pod, config NetworkConfig, netNsPath string, netNsCreated bool) (NetworkNamespace, error) {
+	return n.impl.Add(pod, config, netNsPath, netNsCreated)
+}
+
+func (n *pluggableNetwork) remove(pod Pod, networkNS NetworkNamespace) error {
+	return n.impl.Remove(pod, networkNS)
+}
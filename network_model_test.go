@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+type fakeNetwork struct{}
+
+func (n *fakeNetwork) Init(config NetworkConfig) (string, bool, error) {
+	return "", false, nil
+}
+
+func (n *fakeNetwork) Run(networkNSPath string, cb func() error) error {
+	return cb()
+}
+
+func (n *fakeNetwork) Add(pod Pod, config NetworkConfig, netNsPath string, netNsCreated bool) (NetworkNamespace, error) {
+	return NetworkNamespace{}, nil
+}
+
+func (n *fakeNetwork) Remove(pod Pod, networkNS NetworkNamespace) error {
+	return nil
+}
+
+func clearNetworkModels() {
+	networkModelsLock.Lock()
+	defer networkModelsLock.Unlock()
+	networkModels = make(map[NetworkModel]Network)
+}
+
+func TestRegisterNetworkModel(t *testing.T) {
+	defer clearNetworkModels()
+
+	if err := RegisterNetworkModel("smartnic", &fakeNetwork{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var networkType NetworkModel
+	if err := networkType.Set("smartnic"); err != nil {
+		t.Fatal(err)
+	}
+
+	if networkType.String() != "smartnic" {
+		t.Fatalf("Got %s\nExpecting smartnic", networkType.String())
+	}
+
+	n := newNetwork(networkType)
+	if _, ok := n.(*pluggableNetwork); !ok {
+		t.Fatalf("Got %T\nExpecting a pluggable network", n)
+	}
+}
+
+func TestRegisterNetworkModelDuplicate(t *testing.T) {
+	defer clearNetworkModels()
+
+	if err := RegisterNetworkModel("smartnic", &fakeNetwork{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RegisterNetworkModel("smartnic", &fakeNetwork{}); err == nil {
+		t.Fatal("Registering the same network model twice should fail")
+	}
+}
+
+func TestRegisterNetworkModelInvalid(t *testing.T) {
+	defer clearNetworkModels()
+
+	if err := RegisterNetworkModel("", &fakeNetwork{}); err == nil {
+		t.Fatal("Registering an unnamed network model should fail")
+	}
+
+	if err := RegisterNetworkModel("smartnic", nil); err == nil {
+		t.Fatal("Registering a nil network model should fail")
+	}
+
+	if err := RegisterNetworkModel(string(CNINetworkModel), &fakeNetwork{}); err == nil {
+		t.Fatal("Registering over a built-in network model should fail")
+	}
+}
@@ -17,9 +17,11 @@
 package virtcontainers
 
 import (
+	"io/ioutil"
 	"net"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/containernetworking/plugins/pkg/ns"
@@ -224,6 +226,61 @@ func TestCreateVhostUserEndpoint(t *testing.T) {
 	}
 }
 
+func TestEnableProxyArpNdp(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip(testDisabledAsNonRoot)
+	}
+
+	if err := enableProxyArpNdp("lo"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sysctlPath := range []string{
+		"/proc/sys/net/ipv4/conf/lo/proxy_arp",
+		"/proc/sys/net/ipv6/conf/lo/proxy_ndp",
+	} {
+		value, err := ioutil.ReadFile(sysctlPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.TrimSpace(string(value)) != "1" {
+			t.Fatalf("Got %s\nExpecting 1 in %s", value, sysctlPath)
+		}
+
+		// Restore the default.
+		if err := ioutil.WriteFile(sysctlPath, []byte("0"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnableProxyArpNdpMissingInterface(t *testing.T) {
+	if err := enableProxyArpNdp("nonexistent-iface0"); err == nil {
+		t.Fatal("Enabling proxying on a missing interface should fail")
+	}
+}
+
+func TestEndpointGuestName(t *testing.T) {
+	endpoint := &VirtualEndpoint{
+		NetPair: NetworkInterfacePair{
+			VirtIface: NetworkInterface{
+				Name: "eth4",
+			},
+		},
+	}
+
+	if endpoint.GuestName() != "eth4" {
+		t.Fatalf("Got %s\nExpecting eth4", endpoint.GuestName())
+	}
+
+	endpoint.SetGuestName("net-frontend")
+
+	if endpoint.GuestName() != "net-frontend" {
+		t.Fatalf("Got %s\nExpecting net-frontend", endpoint.GuestName())
+	}
+}
+
 func TestCreateVirtualNetworkEndpoint(t *testing.T) {
 	macAddr := net.HardwareAddr{0x02, 0x00, 0xCA, 0xFE, 0x00, 0x04}
 
@@ -79,3 +79,23 @@ func (n *noopAgent) killContainer(pod Pod, c Container, signal syscall.Signal, a
 func (n *noopAgent) processListContainer(pod Pod, c Container, options ProcessListOptions) (ProcessList, error) {
 	return nil, nil
 }
+
+// execProbe is the Noop agent probe implementation. It does nothing.
+func (n *noopAgent) execProbe(pod *Pod, c Container, cmd Cmd, options ProbeOptions) (ProbeResult, error) {
+	return ProbeResult{}, nil
+}
+
+// attachStreams is the Noop agent stdio attach implementation. It does nothing.
+func (n *noopAgent) attachStreams(pod *Pod, c Container) (*ContainerStreams, error) {
+	return &ContainerStreams{}, nil
+}
+
+// addVolume is the Noop agent volume hot-add implementation. It does nothing.
+func (n *noopAgent) addVolume(pod *Pod, volume Volume) (string, error) {
+	return "", nil
+}
+
+// removeVolume is the Noop agent volume removal implementation. It does nothing.
+func (n *noopAgent) removeVolume(pod *Pod, volume Volume) error {
+	return nil
+}
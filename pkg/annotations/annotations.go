@@ -25,6 +25,9 @@ const (
 	// ImagePath is a pod annotation for passing a per container path pointing at the guest image that will run in the container VM.
 	ImagePath = vcAnnotationsPrefix + "ImagePath"
 
+	// InitrdPath is a pod annotation for passing a per container path pointing at the guest initrd image that will run in the container VM.
+	InitrdPath = vcAnnotationsPrefix + "InitrdPath"
+
 	// HypervisorPath is a pod annotation for passing a per container path pointing at the hypervisor that will run the container VM.
 	HypervisorPath = vcAnnotationsPrefix + "HypervisorPath"
 
@@ -37,15 +40,63 @@ const (
 	// ImageHash is an pod annotation for passing a container guest image SHA-512 hash value.
 	ImageHash = vcAnnotationsPrefix + "ImageHash"
 
+	// InitrdHash is an pod annotation for passing a container guest initrd SHA-512 hash value.
+	InitrdHash = vcAnnotationsPrefix + "InitrdHash"
+
 	// HypervisorHash is an pod annotation for passing a container hypervisor binary SHA-512 hash value.
 	HypervisorHash = vcAnnotationsPrefix + "HypervisorHash"
 
 	// FirmwareHash is an pod annotation for passing a container guest firmware SHA-512 hash value.
 	FirmwareHash = vcAnnotationsPrefix + "FirmwareHash"
 
+	// KernelSignature is a pod annotation carrying a base64 encoded detached signature over the guest kernel content.
+	KernelSignature = vcAnnotationsPrefix + "KernelSignature"
+
+	// ImageSignature is a pod annotation carrying a base64 encoded detached signature over the guest image content.
+	ImageSignature = vcAnnotationsPrefix + "ImageSignature"
+
+	// InitrdSignature is a pod annotation carrying a base64 encoded detached signature over the guest initrd content.
+	InitrdSignature = vcAnnotationsPrefix + "InitrdSignature"
+
+	// FirmwareSignature is a pod annotation carrying a base64 encoded detached signature over the guest firmware content.
+	FirmwareSignature = vcAnnotationsPrefix + "FirmwareSignature"
+
 	// AssetHashType is the hash type used for assets verification
 	AssetHashType = vcAnnotationsPrefix + "AssetHashType"
 
+	// HypervisorProfile is a pod annotation selecting a registered hypervisor configuration profile by name.
+	HypervisorProfile = vcAnnotationsPrefix + "HypervisorProfile"
+
+	// MachineType is a pod annotation overriding the type of machine emulated by the hypervisor.
+	MachineType = vcAnnotationsPrefix + "MachineType"
+
+	// DefaultVCPUs is a pod annotation overriding the default number of vCPUs for the container VM.
+	DefaultVCPUs = vcAnnotationsPrefix + "DefaultVCPUs"
+
+	// DefaultMemory is a pod annotation overriding the default memory size in MiB for the container VM.
+	DefaultMemory = vcAnnotationsPrefix + "DefaultMemory"
+
+	// KernelParams is a pod annotation for passing additional guest kernel parameters.
+	KernelParams = vcAnnotationsPrefix + "KernelParams"
+
+	// HypervisorDebug is a pod annotation enabling hypervisor and kernel debug output.
+	HypervisorDebug = vcAnnotationsPrefix + "HypervisorDebug"
+
+	// ImageName is a container annotation carrying the container image reference, for agents pulling the image inside the guest.
+	ImageName = vcAnnotationsPrefix + "ImageName"
+
+	// GuestImagePull is a container annotation requesting that the agent pulls and unpacks the container image inside the guest.
+	GuestImagePull = vcAnnotationsPrefix + "GuestImagePull"
+
+	// DedicatedVCPUs is a container annotation carrying the number of vCPUs the agent should place in an exclusive cpuset for the container.
+	DedicatedVCPUs = vcAnnotationsPrefix + "DedicatedVCPUs"
+
+	// BlockVolumes is a container annotation listing comma separated mount destinations that should be attached as block devices rather than shared over the pod filesystem.
+	BlockVolumes = vcAnnotationsPrefix + "BlockVolumes"
+
+	// DirectVolumes is a container annotation listing comma separated mount destinations that should be copied onto a dedicated disk image and attached as block devices.
+	DirectVolumes = vcAnnotationsPrefix + "DirectVolumes"
+
 	// ConfigJSONKey is the annotation key to fetch the OCI configuration.
 	ConfigJSONKey = vcAnnotationsPrefix + "pkg.oci.config"
 
@@ -57,6 +108,12 @@ const (
 )
 
 const (
+	// SHA256 is the SHA-256 (32) hash algorithm
+	SHA256 string = "sha256"
+
+	// SHA384 is the SHA-384 (48) hash algorithm
+	SHA384 string = "sha384"
+
 	// SHA512 is the SHA-512 (64) hash algorithm
 	SHA512 string = "sha512"
 )
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package annotations
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// validateFunc checks a single annotation value.
+type validateFunc func(value string) error
+
+func validateAbsPath(value string) error {
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("%s is not an absolute path", value)
+	}
+
+	return nil
+}
+
+func validateHexString(value string) error {
+	if _, err := hex.DecodeString(value); err != nil {
+		return fmt.Errorf("%s is not a valid hexadecimal string", value)
+	}
+
+	return nil
+}
+
+func validateBase64String(value string) error {
+	if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+		return fmt.Errorf("%s is not a valid base64 string", value)
+	}
+
+	return nil
+}
+
+func validateHashType(value string) error {
+	switch value {
+	case SHA256, SHA384, SHA512:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown hash type %s", value)
+}
+
+func validatePositiveInteger(value string) error {
+	size, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || size == 0 {
+		return fmt.Errorf("%s is not a positive integer", value)
+	}
+
+	return nil
+}
+
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("%s is not a boolean", value)
+	}
+
+	return nil
+}
+
+func validateAny(value string) error {
+	return nil
+}
+
+// validators maps every known virtcontainers annotation key to the
+// checker for its value.
+var validators = map[string]validateFunc{
+	KernelPath:        validateAbsPath,
+	ImagePath:         validateAbsPath,
+	InitrdPath:        validateAbsPath,
+	HypervisorPath:    validateAbsPath,
+	FirmwarePath:      validateAbsPath,
+	KernelHash:        validateHexString,
+	ImageHash:         validateHexString,
+	InitrdHash:        validateHexString,
+	HypervisorHash:    validateHexString,
+	FirmwareHash:      validateHexString,
+	KernelSignature:   validateBase64String,
+	ImageSignature:    validateBase64String,
+	InitrdSignature:   validateBase64String,
+	FirmwareSignature: validateBase64String,
+	AssetHashType:     validateHashType,
+	HypervisorProfile: validateAny,
+	MachineType:       validateAny,
+	DefaultVCPUs:      validatePositiveInteger,
+	DefaultMemory:     validatePositiveInteger,
+	KernelParams:      validateAny,
+	HypervisorDebug:   validateBool,
+	ImageName:         validateAny,
+	DedicatedVCPUs:    validatePositiveInteger,
+	BlockVolumes:      validateAny,
+	DirectVolumes:     validateAny,
+	GuestImagePull:    validateBool,
+	ConfigJSONKey:     validateAny,
+	BundlePathKey:     validateAny,
+	ContainerTypeKey:  validateAny,
+}
+
+// Validate checks all annotations from the virtcontainers namespace,
+// rejecting malformed values before they can be turned into a broken
+// VM configuration. When rejectUnknown is set, namespaced keys that
+// are not known to this package are rejected as well. Annotations
+// outside the virtcontainers namespace are always left alone.
+func Validate(podAnnotations map[string]string, rejectUnknown bool) error {
+	for key, value := range podAnnotations {
+		if !strings.HasPrefix(key, vcAnnotationsPrefix) {
+			continue
+		}
+
+		validate, known := validators[key]
+		if !known {
+			if rejectUnknown {
+				return fmt.Errorf("Unknown annotation %s", key)
+			}
+
+			continue
+		}
+
+		if value == "" {
+			continue
+		}
+
+		if err := validate(value); err != nil {
+			return fmt.Errorf("Invalid value for annotation %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package annotations
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	data := []struct {
+		podAnnotations map[string]string
+		rejectUnknown  bool
+		expectError    bool
+	}{
+		// Annotations outside our namespace are always accepted.
+		{map[string]string{"org.clearlinux.key": "whatever"}, true, false},
+
+		// Valid values.
+		{map[string]string{KernelPath: "/usr/share/kernel"}, false, false},
+		{map[string]string{AssetHashType: SHA256}, false, false},
+		{map[string]string{DefaultVCPUs: "4"}, false, false},
+		{map[string]string{HypervisorDebug: "true"}, false, false},
+		{map[string]string{KernelHash: "abcdef0123"}, false, false},
+
+		// Empty values are ignored.
+		{map[string]string{DefaultMemory: ""}, false, false},
+
+		// Malformed values.
+		{map[string]string{KernelPath: "../kernel"}, false, true},
+		{map[string]string{AssetHashType: "sha1"}, false, true},
+		{map[string]string{DefaultVCPUs: "0"}, false, true},
+		{map[string]string{DefaultMemory: "lots"}, false, true},
+		{map[string]string{HypervisorDebug: "maybe"}, false, true},
+		{map[string]string{KernelHash: "not-hex"}, false, true},
+		{map[string]string{KernelSignature: "not base64!"}, false, true},
+
+		// Unknown namespaced keys.
+		{map[string]string{vcAnnotationsPrefix + "NoSuchKey": "value"}, false, false},
+		{map[string]string{vcAnnotationsPrefix + "NoSuchKey": "value"}, true, true},
+	}
+
+	for _, d := range data {
+		err := Validate(d.podAnnotations, d.rejectUnknown)
+		if d.expectError && err == nil {
+			t.Fatalf("Expecting failure with annotations %v", d.podAnnotations)
+		}
+
+		if !d.expectError && err != nil {
+			t.Fatalf("Unexpected failure with annotations %v: %v", d.podAnnotations, err)
+		}
+	}
+}
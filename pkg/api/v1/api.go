@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1 is the stable virtcontainers API. It exposes the pod and
+// container lifecycle together with the configuration types consumed
+// by downstream runtimes, and nothing else.
+//
+// The package follows semantic versioning: within the v1 major
+// version, types and functions are only ever added, never changed or
+// removed. Internal hypervisor, agent and storage plumbing is free to
+// move underneath it.
+package v1
+
+import (
+	vc "github.com/containers/virtcontainers"
+)
+
+// Version is the semantic version of the v1 API.
+const Version = "1.0.0"
+
+// VC is the stable virtcontainers interface, covering the whole pod
+// and container lifecycle.
+type VC = vc.VC
+
+// VCPod is the stable interface of a pod handle.
+type VCPod = vc.VCPod
+
+// VCContainer is the stable interface of a container handle.
+type VCContainer = vc.VCContainer
+
+// Pod and container configuration types.
+type (
+	// PodConfig describes a pod to create.
+	PodConfig = vc.PodConfig
+
+	// ContainerConfig describes a container to create inside a pod.
+	ContainerConfig = vc.ContainerConfig
+
+	// Resources describes the VM sizing of a pod.
+	Resources = vc.Resources
+
+	// HypervisorType describes the hypervisor driving a pod.
+	HypervisorType = vc.HypervisorType
+
+	// HypervisorConfig is the hypervisor configuration.
+	HypervisorConfig = vc.HypervisorConfig
+
+	// AgentType describes the agent managing the guest.
+	AgentType = vc.AgentType
+
+	// ProxyType describes the proxy relaying the agent channel.
+	ProxyType = vc.ProxyType
+
+	// ShimType describes the shim standing for the workload.
+	ShimType = vc.ShimType
+
+	// NetworkModel describes the pod networking model.
+	NetworkModel = vc.NetworkModel
+
+	// NetworkConfig is the pod network configuration.
+	NetworkConfig = vc.NetworkConfig
+
+	// Volume is a shared volume between the host and the pod.
+	Volume = vc.Volume
+
+	// Volumes is a list of shared volumes.
+	Volumes = vc.Volumes
+
+	// Mount is a container mount point.
+	Mount = vc.Mount
+
+	// DeviceInfo describes a device passed to a container.
+	DeviceInfo = vc.DeviceInfo
+
+	// Cmd is a command to run inside a container.
+	Cmd = vc.Cmd
+
+	// EnvVar is a command environment variable.
+	EnvVar = vc.EnvVar
+
+	// Param is a key/value parameter.
+	Param = vc.Param
+)
+
+// Status and runtime types.
+type (
+	// PodStatus is the observable state of a pod.
+	PodStatus = vc.PodStatus
+
+	// ContainerStatus is the observable state of a container.
+	ContainerStatus = vc.ContainerStatus
+
+	// State is the pod or container lifecycle state.
+	State = vc.State
+
+	// Process describes a process started inside a container.
+	Process = vc.Process
+
+	// ProcessList is the output of a container process listing.
+	ProcessList = vc.ProcessList
+
+	// ProcessListOptions drives a container process listing.
+	ProcessListOptions = vc.ProcessListOptions
+)
+
+// New returns the virtcontainers implementation of the stable API.
+func New() VC {
+	return &vc.VCImpl{}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	impl := New()
+	if impl == nil {
+		t.Fatal("Expecting a virtcontainers implementation")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	fields := strings.Split(Version, ".")
+	if len(fields) != 3 {
+		t.Fatalf("Got %s\nExpecting a major.minor.patch version", Version)
+	}
+
+	for _, field := range fields {
+		if _, err := strconv.Atoi(field); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fields[0] != "1" {
+		t.Fatalf("Got %s\nExpecting a v1 version", Version)
+	}
+}
@@ -0,0 +1,310 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package config loads a runtime TOML configuration file into a
+// virtcontainers pod configuration, so that every runtime embedding
+// virtcontainers does not have to reimplement the same parsing.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	vc "github.com/containers/virtcontainers"
+)
+
+// tomlValues maps a section name to its key/value pairs. Only the
+// simple subset of TOML used by runtime configuration files is
+// supported: [section] headers, string, integer and boolean values,
+// and comments.
+type tomlValues map[string]map[string]string
+
+func parseTOML(path string) (tomlValues, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := tomlValues{}
+	section := ""
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, fmt.Errorf("%s:%d: empty section name", path, lineNum)
+			}
+
+			if _, ok := values[section]; !ok {
+				values[section] = map[string]string{}
+			}
+
+			continue
+		}
+
+		keyValue := strings.SplitN(line, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, fmt.Errorf("%s:%d: expecting key = value, got %q", path, lineNum, line)
+		}
+
+		if section == "" {
+			return nil, fmt.Errorf("%s:%d: key %q outside of any section", path, lineNum, keyValue[0])
+		}
+
+		key := strings.TrimSpace(keyValue[0])
+		value := strings.TrimSpace(keyValue[1])
+
+		if strings.HasPrefix(value, "\"") {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid string value %s", path, lineNum, value)
+			}
+
+			value = unquoted
+		}
+
+		values[section][key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (v tomlValues) get(section, key string) string {
+	return v[section][key]
+}
+
+func (v tomlValues) getUint32(section, key string) (uint32, error) {
+	value := v.get(section, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid value for %s.%s: %s", section, key, value)
+	}
+
+	return uint32(parsed), nil
+}
+
+func (v tomlValues) getInt(section, key string) (int, error) {
+	value := v.get(section, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid value for %s.%s: %s", section, key, value)
+	}
+
+	return parsed, nil
+}
+
+func (v tomlValues) getBool(section, key string) (bool, error) {
+	value := v.get(section, key)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid value for %s.%s: %s", section, key, value)
+	}
+
+	return parsed, nil
+}
+
+func loadHypervisorConfig(values tomlValues, config *vc.PodConfig) error {
+	if value := values.get("hypervisor", "type"); value != "" {
+		if err := config.HypervisorType.Set(value); err != nil {
+			return err
+		}
+	}
+
+	config.HypervisorConfig.HypervisorPath = values.get("hypervisor", "path")
+	config.HypervisorConfig.KernelPath = values.get("hypervisor", "kernel")
+	config.HypervisorConfig.ImagePath = values.get("hypervisor", "image")
+	config.HypervisorConfig.InitrdPath = values.get("hypervisor", "initrd")
+	config.HypervisorConfig.FirmwarePath = values.get("hypervisor", "firmware")
+	config.HypervisorConfig.HypervisorMachineType = values.get("hypervisor", "machine_type")
+	config.HypervisorConfig.ProcessLabel = values.get("hypervisor", "process_label")
+
+	vcpus, err := values.getUint32("hypervisor", "default_vcpus")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.DefaultVCPUs = vcpus
+
+	memSz, err := values.getUint32("hypervisor", "default_memory")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.DefaultMemSz = memSz
+
+	bridges, err := values.getUint32("hypervisor", "default_bridges")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.DefaultBridges = bridges
+
+	debug, err := values.getBool("hypervisor", "debug")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.Debug = debug
+
+	nice, err := values.getInt("hypervisor", "nice")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.Nice = nice
+
+	ioClass, err := values.getInt("hypervisor", "io_class")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.IOClass = ioClass
+
+	ioPriority, err := values.getInt("hypervisor", "io_priority")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.IOPriority = ioPriority
+
+	oomScoreAdj, err := values.getInt("hypervisor", "oom_score_adj")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.OOMScoreAdj = oomScoreAdj
+
+	config.HypervisorConfig.Tuning.ClockSource = values.get("hypervisor", "clock_source")
+
+	hpet, err := values.getBool("hypervisor", "hpet")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.Tuning.HPET = hpet
+
+	rngBytes, err := values.getUint32("hypervisor", "rng_bytes_per_period")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.Tuning.RNGBytesPerPeriod = uint(rngBytes)
+
+	rngPeriod, err := values.getUint32("hypervisor", "rng_period_ms")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.Tuning.RNGPeriodMs = uint(rngPeriod)
+
+	sharedImage, err := values.getBool("hypervisor", "shared_image")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.SharedImage = sharedImage
+
+	debugConsole, err := values.getBool("hypervisor", "debug_console")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.DebugConsole = debugConsole
+
+	kernelLogConsole, err := values.getBool("hypervisor", "kernel_log_console")
+	if err != nil {
+		return err
+	}
+	config.HypervisorConfig.KernelLogConsole = kernelLogConsole
+
+	return nil
+}
+
+// LoadConfig parses a runtime TOML configuration file and returns a
+// pod configuration holding its defaults. The returned configuration
+// still needs pod specific fields (ID, containers, annotations) to be
+// filled in by the caller.
+func LoadConfig(path string) (vc.PodConfig, error) {
+	var config vc.PodConfig
+
+	values, err := parseTOML(path)
+	if err != nil {
+		return vc.PodConfig{}, err
+	}
+
+	if err := loadHypervisorConfig(values, &config); err != nil {
+		return vc.PodConfig{}, err
+	}
+
+	if value := values.get("agent", "type"); value != "" {
+		if err := config.AgentType.Set(value); err != nil {
+			return vc.PodConfig{}, err
+		}
+	}
+
+	if value := values.get("proxy", "type"); value != "" {
+		if err := config.ProxyType.Set(value); err != nil {
+			return vc.PodConfig{}, err
+		}
+	}
+	config.ProxyConfig.Path = values.get("proxy", "path")
+
+	proxyDebug, err := values.getBool("proxy", "debug")
+	if err != nil {
+		return vc.PodConfig{}, err
+	}
+	config.ProxyConfig.Debug = proxyDebug
+
+	if value := values.get("shim", "type"); value != "" {
+		if err := config.ShimType.Set(value); err != nil {
+			return vc.PodConfig{}, err
+		}
+	}
+
+	if path := values.get("shim", "path"); path != "" {
+		shimDebug, err := values.getBool("shim", "debug")
+		if err != nil {
+			return vc.PodConfig{}, err
+		}
+
+		config.ShimConfig = vc.ShimConfig{
+			Path:  path,
+			Debug: shimDebug,
+		}
+	}
+
+	if value := values.get("network", "model"); value != "" {
+		if err := config.NetworkModel.Set(value); err != nil {
+			return vc.PodConfig{}, err
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	vc "github.com/containers/virtcontainers"
+)
+
+const testRuntimeConfig = `
+# Runtime configuration file.
+
+[hypervisor]
+type = "qemu"
+path = "/usr/bin/qemu-lite-system-x86_64"
+kernel = "/usr/share/clear-containers/vmlinux.container"
+image = "/usr/share/clear-containers/clear-containers.img"
+machine_type = "pc"
+default_vcpus = 2
+default_memory = 4096
+default_bridges = 2
+debug = true
+
+[agent]
+type = "hyperstart"
+
+[proxy]
+type = "ccProxy"
+path = "/usr/libexec/clear-containers/cc-proxy"
+
+[shim]
+type = "ccShim"
+path = "/usr/libexec/clear-containers/cc-shim"
+debug = true
+
+[network]
+model = "CNM"
+`
+
+func createConfigFile(t *testing.T, content string) (string, func()) {
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tmpfile.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+
+	return tmpfile.Name(), func() {
+		os.Remove(tmpfile.Name())
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path, cleanup := createConfigFile(t, testRuntimeConfig)
+	defer cleanup()
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.HypervisorType != vc.QemuHypervisor {
+		t.Fatalf("Got hypervisor type %s\nExpecting %s", config.HypervisorType, vc.QemuHypervisor)
+	}
+
+	expectedHypervisorConfig := vc.HypervisorConfig{
+		HypervisorPath:        "/usr/bin/qemu-lite-system-x86_64",
+		KernelPath:            "/usr/share/clear-containers/vmlinux.container",
+		ImagePath:             "/usr/share/clear-containers/clear-containers.img",
+		HypervisorMachineType: "pc",
+		DefaultVCPUs:          2,
+		DefaultMemSz:          4096,
+		DefaultBridges:        2,
+		Debug:                 true,
+	}
+
+	if !reflect.DeepEqual(config.HypervisorConfig, expectedHypervisorConfig) {
+		t.Fatalf("Got %+v\nExpecting %+v", config.HypervisorConfig, expectedHypervisorConfig)
+	}
+
+	if config.AgentType != vc.HyperstartAgent {
+		t.Fatalf("Got agent type %s\nExpecting %s", config.AgentType, vc.HyperstartAgent)
+	}
+
+	if config.ProxyType != vc.CCProxyType {
+		t.Fatalf("Got proxy type %s\nExpecting %s", config.ProxyType, vc.CCProxyType)
+	}
+
+	if config.ProxyConfig.Path != "/usr/libexec/clear-containers/cc-proxy" {
+		t.Fatalf("Got proxy path %s", config.ProxyConfig.Path)
+	}
+
+	if config.ShimType != vc.CCShimType {
+		t.Fatalf("Got shim type %s\nExpecting %s", config.ShimType, vc.CCShimType)
+	}
+
+	expectedShimConfig := vc.ShimConfig{
+		Path:  "/usr/libexec/clear-containers/cc-shim",
+		Debug: true,
+	}
+
+	if config.ShimConfig != expectedShimConfig {
+		t.Fatalf("Got %+v\nExpecting %+v", config.ShimConfig, expectedShimConfig)
+	}
+
+	if config.NetworkModel != vc.CNMNetworkModel {
+		t.Fatalf("Got network model %s\nExpecting %s", config.NetworkModel, vc.CNMNetworkModel)
+	}
+}
+
+func TestLoadConfigFailures(t *testing.T) {
+	if _, err := LoadConfig("/this/path/does/not/exist"); err == nil {
+		t.Fatal("Expecting failure with missing file")
+	}
+
+	for _, content := range []string{
+		"key = \"value outside of a section\"\n",
+		"[hypervisor]\nnot a key value pair\n",
+		"[hypervisor]\ndefault_vcpus = \"many\"\n",
+		"[hypervisor]\ntype = \"unsupported\"\n",
+		"[network]\nmodel = \"unsupported\"\n",
+	} {
+		path, cleanup := createConfigFile(t, content)
+		defer cleanup()
+
+		if _, err := LoadConfig(path); err == nil {
+			t.Fatalf("Expecting failure with config %q", content)
+		}
+	}
+}
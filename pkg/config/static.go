@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	vc "github.com/containers/virtcontainers"
+)
+
+// StaticPodRunner reconciles pod definition files from a watch
+// directory into running pods, providing a static pods capability for
+// appliance-style deployments embedding virtcontainers directly.
+//
+// A definition file holds a pod configuration, either as a JSON
+// PodConfig document (.json) or as a TOML runtime configuration
+// (.toml). Adding a file starts the pod, removing it stops and
+// deletes the pod. Changes to an existing file are not applied to the
+// running pod.
+type StaticPodRunner struct {
+	sync.Mutex
+
+	dir      string
+	interval time.Duration
+	vci      vc.VC
+
+	// pods maps definition file names to the pod IDs started from
+	// them.
+	pods map[string]string
+
+	stopCh chan struct{}
+}
+
+// NewStaticPodRunner returns a static pod runner watching the given
+// directory through the provided virtcontainers implementation.
+func NewStaticPodRunner(dir string, interval time.Duration, vci vc.VC) (*StaticPodRunner, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("Watch directory cannot be empty")
+	}
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("Invalid watch interval %v", interval)
+	}
+
+	if vci == nil {
+		return nil, fmt.Errorf("Need a virtcontainers implementation")
+	}
+
+	return &StaticPodRunner{
+		dir:      dir,
+		interval: interval,
+		vci:      vci,
+		pods:     make(map[string]string),
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// loadPodDefinition parses one definition file, dispatching on the
+// file extension.
+func loadPodDefinition(path string) (vc.PodConfig, error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return vc.PodConfig{}, err
+		}
+
+		var podConfig vc.PodConfig
+		if err := json.Unmarshal(data, &podConfig); err != nil {
+			return vc.PodConfig{}, fmt.Errorf("Could not parse %s: %s", path, err)
+		}
+
+		return podConfig, nil
+	case ".toml":
+		return LoadConfig(path)
+	}
+
+	return vc.PodConfig{}, fmt.Errorf("Unknown pod definition format %s", path)
+}
+
+// Reconcile scans the watch directory once, starting pods for new
+// definition files and stopping pods whose file disappeared. The
+// first error encountered is returned after the whole scan completed.
+func (r *StaticPodRunner) Reconcile() error {
+	r.Lock()
+	defer r.Unlock()
+
+	entries, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+
+		ext := filepath.Ext(name)
+		if entry.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+
+		seen[name] = true
+
+		if _, ok := r.pods[name]; ok {
+			continue
+		}
+
+		podConfig, err := loadPodDefinition(filepath.Join(r.dir, name))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		pod, err := r.vci.RunPod(podConfig)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		r.pods[name] = pod.ID()
+	}
+
+	for name, podID := range r.pods {
+		if seen[name] {
+			continue
+		}
+
+		if _, err := r.vci.StopPod(podID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+
+		if _, err := r.vci.DeletePod(podID); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		delete(r.pods, name)
+	}
+
+	return firstErr
+}
+
+// Start runs the reconciliation loop until Stop is called.
+func (r *StaticPodRunner) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.Reconcile()
+			}
+		}
+	}()
+}
+
+// Stop terminates the reconciliation loop. Running pods are left
+// untouched.
+func (r *StaticPodRunner) Stop() {
+	close(r.stopCh)
+}
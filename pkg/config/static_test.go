@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vc "github.com/containers/virtcontainers"
+	"github.com/containers/virtcontainers/pkg/vcMock"
+)
+
+func TestNewStaticPodRunner(t *testing.T) {
+	mock := &vcMock.VCMock{}
+
+	if _, err := NewStaticPodRunner("", time.Second, mock); err == nil {
+		t.Fatal("Expecting an error with an empty directory")
+	}
+
+	if _, err := NewStaticPodRunner("/tmp", 0, mock); err == nil {
+		t.Fatal("Expecting an error with an invalid interval")
+	}
+
+	if _, err := NewStaticPodRunner("/tmp", time.Second, nil); err == nil {
+		t.Fatal("Expecting an error with a nil implementation")
+	}
+
+	if _, err := NewStaticPodRunner("/tmp", time.Second, mock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticPodRunnerReconcile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "virtc-static-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var started, stopped, deleted []string
+
+	mock := &vcMock.VCMock{
+		RunPodFunc: func(podConfig vc.PodConfig) (vc.VCPod, error) {
+			started = append(started, podConfig.ID)
+			return &vcMock.Pod{MockID: podConfig.ID}, nil
+		},
+		StopPodFunc: func(podID string) (vc.VCPod, error) {
+			stopped = append(stopped, podID)
+			return &vcMock.Pod{MockID: podID}, nil
+		},
+		DeletePodFunc: func(podID string) (vc.VCPod, error) {
+			deleted = append(deleted, podID)
+			return &vcMock.Pod{MockID: podID}, nil
+		},
+	}
+
+	runner, err := NewStaticPodRunner(dir, time.Second, mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	definition := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(definition, []byte(`{"ID": "static-pod"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(started) != 1 || started[0] != "static-pod" {
+		t.Fatalf("Got started pods %v\nExpecting [static-pod]", started)
+	}
+
+	// A second pass must not start the pod again.
+	if err := runner.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(started) != 1 {
+		t.Fatalf("Got started pods %v\nExpecting a single pod", started)
+	}
+
+	// Removing the definition stops and deletes the pod.
+	if err := os.Remove(definition); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stopped) != 1 || stopped[0] != "static-pod" {
+		t.Fatalf("Got stopped pods %v\nExpecting [static-pod]", stopped)
+	}
+
+	if len(deleted) != 1 || deleted[0] != "static-pod" {
+		t.Fatalf("Got deleted pods %v\nExpecting [static-pod]", deleted)
+	}
+}
+
+func TestLoadPodDefinitionUnknownFormat(t *testing.T) {
+	if _, err := loadPodDefinition("pod.yaml"); err == nil {
+		t.Fatal("Expecting an error with an unknown format")
+	}
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package cri provides a facade mirroring the Kubernetes Container
+// Runtime Interface (CRI) sandbox and container semantics on top of
+// the virtcontainers pod and container API, so that Kubernetes
+// integrations do not each have to reimplement this mapping.
+package cri
+
+import (
+	"fmt"
+
+	vc "github.com/containers/virtcontainers"
+)
+
+// Runtime maps CRI style operations onto a virtcontainers
+// implementation.
+type Runtime struct {
+	vci vc.VC
+}
+
+// NewRuntime returns a CRI facade built on top of the provided
+// virtcontainers implementation.
+func NewRuntime(vci vc.VC) (*Runtime, error) {
+	if vci == nil {
+		return nil, fmt.Errorf("Need a virtcontainers implementation")
+	}
+
+	return &Runtime{
+		vci: vci,
+	}, nil
+}
+
+// RunPodSandbox creates and starts a pod sandbox and returns its ID.
+func (r *Runtime) RunPodSandbox(config vc.PodConfig) (string, error) {
+	pod, err := r.vci.RunPod(config)
+	if err != nil {
+		return "", err
+	}
+
+	return pod.ID(), nil
+}
+
+// StopPodSandbox stops a running pod sandbox. Stopping a sandbox that
+// is already stopped is not an error.
+func (r *Runtime) StopPodSandbox(sandboxID string) error {
+	status, err := r.vci.StatusPod(sandboxID)
+	if err != nil {
+		return err
+	}
+
+	if status.State.State == vc.StateStopped {
+		return nil
+	}
+
+	_, err = r.vci.StopPod(sandboxID)
+	return err
+}
+
+// RemovePodSandbox removes a pod sandbox and all its containers.
+func (r *Runtime) RemovePodSandbox(sandboxID string) error {
+	_, err := r.vci.DeletePod(sandboxID)
+	return err
+}
+
+// PodSandboxStatus returns the status of a pod sandbox.
+func (r *Runtime) PodSandboxStatus(sandboxID string) (vc.PodStatus, error) {
+	return r.vci.StatusPod(sandboxID)
+}
+
+// ListPodSandbox returns the status of every known pod sandbox.
+func (r *Runtime) ListPodSandbox() ([]vc.PodStatus, error) {
+	return r.vci.ListPod()
+}
+
+// CreateContainer creates a container inside a pod sandbox and returns
+// its ID.
+func (r *Runtime) CreateContainer(sandboxID string, config vc.ContainerConfig) (string, error) {
+	_, container, err := r.vci.CreateContainer(sandboxID, config)
+	if err != nil {
+		return "", err
+	}
+
+	return container.ID(), nil
+}
+
+// StartContainer starts a created container.
+func (r *Runtime) StartContainer(sandboxID, containerID string) error {
+	_, err := r.vci.StartContainer(sandboxID, containerID)
+	return err
+}
+
+// StopContainer stops a running container. Stopping a container that
+// is already stopped is not an error.
+func (r *Runtime) StopContainer(sandboxID, containerID string) error {
+	status, err := r.vci.StatusContainer(sandboxID, containerID)
+	if err != nil {
+		return err
+	}
+
+	if status.State.State == vc.StateStopped {
+		return nil
+	}
+
+	_, err = r.vci.StopContainer(sandboxID, containerID)
+	return err
+}
+
+// RemoveContainer removes a container from its pod sandbox.
+func (r *Runtime) RemoveContainer(sandboxID, containerID string) error {
+	_, err := r.vci.DeleteContainer(sandboxID, containerID)
+	return err
+}
+
+// ContainerStatus returns the status of a container.
+func (r *Runtime) ContainerStatus(sandboxID, containerID string) (vc.ContainerStatus, error) {
+	return r.vci.StatusContainer(sandboxID, containerID)
+}
@@ -0,0 +1,199 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cri
+
+import (
+	"testing"
+
+	vc "github.com/containers/virtcontainers"
+	"github.com/containers/virtcontainers/pkg/vcMock"
+)
+
+const (
+	testSandboxID   = "test-sandbox"
+	testContainerID = "test-container"
+)
+
+func TestNewRuntime(t *testing.T) {
+	if _, err := NewRuntime(nil); err == nil {
+		t.Fatal("Expecting an error with a nil implementation")
+	}
+
+	runtime, err := NewRuntime(&vcMock.VCMock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runtime == nil {
+		t.Fatal("Expecting a runtime")
+	}
+}
+
+func TestRunPodSandbox(t *testing.T) {
+	mock := &vcMock.VCMock{
+		RunPodFunc: func(podConfig vc.PodConfig) (vc.VCPod, error) {
+			return &vcMock.Pod{MockID: testSandboxID}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sandboxID, err := runtime.RunPodSandbox(vc.PodConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sandboxID != testSandboxID {
+		t.Fatalf("Got %q\nExpecting %q", sandboxID, testSandboxID)
+	}
+}
+
+func TestStopPodSandbox(t *testing.T) {
+	stopped := false
+	mock := &vcMock.VCMock{
+		StatusPodFunc: func(podID string) (vc.PodStatus, error) {
+			return vc.PodStatus{
+				ID:    podID,
+				State: vc.State{State: vc.StateRunning},
+			}, nil
+		},
+		StopPodFunc: func(podID string) (vc.VCPod, error) {
+			stopped = true
+			return &vcMock.Pod{MockID: podID}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runtime.StopPodSandbox(testSandboxID); err != nil {
+		t.Fatal(err)
+	}
+
+	if !stopped {
+		t.Fatal("Expecting the sandbox to be stopped")
+	}
+
+	// Stopping an already stopped sandbox is not an error.
+	mock.StatusPodFunc = func(podID string) (vc.PodStatus, error) {
+		return vc.PodStatus{
+			ID:    podID,
+			State: vc.State{State: vc.StateStopped},
+		}, nil
+	}
+	mock.StopPodFunc = nil
+
+	if err := runtime.StopPodSandbox(testSandboxID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPodSandboxStatus(t *testing.T) {
+	mock := &vcMock.VCMock{
+		StatusPodFunc: func(podID string) (vc.PodStatus, error) {
+			return vc.PodStatus{ID: podID}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := runtime.PodSandboxStatus(testSandboxID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.ID != testSandboxID {
+		t.Fatalf("Got %q\nExpecting %q", status.ID, testSandboxID)
+	}
+}
+
+func TestCreateContainer(t *testing.T) {
+	mock := &vcMock.VCMock{
+		CreateContainerFunc: func(podID string, containerConfig vc.ContainerConfig) (vc.VCPod, vc.VCContainer, error) {
+			pod := &vcMock.Pod{MockID: podID}
+			return pod, &vcMock.Container{MockID: containerConfig.ID, MockPod: pod}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	containerID, err := runtime.CreateContainer(testSandboxID, vc.ContainerConfig{ID: testContainerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if containerID != testContainerID {
+		t.Fatalf("Got %q\nExpecting %q", containerID, testContainerID)
+	}
+}
+
+func TestStopContainer(t *testing.T) {
+	stopped := false
+	mock := &vcMock.VCMock{
+		StatusContainerFunc: func(podID, containerID string) (vc.ContainerStatus, error) {
+			return vc.ContainerStatus{
+				ID:    containerID,
+				State: vc.State{State: vc.StateRunning},
+			}, nil
+		},
+		StopContainerFunc: func(podID, containerID string) (vc.VCContainer, error) {
+			stopped = true
+			return &vcMock.Container{MockID: containerID}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runtime.StopContainer(testSandboxID, testContainerID); err != nil {
+		t.Fatal(err)
+	}
+
+	if !stopped {
+		t.Fatal("Expecting the container to be stopped")
+	}
+}
+
+func TestRemoveContainer(t *testing.T) {
+	mock := &vcMock.VCMock{
+		DeleteContainerFunc: func(podID, containerID string) (vc.VCContainer, error) {
+			return &vcMock.Container{MockID: containerID}, nil
+		},
+	}
+
+	runtime, err := NewRuntime(mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runtime.RemoveContainer(testSandboxID, testContainerID); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -71,6 +71,9 @@ const (
 
 	// StateStopped represents a container that has been stopped.
 	StateStopped = "stopped"
+
+	// StatePaused represents a container that has been paused.
+	StatePaused = "paused"
 )
 
 // CompatOCIProcess is a structure inheriting from spec.Process defined
@@ -268,6 +271,48 @@ func containerDeviceInfos(spec CompatOCISpec) ([]vc.DeviceInfo, error) {
 	return devices, nil
 }
 
+// containerResources extracts the OCI resources relevant to VM sizing
+// into a virtcontainers ContainerResources.
+func containerResources(specResources *spec.LinuxResources) vc.ContainerResources {
+	var resources vc.ContainerResources
+
+	if specResources == nil {
+		return resources
+	}
+
+	if specResources.CPU != nil {
+		if specResources.CPU.Quota != nil {
+			resources.CPUQuota = *specResources.CPU.Quota
+		}
+		if specResources.CPU.Period != nil {
+			resources.CPUPeriod = *specResources.CPU.Period
+		}
+		if specResources.CPU.Shares != nil {
+			resources.CPUShares = *specResources.CPU.Shares
+		}
+	}
+
+	if specResources.Memory != nil && specResources.Memory.Limit != nil {
+		resources.MemoryLimitBytes = *specResources.Memory.Limit
+	}
+
+	return resources
+}
+
+func containerIDMappings(specMappings []spec.LinuxIDMapping) []vc.IDMap {
+	var mappings []vc.IDMap
+
+	for _, m := range specMappings {
+		mappings = append(mappings, vc.IDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		})
+	}
+
+	return mappings
+}
+
 func containerCapabilities(s CompatOCISpec) (vc.LinuxCapabilities, error) {
 	capabilities := s.Process.Capabilities
 	var c vc.LinuxCapabilities
@@ -470,8 +515,14 @@ func addAssetAnnotations(ocispec CompatOCISpec, config *vc.PodConfig) {
 	assetAnnotations := []string{
 		vcAnnotations.KernelPath,
 		vcAnnotations.ImagePath,
+		vcAnnotations.InitrdPath,
+		vcAnnotations.HypervisorPath,
+		vcAnnotations.FirmwarePath,
 		vcAnnotations.KernelHash,
 		vcAnnotations.ImageHash,
+		vcAnnotations.InitrdHash,
+		vcAnnotations.HypervisorHash,
+		vcAnnotations.FirmwareHash,
 		vcAnnotations.AssetHashType,
 	}
 
@@ -570,6 +621,8 @@ func ContainerConfig(ocispec CompatOCISpec, bundlePath, cid, console string, det
 		Console:         console,
 		Detach:          detach,
 		NoNewPrivileges: ocispec.Process.NoNewPrivileges,
+		SelinuxLabel:    ocispec.Process.SelinuxLabel,
+		ApparmorProfile: ocispec.Process.ApparmorProfile,
 	}
 
 	cmd.SupplementaryGroups = []string{}
@@ -587,11 +640,36 @@ func ContainerConfig(ocispec CompatOCISpec, bundlePath, cid, console string, det
 		return vc.ContainerConfig{}, err
 	}
 
+	for _, rlimit := range ocispec.Process.Rlimits {
+		cmd.Rlimits = append(cmd.Rlimits, vc.Rlimit{
+			Type: rlimit.Type,
+			Hard: rlimit.Hard,
+			Soft: rlimit.Soft,
+		})
+	}
+
+	var uidMappings, gidMappings []vc.IDMap
+	var sysctls map[string]string
+	var mountLabel string
+	var resources vc.ContainerResources
+	if ocispec.Linux != nil {
+		uidMappings = containerIDMappings(ocispec.Linux.UIDMappings)
+		gidMappings = containerIDMappings(ocispec.Linux.GIDMappings)
+		sysctls = ocispec.Linux.Sysctl
+		mountLabel = ocispec.Linux.MountLabel
+		resources = containerResources(ocispec.Linux.Resources)
+	}
+
 	containerConfig := vc.ContainerConfig{
 		ID:             cid,
 		RootFs:         rootfs,
 		ReadonlyRootfs: ocispec.Spec.Root.Readonly,
 		Cmd:            cmd,
+		UIDMappings:    uidMappings,
+		GIDMappings:    gidMappings,
+		Sysctls:        sysctls,
+		MountLabel:     mountLabel,
+		Resources:      resources,
 		Annotations: map[string]string{
 			vcAnnotations.ConfigJSONKey: string(ociSpecJSON),
 			vcAnnotations.BundlePathKey: bundlePath,
@@ -607,6 +685,14 @@ func ContainerConfig(ocispec CompatOCISpec, bundlePath, cid, console string, det
 
 	containerConfig.Annotations[vcAnnotations.ContainerTypeKey] = string(cType)
 
+	if list, ok := ocispec.Annotations[vcAnnotations.BlockVolumes]; ok && list != "" {
+		containerConfig.BlockVolumes = strings.Split(list, ",")
+	}
+
+	if list, ok := ocispec.Annotations[vcAnnotations.DirectVolumes]; ok && list != "" {
+		containerConfig.DirectVolumes = strings.Split(list, ",")
+	}
+
 	return containerConfig, nil
 }
 
@@ -631,6 +717,8 @@ func StateToOCIState(state vc.State) string {
 		return StateRunning
 	case vc.StateStopped:
 		return StateStopped
+	case vc.StatePaused:
+		return StatePaused
 	default:
 		return ""
 	}
@@ -104,6 +104,9 @@ func TestMinimalPodConfig(t *testing.T) {
 			Permitted:   capList,
 			Ambient:     capList,
 		},
+		Rlimits: []vc.Rlimit{
+			{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 1024},
+		},
 	}
 
 	expectedMounts := []vc.Mount{
@@ -477,6 +480,11 @@ func TestStateToOCIState(t *testing.T) {
 	if ociState := StateToOCIState(state); ociState != "stopped" {
 		t.Fatalf("Expecting \"created\" state, got \"%s\"", ociState)
 	}
+
+	state.State = vc.StatePaused
+	if ociState := StateToOCIState(state); ociState != "paused" {
+		t.Fatalf("Expecting \"paused\" state, got \"%s\"", ociState)
+	}
 }
 
 func TestEnvVars(t *testing.T) {
@@ -0,0 +1,286 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package server exposes the virtcontainers API over a unix socket
+// gRPC service, so non-Go tooling and remote controllers can drive
+// pods and containers without linking the package. The messages are
+// encoded with a JSON codec, keeping the wire format free of
+// generated protobuf code.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	vc "github.com/containers/virtcontainers"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// serviceName is the gRPC service name the management RPCs are
+// registered under.
+const serviceName = "virtcontainers.Management"
+
+// Request is the message carried by every management RPC. Fields not
+// relevant to a given method are ignored.
+type Request struct {
+	PodID           string              `json:"pod_id,omitempty"`
+	ContainerID     string              `json:"container_id,omitempty"`
+	PodConfig       *vc.PodConfig       `json:"pod_config,omitempty"`
+	ContainerConfig *vc.ContainerConfig `json:"container_config,omitempty"`
+	Signal          int                 `json:"signal,omitempty"`
+	All             bool                `json:"all,omitempty"`
+}
+
+// Response is the message returned by management RPCs that do not
+// report a status structure.
+type Response struct {
+	PodID       string `json:"pod_id,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+}
+
+// JSONCodec is the gRPC codec used by both the server and its
+// clients.
+type JSONCodec struct{}
+
+// Marshal implements the gRPC Codec interface.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements the gRPC Codec interface.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// String implements the gRPC Codec interface.
+func (JSONCodec) String() string {
+	return "json"
+}
+
+// managementService is the handler type the gRPC service is
+// registered with. The methods are dispatched through the method map,
+// so there is nothing to express in the interface itself.
+type managementService interface{}
+
+// Server exposes a virtcontainers implementation over a unix socket.
+type Server struct {
+	socketPath string
+	vci        vc.VC
+
+	grpcServer *grpc.Server
+	listener   net.Listener
+}
+
+// New returns a management server listening on the given unix socket
+// path, backed by the provided virtcontainers implementation.
+func New(socketPath string, vci vc.VC) (*Server, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("Socket path cannot be empty")
+	}
+
+	if vci == nil {
+		return nil, fmt.Errorf("Need a virtcontainers implementation")
+	}
+
+	return &Server{
+		socketPath: socketPath,
+		vci:        vci,
+	}, nil
+}
+
+func (s *Server) runPod(req *Request) (interface{}, error) {
+	if req.PodConfig == nil {
+		return nil, fmt.Errorf("Missing pod configuration")
+	}
+
+	pod, err := s.vci.RunPod(*req.PodConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{PodID: pod.ID()}, nil
+}
+
+func (s *Server) createPod(req *Request) (interface{}, error) {
+	if req.PodConfig == nil {
+		return nil, fmt.Errorf("Missing pod configuration")
+	}
+
+	pod, err := s.vci.CreatePod(*req.PodConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{PodID: pod.ID()}, nil
+}
+
+// podOp adapts the pod lifecycle calls sharing the same signature.
+func podOp(op func(string) (vc.VCPod, error)) func(*Request) (interface{}, error) {
+	return func(req *Request) (interface{}, error) {
+		pod, err := op(req.PodID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Response{PodID: pod.ID()}, nil
+	}
+}
+
+func (s *Server) listPod(req *Request) (interface{}, error) {
+	return s.vci.ListPod()
+}
+
+func (s *Server) statusPod(req *Request) (interface{}, error) {
+	status, err := s.vci.StatusPod(req.PodID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+func (s *Server) usagePod(req *Request) (interface{}, error) {
+	usage, err := s.vci.UsagePod(req.PodID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+func (s *Server) createContainer(req *Request) (interface{}, error) {
+	if req.ContainerConfig == nil {
+		return nil, fmt.Errorf("Missing container configuration")
+	}
+
+	pod, container, err := s.vci.CreateContainer(req.PodID, *req.ContainerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{PodID: pod.ID(), ContainerID: container.ID()}, nil
+}
+
+// containerOp adapts the container lifecycle calls sharing the same
+// signature.
+func containerOp(op func(string, string) (vc.VCContainer, error)) func(*Request) (interface{}, error) {
+	return func(req *Request) (interface{}, error) {
+		container, err := op(req.PodID, req.ContainerID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Response{PodID: req.PodID, ContainerID: container.ID()}, nil
+	}
+}
+
+func (s *Server) killContainer(req *Request) (interface{}, error) {
+	if err := s.vci.KillContainer(req.PodID, req.ContainerID, syscall.Signal(req.Signal), req.All); err != nil {
+		return nil, err
+	}
+
+	return &Response{PodID: req.PodID, ContainerID: req.ContainerID}, nil
+}
+
+func (s *Server) statusContainer(req *Request) (interface{}, error) {
+	status, err := s.vci.StatusContainer(req.PodID, req.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// methods maps RPC names to their implementation.
+func (s *Server) methods() map[string]func(*Request) (interface{}, error) {
+	return map[string]func(*Request) (interface{}, error){
+		"RunPod":          s.runPod,
+		"CreatePod":       s.createPod,
+		"StartPod":        podOp(s.vci.StartPod),
+		"StopPod":         podOp(s.vci.StopPod),
+		"DeletePod":       podOp(s.vci.DeletePod),
+		"PausePod":        podOp(s.vci.PausePod),
+		"ResumePod":       podOp(s.vci.ResumePod),
+		"ListPod":         s.listPod,
+		"StatusPod":       s.statusPod,
+		"UsagePod":        s.usagePod,
+		"CreateContainer": s.createContainer,
+		"StartContainer":  containerOp(s.vci.StartContainer),
+		"StopContainer":   containerOp(s.vci.StopContainer),
+		"DeleteContainer": containerOp(s.vci.DeleteContainer),
+		"KillContainer":   s.killContainer,
+		"StatusContainer": s.statusContainer,
+	}
+}
+
+// serviceDesc builds the gRPC service description out of the method
+// map.
+func (s *Server) serviceDesc() *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*managementService)(nil),
+	}
+
+	for name, method := range s.methods() {
+		method := method
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &Request{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+
+				return method(req)
+			},
+		})
+	}
+
+	return desc
+}
+
+// Start makes the server listen on its unix socket and serve RPCs
+// until Stop is called. It only returns once the server stops.
+func (s *Server) Start() error {
+	// A leftover socket from a previous run would prevent binding.
+	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	s.grpcServer = grpc.NewServer(grpc.CustomCodec(JSONCodec{}))
+	s.grpcServer.RegisterService(s.serviceDesc(), s)
+
+	return s.grpcServer.Serve(listener)
+}
+
+// Stop terminates the server and removes its socket.
+func (s *Server) Stop() {
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
+
+	os.Remove(s.socketPath)
+}
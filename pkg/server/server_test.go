@@ -0,0 +1,121 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vc "github.com/containers/virtcontainers"
+	"github.com/containers/virtcontainers/pkg/vcMock"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestNew(t *testing.T) {
+	mock := &vcMock.VCMock{}
+
+	if _, err := New("", mock); err == nil {
+		t.Fatal("Expecting an error with an empty socket path")
+	}
+
+	if _, err := New("/tmp/virtc.sock", nil); err == nil {
+		t.Fatal("Expecting an error with a nil implementation")
+	}
+
+	if _, err := New("/tmp/virtc.sock", mock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerRPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "virtc-server-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "virtc.sock")
+
+	mock := &vcMock.VCMock{
+		RunPodFunc: func(podConfig vc.PodConfig) (vc.VCPod, error) {
+			return &vcMock.Pod{MockID: podConfig.ID}, nil
+		},
+		StatusPodFunc: func(podID string) (vc.PodStatus, error) {
+			return vc.PodStatus{ID: podID}, nil
+		},
+	}
+
+	server, err := New(socketPath, mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go server.Start()
+	defer server.Stop()
+
+	// Wait for the socket to show up.
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(socketPath); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", socketPath, timeout)
+	}
+
+	conn, err := grpc.Dial(socketPath,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+		grpc.WithDialer(dialer),
+		grpc.WithCodec(JSONCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp := &Response{}
+	req := &Request{PodConfig: &vc.PodConfig{ID: "server-pod"}}
+	if err := grpc.Invoke(context.Background(), "/"+serviceName+"/RunPod", req, resp, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.PodID != "server-pod" {
+		t.Fatalf("Got pod ID %q\nExpecting %q", resp.PodID, "server-pod")
+	}
+
+	status := &vc.PodStatus{}
+	if err := grpc.Invoke(context.Background(), "/"+serviceName+"/StatusPod", &Request{PodID: "server-pod"}, status, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.ID != "server-pod" {
+		t.Fatalf("Got pod ID %q\nExpecting %q", status.ID, "server-pod")
+	}
+
+	// An unimplemented mock call must surface as an RPC error.
+	if err := grpc.Invoke(context.Background(), "/"+serviceName+"/StopPod", &Request{PodID: "server-pod"}, &Response{}, conn); err == nil {
+		t.Fatal("Expecting an error for an unimplemented mock function")
+	}
+}
@@ -23,6 +23,11 @@ func (c *Container) ID() string {
 	return c.MockID
 }
 
+// State implements the VCContainer function of the same name.
+func (c *Container) State() vc.State {
+	return c.MockState
+}
+
 // Pod implements the VCContainer function of the same name.
 func (c *Container) Pod() vc.VCPod {
 	return c.MockPod
@@ -26,6 +26,7 @@ package vcMock
 
 import (
 	"fmt"
+	"io"
 	"syscall"
 
 	vc "github.com/containers/virtcontainers"
@@ -61,6 +62,15 @@ func (m *VCMock) DeletePod(podID string) (vc.VCPod, error) {
 	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
 }
 
+// AdoptPod implements the VC function of the same name.
+func (m *VCMock) AdoptPod(podID string) (vc.VCPod, error) {
+	if m.AdoptPodFunc != nil {
+		return m.AdoptPodFunc(podID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
 // StartPod implements the VC function of the same name.
 func (m *VCMock) StartPod(podID string) (vc.VCPod, error) {
 	if m.StartPodFunc != nil {
@@ -79,6 +89,78 @@ func (m *VCMock) StopPod(podID string) (vc.VCPod, error) {
 	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
 }
 
+// DrainPod implements the VC function of the same name.
+func (m *VCMock) DrainPod(podID string, options vc.DrainOptions) (vc.VCPod, error) {
+	if m.DrainPodFunc != nil {
+		return m.DrainPodFunc(podID, options)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// SnapshotPod implements the VC function of the same name.
+func (m *VCMock) SnapshotPod(podID string) ([]vc.SnapshotHandle, error) {
+	if m.SnapshotPodFunc != nil {
+		return m.SnapshotPodFunc(podID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// BackupPod implements the VC function of the same name.
+func (m *VCMock) BackupPod(podID string, incremental bool) ([]vc.BackupHandle, error) {
+	if m.BackupPodFunc != nil {
+		return m.BackupPodFunc(podID, incremental)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// ExportPod implements the VC function of the same name.
+func (m *VCMock) ExportPod(podID string) ([]vc.NBDExport, error) {
+	if m.ExportPodFunc != nil {
+		return m.ExportPodFunc(podID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// UnexportPod implements the VC function of the same name.
+func (m *VCMock) UnexportPod(podID string) error {
+	if m.UnexportPodFunc != nil {
+		return m.UnexportPodFunc(podID)
+	}
+
+	return fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// ClonePod implements the VC function of the same name.
+func (m *VCMock) ClonePod(srcPodID string, options vc.CloneOptions) (vc.VCPod, error) {
+	if m.ClonePodFunc != nil {
+		return m.ClonePodFunc(srcPodID, options)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): srcPodID: %v", mockErrorPrefix, getSelf(), m, srcPodID)
+}
+
+// CreateVMTemplate implements the VC function of the same name.
+func (m *VCMock) CreateVMTemplate(templateDir string, podConfig vc.PodConfig) (*vc.VMTemplate, error) {
+	if m.CreateVMTemplateFunc != nil {
+		return m.CreateVMTemplateFunc(templateDir, podConfig)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): templateDir: %v", mockErrorPrefix, getSelf(), m, templateDir)
+}
+
+// LoadVMTemplate implements the VC function of the same name.
+func (m *VCMock) LoadVMTemplate(templateDir string) (*vc.VMTemplate, error) {
+	if m.LoadVMTemplateFunc != nil {
+		return m.LoadVMTemplateFunc(templateDir)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): templateDir: %v", mockErrorPrefix, getSelf(), m, templateDir)
+}
+
 // RunPod implements the VC function of the same name.
 func (m *VCMock) RunPod(podConfig vc.PodConfig) (vc.VCPod, error) {
 	if m.RunPodFunc != nil {
@@ -97,6 +179,15 @@ func (m *VCMock) ListPod() ([]vc.PodStatus, error) {
 	return nil, fmt.Errorf("%s: %s", mockErrorPrefix, getSelf())
 }
 
+// LeakCheck implements the VC function of the same name.
+func (m *VCMock) LeakCheck() ([]vc.NetworkLeak, error) {
+	if m.LeakCheckFunc != nil {
+		return m.LeakCheckFunc()
+	}
+
+	return nil, fmt.Errorf("%s: %s", mockErrorPrefix, getSelf())
+}
+
 // StatusPod implements the VC function of the same name.
 func (m *VCMock) StatusPod(podID string) (vc.PodStatus, error) {
 	if m.StatusPodFunc != nil {
@@ -124,6 +215,78 @@ func (m *VCMock) ResumePod(podID string) (vc.VCPod, error) {
 	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
 }
 
+// HypervisorCommand implements the VC function of the same name.
+func (m *VCMock) HypervisorCommand(podID, cmd string, args map[string]interface{}) (interface{}, error) {
+	if m.HypervisorCommandFunc != nil {
+		return m.HypervisorCommandFunc(podID, cmd, args)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v, cmd: %v", mockErrorPrefix, getSelf(), m, podID, cmd)
+}
+
+// AttestPod implements the VC function of the same name.
+func (m *VCMock) AttestPod(podID string) (vc.AttestationEvidence, error) {
+	if m.AttestPodFunc != nil {
+		return m.AttestPodFunc(podID)
+	}
+
+	return vc.AttestationEvidence{}, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// UsagePod implements the VC function of the same name.
+func (m *VCMock) UsagePod(podID string) (vc.PodResourceUsage, error) {
+	if m.UsagePodFunc != nil {
+		return m.UsagePodFunc(podID)
+	}
+
+	return vc.PodResourceUsage{}, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// WatermarksPod implements the VC function of the same name.
+func (m *VCMock) WatermarksPod(podID string) (vc.PodWatermarks, error) {
+	if m.WatermarksPodFunc != nil {
+		return m.WatermarksPodFunc(podID)
+	}
+
+	return vc.PodWatermarks{}, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// KSMPod implements the VC function of the same name.
+func (m *VCMock) KSMPod(podID string) (vc.PodKSMStats, error) {
+	if m.KSMPodFunc != nil {
+		return m.KSMPodFunc(podID)
+	}
+
+	return vc.PodKSMStats{}, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// OverheadPod implements the VC function of the same name.
+func (m *VCMock) OverheadPod(podID string) (vc.PodOverhead, error) {
+	if m.OverheadPodFunc != nil {
+		return m.OverheadPodFunc(podID)
+	}
+
+	return vc.PodOverhead{}, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// FetchConsoleLog implements the VC function of the same name.
+func (m *VCMock) FetchConsoleLog(podID string) ([]byte, error) {
+	if m.FetchConsoleLogFunc != nil {
+		return m.FetchConsoleLogFunc(podID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
+// KernelLogPod implements the VC function of the same name.
+func (m *VCMock) KernelLogPod(podID string) (io.ReadCloser, error) {
+	if m.KernelLogPodFunc != nil {
+		return m.KernelLogPodFunc(podID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v", mockErrorPrefix, getSelf(), m, podID)
+}
+
 // CreateContainer implements the VC function of the same name.
 func (m *VCMock) CreateContainer(podID string, containerConfig vc.ContainerConfig) (vc.VCPod, vc.VCContainer, error) {
 	if m.CreateContainerFunc != nil {
@@ -169,6 +332,24 @@ func (m *VCMock) EnterContainer(podID, containerID string, cmd vc.Cmd) (vc.VCPod
 	return nil, nil, nil, fmt.Errorf("%s: %s (%+v): podID: %v, containerID: %v, cmd: %v", mockErrorPrefix, getSelf(), m, podID, containerID, cmd)
 }
 
+// ExecProbe implements the VC function of the same name.
+func (m *VCMock) ExecProbe(podID, containerID string, cmd vc.Cmd, options vc.ProbeOptions) (vc.ProbeResult, error) {
+	if m.ExecProbeFunc != nil {
+		return m.ExecProbeFunc(podID, containerID, cmd, options)
+	}
+
+	return vc.ProbeResult{}, fmt.Errorf("%s: %s (%+v): podID: %v, containerID: %v, cmd: %v", mockErrorPrefix, getSelf(), m, podID, containerID, cmd)
+}
+
+// ReadContainerLogs implements the VC function of the same name.
+func (m *VCMock) ReadContainerLogs(podID, containerID string, options vc.ContainerLogsOptions) (io.ReadCloser, error) {
+	if m.ReadContainerLogsFunc != nil {
+		return m.ReadContainerLogsFunc(podID, containerID, options)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v, containerID: %v", mockErrorPrefix, getSelf(), m, podID, containerID)
+}
+
 // StatusContainer implements the VC function of the same name.
 func (m *VCMock) StatusContainer(podID, containerID string) (vc.ContainerStatus, error) {
 	if m.StatusContainerFunc != nil {
@@ -195,3 +376,12 @@ func (m *VCMock) ProcessListContainer(podID, containerID string, options vc.Proc
 
 	return nil, fmt.Errorf("%s: %s (%+v): podID: %v, containerID: %v", mockErrorPrefix, getSelf(), m, podID, containerID)
 }
+
+// AttachContainer implements the VC function of the same name.
+func (m *VCMock) AttachContainer(podID, containerID string) (*vc.ContainerStreams, error) {
+	if m.AttachContainerFunc != nil {
+		return m.AttachContainerFunc(podID, containerID)
+	}
+
+	return nil, fmt.Errorf("%s: %s (%+v): podID: %v, containerID: %v", mockErrorPrefix, getSelf(), m, podID, containerID)
+}
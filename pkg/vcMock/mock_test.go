@@ -273,6 +273,32 @@ func TestVCMockRunPod(t *testing.T) {
 	assert.True(IsMockError(err))
 }
 
+func TestVCMockAdoptPod(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &VCMock{}
+	assert.Nil(m.AdoptPodFunc)
+
+	_, err := m.AdoptPod(testPodID)
+	assert.Error(err)
+	assert.True(IsMockError(err))
+
+	m.AdoptPodFunc = func(podID string) (vc.VCPod, error) {
+		return &Pod{}, nil
+	}
+
+	pod, err := m.AdoptPod(testPodID)
+	assert.NoError(err)
+	assert.Equal(pod, &Pod{})
+
+	// reset
+	m.AdoptPodFunc = nil
+
+	_, err = m.AdoptPod(testPodID)
+	assert.Error(err)
+	assert.True(IsMockError(err))
+}
+
 func TestVCMockStartPod(t *testing.T) {
 	assert := assert.New(t)
 
@@ -23,6 +23,11 @@ func (p *Pod) ID() string {
 	return p.MockID
 }
 
+// State implements the VCPod function of the same name.
+func (p *Pod) State() vc.State {
+	return p.MockState
+}
+
 // Annotations implements the VCPod function of the same name.
 func (p *Pod) Annotations(key string) (string, error) {
 	return p.MockAnnotations[key], nil
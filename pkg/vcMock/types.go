@@ -15,6 +15,7 @@
 package vcMock
 
 import (
+	"io"
 	"syscall"
 
 	vc "github.com/containers/virtcontainers"
@@ -27,6 +28,7 @@ type Pod struct {
 	MockURL         string
 	MockAnnotations map[string]string
 	MockContainers  []*Container
+	MockState       vc.State
 }
 
 // Container is a fake Container type used for testing
@@ -38,6 +40,7 @@ type Container struct {
 	MockPid         int
 	MockPod         *Pod
 	MockAnnotations map[string]string
+	MockState       vc.State
 }
 
 // VCMock is a type that provides an implementation of the VC interface.
@@ -45,22 +48,47 @@ type Container struct {
 type VCMock struct {
 	SetLoggerFunc func(logger logrus.FieldLogger)
 
+	AdoptPodFunc  func(podID string) (vc.VCPod, error)
 	CreatePodFunc func(podConfig vc.PodConfig) (vc.VCPod, error)
 	DeletePodFunc func(podID string) (vc.VCPod, error)
 	ListPodFunc   func() ([]vc.PodStatus, error)
+	LeakCheckFunc func() ([]vc.NetworkLeak, error)
 	PausePodFunc  func(podID string) (vc.VCPod, error)
 	ResumePodFunc func(podID string) (vc.VCPod, error)
 	RunPodFunc    func(podConfig vc.PodConfig) (vc.VCPod, error)
 	StartPodFunc  func(podID string) (vc.VCPod, error)
 	StatusPodFunc func(podID string) (vc.PodStatus, error)
 	StopPodFunc   func(podID string) (vc.VCPod, error)
+	DrainPodFunc  func(podID string, options vc.DrainOptions) (vc.VCPod, error)
+
+	SnapshotPodFunc func(podID string) ([]vc.SnapshotHandle, error)
+	BackupPodFunc   func(podID string, incremental bool) ([]vc.BackupHandle, error)
+	ExportPodFunc   func(podID string) ([]vc.NBDExport, error)
+	UnexportPodFunc func(podID string) error
+	ClonePodFunc    func(srcPodID string, options vc.CloneOptions) (vc.VCPod, error)
+
+	CreateVMTemplateFunc func(templateDir string, podConfig vc.PodConfig) (*vc.VMTemplate, error)
+	LoadVMTemplateFunc   func(templateDir string) (*vc.VMTemplate, error)
+
+	FetchConsoleLogFunc func(podID string) ([]byte, error)
+	KernelLogPodFunc    func(podID string) (io.ReadCloser, error)
+	UsagePodFunc        func(podID string) (vc.PodResourceUsage, error)
+	WatermarksPodFunc   func(podID string) (vc.PodWatermarks, error)
+	KSMPodFunc          func(podID string) (vc.PodKSMStats, error)
+	OverheadPodFunc     func(podID string) (vc.PodOverhead, error)
+
+	HypervisorCommandFunc func(podID, cmd string, args map[string]interface{}) (interface{}, error)
+	AttestPodFunc         func(podID string) (vc.AttestationEvidence, error)
 
 	CreateContainerFunc      func(podID string, containerConfig vc.ContainerConfig) (vc.VCPod, vc.VCContainer, error)
 	DeleteContainerFunc      func(podID, containerID string) (vc.VCContainer, error)
 	EnterContainerFunc       func(podID, containerID string, cmd vc.Cmd) (vc.VCPod, vc.VCContainer, *vc.Process, error)
+	ExecProbeFunc            func(podID, containerID string, cmd vc.Cmd, options vc.ProbeOptions) (vc.ProbeResult, error)
+	ReadContainerLogsFunc    func(podID, containerID string, options vc.ContainerLogsOptions) (io.ReadCloser, error)
 	KillContainerFunc        func(podID, containerID string, signal syscall.Signal, all bool) error
 	StartContainerFunc       func(podID, containerID string) (vc.VCContainer, error)
 	StatusContainerFunc      func(podID, containerID string) (vc.ContainerStatus, error)
 	StopContainerFunc        func(podID, containerID string) (vc.VCContainer, error)
 	ProcessListContainerFunc func(podID, containerID string, options vc.ProcessListOptions) (vc.ProcessList, error)
+	AttachContainerFunc      func(podID, containerID string) (*vc.ContainerStreams, error)
 }
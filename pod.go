@@ -18,15 +18,28 @@ package virtcontainers
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/containers/virtcontainers/pkg/annotations"
 	"github.com/sirupsen/logrus"
 )
 
+// strictAnnotations controls whether unknown annotation keys from the
+// virtcontainers namespace are rejected at pod creation time.
+var strictAnnotations = false
+
+// SetStrictAnnotations enables or disables the rejection of unknown
+// virtcontainers annotation keys.
+func SetStrictAnnotations(strict bool) {
+	strictAnnotations = strict
+}
+
 // controlSocket is the pod control socket.
 // It is an hypervisor resource, and for example qemu's control
 // socket is the QMP one.
@@ -71,8 +84,43 @@ type State struct {
 
 	// Bool to indicate if the drive for a container was hotplugged.
 	HotpluggedDrive bool `json:"hotpluggedDrive"`
+
+	// GuestMemoryDumpPath is the location of the guest crash dump,
+	// if one has been captured.
+	GuestMemoryDumpPath string `json:"guestMemoryDumpPath,omitempty"`
+
+	// GuestSwapDevice is the predicted guest path of the swap block
+	// device, when guest swap is enabled.
+	GuestSwapDevice string `json:"guestSwapDevice,omitempty"`
+
+	// StartupPhases records how long each phase of the pod startup
+	// took, keyed by the StartupPhase* constants.
+	StartupPhases map[string]time.Duration `json:"startupPhases,omitempty"`
+
+	// NFSVsockPorts records the vsock port serving each NFS backed
+	// volume, keyed by mount tag. The ports are allocated by the
+	// kernel when the exports start.
+	NFSVsockPorts map[string]uint32 `json:"nfsVsockPorts,omitempty"`
 }
 
+// Startup phase names recorded in the pod state, in the order the
+// phases happen during pod startup.
+const (
+	// StartupPhaseHypervisorStart covers the hypervisor process startup.
+	StartupPhaseHypervisorStart = "hypervisor-start"
+
+	// StartupPhaseVMBoot covers firmware and guest kernel boot, up to
+	// the point where the VM answers on its control channel.
+	StartupPhaseVMBoot = "vm-boot"
+
+	// StartupPhaseAgentReady covers starting the proxy and the agent
+	// running inside the guest.
+	StartupPhaseAgentReady = "agent-ready"
+
+	// StartupPhaseContainersStart covers starting the pod containers.
+	StartupPhaseContainersStart = "containers-start"
+)
+
 // valid checks that the pod state is valid.
 func (state *State) valid() bool {
 	for _, validState := range []stateString{StateReady, StateRunning, StatePaused, StateStopped} {
@@ -117,6 +165,21 @@ func (state *State) validTransition(oldState stateString, newState stateString)
 		state.State, newState)
 }
 
+// VolumeBackendType selects how a shared volume is exported to the
+// guest.
+type VolumeBackendType string
+
+const (
+	// Volume9P exports the volume to the guest through virtio-9p.
+	// This is the default backend.
+	Volume9P VolumeBackendType = "9p"
+
+	// VolumeNFS serves the volume to the guest over NFS through a
+	// vsock port, for workloads that need better POSIX locking
+	// semantics than 9p. This backend is experimental.
+	VolumeNFS VolumeBackendType = "nfs"
+)
+
 // Volume is a shared volume between the host and the VM,
 // defined by its mount tag and its host path.
 type Volume struct {
@@ -125,6 +188,10 @@ type Volume struct {
 
 	// HostPath is the host filesystem path for this volume.
 	HostPath string
+
+	// Backend selects how the volume is exported to the guest. An
+	// empty value means the default virtio-9p backend.
+	Backend VolumeBackendType
 }
 
 // Volumes is a Volume list.
@@ -174,6 +241,21 @@ func (v *Volumes) String() string {
 	return strings.Join(volSlice, " ")
 }
 
+// SocketOwnership configures the ownership and mode applied to the
+// per-pod sockets (console, monitor, proxy), so that a non-root
+// monitoring agent can read consoles without being granted full
+// control over the pod.
+type SocketOwnership struct {
+	// UID and GID own the pod sockets. Zero values keep the sockets
+	// owned by the user the hypervisor runs as.
+	UID int
+	GID int
+
+	// Mode is the file mode applied to the pod sockets. A zero
+	// value keeps the mode they were created with.
+	Mode os.FileMode
+}
+
 // Socket defines a socket to communicate between
 // the host and any process inside the VM.
 type Socket struct {
@@ -269,6 +351,17 @@ type LinuxCapabilities struct {
 	Ambient []string
 }
 
+// Rlimit describes a resource limit to apply to the process running
+// inside the container.
+type Rlimit struct {
+	// Type of the rlimit to set.
+	Type string
+	// Hard is the hard limit for the specified type.
+	Hard uint64
+	// Soft is the soft limit for the specified type.
+	Soft uint64
+}
+
 // Cmd represents a command to execute in a running container.
 type Cmd struct {
 	Args    []string
@@ -301,11 +394,26 @@ type Cmd struct {
 	PrimaryGroup        string
 	SupplementaryGroups []string
 
-	Interactive     bool
-	Console         string
+	Interactive bool
+	Console     string
+
+	// ConsoleSocket is a unix socket provided by the caller, over
+	// which the master side of an allocated terminal is passed when
+	// Interactive is set and no Console path is provided.
+	ConsoleSocket string
+
 	Detach          bool
 	NoNewPrivileges bool
 	Capabilities    LinuxCapabilities
+	Rlimits         []Rlimit
+
+	// SelinuxLabel is the SELinux context the workload process runs
+	// with inside the guest, for agents supporting it.
+	SelinuxLabel string
+
+	// ApparmorProfile is the AppArmor profile the workload process
+	// runs with inside the guest, for agents supporting it.
+	ApparmorProfile string
 }
 
 // Resources describes VM resources configuration.
@@ -332,12 +440,49 @@ type PodStatus struct {
 	Annotations map[string]string
 }
 
+// HostAlias is an additional hostname to IP mapping written to the
+// hosts file of every container in the pod.
+type HostAlias struct {
+	// IP is the address the hostnames resolve to.
+	IP string
+
+	// Hostnames is the list of names mapped to IP.
+	Hostnames []string
+}
+
 // PodConfig is a Pod configuration.
 type PodConfig struct {
 	ID string
 
 	Hostname string
 
+	// HostAliases are additional entries for the hosts file of every
+	// container in the pod.
+	HostAliases []HostAlias
+
+	// GuestSwap describes the swap device to create inside the
+	// guest.
+	GuestSwap GuestSwapConfig
+
+	// MemorySoftLimitMiB is a soft memory limit in MiB the agent
+	// applies inside the guest, 0 means no soft limit.
+	MemorySoftLimitMiB uint
+
+	// Cgroups configures the host cgroup the pod host processes are
+	// placed into.
+	Cgroups CgroupsConfig
+
+	// BootTimeoutSecs is the time in seconds the pod boot (VM plus
+	// guest agent) is allowed to take before the VM gets killed and
+	// a diagnostics bundle is collected, 0 meaning the default.
+	BootTimeoutSecs uint
+
+	// SharePidNs instructs the agent to create every container of
+	// the pod in a single PID namespace (Kubernetes
+	// shareProcessNamespace). The agent reaps the zombies of all
+	// containers sharing the namespace.
+	SharePidNs bool
+
 	// Field specific to OCI specs, needed to setup all the hooks
 	Hooks Hooks
 
@@ -362,6 +507,28 @@ type PodConfig struct {
 	// Volumes is a list of shared volumes between the host and the Pod.
 	Volumes []Volume
 
+	// MDevDevices lists the mediated device instances (e.g. vGPU
+	// slices) to create from parent host devices for the pod
+	// lifetime and pass through to the guest.
+	MDevDevices []MDevDevice
+
+	// WatermarkThresholds defines the guest resource utilization
+	// levels above which a resource alert is emitted.
+	WatermarkThresholds WatermarkThresholds
+
+	// BalloonReclaim configures the automatic balloon reclaim
+	// policy for the pod.
+	BalloonReclaim BalloonReclaimConfig
+
+	// Priority is the priority class translated into host scheduler
+	// weights for the pod host processes. An empty class keeps the
+	// host defaults.
+	Priority PriorityClass
+
+	// Injection defines the pod-wide environment variables and
+	// secret files injected into every container.
+	Injection PodInjection
+
 	// Containers describe the list of containers within a Pod.
 	// This list can be empty and populated by adding containers
 	// to the Pod a posteriori.
@@ -370,19 +537,45 @@ type PodConfig struct {
 	// Annotations keys must be unique strings and must be name-spaced
 	// with e.g. reverse domain notation (org.clearlinux.key).
 	Annotations map[string]string
+
+	// SocketOwnership is the ownership and mode applied to the
+	// per-pod sockets once the pod is started.
+	SocketOwnership SocketOwnership
+
+	// ContainerStopOrder lists container IDs in the order they must
+	// be stopped when the pod is drained, typically the reverse of
+	// their dependency order. Containers not listed are stopped
+	// first, in creation order.
+	ContainerStopOrder []string
+
+	// GuestMetadata is arbitrary user data exposed to the guest,
+	// along with the pod ID and the container list, through a
+	// metadata image attached to the VM. A nil map disables the
+	// metadata channel.
+	GuestMetadata map[string]string
 }
 
-// valid checks that the pod configuration is valid.
-func (podConfig *PodConfig) valid() bool {
+// validate runs every check on the pod configuration and returns the
+// complete list of problems found.
+func (podConfig *PodConfig) validate() []error {
+	var errs []error
+
 	if podConfig.ID == "" {
-		return false
+		errs = append(errs, errNeedPodID)
 	}
 
 	if _, err := newHypervisor(podConfig.HypervisorType); err != nil {
 		podConfig.HypervisorType = QemuHypervisor
 	}
 
-	return true
+	errs = append(errs, podConfig.HypervisorConfig.validate()...)
+
+	return errs
+}
+
+// valid checks that the pod configuration is valid.
+func (podConfig *PodConfig) valid() bool {
+	return len(podConfig.validate()) == 0
 }
 
 const (
@@ -466,6 +659,13 @@ type Pod struct {
 
 	lockFile *os.File
 
+	consoleLog *consoleLogger
+	consolePty *consolePty
+
+	balloonReclaim *balloonReclaimer
+
+	nfsServers []*nfsVsockServer
+
 	annotationsLock *sync.RWMutex
 
 	wg *sync.WaitGroup
@@ -476,6 +676,12 @@ func (p *Pod) ID() string {
 	return p.id
 }
 
+// State returns the pod lifecycle state, so consumers can observe it
+// without reaching for the internal fields.
+func (p *Pod) State() State {
+	return p.state
+}
+
 // Logger returns a logrus logger appropriate for logging Pod messages
 func (p *Pod) Logger() *logrus.Entry {
 	return virtLog.WithFields(logrus.Fields{
@@ -551,7 +757,36 @@ func createAssets(podConfig *PodConfig) error {
 		return err
 	}
 
-	for _, a := range []*asset{kernel, image} {
+	initrd, err := newAsset(podConfig, initrdAsset)
+	if err != nil {
+		return err
+	}
+
+	hypervisor, err := newAsset(podConfig, hypervisorAsset)
+	if err != nil {
+		return err
+	}
+
+	// A pod provided hypervisor binary must be part of the host-wide
+	// allow-list before it is accepted.
+	if hypervisor != nil {
+		if err := podConfig.HypervisorConfig.validateTrustedHypervisor(hypervisor); err != nil {
+			return err
+		}
+	}
+
+	firmware, err := newAsset(podConfig, firmwareAsset)
+	if err != nil {
+		return err
+	}
+
+	assets := []*asset{kernel, image, initrd, hypervisor, firmware}
+
+	if err := verifyAssetSignatures(podConfig, assets); err != nil {
+		return err
+	}
+
+	for _, a := range assets {
 		if err := podConfig.HypervisorConfig.addCustomAsset(a); err != nil {
 			return err
 		}
@@ -566,6 +801,18 @@ func createAssets(podConfig *PodConfig) error {
 // to physically create that pod i.e. starts a VM for that pod to eventually
 // be started.
 func createPod(podConfig PodConfig) (*Pod, error) {
+	if err := annotations.Validate(podConfig.Annotations, strictAnnotations); err != nil {
+		return nil, err
+	}
+
+	if err := applyHypervisorProfile(&podConfig); err != nil {
+		return nil, err
+	}
+
+	if err := podConfig.HypervisorConfig.applyAnnotations(podConfig.Annotations); err != nil {
+		return nil, err
+	}
+
 	if err := createAssets(&podConfig); err != nil {
 		return nil, err
 	}
@@ -586,6 +833,10 @@ func createPod(podConfig PodConfig) (*Pod, error) {
 	}
 
 	// Below code path is called only during create, because of earlier check.
+	if err := p.attachSwapDevice(); err != nil {
+		return nil, err
+	}
+
 	if err := p.agent.createPod(p); err != nil {
 		return nil, err
 	}
@@ -604,8 +855,8 @@ func createPod(podConfig PodConfig) (*Pod, error) {
 }
 
 func newPod(podConfig PodConfig) (*Pod, error) {
-	if podConfig.valid() == false {
-		return nil, fmt.Errorf("Invalid pod configuration")
+	if errs := podConfig.validate(); len(errs) > 0 {
+		return nil, &ConfigValidationError{Errors: errs}
 	}
 
 	agent := newAgent(podConfig.AgentType)
@@ -636,18 +887,28 @@ func newPod(podConfig PodConfig) (*Pod, error) {
 		return nil, err
 	}
 
+	// Mediated device instances live for the pod lifetime and must
+	// exist before the hypervisor configuration references them.
+	if err := p.createMDevInstances(); err != nil {
+		p.storage.deletePodResources(p.id, nil)
+		return nil, err
+	}
+
 	if err := p.hypervisor.init(p); err != nil {
+		p.destroyMDevInstances()
 		p.storage.deletePodResources(p.id, nil)
 		return nil, err
 	}
 
 	if err := p.hypervisor.createPod(podConfig); err != nil {
+		p.destroyMDevInstances()
 		p.storage.deletePodResources(p.id, nil)
 		return nil, err
 	}
 
 	agentConfig := newAgentConfig(podConfig)
 	if err := p.agent.init(p, agentConfig); err != nil {
+		p.destroyMDevInstances()
 		p.storage.deletePodResources(p.id, nil)
 		return nil, err
 	}
@@ -728,29 +989,204 @@ func (p *Pod) delete() error {
 		return fmt.Errorf("Pod not ready, paused or stopped, impossible to delete")
 	}
 
+	// The pod stops claiming host resources once deleted. A stale
+	// claim only makes admission control more conservative, so a
+	// failure here does not prevent the deletion.
+	if err := releasePodResources(p.id); err != nil {
+		p.Logger().WithError(err).Warn("Could not release the pod resource claim")
+	}
+
+	// Leaked mediated device instances keep their parent device
+	// sliced up, so the deletion fails while any of them remains.
+	if err := p.destroyMDevInstances(); err != nil {
+		return err
+	}
+
 	return p.storage.deletePodResources(p.id, nil)
 }
 
 // startVM starts the VM.
+// AddVolume exports a host directory to a running pod, making its
+// content available to the guest without a restart. It returns the
+// path at which the guest sees the volume. This is how secrets or
+// configuration maps updated after pod start can be attached.
+func (p *Pod) AddVolume(volume Volume) (string, error) {
+	if p.state.State != StateRunning {
+		return "", fmt.Errorf("Pod not running, impossible to add a volume")
+	}
+
+	return p.agent.addVolume(p, volume)
+}
+
+// RemoveVolume withdraws a volume previously added with AddVolume.
+func (p *Pod) RemoveVolume(volume Volume) error {
+	if p.state.State != StateRunning {
+		return fmt.Errorf("Pod not running, impossible to remove a volume")
+	}
+
+	return p.agent.removeVolume(p, volume)
+}
+
+// recordStartupPhase stores how long a pod startup phase took in the
+// pod state, where StatusPod exposes it. Timings are informative only,
+// so storage failures are logged rather than returned.
+func (p *Pod) recordStartupPhase(phase string, duration time.Duration) {
+	if p.state.StartupPhases == nil {
+		p.state.StartupPhases = make(map[string]time.Duration)
+	}
+
+	p.state.StartupPhases[phase] = duration
+
+	if err := p.storage.storePodResource(p.id, stateFileType, p.state); err != nil {
+		p.Logger().WithError(err).Warn("Could not store the startup phase timings")
+	}
+}
+
+// bootTimeout returns the pod boot timeout in seconds.
+func (p *Pod) bootTimeout() int {
+	if p.config.BootTimeoutSecs == 0 {
+		return vmStartTimeout
+	}
+
+	return int(p.config.BootTimeoutSecs)
+}
+
 func (p *Pod) startVM(netNsPath string) error {
 	p.Logger().Info("Starting VM")
 
+	// SR-IOV virtual functions get their identity programmed on the
+	// physical function before the guest takes them over.
+	if err := setupVFs(p.config.NetworkConfig); err != nil {
+		return err
+	}
+
+	phaseStart := time.Now()
+
 	if err := p.network.run(netNsPath, func() error {
 		return p.hypervisor.startPod()
 	}); err != nil {
 		return err
 	}
 
-	if err := p.hypervisor.waitPod(vmStartTimeout); err != nil {
-		return err
+	p.recordStartupPhase(StartupPhaseHypervisorStart, time.Since(phaseStart))
+
+	phaseStart = time.Now()
+
+	if err := p.hypervisor.waitPod(p.bootTimeout()); err != nil {
+		return p.bootFailure(err)
 	}
 
+	p.recordStartupPhase(StartupPhaseVMBoot, time.Since(phaseStart))
+
 	p.Logger().Info("VM started")
 
+	// Collect the guest console output for post-mortem analysis,
+	// unless the proxy already holds the console socket. Pods running
+	// without an agent get an interactive terminal on their console
+	// instead, there is no other way into the guest.
+	if !consoleConsumedByProxy(p.config.ProxyType) {
+		if p.config.AgentType == NoopAgentType {
+			cp := newConsolePty(p.id, p.hypervisor.getPodConsole(p.id))
+			if err := cp.start(); err != nil {
+				p.Logger().WithError(err).Warn("Could not start the console terminal")
+			} else {
+				p.consolePty = cp
+			}
+		} else {
+			cl := newConsoleLogger(p.id, p.hypervisor.getPodConsole(p.id))
+			if err := cl.start(); err != nil {
+				p.Logger().WithError(err).Warn("Could not start the console logger")
+			} else {
+				p.consoleLog = cl
+			}
+		}
+	}
+
+	// NFS backed volumes must be served before the agent mounts
+	// anything inside the guest.
+	if err := p.startNFSVolumes(); err != nil {
+		return err
+	}
+
 	// Once startVM is done, we want to guarantee
 	// that the pod is manageable. For that we need
-	// to start the pod inside the VM.
-	return p.agent.startPod(*p)
+	// to start the pod inside the VM. The agent start is bounded by
+	// the boot timeout so that a guest image with no agent cannot
+	// hang the pod start forever.
+	phaseStart = time.Now()
+
+	agentErrCh := make(chan error)
+	go func() {
+		agentErrCh <- p.agent.startPod(*p)
+	}()
+
+	select {
+	case err := <-agentErrCh:
+		if err != nil {
+			return p.bootFailure(err)
+		}
+	case <-time.After(time.Duration(p.bootTimeout()) * time.Second):
+		return p.bootFailure(fmt.Errorf("Timed out waiting for the guest agent after %d seconds", p.bootTimeout()))
+	}
+
+	p.recordStartupPhase(StartupPhaseAgentReady, time.Since(phaseStart))
+
+	// The balloon reclaim policy needs the agent, so it only starts
+	// once the guest is manageable.
+	if p.config.BalloonReclaim.Enabled {
+		br := newBalloonReclaimer(p, p.config.BalloonReclaim)
+		if err := br.start(); err != nil {
+			p.Logger().WithError(err).Warn("Could not start the balloon reclaim policy")
+		} else {
+			p.balloonReclaim = br
+		}
+	}
+
+	// Every pod socket exists by now, the hypervisor and the proxy
+	// are both running.
+	if err := p.applySocketOwnership(); err != nil {
+		return err
+	}
+
+	// Place the hypervisor and proxy processes into the pod host
+	// cgroup, now that both are running.
+	return p.setupHostCgroup()
+}
+
+// applySocketOwnership applies the configured ownership and mode to
+// every socket in the pod runtime directory.
+func (p *Pod) applySocketOwnership() error {
+	ownership := p.config.SocketOwnership
+	if ownership.UID == 0 && ownership.GID == 0 && ownership.Mode == 0 {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(runStoragePath, p.id))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSocket == 0 {
+			continue
+		}
+
+		path := filepath.Join(runStoragePath, p.id, entry.Name())
+
+		if ownership.UID != 0 || ownership.GID != 0 {
+			if err := os.Chown(path, ownership.UID, ownership.GID); err != nil {
+				return err
+			}
+		}
+
+		if ownership.Mode != 0 {
+			if err := os.Chmod(path, ownership.Mode); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func (p *Pod) addContainer(c *Container) error {
@@ -798,6 +1234,10 @@ func (p *Pod) createContainers() error {
 // start starts a pod. The containers that are making the pod
 // will be started.
 func (p *Pod) start() error {
+	if nbdExportActive(p.id) {
+		return fmt.Errorf("Pod %s storage is exported over NBD, impossible to start", p.id)
+	}
+
 	if err := p.state.validTransition(p.state.State, StateRunning); err != nil {
 		return err
 	}
@@ -806,17 +1246,93 @@ func (p *Pod) start() error {
 		return err
 	}
 
+	phaseStart := time.Now()
+
 	for _, c := range p.containers {
 		if err := c.start(); err != nil {
 			return err
 		}
 	}
 
+	p.recordStartupPhase(StartupPhaseContainersStart, time.Since(phaseStart))
+
 	p.Logger().Info("Pod is started")
 
 	return nil
 }
 
+// DrainOptions bounds a deadline aware pod stop.
+type DrainOptions struct {
+	// GracePeriodSecs is the default time in seconds each workload
+	// gets between a termination request and being killed.
+	GracePeriodSecs uint32
+
+	// ContainerGracePeriodSecs overrides the grace period for
+	// individual containers, keyed by container ID.
+	ContainerGracePeriodSecs map[string]uint32
+}
+
+// drainOrder returns the pod containers in the order they must be
+// stopped during a drain: containers absent from the configured stop
+// order come first, in creation order, followed by the listed ones.
+func (p *Pod) drainOrder() []*Container {
+	listed := make(map[string]bool)
+	for _, id := range p.config.ContainerStopOrder {
+		listed[id] = true
+	}
+
+	var ordered []*Container
+
+	for _, c := range p.containers {
+		if !listed[c.id] {
+			ordered = append(ordered, c)
+		}
+	}
+
+	for _, id := range p.config.ContainerStopOrder {
+		if c, err := p.findContainer(id); err == nil {
+			ordered = append(ordered, c)
+		}
+	}
+
+	return ordered
+}
+
+// drain stops a pod like stop does, but stops the containers in the
+// configured stop order and grants each workload a grace period to
+// exit on its own before it is killed.
+func (p *Pod) drain(options DrainOptions) error {
+	if err := p.state.validTransition(p.state.State, StateStopped); err != nil {
+		return err
+	}
+
+	// This handles the special case of draining a pod in ready state.
+	if p.state.State == StateReady {
+		return p.setPodState(StateStopped)
+	}
+
+	for _, c := range p.drainOrder() {
+		if c.state.State != StateRunning && c.state.State != StatePaused {
+			continue
+		}
+
+		gracePeriod := options.GracePeriodSecs
+		if containerGracePeriod, ok := options.ContainerGracePeriodSecs[c.id]; ok {
+			gracePeriod = containerGracePeriod
+		}
+
+		if err := c.drain(gracePeriod); err != nil {
+			return err
+		}
+	}
+
+	if err := p.agent.stopPod(*p); err != nil {
+		return err
+	}
+
+	return p.setPodState(StateStopped)
+}
+
 // stopShims stops all remaining shims corresponfing to not started/stopped
 // containers.
 func (p *Pod) stopShims() error {
@@ -839,7 +1355,38 @@ func (p *Pod) stopShims() error {
 func (p *Pod) stopVM() error {
 	p.Logger().Info("Stopping VM")
 
-	return p.hypervisor.stopPod()
+	if p.balloonReclaim != nil {
+		p.balloonReclaim.stop()
+		p.balloonReclaim = nil
+	}
+
+	if p.consoleLog != nil {
+		p.consoleLog.stop()
+		p.consoleLog = nil
+	}
+
+	if p.consolePty != nil {
+		p.consolePty.stop()
+		p.consolePty = nil
+	}
+
+	p.stopNFSVolumes()
+
+	if err := p.hypervisor.stopPod(); err != nil {
+		return err
+	}
+
+	// The physical function state programmed for passed through
+	// virtual functions is restored once the guest released them.
+	if err := clearVFs(p.config.NetworkConfig); err != nil {
+		p.Logger().WithError(err).Warn("Could not restore the VF state on the physical functions")
+	}
+
+	if err := p.deleteHostCgroup(); err != nil {
+		p.Logger().WithError(err).Warn("Could not delete the pod host cgroup")
+	}
+
+	return nil
 }
 
 // stop stops a pod. The containers that are making the pod
@@ -906,7 +1453,13 @@ func (p *Pod) setPodState(state stateString) error {
 	p.state.State = state
 
 	// update on-disk state
-	return p.storage.storePodResource(p.id, stateFileType, p.state)
+	if err := p.storage.storePodResource(p.id, stateFileType, p.state); err != nil {
+		return err
+	}
+
+	watchers.publish(p.id, "", state)
+
+	return nil
 }
 
 func (p *Pod) pauseSetStates() error {
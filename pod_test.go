@@ -24,7 +24,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/containers/virtcontainers/pkg/annotations"
 	"github.com/stretchr/testify/assert"
@@ -1262,3 +1264,144 @@ func TestFindContainerSuccess(t *testing.T) {
 
 	assert.True(t, c == pod.containers[0], "Container pointers should point to the same address")
 }
+
+func TestPodRecordStartupPhase(t *testing.T) {
+	hConfig := newHypervisorConfig(nil, nil)
+	p, err := testCreatePod(t, testPodID, MockHypervisor, hConfig, NoopAgentType, NoopNetworkModel, NetworkConfig{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(p.configPath)
+
+	p.recordStartupPhase(StartupPhaseVMBoot, 42*time.Millisecond)
+
+	if p.state.StartupPhases[StartupPhaseVMBoot] != 42*time.Millisecond {
+		t.Fatalf("Got startup phases %+v", p.state.StartupPhases)
+	}
+
+	state, err := p.storage.fetchPodState(p.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state.StartupPhases[StartupPhaseVMBoot] != 42*time.Millisecond {
+		t.Fatalf("Got stored startup phases %+v", state.StartupPhases)
+	}
+}
+
+func TestPodAddVolumeNotRunning(t *testing.T) {
+	pod := &Pod{
+		state: State{State: StateReady},
+	}
+
+	volume := Volume{
+		MountTag: "secrets",
+		HostPath: "/tmp/secrets",
+	}
+
+	if _, err := pod.AddVolume(volume); err == nil {
+		t.Fatal("Expecting an error when the pod is not running")
+	}
+
+	if err := pod.RemoveVolume(volume); err == nil {
+		t.Fatal("Expecting an error when the pod is not running")
+	}
+}
+
+func TestKataAgentAddVolumeNeedsTagAndPath(t *testing.T) {
+	k := &kataAgent{}
+	pod := &Pod{id: "vol-test-pod"}
+
+	if _, err := k.addVolume(pod, Volume{MountTag: "secrets"}); err == nil {
+		t.Fatal("Expecting an error for a volume without a host path")
+	}
+
+	if _, err := k.addVolume(pod, Volume{HostPath: "/tmp/secrets"}); err == nil {
+		t.Fatal("Expecting an error for a volume without a mount tag")
+	}
+}
+
+func TestApplySocketOwnership(t *testing.T) {
+	podDir := filepath.Join(runStoragePath, testPodID)
+	if err := os.MkdirAll(podDir, dirMode); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(podDir)
+
+	sockPath := filepath.Join(podDir, "console.sock")
+	if err := syscall.Mknod(sockPath, syscall.S_IFSOCK|0600, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(podDir, "config.json")
+	if err := ioutil.WriteFile(filePath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			SocketOwnership: SocketOwnership{
+				Mode: 0660,
+			},
+		},
+	}
+
+	if err := pod.applySocketOwnership(); err != nil {
+		t.Fatal(err)
+	}
+
+	sockInfo, err := os.Stat(sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sockInfo.Mode().Perm() != 0660 {
+		t.Fatalf("Got socket mode %v\nExpecting 0660", sockInfo.Mode().Perm())
+	}
+
+	// Regular files are left alone.
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fileInfo.Mode().Perm() != 0600 {
+		t.Fatalf("Got file mode %v\nExpecting 0600", fileInfo.Mode().Perm())
+	}
+}
+
+func TestApplySocketOwnershipDefaultNoop(t *testing.T) {
+	pod := &Pod{
+		id:     "nonexistent-ownership-pod",
+		config: &PodConfig{},
+	}
+
+	// The pod directory does not exist, the default configuration
+	// must not even look at it.
+	if err := pod.applySocketOwnership(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPodDrainOrder(t *testing.T) {
+	pod := &Pod{
+		config: &PodConfig{
+			ContainerStopOrder: []string{"300", "100"},
+		},
+	}
+
+	for _, id := range []string{"100", "200", "300"} {
+		pod.containers = append(pod.containers, &Container{id: id, pod: pod})
+	}
+
+	var order []string
+	for _, c := range pod.drainOrder() {
+		order = append(order, c.id)
+	}
+
+	expected := []string{"200", "300", "100"}
+	if reflect.DeepEqual(order, expected) == false {
+		t.Fatalf("Got stop order %v\nExpecting %v", order, expected)
+	}
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// IO scheduling classes, as defined by the ioprio_set system call.
+const (
+	// ioClassNone keeps the kernel default IO scheduling.
+	ioClassNone = iota
+
+	// ioClassRealtime gets first access to the disk.
+	ioClassRealtime
+
+	// ioClassBestEffort is the default scheduling class.
+	ioClassBestEffort
+
+	// ioClassIdle only gets disk time when no other program needs it.
+	ioClassIdle
+)
+
+// ioprio_set "who" value selecting a single process.
+const ioprioWhoProcess = 1
+
+// Number of bits reserved for the IO priority level in the ioprio_set
+// value.
+const ioprioClassShift = 13
+
+// setProcessNice sets the nice value of the given process.
+func setProcessNice(pid, nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("Invalid nice value %d", nice)
+	}
+
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+// setProcessIOPriority sets the IO scheduling class and priority level
+// of the given process.
+func setProcessIOPriority(pid, class, level int) error {
+	if class < ioClassNone || class > ioClassIdle {
+		return fmt.Errorf("Invalid IO scheduling class %d", class)
+	}
+
+	if level < 0 || level > 7 {
+		return fmt.Errorf("Invalid IO priority level %d", level)
+	}
+
+	ioprio := class<<ioprioClassShift | level
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET,
+		uintptr(ioprioWhoProcess), uintptr(pid), uintptr(ioprio))
+	if errno != 0 {
+		return fmt.Errorf("Could not set IO priority of process %d: %v", pid, errno)
+	}
+
+	return nil
+}
+
+// setProcessOOMScoreAdj adjusts the host OOM killer score of the given
+// process.
+func setProcessOOMScoreAdj(pid, score int) error {
+	if score < -1000 || score > 1000 {
+		return fmt.Errorf("Invalid oom_score_adj value %d", score)
+	}
+
+	path := filepath.Join(procPath, strconv.Itoa(pid), "oom_score_adj")
+
+	return ioutil.WriteFile(path, []byte(strconv.Itoa(score)), 0600)
+}
+
+// applyProcessPriority applies the configured nice value, IO scheduling
+// class and OOM score adjustment to the hypervisor process.
+func applyProcessPriority(pid int, config HypervisorConfig) error {
+	if config.Nice != 0 {
+		if err := setProcessNice(pid, config.Nice); err != nil {
+			return err
+		}
+	}
+
+	if config.IOClass != ioClassNone {
+		if err := setProcessIOPriority(pid, config.IOClass, config.IOPriority); err != nil {
+			return err
+		}
+	}
+
+	if config.OOMScoreAdj != 0 {
+		if err := setProcessOOMScoreAdj(pid, config.OOMScoreAdj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetProcessNiceInvalid(t *testing.T) {
+	for _, nice := range []int{-21, 20} {
+		if err := setProcessNice(os.Getpid(), nice); err == nil {
+			t.Fatalf("Nice value %d should fail", nice)
+		}
+	}
+}
+
+func TestSetProcessIOPriorityInvalid(t *testing.T) {
+	if err := setProcessIOPriority(os.Getpid(), 4, 0); err == nil {
+		t.Fatal("IO scheduling class 4 should fail")
+	}
+
+	if err := setProcessIOPriority(os.Getpid(), ioClassBestEffort, 8); err == nil {
+		t.Fatal("IO priority level 8 should fail")
+	}
+}
+
+func TestSetProcessOOMScoreAdj(t *testing.T) {
+	proc, err := ioutil.TempDir(testDir, "proc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(proc)
+
+	savedProcPath := procPath
+	procPath = proc
+	defer func() {
+		procPath = savedProcPath
+	}()
+
+	pidPath := filepath.Join(proc, "1234")
+	if err := os.MkdirAll(pidPath, dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setProcessOOMScoreAdj(1234, -500); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(pidPath, "oom_score_adj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "-500" {
+		t.Fatalf("Got %q\nExpecting %q", string(content), "-500")
+	}
+
+	if err := setProcessOOMScoreAdj(1234, -1001); err == nil {
+		t.Fatal("oom_score_adj value -1001 should fail")
+	}
+}
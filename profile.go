@@ -0,0 +1,82 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/containers/virtcontainers/pkg/annotations"
+)
+
+// hypervisorProfiles holds the named hypervisor configurations
+// registered by the embedding runtime. A pod selects one through the
+// HypervisorProfile annotation.
+var hypervisorProfiles = struct {
+	sync.Mutex
+	profiles map[string]HypervisorConfig
+}{
+	profiles: make(map[string]HypervisorConfig),
+}
+
+// RegisterHypervisorProfile registers a named hypervisor configuration
+// profile. Registering an already existing profile name overwrites the
+// previous configuration.
+func RegisterHypervisorProfile(name string, config HypervisorConfig) error {
+	if name == "" {
+		return fmt.Errorf("Profile name cannot be empty")
+	}
+
+	hypervisorProfiles.Lock()
+	defer hypervisorProfiles.Unlock()
+
+	hypervisorProfiles.profiles[name] = config
+
+	return nil
+}
+
+// UnregisterHypervisorProfile removes a named hypervisor configuration
+// profile.
+func UnregisterHypervisorProfile(name string) {
+	hypervisorProfiles.Lock()
+	defer hypervisorProfiles.Unlock()
+
+	delete(hypervisorProfiles.profiles, name)
+}
+
+// applyHypervisorProfile replaces the pod hypervisor configuration
+// with the profile selected through annotations, if any. It runs
+// before the more specific tuning annotations are applied, so that
+// those can override individual profile values.
+func applyHypervisorProfile(podConfig *PodConfig) error {
+	name, ok := podConfig.Annotations[annotations.HypervisorProfile]
+	if !ok || name == "" {
+		return nil
+	}
+
+	hypervisorProfiles.Lock()
+	profile, ok := hypervisorProfiles.profiles[name]
+	hypervisorProfiles.Unlock()
+
+	if !ok {
+		return fmt.Errorf("Unknown hypervisor profile %s", name)
+	}
+
+	podConfig.HypervisorConfig = profile
+
+	return nil
+}
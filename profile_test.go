@@ -0,0 +1,68 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"testing"
+
+	"github.com/containers/virtcontainers/pkg/annotations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterHypervisorProfileEmptyName(t *testing.T) {
+	err := RegisterHypervisorProfile("", HypervisorConfig{})
+	assert.NotNil(t, err)
+}
+
+func TestApplyHypervisorProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	profile := newHypervisorConfig(nil, nil)
+	profile.DefaultVCPUs = 2
+	profile.DefaultMemSz = 4096
+
+	err := RegisterHypervisorProfile("large", profile)
+	assert.Nil(err)
+	defer UnregisterHypervisorProfile("large")
+
+	podConfig := PodConfig{
+		Annotations: map[string]string{
+			annotations.HypervisorProfile: "large",
+		},
+	}
+
+	err = applyHypervisorProfile(&podConfig)
+	assert.Nil(err)
+	assert.Equal(profile, podConfig.HypervisorConfig)
+
+	// More specific annotations override profile values.
+	podConfig.Annotations[annotations.DefaultVCPUs] = "4"
+	err = podConfig.HypervisorConfig.applyAnnotations(podConfig.Annotations)
+	assert.Nil(err)
+	assert.Equal(uint32(4), podConfig.HypervisorConfig.DefaultVCPUs)
+	assert.Equal(uint32(4096), podConfig.HypervisorConfig.DefaultMemSz)
+
+	// Selecting an unknown profile must fail.
+	podConfig.Annotations[annotations.HypervisorProfile] = "no-such-profile"
+	err = applyHypervisorProfile(&podConfig)
+	assert.NotNil(err)
+
+	// Without a profile annotation, the configuration is left alone.
+	delete(podConfig.Annotations, annotations.HypervisorProfile)
+	err = applyHypervisorProfile(&podConfig)
+	assert.Nil(err)
+}
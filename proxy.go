@@ -29,6 +29,10 @@ import (
 type ProxyConfig struct {
 	Path  string
 	Debug bool
+
+	// TLS secures the proxy control socket with mutual
+	// authentication when enabled.
+	TLS TLSConfig
 }
 
 // proxyParams is the structure providing specific parameters needed
@@ -0,0 +1,88 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// newConsolePTY opens a new pseudo terminal and returns the master
+// side along with the slave path.
+func newConsolePTY() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var ptyNr uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptyNr))); errno != 0 {
+		master.Close()
+		return nil, "", fmt.Errorf("Could not retrieve pty number: %s", errno)
+	}
+
+	var unlock uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		master.Close()
+		return nil, "", fmt.Errorf("Could not unlock pty: %s", errno)
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNr), nil
+}
+
+// sendConsoleFd passes the terminal master to the caller listening on
+// the provided unix socket, following the OCI console socket handoff
+// convention (SCM_RIGHTS).
+func sendConsoleFd(socketPath string, master *os.File) error {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("Console socket %s is not a unix socket", socketPath)
+	}
+
+	oob := syscall.UnixRights(int(master.Fd()))
+	if _, _, err := unixConn.WriteMsgUnix([]byte(master.Name()), oob, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setupConsoleSocket allocates a terminal for the container, hands the
+// master side to the caller over the console socket and returns the
+// slave path to wire to the container process.
+func setupConsoleSocket(socketPath string) (string, error) {
+	master, slavePath, err := newConsolePTY()
+	if err != nil {
+		return "", err
+	}
+	defer master.Close()
+
+	if err := sendConsoleFd(socketPath, master); err != nil {
+		return "", err
+	}
+
+	return slavePath, nil
+}
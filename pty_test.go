@@ -0,0 +1,109 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestNewConsolePTY(t *testing.T) {
+	master, slavePath, err := newConsolePTY()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	if !strings.HasPrefix(slavePath, "/dev/pts/") {
+		t.Fatalf("Got slave path %q\nExpecting a /dev/pts path", slavePath)
+	}
+
+	if _, err := os.Stat(slavePath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetupConsoleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "virtc-console-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "console.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- err
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 256)
+		oob := make([]byte, 256)
+
+		_, oobn, _, _, err := conn.(*net.UnixConn).ReadMsgUnix(buf, oob)
+		if err != nil {
+			received <- err
+			return
+		}
+
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			received <- err
+			return
+		}
+
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil {
+			received <- err
+			return
+		}
+
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+
+		received <- nil
+	}()
+
+	slavePath, err := setupConsoleSocket(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(slavePath, "/dev/pts/") {
+		t.Fatalf("Got slave path %q\nExpecting a /dev/pts path", slavePath)
+	}
+
+	if err := <-received; err != nil {
+		t.Fatal(err)
+	}
+}
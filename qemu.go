@@ -18,9 +18,13 @@ package virtcontainers
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,16 +35,21 @@ import (
 )
 
 type qmpChannel struct {
-	ctx  context.Context
-	path string
-	wg   sync.WaitGroup
-	qmp  *govmmQemu.QMP
+	ctx    context.Context
+	cancel context.CancelFunc
+	path   string
+	wg     sync.WaitGroup
+	qmp    *govmmQemu.QMP
 }
 
 // QemuState keeps Qemu's state
 type QemuState struct {
 	Bridges []Bridge
 	UUID    string
+
+	// HotpluggedVCPUs maps a container ID to the IDs of the vCPU
+	// devices hotplugged for it.
+	HotpluggedVCPUs map[string][]string
 }
 
 // qemu is an Hypervisor interface implementation for the Linux qemu hypervisor.
@@ -58,6 +67,8 @@ type qemu struct {
 
 	nestedRun bool
 
+	version qemuVersion
+
 	pod *Pod
 
 	state QemuState
@@ -84,27 +95,18 @@ const qmpCapErrMsg = "Failed to negoatiate QMP capabilities"
 
 const qmpSockPathSizeLimit = 107
 
-// Mapping between machine types and QEMU binary paths.
-var qemuPaths = map[string]string{
-	QemuPCLite: "/usr/bin/qemu-lite-system-x86_64",
-	QemuPC:     defaultQemuPath,
-	QemuQ35:    "/usr/bin/qemu-35-system-x86_64",
-}
+// defaultQMPTimeout is the default time an interaction with the
+// hypervisor monitor may take before the hypervisor is declared
+// unresponsive.
+const defaultQMPTimeout = 10 * time.Second
 
-var supportedQemuMachines = []govmmQemu.Machine{
-	{
-		Type:         QemuPCLite,
-		Acceleration: defaultQemuMachineAccelerators,
-	},
-	{
-		Type:         QemuPC,
-		Acceleration: defaultQemuMachineAccelerators,
-	},
-	{
-		Type:         QemuQ35,
-		Acceleration: defaultQemuMachineAccelerators,
-	},
-}
+// defaultQMPRetries is the default number of times a failed connection
+// to the hypervisor monitor gets attempted.
+const defaultQMPRetries = 3
+
+// qmpRetryInterval is the pause between two attempts to connect to the
+// hypervisor monitor.
+const qmpRetryInterval = 50 * time.Millisecond
 
 const (
 	defaultCores   uint32 = 1
@@ -117,8 +119,23 @@ const (
 
 const (
 	defaultConsole = "console.sock"
+
+	// debugConsoleSock is the host socket backing the hvc console
+	// reserved for an interactive debug shell in the guest.
+	debugConsoleSock = "console-debug.sock"
+
+	// kmsgConsoleSock is the host socket backing the hvc console the
+	// guest kernel messages are directed to.
+	kmsgConsoleSock = "console-kmsg.sock"
 )
 
+// guestMemoryDumpFile is the file name storing a guest crash dump.
+// It lives in the pod runtime directory.
+const guestMemoryDumpFile = "guest-memory.dump"
+
+// guestPanickedEvent is the QMP event emitted when the guest kernel panics.
+const guestPanickedEvent = "GUEST_PANICKED"
+
 const (
 	maxDevIDSize = 31
 )
@@ -170,8 +187,6 @@ var kernelDefaultParams = []Param{
 	{"root", "/dev/pmem0p1"},
 	{"rootflags", "dax,data=ordered,errors=remount-ro rw"},
 	{"rootfstype", "ext4"},
-	{"tsc", "reliable"},
-	{"no_timer_check", ""},
 	{"rcupdate.rcu_expedited", "1"},
 	{"i8042.direct", "1"},
 	{"i8042.dumbkbd", "1"},
@@ -214,6 +229,9 @@ func (q *qemu) buildKernelParams() error {
 	// get a list of default kernel parameters
 	params := q.defaultKernelParams()
 
+	// apply the guest OOM policy.
+	params = append(params, q.config.GuestOOM.kernelParams()...)
+
 	// add the params specified by the provided config. As the kernel
 	// honours the last parameter value set and since the config-provided
 	// params are added here, they will take priority over the defaults.
@@ -227,6 +245,22 @@ func (q *qemu) buildKernelParams() error {
 func (q *qemu) defaultKernelParams() []Param {
 	params := kernelDefaultParams
 
+	if q.config.KernelLogConsole {
+		// Drop the default consoles and direct the kernel messages
+		// to the dedicated kernel log console only, so they do not
+		// interleave with application output.
+		var filtered []Param
+		for _, p := range params {
+			if p.Key != "console" {
+				filtered = append(filtered, p)
+			}
+		}
+		params = append(filtered, Param{"console", fmt.Sprintf("hvc%d", q.kernelLogConsoleIndex())})
+	}
+
+	// The clock parameters come from the tuning configuration.
+	params = append(params, q.config.Tuning.clockKernelParams()...)
+
 	if q.config.Debug {
 		params = append(params, kernelDefaultParamsDebug...)
 	} else {
@@ -236,15 +270,112 @@ func (q *qemu) defaultKernelParams() []Param {
 	return params
 }
 
-// Adds all capabilities supported by qemu implementation of hypervisor interface
+// qemuVersion holds the major and minor version of the qemu binary.
+type qemuVersion struct {
+	major int
+	minor int
+}
+
+// Version gated capabilities. Device hotplug settled with qemu 2.6,
+// the CPU hotplug query commands only exist from 2.7 on.
+var (
+	qemuMinHotplugVersion    = qemuVersion{major: 2, minor: 6}
+	qemuMinCPUHotplugVersion = qemuVersion{major: 2, minor: 7}
+)
+
+// olderThan tells if the qemu binary is known to be older than the
+// given version. An unprobed version is not considered too old, so
+// that a missing binary does not mask the machine type capabilities.
+func (v qemuVersion) olderThan(min qemuVersion) bool {
+	if v.major == 0 && v.minor == 0 {
+		return false
+	}
+
+	return v.major < min.major || (v.major == min.major && v.minor < min.minor)
+}
+
+// parseQemuVersion extracts the version from the output of the qemu
+// binary, of the form "QEMU emulator version 2.11.1(...)".
+func parseQemuVersion(output string) (qemuVersion, error) {
+	for _, field := range strings.Fields(output) {
+		parts := strings.SplitN(field, ".", 3)
+		if len(parts) < 2 {
+			continue
+		}
+
+		major, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+
+		minor, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+
+		return qemuVersion{major: major, minor: minor}, nil
+	}
+
+	return qemuVersion{}, fmt.Errorf("Could not find a qemu version in %q", output)
+}
+
+// probeQemuVersion asks the qemu binary for its version.
+func probeQemuVersion(path string) (qemuVersion, error) {
+	output, err := exec.Command(path, "--version").CombinedOutput()
+	if err != nil {
+		return qemuVersion{}, fmt.Errorf("Could not probe the qemu version: %v", err)
+	}
+
+	return parseQemuVersion(string(output))
+}
+
+// hasSCSIController tells if the VM carries a virtio-scsi controller,
+// which accepts hotplugged disks on its own bus, with no PCI slot or
+// bridge involved.
+func (q *qemu) hasSCSIController() bool {
+	for _, dev := range q.qemuConfig.Devices {
+		if _, ok := dev.(govmmQemu.SCSIController); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// capabilities returns the hotplug matrix of the configured VM,
+// crossing the machine type with the qemu version, the available PCI
+// bridges and the attached controllers.
 func (q *qemu) capabilities() capabilities {
 	var caps capabilities
 
-	// Only pc machine type supports hotplugging drives
-	if q.qemuConfig.Machine.Type == QemuPC {
+	// On pc the root bus itself accepts hotplugged devices, q35
+	// needs a PCI bridge to plug them onto. pc-lite has no
+	// hotpluggable bus at all.
+	pciHotplug := false
+	switch q.qemuConfig.Machine.Type {
+	case QemuPC:
+		pciHotplug = true
+	case QemuQ35:
+		pciHotplug = len(q.state.Bridges) > 0
+	}
+
+	if (pciHotplug || q.hasSCSIController()) && !q.version.olderThan(qemuMinHotplugVersion) {
 		caps.setBlockDeviceHotplugSupport()
 	}
 
+	if pciHotplug && !q.version.olderThan(qemuMinHotplugVersion) {
+		caps.setNetDeviceHotplugSupport()
+	}
+
+	if pciHotplug && !q.version.olderThan(qemuMinCPUHotplugVersion) {
+		caps.setCPUHotplugSupport()
+	}
+
+	// Memory hotplug needs spare memory slots declared at boot.
+	if q.qemuConfig.Memory.Slots > 0 && q.qemuConfig.Memory.MaxMem != "" {
+		caps.setMemoryHotplugSupport()
+	}
+
 	return caps
 }
 
@@ -318,9 +449,35 @@ func (q *qemu) appendVhostUserDevice(devices []govmmQemu.Device, vhostUserDevice
 
 	return devices
 }
-func (q *qemu) appendVFIODevice(devices []govmmQemu.Device, vfDevice VFIODevice) []govmmQemu.Device {
+func (q *qemu) appendVFIODevice(devices []govmmQemu.Device, vfDevice VFIODevice) ([]govmmQemu.Device, error) {
 	if vfDevice.BDF == "" {
-		return devices
+		return devices, nil
+	}
+
+	// The functions of a multi-function device are kept together on
+	// one guest slot, mirroring their host function numbers, as
+	// several GPU drivers expect the companion functions (e.g. audio)
+	// next to the main one.
+	if len(vfDevice.Functions) > 0 {
+		addr, bus, err := q.addDeviceToBridge(vfDevice.Functions[0])
+		if err != nil {
+			return nil, err
+		}
+
+		for i, bdf := range vfDevice.Functions {
+			function := bdf[strings.LastIndex(bdf, ".")+1:]
+
+			devices = append(devices,
+				govmmQemu.VFIODevice{
+					BDF:           bdf,
+					Bus:           bus,
+					Addr:          fmt.Sprintf("%s.%s", addr, function),
+					Multifunction: i == 0,
+				},
+			)
+		}
+
+		return devices, nil
 	}
 
 	devices = append(devices,
@@ -329,7 +486,7 @@ func (q *qemu) appendVFIODevice(devices []govmmQemu.Device, vfDevice VFIODevice)
 		},
 	)
 
-	return devices
+	return devices, nil
 }
 
 func (q *qemu) appendSocket(devices []govmmQemu.Device, socket Socket) []govmmQemu.Device {
@@ -396,8 +553,13 @@ func (q *qemu) appendNetwork(devices []govmmQemu.Device, endpoint Endpoint) []go
 }
 
 func (q *qemu) appendFSDevices(devices []govmmQemu.Device, podConfig PodConfig) []govmmQemu.Device {
-	// Add the shared volumes
+	// Add the shared volumes. NFS backed volumes are served over
+	// vsock, they do not need a 9p device.
 	for _, v := range podConfig.Volumes {
+		if v.Backend == VolumeNFS {
+			continue
+		}
+
 		devices = q.appendVolume(devices, v)
 	}
 
@@ -431,6 +593,17 @@ func (q *qemu) appendBridges(devices []govmmQemu.Device, podConfig PodConfig) ([
 	return devices, nil
 }
 
+// kernelLogConsoleIndex returns the hvc index of the kernel log
+// console, which comes after the default console and, when attached,
+// the debug console.
+func (q *qemu) kernelLogConsoleIndex() int {
+	if q.config.DebugConsole {
+		return 2
+	}
+
+	return 1
+}
+
 func (q *qemu) appendConsoles(devices []govmmQemu.Device, podConfig PodConfig) []govmmQemu.Device {
 	serial := govmmQemu.SerialDevice{
 		Driver:        govmmQemu.VirtioSerial,
@@ -440,6 +613,9 @@ func (q *qemu) appendConsoles(devices []govmmQemu.Device, podConfig PodConfig) [
 
 	devices = append(devices, serial)
 
+	// Each console is a separate virtio-serial port backed by its own
+	// host socket, so the streams never interleave. The ports show up
+	// in the guest as hvc0, hvc1... in the order they are appended.
 	var console govmmQemu.CharDevice
 
 	console = govmmQemu.CharDevice{
@@ -452,9 +628,44 @@ func (q *qemu) appendConsoles(devices []govmmQemu.Device, podConfig PodConfig) [
 
 	devices = append(devices, console)
 
+	if q.config.DebugConsole {
+		devices = append(devices, govmmQemu.CharDevice{
+			Driver:   govmmQemu.Console,
+			Backend:  govmmQemu.Socket,
+			DeviceID: "debugconsole0",
+			ID:       "chardebugconsole0",
+			Path:     q.getPodDebugConsole(podConfig.ID),
+		})
+	}
+
+	if q.config.KernelLogConsole {
+		devices = append(devices, govmmQemu.CharDevice{
+			Driver:   govmmQemu.Console,
+			Backend:  govmmQemu.Socket,
+			DeviceID: "kmsgconsole0",
+			ID:       "charkmsgconsole0",
+			Path:     q.getPodKernelLogConsole(podConfig.ID),
+		})
+	}
+
 	return devices
 }
 
+func (q *qemu) appendRNGDevice(devices []govmmQemu.Device) []govmmQemu.Device {
+	if q.config.Tuning.RNGBytesPerPeriod == 0 {
+		return devices
+	}
+
+	rng := govmmQemu.RngDevice{
+		ID:       "rng0",
+		Filename: "/dev/urandom",
+		MaxBytes: q.config.Tuning.RNGBytesPerPeriod,
+		Period:   q.config.Tuning.rngPeriodMs(),
+	}
+
+	return append(devices, rng)
+}
+
 func (q *qemu) appendImage(devices []govmmQemu.Device, podConfig PodConfig) ([]govmmQemu.Device, error) {
 	imagePath, err := q.config.ImageAssetPath()
 	if err != nil {
@@ -479,6 +690,9 @@ func (q *qemu) appendImage(devices []govmmQemu.Device, podConfig PodConfig) ([]g
 		ID:       "mem0",
 		MemPath:  imagePath,
 		Size:     (uint64)(imageStat.Size()),
+		// A shared image is mapped read-only so that several pods
+		// can be backed by the same image file.
+		ReadOnly: q.config.SharedImage,
 	}
 
 	devices = append(devices, object)
@@ -486,14 +700,51 @@ func (q *qemu) appendImage(devices []govmmQemu.Device, podConfig PodConfig) ([]g
 	return devices, nil
 }
 
-func (q *qemu) getMachine(name string) (govmmQemu.Machine, error) {
-	for _, m := range supportedQemuMachines {
-		if m.Type == name {
-			return m, nil
-		}
+// appendFwCfgBlobs attaches the configured named blobs to the guest
+// firmware configuration device.
+func (q *qemu) appendFwCfgBlobs(devices []govmmQemu.Device) []govmmQemu.Device {
+	for _, blob := range q.config.FwCfgBlobs {
+		devices = append(devices, govmmQemu.FwCfg{
+			Name: blob.Name,
+			File: blob.Path,
+		})
 	}
 
-	return govmmQemu.Machine{}, fmt.Errorf("unrecognised machine type: %v", name)
+	return devices
+}
+
+// appendGuestMetadata writes the pod metadata image and attaches it to
+// the VM as a read-only NVDIMM, next to the one backing the pod image.
+// A nil GuestMetadata map disables the metadata channel.
+func (q *qemu) appendGuestMetadata(devices []govmmQemu.Device, podConfig PodConfig) ([]govmmQemu.Device, error) {
+	if podConfig.GuestMetadata == nil {
+		return devices, nil
+	}
+
+	metadataPath := filepath.Join(runStoragePath, podConfig.ID, guestMetadataFile)
+
+	size, err := writeGuestMetadataImage(metadataPath, buildGuestMetadata(podConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	object := govmmQemu.Object{
+		Driver:   govmmQemu.NVDIMM,
+		Type:     govmmQemu.MemoryBackendFile,
+		DeviceID: "nvmeta0",
+		ID:       "memmeta0",
+		MemPath:  metadataPath,
+		Size:     (uint64)(size),
+		ReadOnly: true,
+	}
+
+	devices = append(devices, object)
+
+	return devices, nil
+}
+
+func (q *qemu) getMachine(name string) (govmmQemu.Machine, error) {
+	return qemuMachines.resolve(name)
 }
 
 // Build the QEMU binary path
@@ -511,10 +762,10 @@ func (q *qemu) buildPath() error {
 	// We do not have a configured path, let's try to map one from the machine type
 	machineType := q.config.HypervisorMachineType
 	if machineType == "" {
-		machineType = defaultQemuMachineType
+		machineType = defaultMachineType()
 	}
 
-	p, ok := qemuPaths[machineType]
+	p, ok := qemuMachines.binaryPath(machineType)
 	if !ok {
 		q.Logger().WithField("machine-type", machineType).Warn("Unknown machine type")
 		p = defaultQemuPath
@@ -555,18 +806,28 @@ func (q *qemu) init(pod *Pod) error {
 		return err
 	}
 
-	if err := q.buildKernelParams(); err != nil {
-		return err
+	// The version feeds the capabilities matrix. Not being able to
+	// probe it is not fatal, it only keeps the version gated
+	// capabilities off.
+	if version, err := probeQemuVersion(q.path); err != nil {
+		q.Logger().WithError(err).Debug("Could not probe the qemu version")
+	} else {
+		q.version = version
 	}
 
-	nested, err := RunningOnVMM(procCPUInfo)
-	if err != nil {
+	if err := q.buildKernelParams(); err != nil {
 		return err
 	}
 
+	// Detect automatically whether the host is itself a VM, so the
+	// nested workarounds (DisableModern and friends) apply without
+	// the caller having to know. DisableNestingChecks overrides the
+	// detection.
+	nested := runningOnVMM()
+
 	if q.config.DisableNestingChecks {
 		//Intentionally ignore the nesting check
-		q.Logger().WithField("inside-vm", fmt.Sprintf("%t", nested)).Debug("Disable nesting environment checksx")
+		q.Logger().WithField("inside-vm", fmt.Sprintf("%t", nested)).Debug("Disable nesting environment checks")
 		q.nestedRun = false
 	} else {
 		q.nestedRun = nested
@@ -579,6 +840,8 @@ func (q *qemu) setCPUResources(podConfig PodConfig) govmmQemu.SMP {
 	vcpus := q.config.DefaultVCPUs
 	if podConfig.VMConfig.VCPUs > 0 {
 		vcpus = uint32(podConfig.VMConfig.VCPUs)
+	} else if sizing := podConfig.vmSizing(); sizing.VCPUs > 0 {
+		vcpus = uint32(sizing.VCPUs)
 	}
 
 	// Network IO shows better performance with 1 CPU 1 Socket
@@ -593,7 +856,7 @@ func (q *qemu) setCPUResources(podConfig PodConfig) govmmQemu.SMP {
 }
 
 func (q *qemu) setMemoryResources(podConfig PodConfig) (govmmQemu.Memory, error) {
-	hostMemKb, err := getHostMemorySizeKb(procMemInfo)
+	hostMemKb, err := hostMemory.get()
 	if err != nil {
 		return govmmQemu.Memory{}, fmt.Errorf("Unable to read memory info: %s", err)
 	}
@@ -601,16 +864,36 @@ func (q *qemu) setMemoryResources(podConfig PodConfig) (govmmQemu.Memory, error)
 		return govmmQemu.Memory{}, fmt.Errorf("Error host memory size 0")
 	}
 
-	// add 1G memory space for nvdimm device (vm guest image)
-	memMax := fmt.Sprintf("%dM", int(float64(hostMemKb)/1024)+maxMemoryOffset)
+	// The memory space for devices backed by guest memory, such as
+	// the nvdimm device holding the guest image, comes on top of the
+	// maximum memory.
+	memOffset := int(q.config.MemOffsetMiB)
+	if memOffset == 0 {
+		memOffset = maxMemoryOffset
+	}
+
+	maxMemMb := int(q.config.MemMaxMiB)
+	if maxMemMb == 0 {
+		maxMemMb = int(float64(hostMemKb) / 1024)
+	}
+
+	memMax := fmt.Sprintf("%dM", maxMemMb+memOffset)
+
+	slots := q.config.MemSlots
+	if slots == 0 {
+		slots = defaultMemSlots
+	}
+
 	mem := fmt.Sprintf("%dM", q.config.DefaultMemSz)
 	if podConfig.VMConfig.Memory > 0 {
 		mem = fmt.Sprintf("%dM", podConfig.VMConfig.Memory)
+	} else if sizing := podConfig.vmSizing(); sizing.Memory > 0 {
+		mem = fmt.Sprintf("%dM", sizing.Memory)
 	}
 
 	memory := govmmQemu.Memory{
 		Size:   mem,
-		Slots:  defaultMemSlots,
+		Slots:  slots,
 		MaxMem: memMax,
 	}
 
@@ -640,7 +923,7 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 
 	machineType := q.config.HypervisorMachineType
 	if machineType == "" {
-		machineType = defaultQemuMachineType
+		machineType = defaultMachineType()
 	}
 
 	machine, err := q.getMachine(machineType)
@@ -648,6 +931,12 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 		return err
 	}
 
+	// Make sure the machine type is not only registered but actually
+	// provided by the hypervisor binary.
+	if err := validateMachineType(q.path, machine.Type); err != nil {
+		return err
+	}
+
 	accelerators := podConfig.HypervisorConfig.MachineAccelerators
 	if accelerators != "" {
 		if !strings.HasPrefix(accelerators, ",") {
@@ -656,6 +945,15 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 		machine.Acceleration += accelerators
 	}
 
+	// KSM can only merge pages the hypervisor marked mergeable,
+	// which is a machine level knob.
+	if podConfig.HypervisorConfig.MemMergeable {
+		if machine.Options != "" {
+			machine.Options += ","
+		}
+		machine.Options += "mem-merge=on"
+	}
+
 	smp := q.setCPUResources(podConfig)
 
 	memory, err := q.setMemoryResources(podConfig)
@@ -672,6 +970,7 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 		HugePages:    q.config.HugePages,
 		Realtime:     q.config.Realtime,
 		Mlock:        q.config.Mlock,
+		NoHPET:       !q.config.Tuning.HPET,
 	}
 
 	kernelPath, err := q.config.KernelAssetPath()
@@ -679,9 +978,15 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 		return err
 	}
 
+	initrdPath, err := q.config.InitrdAssetPath()
+	if err != nil {
+		return err
+	}
+
 	kernel := govmmQemu.Kernel{
-		Path:   kernelPath,
-		Params: strings.Join(q.kernelParams, " "),
+		Path:       kernelPath,
+		InitrdPath: initrdPath,
+		Params:     strings.Join(q.kernelParams, " "),
 	}
 
 	rtc := govmmQemu.RTC{
@@ -730,11 +1035,24 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 
 	devices = q.appendFSDevices(devices, podConfig)
 	devices = q.appendConsoles(devices, podConfig)
+	devices = q.appendRNGDevice(devices)
 	devices, err = q.appendImage(devices, podConfig)
 	if err != nil {
 		return err
 	}
 
+	devices = q.appendFwCfgBlobs(devices)
+
+	devices, err = q.appendGuestMetadata(devices, podConfig)
+	if err != nil {
+		return err
+	}
+
+	devices, err = q.appendMDevDevices(devices, podConfig)
+	if err != nil {
+		return err
+	}
+
 	devices, err = q.appendBridges(devices, podConfig)
 	if err != nil {
 		return err
@@ -774,6 +1092,56 @@ func (q *qemu) createPod(podConfig PodConfig) error {
 	return nil
 }
 
+// appendMDevDevices passes the mediated device instances created for
+// the pod (e.g. vGPU slices) through to the guest.
+func (q *qemu) appendMDevDevices(devices []govmmQemu.Device, podConfig PodConfig) ([]govmmQemu.Device, error) {
+	if len(podConfig.MDevDevices) == 0 {
+		return devices, nil
+	}
+
+	instances, err := loadMDevInstances(podConfig.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		devices = append(devices,
+			govmmQemu.VFIODevice{
+				SysfsDev: filepath.Join(mdevDevicesPath, instance.UUID),
+			},
+		)
+	}
+
+	return devices, nil
+}
+
+// runBootPolicies hands the final qemu configuration to the registered
+// boot policy hooks and folds accepted kernel command line mutations
+// back in before launch.
+func (q *qemu) runBootPolicies() error {
+	var devices []string
+	for _, d := range q.qemuConfig.Devices {
+		devices = append(devices, strings.Join(d.QemuParams(&q.qemuConfig), " "))
+	}
+
+	params := &BootParams{
+		PodID:          q.pod.id,
+		HypervisorPath: q.qemuConfig.Path,
+		MachineType:    q.qemuConfig.Machine.Type,
+		KernelPath:     q.qemuConfig.Kernel.Path,
+		KernelParams:   q.qemuConfig.Kernel.Params,
+		Devices:        devices,
+	}
+
+	if err := applyBootPolicies(params); err != nil {
+		return err
+	}
+
+	q.qemuConfig.Kernel.Params = params.KernelParams
+
+	return nil
+}
+
 // startPod will start the Pod's VM.
 func (q *qemu) startPod() error {
 	if q.config.Debug {
@@ -788,18 +1156,49 @@ func (q *qemu) startPod() error {
 		q.Logger().WithField("default-kernel-parameters", formatted).Debug()
 	}
 
+	if err := q.runBootPolicies(); err != nil {
+		return err
+	}
+
+	if label := q.config.ProcessLabel; label != "" {
+		// The exec label is a per thread attribute inherited across
+		// fork, pin the launch to the labelled thread.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if err := setExecSecurityLabel(label); err != nil {
+			return err
+		}
+		defer clearExecSecurityLabel()
+	}
+
 	strErr, err := govmmQemu.LaunchQemu(q.qemuConfig, newQMPLogger())
 	if err != nil {
 		return fmt.Errorf("%s", strErr)
 	}
 
+	if q.config.Nice != 0 || q.config.IOClass != ioClassNone || q.config.OOMScoreAdj != 0 {
+		pid, err := findHypervisorPid(q.state.UUID)
+		if err != nil {
+			return err
+		}
+
+		if err := applyProcessPriority(pid, q.config); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // waitPod will wait for the Pod's VM to be up and running.
 func (q *qemu) waitPod(timeout int) error {
+	// The QMP connection is kept open when it is needed for watching
+	// guest events.
+	keepConn := false
+
 	defer func(qemu *qemu) {
-		if q.qmpMonitorCh.qmp != nil {
+		if q.qmpMonitorCh.qmp != nil && !keepConn {
 			q.qmpMonitorCh.qmp.Shutdown()
 		}
 	}(q)
@@ -810,13 +1209,20 @@ func (q *qemu) waitPod(timeout int) error {
 
 	cfg := govmmQemu.QMPConfig{Logger: newQMPLogger()}
 
+	var eventCh chan govmmQemu.QMPEvent
+	if q.config.GuestMemoryDump {
+		eventCh = make(chan govmmQemu.QMPEvent)
+		cfg.EventCh = eventCh
+	}
+
 	var qmp *govmmQemu.QMP
 	var ver *govmmQemu.QMPVersion
 	var err error
+	var disconnectCh chan struct{}
 
 	timeStart := time.Now()
 	for {
-		disconnectCh := make(chan struct{})
+		disconnectCh = make(chan struct{})
 		qmp, ver, err = govmmQemu.QMPStart(q.qmpMonitorCh.ctx, q.qmpMonitorCh.path, cfg, disconnectCh)
 		if err == nil {
 			break
@@ -843,90 +1249,205 @@ func (q *qemu) waitPod(timeout int) error {
 		return err
 	}
 
+	if q.config.GuestMemoryDump {
+		go q.watchGuestEvents(qmp, eventCh, disconnectCh)
+		keepConn = true
+	}
+
 	return nil
 }
 
-// stopPod will stop the Pod's VM.
-func (q *qemu) stopPod() error {
-	cfg := govmmQemu.QMPConfig{Logger: newQMPLogger()}
-	disconnectCh := make(chan struct{})
+// watchGuestEvents listens for QMP events coming from the VM and
+// triggers a guest memory dump when the guest kernel panics. It holds
+// its own monitor connection so that it does not get torn down by
+// other monitor interactions.
+func (q *qemu) watchGuestEvents(qmp *govmmQemu.QMP, eventCh chan govmmQemu.QMPEvent, disconnectCh chan struct{}) {
+	for {
+		select {
+		case e := <-eventCh:
+			if e.Name != guestPanickedEvent {
+				continue
+			}
+
+			q.Logger().Warn("Guest panicked, dumping guest memory")
+
+			if err := q.dumpGuestMemory(qmp); err != nil {
+				q.Logger().WithError(err).Error("Could not dump guest memory")
+			}
+		case <-disconnectCh:
+			return
+		}
+	}
+}
 
-	q.Logger().Info("Stopping Pod")
-	qmp, _, err := govmmQemu.QMPStart(q.qmpControlCh.ctx, q.qmpControlCh.path, cfg, disconnectCh)
-	if err != nil {
-		q.Logger().WithError(err).Error("Failed to connect to QEMU instance")
-		return err
+// guestMemoryDumpAllowed tells if a guest memory dump can be captured,
+// comparing the guest memory size with the configured cap.
+func (q *qemu) guestMemoryDumpAllowed() bool {
+	if q.config.GuestMemoryDumpSizeMiB == 0 {
+		return true
 	}
 
-	err = qmp.ExecuteQMPCapabilities(q.qmpMonitorCh.ctx)
-	if err != nil {
-		q.Logger().WithError(err).Error(qmpCapErrMsg)
-		return err
+	memMiB := q.config.DefaultMemSz
+	if q.pod != nil && q.pod.config.VMConfig.Memory > 0 {
+		memMiB = uint32(q.pod.config.VMConfig.Memory)
 	}
 
-	return qmp.ExecuteQuit(q.qmpMonitorCh.ctx)
+	return memMiB <= q.config.GuestMemoryDumpSizeMiB
 }
 
-func (q *qemu) togglePausePod(pause bool) error {
-	defer func(qemu *qemu) {
-		if q.qmpMonitorCh.qmp != nil {
-			q.qmpMonitorCh.qmp.Shutdown()
-		}
-	}(q)
+// dumpGuestMemory captures a guest crash dump into the pod runtime
+// directory and records its location in the pod state.
+func (q *qemu) dumpGuestMemory(qmp *govmmQemu.QMP) error {
+	if !q.guestMemoryDumpAllowed() {
+		return fmt.Errorf("Guest memory size exceeds the %dMiB dump cap",
+			q.config.GuestMemoryDumpSizeMiB)
+	}
 
-	cfg := govmmQemu.QMPConfig{Logger: newQMPLogger()}
+	dumpPath := filepath.Join(runStoragePath, q.pod.id, guestMemoryDumpFile)
 
-	// Auto-closed by QMPStart().
-	disconnectCh := make(chan struct{})
+	err := qmp.ExecuteDumpGuestMemory(context.Background(),
+		fmt.Sprintf("file:%s", dumpPath), false, "elf")
+	if err != nil {
+		return err
+	}
+
+	q.pod.state.GuestMemoryDumpPath = dumpPath
 
-	qmp, _, err := govmmQemu.QMPStart(q.qmpControlCh.ctx, q.qmpControlCh.path, cfg, disconnectCh)
+	return q.pod.storage.storePodResource(q.pod.id, stateFileType, q.pod.state)
+}
+
+// stopPod will stop the Pod's VM.
+func (q *qemu) stopPod() error {
+	q.Logger().Info("Stopping Pod")
+
+	qmp, err := q.qmpSetup()
 	if err != nil {
-		q.Logger().WithError(err).Error("Failed to connect to QEMU instance")
 		return err
 	}
+	defer q.qmpTeardown()
 
-	q.qmpMonitorCh.qmp = qmp
+	return q.qmpResult(qmp.ExecuteQuit(q.qmpMonitorCh.ctx))
+}
 
-	err = qmp.ExecuteQMPCapabilities(q.qmpMonitorCh.ctx)
+func (q *qemu) togglePausePod(pause bool) error {
+	qmp, err := q.qmpSetup()
 	if err != nil {
-		q.Logger().WithError(err).Error(qmpCapErrMsg)
 		return err
 	}
+	defer q.qmpTeardown()
 
 	if pause {
-		err = q.qmpMonitorCh.qmp.ExecuteStop(q.qmpMonitorCh.ctx)
+		err = qmp.ExecuteStop(q.qmpMonitorCh.ctx)
 	} else {
-		err = q.qmpMonitorCh.qmp.ExecuteCont(q.qmpMonitorCh.ctx)
+		err = qmp.ExecuteCont(q.qmpMonitorCh.ctx)
 	}
 
-	if err != nil {
-		return err
+	return q.qmpResult(err)
+}
+
+// qmpTimeout returns how long an interaction with the hypervisor
+// monitor may take.
+func (q *qemu) qmpTimeout() time.Duration {
+	if q.config.QMPTimeoutSecs != 0 {
+		return time.Duration(q.config.QMPTimeoutSecs) * time.Second
 	}
 
-	return nil
+	return defaultQMPTimeout
+}
+
+// qmpRetries returns how many times a failed connection to the
+// hypervisor monitor gets attempted.
+func (q *qemu) qmpRetries() uint32 {
+	if q.config.QMPRetries != 0 {
+		return q.config.QMPRetries
+	}
+
+	return defaultQMPRetries
 }
 
+// qmpResult maps a monitor interaction that failed on an expired
+// deadline to ErrHypervisorUnresponsive.
+func (q *qemu) qmpResult(err error) error {
+	if err != nil && q.qmpMonitorCh.ctx != nil && q.qmpMonitorCh.ctx.Err() == context.DeadlineExceeded {
+		return ErrHypervisorUnresponsive
+	}
+
+	return err
+}
+
+// qmpSetup connects to the hypervisor monitor, retrying a bounded
+// number of times. The connection and every command sent over it
+// share a single deadline, so a wedged monitor socket surfaces
+// ErrHypervisorUnresponsive instead of hanging the caller forever.
+// qmpTeardown must be called once the interaction is over.
 func (q *qemu) qmpSetup() (*govmmQemu.QMP, error) {
 	cfg := govmmQemu.QMPConfig{Logger: newQMPLogger()}
 
-	// Auto-closed by QMPStart().
-	disconnectCh := make(chan struct{})
+	q.qmpMonitorCh.ctx, q.qmpMonitorCh.cancel = context.WithTimeout(context.Background(), q.qmpTimeout())
+
+	var qmp *govmmQemu.QMP
+	var err error
+
+	for attempt := uint32(0); attempt < q.qmpRetries(); attempt++ {
+		// Auto-closed by QMPStart().
+		disconnectCh := make(chan struct{})
+
+		qmp, _, err = govmmQemu.QMPStart(q.qmpMonitorCh.ctx, q.qmpControlCh.path, cfg, disconnectCh)
+		if err == nil {
+			break
+		}
+
+		if q.qmpMonitorCh.ctx.Err() != nil {
+			break
+		}
+
+		time.Sleep(qmpRetryInterval)
+	}
 
-	qmp, _, err := govmmQemu.QMPStart(q.qmpControlCh.ctx, q.qmpControlCh.path, cfg, disconnectCh)
 	if err != nil {
 		q.Logger().WithError(err).Error("Failed to connect to QEMU instance")
+		err = q.qmpResult(err)
+		q.qmpTeardown()
 		return nil, err
 	}
 
-	err = qmp.ExecuteQMPCapabilities(q.qmpMonitorCh.ctx)
-	if err != nil {
+	q.qmpMonitorCh.qmp = qmp
+
+	if err := qmp.ExecuteQMPCapabilities(q.qmpMonitorCh.ctx); err != nil {
 		q.Logger().WithError(err).Error(qmpCapErrMsg)
+		err = q.qmpResult(err)
+		q.qmpTeardown()
 		return nil, err
 	}
 
 	return qmp, nil
 }
 
+// reconnectMonitor checks the monitor socket of an already running VM
+// still answers, so a restarted management process can adopt the pod.
+func (q *qemu) reconnectMonitor() error {
+	if _, err := q.qmpSetup(); err != nil {
+		return err
+	}
+	q.qmpTeardown()
+
+	return nil
+}
+
+// qmpTeardown closes the connection opened by qmpSetup and releases
+// its deadline.
+func (q *qemu) qmpTeardown() {
+	if q.qmpMonitorCh.qmp != nil {
+		q.qmpMonitorCh.qmp.Shutdown()
+		q.qmpMonitorCh.qmp = nil
+	}
+
+	if q.qmpMonitorCh.cancel != nil {
+		q.qmpMonitorCh.cancel()
+		q.qmpMonitorCh.cancel = nil
+	}
+}
+
 func (q *qemu) addDeviceToBridge(ID string) (string, string, error) {
 	var err error
 	var addr uint32
@@ -939,7 +1460,55 @@ func (q *qemu) addDeviceToBridge(ID string) (string, string, error) {
 		}
 	}
 
-	return "", "", err
+	// All bridges are full, hotplug an additional one. The updated
+	// bridge list is persisted with the rest of the hypervisor state
+	// once the device hotplug completes.
+	bridge, err := q.hotplugBridge()
+	if err != nil {
+		return "", "", fmt.Errorf("Could not hot plug device %s: %v", ID, err)
+	}
+
+	addr, err = bridge.addDevice(ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return fmt.Sprintf("0x%x", addr), bridge.ID, nil
+}
+
+// hotplugBridge adds a PCI bridge to the running VM, growing the
+// space available for device hotplug. It fails once the maximum
+// number of bridges is reached.
+func (q *qemu) hotplugBridge() (*Bridge, error) {
+	if uint32(len(q.state.Bridges)) >= maxDefaultBridges {
+		return nil, fmt.Errorf("PCI bus exhausted: the maximum of %d bridges is reached", maxDefaultBridges)
+	}
+
+	if q.qmpMonitorCh.qmp == nil {
+		return nil, fmt.Errorf("QMP connection not established, cannot hot plug a bridge")
+	}
+
+	bridge := Bridge{
+		Type:    pciBridge,
+		ID:      fmt.Sprintf("%s-bridge-%d", pciBridge, len(q.state.Bridges)),
+		Address: make(map[uint32]string),
+	}
+
+	args := map[string]interface{}{
+		"driver": "pci-bridge",
+		"id":     bridge.ID,
+		// Each bridge is required to be assigned a unique chassis id > 0
+		"chassis_nr": len(q.state.Bridges) + 1,
+		"shpc":       true,
+	}
+
+	if _, err := q.qmpMonitorCh.qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "device_add", args); err != nil {
+		return nil, err
+	}
+
+	q.state.Bridges = append(q.state.Bridges, bridge)
+
+	return &q.state.Bridges[len(q.state.Bridges)-1], nil
 }
 
 func (q *qemu) removeDeviceFromBridge(ID string) error {
@@ -956,18 +1525,10 @@ func (q *qemu) removeDeviceFromBridge(ID string) error {
 }
 
 func (q *qemu) hotplugBlockDevice(drive Drive, op operation) error {
-	defer func(qemu *qemu) {
-		if q.qmpMonitorCh.qmp != nil {
-			q.qmpMonitorCh.qmp.Shutdown()
-		}
-	}(q)
-
-	qmp, err := q.qmpSetup()
-	if err != nil {
+	if _, err := q.qmpSetup(); err != nil {
 		return err
 	}
-
-	q.qmpMonitorCh.qmp = qmp
+	defer q.qmpTeardown()
 
 	devID := "virtio-" + drive.ID
 
@@ -987,6 +1548,20 @@ func (q *qemu) hotplugBlockDevice(drive Drive, op operation) error {
 			return err
 		}
 
+		// Track the blocks the guest writes so that incremental
+		// backups only need to copy what changed.
+		if q.config.TrackDirtyBlocks {
+			args := map[string]interface{}{
+				"node":       drive.ID,
+				"name":       dirtyBitmapName(drive.ID),
+				"persistent": true,
+			}
+
+			if _, err := q.qmpMonitorCh.qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "block-dirty-bitmap-add", args); err != nil {
+				return err
+			}
+		}
+
 	} else {
 		if err := q.removeDeviceFromBridge(drive.ID); err != nil {
 			return err
@@ -1004,13 +1579,115 @@ func (q *qemu) hotplugBlockDevice(drive Drive, op operation) error {
 	return nil
 }
 
+func (q *qemu) hotplugCPUDevice(dev CPUDevice, op operation) error {
+	if _, err := q.qmpSetup(); err != nil {
+		return err
+	}
+	defer q.qmpTeardown()
+
+	if op == removeDevice {
+		for _, cpuID := range q.state.HotpluggedVCPUs[dev.ID] {
+			if err := q.qmpMonitorCh.qmp.ExecuteDeviceDel(q.qmpMonitorCh.ctx, cpuID); err != nil {
+				return err
+			}
+		}
+
+		delete(q.state.HotpluggedVCPUs, dev.ID)
+
+		return nil
+	}
+
+	hotpluggable, err := q.qmpMonitorCh.qmp.ExecuteQueryHotpluggableCPUs(q.qmpMonitorCh.ctx)
+	if err != nil {
+		return err
+	}
+
+	var cpuIDs []string
+	for _, hc := range hotpluggable {
+		if uint32(len(cpuIDs)) == dev.Count {
+			break
+		}
+
+		// CPUs with a QOM path are already plugged in.
+		if hc.QOMPath != "" {
+			continue
+		}
+
+		cpuID := fmt.Sprintf("cpu-%s-%d", dev.ID, len(cpuIDs))
+		socketID := fmt.Sprintf("%d", hc.Properties.Socket)
+		coreID := fmt.Sprintf("%d", hc.Properties.Core)
+		threadID := fmt.Sprintf("%d", hc.Properties.Thread)
+
+		if err := q.qmpMonitorCh.qmp.ExecuteCPUDeviceAdd(q.qmpMonitorCh.ctx, hc.Type, cpuID, socketID, coreID, threadID); err != nil {
+			return err
+		}
+
+		cpuIDs = append(cpuIDs, cpuID)
+	}
+
+	if uint32(len(cpuIDs)) < dev.Count {
+		return fmt.Errorf("Not enough free vCPU slots: got %d, need %d", len(cpuIDs), dev.Count)
+	}
+
+	if q.state.HotpluggedVCPUs == nil {
+		q.state.HotpluggedVCPUs = make(map[string][]string)
+	}
+	q.state.HotpluggedVCPUs[dev.ID] = cpuIDs
+
+	if len(dev.HostCPUs) == 0 {
+		return nil
+	}
+
+	return q.pinVCPUThreads(dev.Count, dev.HostCPUs)
+}
+
+// pinVCPUThreads pins the threads backing the most recently hotplugged
+// vCPUs to the given host CPU set.
+func (q *qemu) pinVCPUThreads(count uint32, hostCPUs []int) error {
+	response, err := q.qmpMonitorCh.qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "query-cpus", nil)
+	if err != nil {
+		return err
+	}
+
+	cpus, ok := response.([]interface{})
+	if !ok {
+		return fmt.Errorf("Unexpected query-cpus response %v", response)
+	}
+
+	if uint32(len(cpus)) < count {
+		return fmt.Errorf("Missing vCPUs in query-cpus response: got %d, need %d", len(cpus), count)
+	}
+
+	// The hotplugged vCPUs are the last ones reported.
+	for _, cpu := range cpus[uint32(len(cpus))-count:] {
+		properties, ok := cpu.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Unexpected query-cpus entry %v", cpu)
+		}
+
+		tid, ok := properties["thread_id"].(float64)
+		if !ok {
+			return fmt.Errorf("Missing vCPU thread ID in %v", properties)
+		}
+
+		if err := pinThreadToCPUs(int(tid), hostCPUs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (q *qemu) hotplugDevice(devInfo interface{}, devType deviceType, op operation) error {
 	switch devType {
 	case blockDev:
 		drive := devInfo.(Drive)
-		return q.hotplugBlockDevice(drive, op)
+		return q.qmpResult(q.hotplugBlockDevice(drive, op))
+	case cpuDev:
+		dev := devInfo.(CPUDevice)
+		return q.qmpResult(q.hotplugCPUDevice(dev, op))
 	default:
-		return fmt.Errorf("Only hotplug for block devices supported for now, provided device type : %v", devType)
+		return fmt.Errorf("Hotplug is not supported for device type %v", devType)
 	}
 }
 
@@ -1058,7 +1735,11 @@ func (q *qemu) addDevice(devInfo interface{}, devType deviceType) error {
 	case VhostUserBlkDevice:
 		q.qemuConfig.Devices = q.appendVhostUserDevice(q.qemuConfig.Devices, &v)
 	case VFIODevice:
-		q.qemuConfig.Devices = q.appendVFIODevice(q.qemuConfig.Devices, v)
+		devices, err := q.appendVFIODevice(q.qemuConfig.Devices, v)
+		if err != nil {
+			return err
+		}
+		q.qemuConfig.Devices = devices
 	default:
 		break
 	}
@@ -1066,12 +1747,187 @@ func (q *qemu) addDevice(devInfo interface{}, devType deviceType) error {
 	return nil
 }
 
+// sendCommand sends a raw command to the QMP monitor and returns the
+// decoded response.
+func (q *qemu) sendCommand(cmd string, args map[string]interface{}) (interface{}, error) {
+	qmp, err := q.qmpSetup()
+	if err != nil {
+		return nil, err
+	}
+	defer q.qmpTeardown()
+
+	response, err := qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, cmd, args)
+
+	return response, q.qmpResult(err)
+}
+
+// saveState snapshots the guest memory and device state into a file.
+// The guest is stopped first so that the snapshot is taken from a
+// quiesced machine.
+func (q *qemu) saveState(stateFile string) error {
+	qmp, err := q.qmpSetup()
+	if err != nil {
+		return err
+	}
+	defer q.qmpTeardown()
+
+	if err := qmp.ExecuteStop(q.qmpMonitorCh.ctx); err != nil {
+		return q.qmpResult(err)
+	}
+
+	args := map[string]interface{}{
+		"uri": fmt.Sprintf("exec:cat > %s", stateFile),
+	}
+
+	if _, err := qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "migrate", args); err != nil {
+		return q.qmpResult(err)
+	}
+
+	// The migration runs asynchronously, poll it to completion. The
+	// monitor context bounds how long this can take.
+	for {
+		response, err := qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "query-migrate", nil)
+		if err != nil {
+			return q.qmpResult(err)
+		}
+
+		migrateInfo, ok := response.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Unexpected migration status format %T", response)
+		}
+
+		switch migrateInfo["status"] {
+		case "completed":
+			return nil
+		case "failed", "cancelled":
+			return fmt.Errorf("Guest state migration %v", migrateInfo["status"])
+		}
+
+		select {
+		case <-q.qmpMonitorCh.ctx.Done():
+			return q.qmpResult(q.qmpMonitorCh.ctx.Err())
+		case <-time.After(qmpRetryInterval):
+		}
+	}
+}
+
+// dirtyBitmapName returns the name of the dirty bitmap tracking guest
+// writes to a drive.
+func dirtyBitmapName(deviceID string) string {
+	return "backup-" + deviceID
+}
+
+// backupBlockDevice copies an attached drive into a backup target
+// file. An incremental backup only copies the blocks recorded in the
+// drive dirty bitmap since the last backup, and resets the bitmap.
+func (q *qemu) backupBlockDevice(deviceID, target string, incremental bool) error {
+	qmp, err := q.qmpSetup()
+	if err != nil {
+		return err
+	}
+	defer q.qmpTeardown()
+
+	args := map[string]interface{}{
+		"device": deviceID,
+		"target": target,
+		"format": "qcow2",
+		"sync":   "full",
+	}
+
+	if incremental {
+		args["sync"] = "incremental"
+		args["bitmap"] = dirtyBitmapName(deviceID)
+	}
+
+	_, err = qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "drive-backup", args)
+
+	return q.qmpResult(err)
+}
+
+// snapshotBlockDevice takes a live snapshot of an attached drive. The
+// drive keeps writing to the new overlay file, the previous file holds
+// the frozen snapshot content.
+func (q *qemu) snapshotBlockDevice(deviceID, snapshotFile string) error {
+	qmp, err := q.qmpSetup()
+	if err != nil {
+		return err
+	}
+	defer q.qmpTeardown()
+
+	args := map[string]interface{}{
+		"device":        deviceID,
+		"snapshot-file": snapshotFile,
+		"format":        "qcow2",
+	}
+
+	_, err = qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "blockdev-snapshot-sync", args)
+
+	return q.qmpResult(err)
+}
+
+// attestationEvidence retrieves the launch measurement of a
+// confidential guest from the hypervisor monitor.
+func (q *qemu) attestationEvidence() (AttestationEvidence, error) {
+	qmp, err := q.qmpSetup()
+	if err != nil {
+		return AttestationEvidence{}, err
+	}
+	defer q.qmpTeardown()
+
+	response, err := qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "query-sev", nil)
+	if err != nil {
+		return AttestationEvidence{}, q.qmpResult(err)
+	}
+
+	sevInfo, ok := response.(map[string]interface{})
+	if !ok || sevInfo["enabled"] != true {
+		return AttestationEvidence{}, fmt.Errorf("Guest is not running in a supported TEE")
+	}
+
+	response, err = qmp.ExecuteRawCommand(q.qmpMonitorCh.ctx, "query-sev-launch-measure", nil)
+	if err != nil {
+		return AttestationEvidence{}, q.qmpResult(err)
+	}
+
+	measureInfo, ok := response.(map[string]interface{})
+	if !ok {
+		return AttestationEvidence{}, fmt.Errorf("Unexpected launch measurement format %T", response)
+	}
+
+	data, ok := measureInfo["data"].(string)
+	if !ok {
+		return AttestationEvidence{}, fmt.Errorf("Missing launch measurement data")
+	}
+
+	measurement, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return AttestationEvidence{}, fmt.Errorf("Could not decode launch measurement: %v", err)
+	}
+
+	return AttestationEvidence{
+		TeeType:           TeeSEV,
+		LaunchMeasurement: measurement,
+	}, nil
+}
+
 // getPodConsole builds the path of the console where we can read
 // logs coming from the pod.
 func (q *qemu) getPodConsole(podID string) string {
 	return filepath.Join(runStoragePath, podID, defaultConsole)
 }
 
+// getPodDebugConsole builds the path of the socket backing the guest
+// debug shell console.
+func (q *qemu) getPodDebugConsole(podID string) string {
+	return filepath.Join(runStoragePath, podID, debugConsoleSock)
+}
+
+// getPodKernelLogConsole builds the path of the socket the guest kernel
+// messages can be read from.
+func (q *qemu) getPodKernelLogConsole(podID string) string {
+	return filepath.Join(runStoragePath, podID, kmsgConsoleSock)
+}
+
 func (q *qemu) getState() interface{} {
 	return q.state
 }
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	govmmQemu "github.com/intel/govmm/qemu"
 )
@@ -60,7 +61,7 @@ func testQemuBuildKernelParams(t *testing.T, kernelParams []Param, expected stri
 	}
 }
 
-var testQemuKernelParamsBase = "root=/dev/pmem0p1 rootflags=dax,data=ordered,errors=remount-ro rw rootfstype=ext4 tsc=reliable no_timer_check rcupdate.rcu_expedited=1 i8042.direct=1 i8042.dumbkbd=1 i8042.nopnp=1 i8042.noaux=1 noreplace-smp reboot=k panic=1 console=hvc0 console=hvc1 initcall_debug iommu=off cryptomgr.notests net.ifnames=0 pci=lastbus=0"
+var testQemuKernelParamsBase = "root=/dev/pmem0p1 rootflags=dax,data=ordered,errors=remount-ro rw rootfstype=ext4 rcupdate.rcu_expedited=1 i8042.direct=1 i8042.dumbkbd=1 i8042.nopnp=1 i8042.noaux=1 noreplace-smp reboot=k panic=1 console=hvc0 console=hvc1 initcall_debug iommu=off cryptomgr.notests net.ifnames=0 pci=lastbus=0 clocksource=kvm-clock no_timer_check"
 var testQemuKernelParamsNonDebug = "quiet systemd.show_status=false"
 var testQemuKernelParamsDebug = "debug systemd.show_status=true systemd.log_level=debug"
 
@@ -122,7 +123,7 @@ func testQemuAppend(t *testing.T, structure interface{}, expected []govmmQemu.De
 	case Drive:
 		devices = q.appendBlockDevice(devices, s)
 	case VFIODevice:
-		devices = q.appendVFIODevice(devices, s)
+		devices, _ = q.appendVFIODevice(devices, s)
 	case VhostUserNetDevice:
 		devices = q.appendVhostUserDevice(devices, &s)
 	}
@@ -212,6 +213,33 @@ func TestQemuAppendBlockDevice(t *testing.T) {
 	testQemuAppend(t, drive, expectedOut, -1, nestedVM)
 }
 
+func TestQemuAppendFwCfgBlobs(t *testing.T) {
+	expectedOut := []govmmQemu.Device{
+		govmmQemu.FwCfg{
+			Name: "opt/org.test/config",
+			File: "/tmp/config.json",
+		},
+	}
+
+	q := &qemu{
+		config: HypervisorConfig{
+			FwCfgBlobs: []FwCfgBlob{
+				{
+					Name: "opt/org.test/config",
+					Path: "/tmp/config.json",
+				},
+			},
+		},
+	}
+
+	var devices []govmmQemu.Device
+	devices = q.appendFwCfgBlobs(devices)
+
+	if reflect.DeepEqual(devices, expectedOut) == false {
+		t.Fatalf("\n\tGot %v\n\tExpecting %v", devices, expectedOut)
+	}
+}
+
 func TestQemuAppendVFIODevice(t *testing.T) {
 	nestedVM := true
 	bdf := "02:10.1"
@@ -229,6 +257,39 @@ func TestQemuAppendVFIODevice(t *testing.T) {
 	testQemuAppend(t, vfDevice, expectedOut, -1, nestedVM)
 }
 
+func TestQemuAppendVFIOMultifunctionDevice(t *testing.T) {
+	q := &qemu{}
+	q.state.Bridges = NewBridges(1, QemuPC)
+
+	vfDevice := VFIODevice{
+		BDF:       "02:10.0",
+		Functions: []string{"02:10.0", "02:10.1"},
+	}
+
+	devices, err := q.appendVFIODevice(nil, vfDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOut := []govmmQemu.Device{
+		govmmQemu.VFIODevice{
+			BDF:           "02:10.0",
+			Bus:           "pci-bridge-0",
+			Addr:          "0x1.0",
+			Multifunction: true,
+		},
+		govmmQemu.VFIODevice{
+			BDF:  "02:10.1",
+			Bus:  "pci-bridge-0",
+			Addr: "0x1.1",
+		},
+	}
+
+	if reflect.DeepEqual(devices, expectedOut) == false {
+		t.Fatalf("\n\tGot %v\n\tExpecting %v", devices, expectedOut)
+	}
+}
+
 func TestQemuAppendVhostUserDevice(t *testing.T) {
 	nestedVM := true
 	socketPath := "nonexistentpath.sock"
@@ -341,6 +402,63 @@ func TestQemuAppendConsoles(t *testing.T) {
 	testQemuAppend(t, podConfig, expectedOut, consoleDev, nestedVM)
 }
 
+func TestQemuAppendConsolesMux(t *testing.T) {
+	podID := "testPodID"
+
+	var devices []govmmQemu.Device
+	q := &qemu{
+		config: HypervisorConfig{
+			DebugConsole:     true,
+			KernelLogConsole: true,
+		},
+	}
+
+	devices = q.appendConsoles(devices, PodConfig{ID: podID})
+
+	expectedOut := []govmmQemu.Device{
+		govmmQemu.SerialDevice{
+			Driver: govmmQemu.VirtioSerial,
+			ID:     "serial0",
+		},
+		govmmQemu.CharDevice{
+			Driver:   govmmQemu.Console,
+			Backend:  govmmQemu.Socket,
+			DeviceID: "console0",
+			ID:       "charconsole0",
+			Path:     filepath.Join(runStoragePath, podID, defaultConsole),
+		},
+		govmmQemu.CharDevice{
+			Driver:   govmmQemu.Console,
+			Backend:  govmmQemu.Socket,
+			DeviceID: "debugconsole0",
+			ID:       "chardebugconsole0",
+			Path:     filepath.Join(runStoragePath, podID, debugConsoleSock),
+		},
+		govmmQemu.CharDevice{
+			Driver:   govmmQemu.Console,
+			Backend:  govmmQemu.Socket,
+			DeviceID: "kmsgconsole0",
+			ID:       "charkmsgconsole0",
+			Path:     filepath.Join(runStoragePath, podID, kmsgConsoleSock),
+		},
+	}
+
+	if reflect.DeepEqual(devices, expectedOut) == false {
+		t.Fatalf("\n\tGot %v\n\tExpecting %v", devices, expectedOut)
+	}
+
+	if q.kernelLogConsoleIndex() != 2 {
+		t.Fatalf("Got kernel log console index %d\nExpecting 2", q.kernelLogConsoleIndex())
+	}
+
+	params := q.defaultKernelParams()
+	for _, p := range params {
+		if p.Key == "console" && p.Value != "hvc2" {
+			t.Fatalf("Got unexpected console parameter %s=%s", p.Key, p.Value)
+		}
+	}
+}
+
 func TestQemuAppendImage(t *testing.T) {
 	var devices []govmmQemu.Device
 
@@ -383,6 +501,34 @@ func TestQemuAppendImage(t *testing.T) {
 	}
 }
 
+func TestQemuAppendSharedImage(t *testing.T) {
+	var devices []govmmQemu.Device
+
+	qemuConfig := newQemuConfig()
+	qemuConfig.SharedImage = true
+	q := &qemu{
+		config: qemuConfig,
+	}
+
+	devices, err := q.appendImage(devices, PodConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("Got %d devices\nExpecting 1", len(devices))
+	}
+
+	object, ok := devices[0].(govmmQemu.Object)
+	if !ok {
+		t.Fatalf("Got device %v\nExpecting a govmmQemu.Object", devices[0])
+	}
+
+	if object.ReadOnly == false {
+		t.Fatal("Expected the shared image to be attached read-only")
+	}
+}
+
 func TestQemuInit(t *testing.T) {
 	qemuConfig := newQemuConfig()
 	q := &qemu{}
@@ -666,3 +812,249 @@ func TestQemuBlockHotplugCapabilities(t *testing.T) {
 		}
 	}
 }
+
+func TestQemuGuestMemoryDumpAllowed(t *testing.T) {
+	type testData struct {
+		sizeCapMiB   uint32
+		defaultMemSz uint32
+		vmMemory     uint
+		expected     bool
+	}
+
+	data := []testData{
+		{0, 2048, 0, true},
+		{0, 2048, 8192, true},
+		{4096, 2048, 0, true},
+		{4096, 8192, 0, false},
+		{4096, 2048, 4096, true},
+		{4096, 2048, 8192, false},
+	}
+
+	for _, d := range data {
+		q := &qemu{
+			config: HypervisorConfig{
+				DefaultMemSz:           d.defaultMemSz,
+				GuestMemoryDumpSizeMiB: d.sizeCapMiB,
+			},
+			pod: &Pod{
+				config: &PodConfig{
+					VMConfig: Resources{
+						Memory: d.vmMemory,
+					},
+				},
+			},
+		}
+
+		if result := q.guestMemoryDumpAllowed(); result != d.expected {
+			t.Fatalf("Got %t for %+v\nExpecting %t", result, d, d.expected)
+		}
+	}
+}
+
+func TestQemuHotplugBridgeExhausted(t *testing.T) {
+	q := &qemu{}
+
+	// Fill the maximum number of bridges, with every slot taken.
+	for i := uint32(0); i < maxDefaultBridges; i++ {
+		bridge := Bridge{
+			Type:    pciBridge,
+			ID:      fmt.Sprintf("%s-bridge-%d", pciBridge, i),
+			Address: make(map[uint32]string),
+		}
+
+		for addr := uint32(1); addr <= pciBridgeMaxCapacity; addr++ {
+			bridge.Address[addr] = fmt.Sprintf("dev-%d-%d", i, addr)
+		}
+
+		q.state.Bridges = append(q.state.Bridges, bridge)
+	}
+
+	_, _, err := q.addDeviceToBridge("test-dev")
+	if err == nil {
+		t.Fatal("Adding a device to exhausted bridges should fail")
+	}
+
+	if !strings.Contains(err.Error(), "PCI bus exhausted") {
+		t.Fatalf("Got %q\nExpecting a PCI bus exhaustion error", err)
+	}
+}
+
+func TestQemuHotplugBridgeNoQMP(t *testing.T) {
+	q := &qemu{}
+
+	// A free slot exists once a bridge is hotplugged, but without a
+	// QMP connection the bridge hotplug cannot proceed.
+	if _, err := q.hotplugBridge(); err == nil {
+		t.Fatal("Bridge hotplug without a QMP connection should fail")
+	}
+}
+
+func TestQemuQMPTimeoutDefault(t *testing.T) {
+	q := &qemu{}
+
+	if timeout := q.qmpTimeout(); timeout != defaultQMPTimeout {
+		t.Fatalf("Got QMP timeout %v\nExpecting %v", timeout, defaultQMPTimeout)
+	}
+
+	q.config.QMPTimeoutSecs = 3
+
+	if timeout := q.qmpTimeout(); timeout != 3*time.Second {
+		t.Fatalf("Got QMP timeout %v\nExpecting %v", timeout, 3*time.Second)
+	}
+}
+
+func TestQemuQMPRetriesDefault(t *testing.T) {
+	q := &qemu{}
+
+	if retries := q.qmpRetries(); retries != defaultQMPRetries {
+		t.Fatalf("Got %d QMP retries\nExpecting %d", retries, defaultQMPRetries)
+	}
+
+	q.config.QMPRetries = 1
+
+	if retries := q.qmpRetries(); retries != 1 {
+		t.Fatalf("Got %d QMP retries\nExpecting 1", retries)
+	}
+}
+
+func TestQemuQMPSetupBoundedRetries(t *testing.T) {
+	q := &qemu{
+		config: HypervisorConfig{
+			QMPTimeoutSecs: 1,
+			QMPRetries:     2,
+		},
+	}
+	q.qmpControlCh.path = "/nonexistent/qmp.sock"
+
+	start := time.Now()
+
+	if _, err := q.qmpSetup(); err == nil {
+		t.Fatal("QMP setup against a missing socket should fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("QMP setup took %v, the retries are not bounded", elapsed)
+	}
+}
+
+func TestParseQemuVersion(t *testing.T) {
+	version, err := parseQemuVersion("QEMU emulator version 2.11.1(qemu-2.11.1-2.fc27)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version.major != 2 || version.minor != 11 {
+		t.Fatalf("Got version %d.%d\nExpecting 2.11", version.major, version.minor)
+	}
+
+	if _, err := parseQemuVersion("no version here"); err == nil {
+		t.Fatal("Parsing an output without a version should fail")
+	}
+}
+
+func TestQemuVersionOlderThan(t *testing.T) {
+	version := qemuVersion{major: 2, minor: 6}
+
+	if version.olderThan(qemuVersion{major: 2, minor: 6}) {
+		t.Fatal("2.6 should not be older than 2.6")
+	}
+
+	if !version.olderThan(qemuVersion{major: 2, minor: 7}) {
+		t.Fatal("2.6 should be older than 2.7")
+	}
+
+	unprobed := qemuVersion{}
+
+	if unprobed.olderThan(qemuVersion{major: 2, minor: 7}) {
+		t.Fatal("An unprobed version should not be considered too old")
+	}
+}
+
+func TestQemuCapabilitiesMatrix(t *testing.T) {
+	q := &qemu{}
+
+	// q35 without a bridge has nothing to plug onto.
+	q.qemuConfig.Machine.Type = QemuQ35
+
+	caps := q.capabilities()
+	if caps.isBlockDeviceHotplugSupported() || caps.isNetDeviceHotplugSupported() {
+		t.Fatal("q35 without a bridge should not support device hotplug")
+	}
+
+	// A bridge opens up PCI hotplug.
+	q.state.Bridges = []Bridge{{ID: "test-bridge"}}
+
+	caps = q.capabilities()
+	if !caps.isBlockDeviceHotplugSupported() || !caps.isNetDeviceHotplugSupported() || !caps.isCPUHotplugSupported() {
+		t.Fatal("q35 with a bridge should support device hotplug")
+	}
+
+	// A qemu older than the hotplug baseline turns it all off.
+	q.version = qemuVersion{major: 2, minor: 3}
+
+	caps = q.capabilities()
+	if caps.isBlockDeviceHotplugSupported() || caps.isCPUHotplugSupported() {
+		t.Fatal("An old qemu should not support device hotplug")
+	}
+}
+
+func TestQemuCapabilitiesSCSIController(t *testing.T) {
+	q := &qemu{}
+	q.qemuConfig.Machine.Type = QemuPCLite
+	q.qemuConfig.Devices = []govmmQemu.Device{
+		govmmQemu.SCSIController{ID: "scsi0"},
+	}
+
+	caps := q.capabilities()
+	if !caps.isBlockDeviceHotplugSupported() {
+		t.Fatal("A virtio-scsi controller should allow block hotplug")
+	}
+
+	if caps.isNetDeviceHotplugSupported() {
+		t.Fatal("A virtio-scsi controller should not allow net hotplug")
+	}
+}
+
+func TestQemuCapabilitiesMemoryHotplug(t *testing.T) {
+	q := &qemu{}
+
+	if caps := q.capabilities(); caps.isMemoryHotplugSupported() {
+		t.Fatal("Memory hotplug without spare slots should not be supported")
+	}
+
+	q.qemuConfig.Memory = govmmQemu.Memory{
+		Size:   "2048M",
+		Slots:  2,
+		MaxMem: "4096M",
+	}
+
+	if caps := q.capabilities(); !caps.isMemoryHotplugSupported() {
+		t.Fatal("Memory hotplug with spare slots should be supported")
+	}
+}
+
+func TestQemuSetMemoryResourcesConfigured(t *testing.T) {
+	q := &qemu{
+		config: HypervisorConfig{
+			DefaultMemSz: 1000,
+			MemSlots:     4,
+			MemMaxMiB:    8192,
+			MemOffsetMiB: 512,
+		},
+	}
+
+	expectedOut := govmmQemu.Memory{
+		Size:   "1000M",
+		Slots:  uint8(4),
+		MaxMem: "8704M",
+	}
+
+	memory, err := q.setMemoryResources(PodConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(memory, expectedOut) == false {
+		t.Fatalf("Got %v\nExpecting %v", memory, expectedOut)
+	}
+}
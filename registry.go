@@ -0,0 +1,255 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Names of the files, below the run storage path, backing the host
+// wide resource registry.
+const (
+	resourceRegistryFile     = "resource-registry.json"
+	resourceRegistryLockFile = "resource-registry.lock"
+)
+
+// ResourceClaim describes the host resources claimed by one pod.
+type ResourceClaim struct {
+	// VCPUs and MemoryMiB are the VM sizing of the pod.
+	VCPUs     uint `json:"vcpus"`
+	MemoryMiB uint `json:"memoryMiB"`
+
+	// HugepagesMiB is the amount of VM memory backed by huge pages,
+	// which the host must have preallocated.
+	HugepagesMiB uint `json:"hugepagesMiB,omitempty"`
+
+	// VFIODevices lists the host VFIO device paths assigned to the
+	// pod.
+	VFIODevices []string `json:"vfioDevices,omitempty"`
+
+	// VhostUserSockets lists the vhost-user sockets the pod is
+	// attached to.
+	VhostUserSockets []string `json:"vhostUserSockets,omitempty"`
+}
+
+// HostCapacity describes the host resources the embedding runtime
+// dedicates to pods.
+type HostCapacity struct {
+	VCPUs        uint
+	MemoryMiB    uint
+	HugepagesMiB uint
+}
+
+// resourceRegistry is the persisted host wide record of the resources
+// claimed by all pods, keyed by pod ID.
+type resourceRegistry struct {
+	Claims map[string]ResourceClaim `json:"claims"`
+}
+
+// lockResourceRegistry takes the host wide registry lock, serializing
+// registry updates across every process embedding virtcontainers.
+func lockResourceRegistry() (*os.File, error) {
+	if err := os.MkdirAll(runStoragePath, dirMode); err != nil {
+		return nil, err
+	}
+
+	lockFile, err := os.OpenFile(filepath.Join(runStoragePath, resourceRegistryLockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		lockFile.Close()
+		return nil, err
+	}
+
+	return lockFile, nil
+}
+
+func unlockResourceRegistry(lockFile *os.File) {
+	syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+	lockFile.Close()
+}
+
+// fetchResourceRegistry loads the persisted registry, an absent file
+// meaning no pod has claimed anything yet.
+func fetchResourceRegistry() (resourceRegistry, error) {
+	registry := resourceRegistry{
+		Claims: map[string]ResourceClaim{},
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(runStoragePath, resourceRegistryFile))
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return registry, err
+	}
+
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return registry, fmt.Errorf("Could not parse the resource registry: %v", err)
+	}
+
+	if registry.Claims == nil {
+		registry.Claims = map[string]ResourceClaim{}
+	}
+
+	return registry, nil
+}
+
+func storeResourceRegistry(registry resourceRegistry) error {
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(runStoragePath, resourceRegistryFile), data, 0600)
+}
+
+// resourceClaim computes the host resources the pod claims, from its
+// configuration and network attachments.
+func (p *Pod) resourceClaim(networkNS NetworkNamespace) ResourceClaim {
+	claim := ResourceClaim{
+		VCPUs:     uint(p.config.HypervisorConfig.DefaultVCPUs),
+		MemoryMiB: uint(p.config.HypervisorConfig.DefaultMemSz),
+	}
+
+	if p.config.VMConfig.VCPUs > 0 {
+		claim.VCPUs = p.config.VMConfig.VCPUs
+	}
+
+	if p.config.VMConfig.Memory > 0 {
+		claim.MemoryMiB = p.config.VMConfig.Memory
+	}
+
+	if p.config.HypervisorConfig.HugePages {
+		claim.HugepagesMiB = claim.MemoryMiB
+	}
+
+	for _, container := range p.config.Containers {
+		for _, device := range container.DeviceInfos {
+			if isVFIO(device.HostPath) {
+				claim.VFIODevices = append(claim.VFIODevices, device.HostPath)
+			}
+		}
+	}
+
+	for _, endpoint := range networkNS.Endpoints {
+		if vhostEndpoint, ok := endpoint.(*VhostUserEndpoint); ok {
+			claim.VhostUserSockets = append(claim.VhostUserSockets, vhostEndpoint.SocketPath)
+		}
+	}
+
+	return claim
+}
+
+// registerPodResources records the pod claim in the host wide
+// registry.
+func registerPodResources(p *Pod, networkNS NetworkNamespace) error {
+	lockFile, err := lockResourceRegistry()
+	if err != nil {
+		return err
+	}
+	defer unlockResourceRegistry(lockFile)
+
+	registry, err := fetchResourceRegistry()
+	if err != nil {
+		return err
+	}
+
+	registry.Claims[p.id] = p.resourceClaim(networkNS)
+
+	return storeResourceRegistry(registry)
+}
+
+// releasePodResources drops the pod claim from the host wide registry.
+func releasePodResources(podID string) error {
+	lockFile, err := lockResourceRegistry()
+	if err != nil {
+		return err
+	}
+	defer unlockResourceRegistry(lockFile)
+
+	registry, err := fetchResourceRegistry()
+	if err != nil {
+		return err
+	}
+
+	delete(registry.Claims, podID)
+
+	return storeResourceRegistry(registry)
+}
+
+// ClaimedResources returns the aggregate of the resources claimed by
+// every pod on the host.
+func ClaimedResources() (ResourceClaim, error) {
+	lockFile, err := lockResourceRegistry()
+	if err != nil {
+		return ResourceClaim{}, err
+	}
+	defer unlockResourceRegistry(lockFile)
+
+	registry, err := fetchResourceRegistry()
+	if err != nil {
+		return ResourceClaim{}, err
+	}
+
+	var claimed ResourceClaim
+	for _, claim := range registry.Claims {
+		claimed.VCPUs += claim.VCPUs
+		claimed.MemoryMiB += claim.MemoryMiB
+		claimed.HugepagesMiB += claim.HugepagesMiB
+		claimed.VFIODevices = append(claimed.VFIODevices, claim.VFIODevices...)
+		claimed.VhostUserSockets = append(claimed.VhostUserSockets, claim.VhostUserSockets...)
+	}
+
+	return claimed, nil
+}
+
+// RemainingCapacity subtracts the resources claimed by every pod from
+// the given host capacity, so that the embedding runtime can reject
+// pods the host cannot accommodate before trying to create them.
+// Exhausted resources are reported as zero, never negative. The
+// returned claim also lists the VFIO devices and vhost-user sockets
+// already taken, which a new pod must not conflict with.
+func RemainingCapacity(capacity HostCapacity) (HostCapacity, ResourceClaim, error) {
+	claimed, err := ClaimedResources()
+	if err != nil {
+		return HostCapacity{}, ResourceClaim{}, err
+	}
+
+	remaining := HostCapacity{}
+
+	if capacity.VCPUs > claimed.VCPUs {
+		remaining.VCPUs = capacity.VCPUs - claimed.VCPUs
+	}
+
+	if capacity.MemoryMiB > claimed.MemoryMiB {
+		remaining.MemoryMiB = capacity.MemoryMiB - claimed.MemoryMiB
+	}
+
+	if capacity.HugepagesMiB > claimed.HugepagesMiB {
+		remaining.HugepagesMiB = capacity.HugepagesMiB - claimed.HugepagesMiB
+	}
+
+	return remaining, claimed, nil
+}
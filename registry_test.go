@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResourceRegistryClaimRelease(t *testing.T) {
+	defer os.Remove(filepath.Join(runStoragePath, resourceRegistryFile))
+
+	pod := &Pod{
+		id: "registry-test-pod",
+		config: &PodConfig{
+			VMConfig: Resources{
+				VCPUs:  2,
+				Memory: 512,
+			},
+		},
+	}
+
+	if err := registerPodResources(pod, NetworkNamespace{}); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := ClaimedResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed.VCPUs != 2 || claimed.MemoryMiB != 512 {
+		t.Fatalf("Wrong aggregate claim: %+v", claimed)
+	}
+
+	remaining, _, err := RemainingCapacity(HostCapacity{VCPUs: 8, MemoryMiB: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := HostCapacity{VCPUs: 6, MemoryMiB: 1536}
+	if !reflect.DeepEqual(remaining, expected) {
+		t.Fatalf("Expecting %+v, got %+v", expected, remaining)
+	}
+
+	// An overcommitted host reports zero, not negative, capacity.
+	remaining, _, err = RemainingCapacity(HostCapacity{VCPUs: 1, MemoryMiB: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining.VCPUs != 0 || remaining.MemoryMiB != 0 {
+		t.Fatalf("Expecting exhausted capacity, got %+v", remaining)
+	}
+
+	if err := releasePodResources(pod.id); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err = ClaimedResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed.VCPUs != 0 || claimed.MemoryMiB != 0 {
+		t.Fatalf("Expecting no claims after release, got %+v", claimed)
+	}
+}
+
+func TestResourceClaimHugePages(t *testing.T) {
+	pod := &Pod{
+		id: "registry-hugepages-pod",
+		config: &PodConfig{
+			HypervisorConfig: HypervisorConfig{
+				DefaultVCPUs: 1,
+				DefaultMemSz: 1024,
+				HugePages:    true,
+			},
+		},
+	}
+
+	claim := pod.resourceClaim(NetworkNamespace{})
+
+	if claim.HugepagesMiB != 1024 {
+		t.Fatalf("Expecting the VM memory to be claimed from huge pages, got %+v", claim)
+	}
+}
@@ -0,0 +1,264 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procPath is the standard procfs mount point. It is a variable so
+// tests can override it.
+var procPath = "/proc"
+
+// ProcessResourceUsage describes the host-side resource consumption of
+// a single process, as read from procfs.
+type ProcessResourceUsage struct {
+	// Pid is the host process ID.
+	Pid int
+
+	// RSSBytes is the resident set size in bytes.
+	RSSBytes uint64
+
+	// CPUTimeTicks is the amount of time scheduled in user and kernel
+	// mode, in clock ticks.
+	CPUTimeTicks uint64
+
+	// OpenFdCount is the number of open file descriptors.
+	OpenFdCount int
+
+	// ThreadCount is the number of threads.
+	ThreadCount int
+}
+
+// PodResourceUsage describes the host-side resource consumption of all
+// the processes supporting a pod, allowing the overhead of VM isolation
+// to be attributed per pod.
+type PodResourceUsage struct {
+	// Hypervisor is the usage of the hypervisor process, nil if it
+	// could not be found.
+	Hypervisor *ProcessResourceUsage
+
+	// Proxy is the usage of the proxy process, nil when no proxy is
+	// running for the pod.
+	Proxy *ProcessResourceUsage
+
+	// Shims is the usage of the per-container shim processes.
+	Shims []ProcessResourceUsage
+}
+
+// PodOverhead describes the fixed per pod cost of VM isolation, which
+// schedulers need to account for on top of the workload requests
+// themselves.
+type PodOverhead struct {
+	// VMMemoryMiB is the current memory size of the VM in MiB. It is
+	// refreshed on every call, so it tracks memory hotplug over the
+	// pod lifetime.
+	VMMemoryMiB uint32
+
+	// HypervisorRSSBytes is the resident set size of the hypervisor
+	// process, 0 if it could not be found.
+	HypervisorRSSBytes uint64
+
+	// ProxyRSSBytes is the resident set size of the proxy process,
+	// 0 when no proxy is running for the pod.
+	ProxyRSSBytes uint64
+}
+
+// processResourceUsage collects the resource consumption of the given
+// process from procfs.
+func processResourceUsage(pid int) (ProcessResourceUsage, error) {
+	if pid <= 0 {
+		return ProcessResourceUsage{}, fmt.Errorf("Invalid pid %d", pid)
+	}
+
+	usage := ProcessResourceUsage{
+		Pid: pid,
+	}
+
+	pidPath := filepath.Join(procPath, strconv.Itoa(pid))
+
+	stat, err := ioutil.ReadFile(filepath.Join(pidPath, "stat"))
+	if err != nil {
+		return ProcessResourceUsage{}, err
+	}
+
+	// The process name (2nd field) is surrounded by parentheses and can
+	// contain spaces, everything after the last closing parenthesis is
+	// well defined.
+	idx := strings.LastIndexByte(string(stat), ')')
+	if idx == -1 {
+		return ProcessResourceUsage{}, fmt.Errorf("Malformed stat file for pid %d", pid)
+	}
+
+	fields := strings.Fields(string(stat)[idx+1:])
+
+	// utime and stime are the 14th and 15th fields of the stat file,
+	// num_threads the 20th. Offset by the 2 fields skipped above.
+	const utimeIdx, stimeIdx, threadsIdx = 11, 12, 17
+	if len(fields) <= threadsIdx {
+		return ProcessResourceUsage{}, fmt.Errorf("Malformed stat file for pid %d", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[utimeIdx], 10, 64)
+	if err != nil {
+		return ProcessResourceUsage{}, err
+	}
+
+	stime, err := strconv.ParseUint(fields[stimeIdx], 10, 64)
+	if err != nil {
+		return ProcessResourceUsage{}, err
+	}
+
+	usage.CPUTimeTicks = utime + stime
+
+	threads, err := strconv.Atoi(fields[threadsIdx])
+	if err != nil {
+		return ProcessResourceUsage{}, err
+	}
+
+	usage.ThreadCount = threads
+
+	status, err := ioutil.ReadFile(filepath.Join(pidPath, "status"))
+	if err != nil {
+		return ProcessResourceUsage{}, err
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		// Expected format: ["VmRSS:", "1234", "kB"]
+		parts := strings.Fields(line)
+		if len(parts) < 3 || parts[0] != "VmRSS:" {
+			continue
+		}
+
+		rssKb, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		usage.RSSBytes = rssKb * 1024
+		break
+	}
+
+	// Being unable to list the file descriptors is not fatal, this
+	// requires more privileges than the rest of procfs.
+	if fds, err := ioutil.ReadDir(filepath.Join(pidPath, "fd")); err == nil {
+		usage.OpenFdCount = len(fds)
+	}
+
+	return usage, nil
+}
+
+// findHypervisorPid scans procfs for the hypervisor process of a pod,
+// identified by the VM UUID present on its command line.
+func findHypervisorPid(uuid string) (int, error) {
+	if uuid == "" {
+		return -1, fmt.Errorf("UUID cannot be empty")
+	}
+
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		cmdline, err := ioutil.ReadFile(filepath.Join(procPath, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+
+		for _, arg := range strings.Split(string(cmdline), "\x00") {
+			if arg == uuid {
+				return pid, nil
+			}
+		}
+	}
+
+	return -1, fmt.Errorf("Could not find hypervisor process for UUID %s", uuid)
+}
+
+// resourceUsage returns the host-side resource consumption of the
+// hypervisor, proxy and shim processes supporting the pod.
+func (p *Pod) resourceUsage() (PodResourceUsage, error) {
+	var usage PodResourceUsage
+
+	// The hypervisor process is found from the VM UUID, when the
+	// hypervisor implementation exposes one.
+	if state, ok := p.hypervisor.getState().(QemuState); ok {
+		if pid, err := findHypervisorPid(state.UUID); err == nil {
+			if u, err := processResourceUsage(pid); err == nil {
+				usage.Hypervisor = &u
+			}
+		}
+	}
+
+	// The proxy pid is part of the persisted agent state.
+	var agentState struct {
+		ProxyPid int
+	}
+	if err := p.storage.fetchAgentState(p.id, &agentState); err == nil && agentState.ProxyPid > 0 {
+		if u, err := processResourceUsage(agentState.ProxyPid); err == nil {
+			usage.Proxy = &u
+		}
+	}
+
+	for _, c := range p.containers {
+		if c.process.Pid <= 0 {
+			continue
+		}
+
+		u, err := processResourceUsage(c.process.Pid)
+		if err != nil {
+			continue
+		}
+
+		usage.Shims = append(usage.Shims, u)
+	}
+
+	return usage, nil
+}
+
+// overhead returns the fixed per pod cost of VM isolation, computed
+// from the current VM size and the host-side resource usage.
+func (p *Pod) overhead() (PodOverhead, error) {
+	overhead := PodOverhead{
+		VMMemoryMiB: uint32(p.config.VMConfig.Memory),
+	}
+
+	usage, err := p.resourceUsage()
+	if err != nil {
+		return PodOverhead{}, err
+	}
+
+	if usage.Hypervisor != nil {
+		overhead.HypervisorRSSBytes = usage.Hypervisor.RSSBytes
+	}
+
+	if usage.Proxy != nil {
+		overhead.ProxyRSSBytes = usage.Proxy.RSSBytes
+	}
+
+	return overhead, nil
+}
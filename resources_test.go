@@ -0,0 +1,121 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProcStat = "1234 (test proc) S 1 1234 1234 0 -1 4194560 1417 0 1 0 10 20 0 0 20 0 3 0 4284 1000000 1845 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0"
+
+const testProcStatus = "Name:\ttest proc\nVmPeak:\t  20000 kB\nVmRSS:\t  10000 kB\nThreads:\t3\n"
+
+func createTestProcEntry(t *testing.T, pid string, uuid string) string {
+	dir, err := ioutil.TempDir(testDir, "proc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pidPath := filepath.Join(dir, pid)
+	if err := os.MkdirAll(filepath.Join(pidPath, "fd"), dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(pidPath, "stat"), []byte(testProcStat), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(pidPath, "status"), []byte(testProcStatus), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmdline := "qemu-system-x86_64\x00-uuid\x00" + uuid + "\x00"
+	if err := ioutil.WriteFile(filepath.Join(pidPath, "cmdline"), []byte(cmdline), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fd := range []string{"0", "1"} {
+		if err := ioutil.WriteFile(filepath.Join(pidPath, "fd", fd), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestProcessResourceUsage(t *testing.T) {
+	savedProcPath := procPath
+	defer func() {
+		procPath = savedProcPath
+	}()
+
+	procPath = createTestProcEntry(t, "1234", "uuid")
+	defer os.RemoveAll(procPath)
+
+	usage, err := processResourceUsage(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ProcessResourceUsage{
+		Pid:          1234,
+		RSSBytes:     10000 * 1024,
+		CPUTimeTicks: 30,
+		OpenFdCount:  2,
+		ThreadCount:  3,
+	}
+
+	if usage != expected {
+		t.Fatalf("Got %+v\nExpecting %+v", usage, expected)
+	}
+}
+
+func TestProcessResourceUsageInvalidPid(t *testing.T) {
+	if _, err := processResourceUsage(-1); err == nil {
+		t.Fatal("Expected error for invalid pid")
+	}
+}
+
+func TestFindHypervisorPid(t *testing.T) {
+	savedProcPath := procPath
+	defer func() {
+		procPath = savedProcPath
+	}()
+
+	procPath = createTestProcEntry(t, "1234", "test-uuid")
+	defer os.RemoveAll(procPath)
+
+	pid, err := findHypervisorPid("test-uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pid != 1234 {
+		t.Fatalf("Got pid %d\nExpecting 1234", pid)
+	}
+
+	if _, err := findHypervisorPid("unknown-uuid"); err == nil {
+		t.Fatal("Expected error for unknown UUID")
+	}
+
+	if _, err := findHypervisorPid(""); err == nil {
+		t.Fatal("Expected error for empty UUID")
+	}
+}
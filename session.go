@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "fmt"
+
+// keepAliveProber is implemented by agents able to check that the
+// agent inside the guest still answers.
+type keepAliveProber interface {
+	keepAlive(pod *Pod) error
+}
+
+// sessionResumer is implemented by agents able to re-establish their
+// session after the proxy or the management process restarted,
+// re-registering the VM and its IO streams.
+type sessionResumer interface {
+	resumeSession(pod *Pod) error
+}
+
+// monitorReconnecter is implemented by hypervisors able to reconnect
+// to their monitor socket after the management process restarted.
+type monitorReconnecter interface {
+	reconnectMonitor() error
+}
+
+// adopt re-establishes the communication channels of a running pod:
+// the hypervisor monitor, the agent session and its IO streams. It is
+// the building block allowing a restarted management process to keep
+// managing pods it did not start.
+func (p *Pod) adopt() error {
+	state := p.state.State
+	if state != StateRunning {
+		return fmt.Errorf("Pod is not running, impossible to adopt it")
+	}
+
+	if reconnecter, ok := p.hypervisor.(monitorReconnecter); ok {
+		if err := reconnecter.reconnectMonitor(); err != nil {
+			return fmt.Errorf("Could not reconnect to the hypervisor monitor: %v", err)
+		}
+	}
+
+	if resumer, ok := p.agent.(sessionResumer); ok {
+		if err := resumer.resumeSession(p); err != nil {
+			return fmt.Errorf("Could not resume the agent session: %v", err)
+		}
+	}
+
+	if prober, ok := p.agent.(keepAliveProber); ok {
+		if err := prober.keepAlive(p); err != nil {
+			return fmt.Errorf("The pod agent does not answer: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+// sessionTestAgent is a noop agent recording the session calls it
+// received.
+type sessionTestAgent struct {
+	noopAgent
+	resumed bool
+	probed  bool
+}
+
+func (a *sessionTestAgent) resumeSession(pod *Pod) error {
+	a.resumed = true
+	return nil
+}
+
+func (a *sessionTestAgent) keepAlive(pod *Pod) error {
+	a.probed = true
+	return nil
+}
+
+// sessionTestHypervisor is a mock hypervisor recording monitor
+// reconnections.
+type sessionTestHypervisor struct {
+	mockHypervisor
+	reconnected bool
+}
+
+func (h *sessionTestHypervisor) reconnectMonitor() error {
+	h.reconnected = true
+	return nil
+}
+
+func TestPodAdopt(t *testing.T) {
+	agent := &sessionTestAgent{}
+	hypervisor := &sessionTestHypervisor{}
+
+	pod := &Pod{
+		id:         testPodID,
+		state:      State{State: StateRunning},
+		agent:      agent,
+		hypervisor: hypervisor,
+	}
+
+	if err := pod.adopt(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !hypervisor.reconnected {
+		t.Fatal("Expecting the hypervisor monitor to be reconnected")
+	}
+
+	if !agent.resumed || !agent.probed {
+		t.Fatal("Expecting the agent session to be resumed and probed")
+	}
+}
+
+func TestPodAdoptNotRunning(t *testing.T) {
+	pod := &Pod{
+		id:         testPodID,
+		state:      State{State: StateReady},
+		agent:      &noopAgent{},
+		hypervisor: &mockHypervisor{},
+	}
+
+	if err := pod.adopt(); err == nil {
+		t.Fatal("Adopting a pod that is not running should fail")
+	}
+}
+
+func TestPodAdoptNoCapabilities(t *testing.T) {
+	pod := &Pod{
+		id:         testPodID,
+		state:      State{State: StateRunning},
+		agent:      &noopAgent{},
+		hypervisor: &mockHypervisor{},
+	}
+
+	if err := pod.adopt(); err != nil {
+		t.Fatal(err)
+	}
+}
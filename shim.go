@@ -157,11 +157,23 @@ func prepareAndStartShim(pod *Pod, shim shim, cid, token, url string, cmd Cmd) (
 		StartTime: time.Now().UTC(),
 	}
 
+	console := cmd.Console
+	if console == "" && cmd.Interactive && cmd.ConsoleSocket != "" {
+		// OCI-style console handoff: allocate the terminal here,
+		// pass the master to the caller and give the slave to the
+		// container process.
+		var err error
+		console, err = setupConsoleSocket(cmd.ConsoleSocket)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	shimParams := ShimParams{
 		Container: cid,
 		Token:     token,
 		URL:       url,
-		Console:   cmd.Console,
+		Console:   console,
 		Terminal:  cmd.Interactive,
 		Detach:    cmd.Detach,
 	}
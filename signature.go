@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/containers/virtcontainers/pkg/annotations"
+)
+
+// signatureAnnotation returns the annotation carrying the detached
+// signature for a given asset type. Only boot critical assets coming
+// from untrusted annotations can be signed.
+func (t assetType) signatureAnnotation() (string, error) {
+	switch t {
+	case kernelAsset:
+		return annotations.KernelSignature, nil
+	case imageAsset:
+		return annotations.ImageSignature, nil
+	case initrdAsset:
+		return annotations.InitrdSignature, nil
+	case firmwareAsset:
+		return annotations.FirmwareSignature, nil
+	}
+
+	return "", fmt.Errorf("No signature annotation for asset type %s", t)
+}
+
+// loadTrustRoot parses a PEM encoded file containing one or more
+// trusted public keys (ECDSA or Ed25519) used to verify detached
+// asset signatures.
+func loadTrustRoot(path string) ([]crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []crypto.PublicKey
+
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid public key in trust root %s: %v", path, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No public key found in trust root %s", path)
+	}
+
+	return keys, nil
+}
+
+// verifySignature checks a detached signature over the asset content
+// against the given trusted public keys. ECDSA signatures are expected
+// to be ASN.1 encoded over the SHA-256 digest of the content, the
+// cosign convention, while Ed25519 signatures cover the raw content.
+func (a *asset) verifySignature(signature []byte, keys []crypto.PublicKey) error {
+	content, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(content)
+
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], signature) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, content, signature) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("Signature verification failed for %s asset %s", a.kind, a.path)
+}
+
+// verifyAssetSignatures verifies detached signatures for signable
+// custom assets whenever a trust root is configured. With a trust root,
+// an unsigned custom asset is rejected since its annotations cannot be
+// trusted.
+func verifyAssetSignatures(podConfig *PodConfig, assets []*asset) error {
+	trustRootPath := podConfig.HypervisorConfig.AssetTrustRootPath
+	if trustRootPath == "" {
+		return nil
+	}
+
+	keys, err := loadTrustRoot(trustRootPath)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if a == nil {
+			continue
+		}
+
+		sigAnnotation, err := a.kind.signatureAnnotation()
+		if err != nil {
+			// The hypervisor asset is the only type without a
+			// signature annotation, it is covered by the trusted
+			// hypervisor allow-list instead.
+			continue
+		}
+
+		sigEncoded, ok := podConfig.Annotations[sigAnnotation]
+		if !ok || sigEncoded == "" {
+			return fmt.Errorf("Missing signature for %s asset %s", a.kind, a.path)
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(sigEncoded)
+		if err != nil {
+			return fmt.Errorf("Invalid signature encoding for %s asset: %v", a.kind, err)
+		}
+
+		if err := a.verifySignature(signature, keys); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
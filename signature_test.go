@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/containers/virtcontainers/pkg/annotations"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTrustRoot(t *testing.T) (trustRootPath string, key *ecdsa.PrivateKey, cleanup func()) {
+	assert := assert.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(err)
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(err)
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubBytes,
+	})
+
+	trustRoot, err := ioutil.TempFile("", "virtcontainers-test-trust-root-")
+	assert.Nil(err)
+
+	_, err = trustRoot.Write(pemBytes)
+	assert.Nil(err)
+	trustRoot.Close()
+
+	return trustRoot.Name(), key, func() {
+		os.Remove(trustRoot.Name())
+	}
+}
+
+func signTestAsset(t *testing.T, key *ecdsa.PrivateKey, content []byte) string {
+	assert := assert.New(t)
+
+	digest := sha256.Sum256(content)
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.Nil(err)
+
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+func TestLoadTrustRootInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := loadTrustRoot("/this/path/does/not/exist")
+	assert.NotNil(err)
+
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	assert.Nil(err)
+
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name()) // clean up
+	}()
+
+	_, err = tmpfile.Write([]byte("not a PEM file"))
+	assert.Nil(err)
+
+	_, err = loadTrustRoot(tmpfile.Name())
+	assert.NotNil(err)
+}
+
+func TestVerifyAssetSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	trustRootPath, key, cleanup := createTestTrustRoot(t)
+	defer cleanup()
+
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	assert.Nil(err)
+
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name()) // clean up
+	}()
+
+	_, err = tmpfile.Write(assetContent)
+	assert.Nil(err)
+
+	p := &PodConfig{
+		Annotations: map[string]string{
+			annotations.KernelPath:      tmpfile.Name(),
+			annotations.KernelSignature: signTestAsset(t, key, assetContent),
+		},
+		HypervisorConfig: HypervisorConfig{
+			AssetTrustRootPath: trustRootPath,
+		},
+	}
+
+	kernel, err := newAsset(p, kernelAsset)
+	assert.Nil(err)
+	assert.NotNil(kernel)
+
+	err = verifyAssetSignatures(p, []*asset{kernel})
+	assert.Nil(err)
+
+	// An unsigned asset must be rejected when a trust root is set.
+	delete(p.Annotations, annotations.KernelSignature)
+	err = verifyAssetSignatures(p, []*asset{kernel})
+	assert.NotNil(err)
+
+	// A corrupted signature must be rejected.
+	p.Annotations[annotations.KernelSignature] = signTestAsset(t, key, []byte("some other content"))
+	err = verifyAssetSignatures(p, []*asset{kernel})
+	assert.NotNil(err)
+
+	// Without a trust root, signatures are not enforced.
+	p.HypervisorConfig.AssetTrustRootPath = ""
+	delete(p.Annotations, annotations.KernelSignature)
+	err = verifyAssetSignatures(p, []*asset{kernel})
+	assert.Nil(err)
+}
+
+func TestVerifyAssetSignaturesInitrdFirmware(t *testing.T) {
+	assert := assert.New(t)
+
+	trustRootPath, key, cleanup := createTestTrustRoot(t)
+	defer cleanup()
+
+	tmpfile, err := ioutil.TempFile("", "virtcontainers-test-")
+	assert.Nil(err)
+
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name()) // clean up
+	}()
+
+	_, err = tmpfile.Write(assetContent)
+	assert.Nil(err)
+
+	p := &PodConfig{
+		Annotations: map[string]string{
+			annotations.InitrdPath:   tmpfile.Name(),
+			annotations.FirmwarePath: tmpfile.Name(),
+		},
+		HypervisorConfig: HypervisorConfig{
+			AssetTrustRootPath: trustRootPath,
+		},
+	}
+
+	initrd, err := newAsset(p, initrdAsset)
+	assert.Nil(err)
+	assert.NotNil(initrd)
+
+	firmware, err := newAsset(p, firmwareAsset)
+	assert.Nil(err)
+	assert.NotNil(firmware)
+
+	// Initrd and firmware are boot critical too, unsigned ones must
+	// be rejected when a trust root is set.
+	err = verifyAssetSignatures(p, []*asset{initrd, firmware})
+	assert.NotNil(err)
+
+	p.Annotations[annotations.InitrdSignature] = signTestAsset(t, key, assetContent)
+	err = verifyAssetSignatures(p, []*asset{initrd, firmware})
+	assert.NotNil(err)
+
+	p.Annotations[annotations.FirmwareSignature] = signTestAsset(t, key, assetContent)
+	err = verifyAssetSignatures(p, []*asset{initrd, firmware})
+	assert.Nil(err)
+}
@@ -0,0 +1,79 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+// ContainerResources is the subset of the OCI resources relevant to
+// VM sizing.
+type ContainerResources struct {
+	// CPUQuota and CPUPeriod bound the CPU time of the container, a
+	// quota of -1 or 0 means no bound.
+	CPUQuota  int64
+	CPUPeriod uint64
+
+	// CPUShares is the relative CPU weight of the container, only
+	// used for sizing when no quota is set.
+	CPUShares uint64
+
+	// MemoryLimitBytes is the memory limit of the container, 0
+	// means no limit.
+	MemoryLimitBytes int64
+}
+
+// sharesPerVCPU is the conventional amount of CPU shares representing
+// a full CPU.
+const sharesPerVCPU = 1024
+
+// vcpus converts the container CPU parameters into a number of vCPUs,
+// rounding up. The quota is authoritative when set, the shares are a
+// fallback.
+func (r ContainerResources) vcpus() uint {
+	if r.CPUQuota > 0 && r.CPUPeriod > 0 {
+		return uint((uint64(r.CPUQuota) + r.CPUPeriod - 1) / r.CPUPeriod)
+	}
+
+	if r.CPUShares > 0 {
+		return uint((r.CPUShares + sharesPerVCPU - 1) / sharesPerVCPU)
+	}
+
+	return 0
+}
+
+// memoryMiB converts the container memory limit into MiB, rounding
+// up.
+func (r ContainerResources) memoryMiB() uint {
+	if r.MemoryLimitBytes <= 0 {
+		return 0
+	}
+
+	return uint((uint64(r.MemoryLimitBytes) + (1 << 20) - 1) >> 20)
+}
+
+// vmSizing aggregates the resources of every container in the pod
+// configuration into a VM sizing recommendation. Containers without
+// explicit limits contribute nothing, so a pod where no container is
+// constrained yields an empty recommendation and the configured
+// defaults apply.
+func (podConfig *PodConfig) vmSizing() Resources {
+	var sizing Resources
+
+	for _, container := range podConfig.Containers {
+		sizing.VCPUs += container.Resources.vcpus()
+		sizing.Memory += container.Resources.memoryMiB()
+	}
+
+	return sizing
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+func TestContainerResourcesVCPUs(t *testing.T) {
+	tests := []struct {
+		resources ContainerResources
+		expected  uint
+	}{
+		{ContainerResources{}, 0},
+		{ContainerResources{CPUQuota: 100000, CPUPeriod: 100000}, 1},
+		{ContainerResources{CPUQuota: 150000, CPUPeriod: 100000}, 2},
+		{ContainerResources{CPUQuota: -1, CPUPeriod: 100000}, 0},
+		{ContainerResources{CPUShares: 512}, 1},
+		{ContainerResources{CPUShares: 2048}, 2},
+		{ContainerResources{CPUQuota: 100000, CPUPeriod: 100000, CPUShares: 4096}, 1},
+	}
+
+	for _, test := range tests {
+		if vcpus := test.resources.vcpus(); vcpus != test.expected {
+			t.Fatalf("Got %d vCPUs for %+v\nExpecting %d", vcpus, test.resources, test.expected)
+		}
+	}
+}
+
+func TestContainerResourcesMemoryMiB(t *testing.T) {
+	tests := []struct {
+		resources ContainerResources
+		expected  uint
+	}{
+		{ContainerResources{}, 0},
+		{ContainerResources{MemoryLimitBytes: -1}, 0},
+		{ContainerResources{MemoryLimitBytes: 1 << 20}, 1},
+		{ContainerResources{MemoryLimitBytes: (1 << 20) + 1}, 2},
+		{ContainerResources{MemoryLimitBytes: 512 << 20}, 512},
+	}
+
+	for _, test := range tests {
+		if mem := test.resources.memoryMiB(); mem != test.expected {
+			t.Fatalf("Got %d MiB for %+v\nExpecting %d", mem, test.resources, test.expected)
+		}
+	}
+}
+
+func TestPodConfigVMSizing(t *testing.T) {
+	podConfig := &PodConfig{
+		Containers: []ContainerConfig{
+			{
+				Resources: ContainerResources{
+					CPUQuota:         150000,
+					CPUPeriod:        100000,
+					MemoryLimitBytes: 256 << 20,
+				},
+			},
+			{
+				Resources: ContainerResources{
+					CPUShares:        512,
+					MemoryLimitBytes: 128 << 20,
+				},
+			},
+			{},
+		},
+	}
+
+	sizing := podConfig.vmSizing()
+
+	if sizing.VCPUs != 3 {
+		t.Fatalf("Got %d vCPUs\nExpecting 3", sizing.VCPUs)
+	}
+
+	if sizing.Memory != 384 {
+		t.Fatalf("Got %d MiB\nExpecting 384", sizing.Memory)
+	}
+}
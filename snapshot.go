@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotHandle identifies one storage snapshot taken from a pod, for
+// consumption by backup tooling.
+type SnapshotHandle struct {
+	// ContainerID is the container the snapshotted drive belongs to.
+	ContainerID string
+
+	// DeviceID is the hypervisor identifier of the snapshotted
+	// drive.
+	DeviceID string
+
+	// File is the host path of the snapshot overlay. The previous
+	// drive file holds the frozen snapshot content.
+	File string
+}
+
+// fsFreezer is implemented by agents able to freeze and thaw the
+// filesystems inside the guest, so that storage snapshots are taken on
+// quiesced data.
+type fsFreezer interface {
+	freezeFilesystems(pod *Pod) error
+	thawFilesystems(pod *Pod) error
+}
+
+// BackupHandle identifies one storage backup taken from a pod.
+type BackupHandle struct {
+	// ContainerID is the container the backed up drive belongs to.
+	ContainerID string
+
+	// DeviceID is the hypervisor identifier of the backed up drive.
+	DeviceID string
+
+	// File is the host path the drive content is exported to.
+	File string
+
+	// Incremental tells whether the file only holds the blocks
+	// changed since the last backup.
+	Incremental bool
+}
+
+// backupStorage exports the content of every block drive attached to
+// the pod containers. An incremental backup only exports the blocks
+// written since the last backup, as recorded by the drive dirty
+// bitmaps.
+func (p *Pod) backupStorage(incremental bool) ([]BackupHandle, error) {
+	if p.state.State != StateRunning {
+		return nil, fmt.Errorf("Pod not running, impossible to back up its storage")
+	}
+
+	var handles []BackupHandle
+
+	for _, c := range p.containers {
+		if !c.isDriveUsed() || !c.state.HotpluggedDrive {
+			continue
+		}
+
+		devID := makeNameID("drive", c.id)
+		target := filepath.Join(runStoragePath, p.id, c.id,
+			fmt.Sprintf("backup-%d.qcow2", time.Now().UnixNano()))
+
+		if err := p.hypervisor.backupBlockDevice(devID, target, incremental); err != nil {
+			return nil, err
+		}
+
+		handles = append(handles, BackupHandle{
+			ContainerID: c.id,
+			DeviceID:    devID,
+			File:        target,
+			Incremental: incremental,
+		})
+	}
+
+	return handles, nil
+}
+
+// snapshotStorage takes a snapshot of every block drive attached to
+// the pod containers. When the agent can freeze the guest filesystems
+// the snapshots are filesystem consistent, otherwise they are crash
+// consistent only.
+func (p *Pod) snapshotStorage() ([]SnapshotHandle, error) {
+	if p.state.State != StateRunning {
+		return nil, fmt.Errorf("Pod not running, impossible to snapshot its storage")
+	}
+
+	if freezer, ok := p.agent.(fsFreezer); ok {
+		if err := freezer.freezeFilesystems(p); err != nil {
+			return nil, err
+		}
+		defer freezer.thawFilesystems(p)
+	} else {
+		p.Logger().Warn("Agent cannot freeze the guest filesystems, snapshots will be crash consistent")
+	}
+
+	var handles []SnapshotHandle
+
+	for _, c := range p.containers {
+		if !c.isDriveUsed() || !c.state.HotpluggedDrive {
+			continue
+		}
+
+		devID := makeNameID("drive", c.id)
+		snapshotFile := filepath.Join(runStoragePath, p.id, c.id,
+			fmt.Sprintf("snapshot-%d.qcow2", time.Now().UnixNano()))
+
+		if err := p.hypervisor.snapshotBlockDevice(devID, snapshotFile); err != nil {
+			return nil, err
+		}
+
+		handles = append(handles, SnapshotHandle{
+			ContainerID: c.id,
+			DeviceID:    devID,
+			File:        snapshotFile,
+		})
+	}
+
+	return handles, nil
+}
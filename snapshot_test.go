@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"testing"
+)
+
+func TestSnapshotStorageNotRunning(t *testing.T) {
+	pod := &Pod{
+		id:    testPodID,
+		state: State{State: StateReady},
+	}
+
+	if _, err := pod.snapshotStorage(); err == nil {
+		t.Fatal("Snapshotting a pod that is not running should fail")
+	}
+}
+
+func TestSnapshotStorageNoDrives(t *testing.T) {
+	pod := &Pod{
+		id:         testPodID,
+		state:      State{State: StateRunning},
+		agent:      &noopAgent{},
+		hypervisor: &mockHypervisor{},
+	}
+
+	pod.containers = []*Container{
+		{
+			id:  "100",
+			pod: pod,
+		},
+	}
+
+	handles, err := pod.snapshotStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handles) != 0 {
+		t.Fatalf("Got %d snapshot handles\nExpecting none", len(handles))
+	}
+}
+
+func TestBackupStorageNotRunning(t *testing.T) {
+	pod := &Pod{
+		id:    testPodID,
+		state: State{State: StateReady},
+	}
+
+	if _, err := pod.backupStorage(true); err == nil {
+		t.Fatal("Backing up a pod that is not running should fail")
+	}
+}
+
+func TestBackupPodFailures(t *testing.T) {
+	if _, err := BackupPod("", false); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	cleanUp()
+
+	if _, err := BackupPod(testPodID, true); err == nil {
+		t.Fatal("Backing up a non existing pod should fail")
+	}
+}
+
+func TestDirtyBitmapName(t *testing.T) {
+	if name := dirtyBitmapName("drive-100"); name != "backup-drive-100" {
+		t.Fatalf("Got bitmap name %s\nExpecting backup-drive-100", name)
+	}
+}
+
+func TestSnapshotPodFailures(t *testing.T) {
+	if _, err := SnapshotPod(""); err != errNeedPodID {
+		t.Fatalf("Expected errNeedPodID, got %v", err)
+	}
+
+	cleanUp()
+
+	if _, err := SnapshotPod(testPodID); err == nil {
+		t.Fatal("Snapshotting a non existing pod should fail")
+	}
+}
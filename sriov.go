@@ -0,0 +1,143 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// VFConfig describes the identity programmed on an SR-IOV virtual
+// function through its physical function before the VF is passed
+// through, so the guest sees the expected MAC, VLAN and trust state.
+type VFConfig struct {
+	// PFName is the host physical function netdevice, e.g. "eth0".
+	PFName string
+
+	// VFIndex is the virtual function index on the PF.
+	VFIndex int
+
+	// MAC is the hardware address the guest should see. An empty
+	// MAC keeps the driver assigned one.
+	MAC string
+
+	// VLAN is the VLAN ID enforced on the VF, 0 meaning none.
+	VLAN int
+
+	// Trusted marks the VF as trusted, allowing the guest to change
+	// its MAC or enable promiscuous mode.
+	Trusted bool
+}
+
+// setupVF programs one virtual function identity on its physical
+// function.
+func setupVF(vf VFConfig) error {
+	link, err := netlink.LinkByName(vf.PFName)
+	if err != nil {
+		return fmt.Errorf("Could not find the physical function %s: %v", vf.PFName, err)
+	}
+
+	if vf.MAC != "" {
+		hwAddr, err := net.ParseMAC(vf.MAC)
+		if err != nil {
+			return fmt.Errorf("Invalid MAC address %s for VF %d on %s: %v", vf.MAC, vf.VFIndex, vf.PFName, err)
+		}
+
+		if err := netlink.LinkSetVfHardwareAddr(link, vf.VFIndex, hwAddr); err != nil {
+			return err
+		}
+	}
+
+	if vf.VLAN != 0 {
+		if err := netlink.LinkSetVfVlan(link, vf.VFIndex, vf.VLAN); err != nil {
+			return err
+		}
+	}
+
+	if vf.Trusted {
+		if err := netlink.LinkSetVfTrust(link, vf.VFIndex, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearVF restores the default state of a virtual function on its
+// physical function: an all zero MAC lets the driver assign a fresh
+// one, no VLAN filtering and no trust.
+func clearVF(vf VFConfig) error {
+	link, err := netlink.LinkByName(vf.PFName)
+	if err != nil {
+		return fmt.Errorf("Could not find the physical function %s: %v", vf.PFName, err)
+	}
+
+	var firstErr error
+
+	if vf.MAC != "" {
+		zeroAddr := make(net.HardwareAddr, 6)
+		if err := netlink.LinkSetVfHardwareAddr(link, vf.VFIndex, zeroAddr); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if vf.VLAN != 0 {
+		if err := netlink.LinkSetVfVlan(link, vf.VFIndex, 0); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if vf.Trusted {
+		if err := netlink.LinkSetVfTrust(link, vf.VFIndex, false); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// setupVFs programs every configured virtual function, undoing the
+// already programmed ones if any of them fails.
+func setupVFs(config NetworkConfig) error {
+	for i, vf := range config.VFConfigs {
+		if err := setupVF(vf); err != nil {
+			for _, programmed := range config.VFConfigs[:i] {
+				clearVF(programmed)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearVFs restores the physical function state for every configured
+// virtual function, returning the first error encountered.
+func clearVFs(config NetworkConfig) error {
+	var firstErr error
+
+	for _, vf := range config.VFConfigs {
+		if err := clearVF(vf); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,57 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "testing"
+
+func TestSetupVFsNoVF(t *testing.T) {
+	if err := setupVFs(NetworkConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clearVFs(NetworkConfig{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetupVFMissingPF(t *testing.T) {
+	vf := VFConfig{
+		PFName:  "nonexistent-pf0",
+		VFIndex: 0,
+		MAC:     "02:00:ca:fe:00:01",
+	}
+
+	if err := setupVF(vf); err == nil {
+		t.Fatal("Programming a VF on a missing PF should fail")
+	}
+
+	if err := clearVF(vf); err == nil {
+		t.Fatal("Clearing a VF on a missing PF should fail")
+	}
+}
+
+func TestSetupVFInvalidMAC(t *testing.T) {
+	vf := VFConfig{
+		PFName:  "lo",
+		VFIndex: 0,
+		MAC:     "not-a-mac",
+	}
+
+	if err := setupVF(vf); err == nil {
+		t.Fatal("An invalid VF MAC address should be rejected")
+	}
+}
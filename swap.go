@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GuestSwapConfig describes the swap device to create inside the guest.
+type GuestSwapConfig struct {
+	// Enabled turns on a swap device inside the guest, backed by a
+	// sparse host file attached as a block device.
+	Enabled bool
+
+	// SizeMiB is the size of the swap device in MiB, it is required
+	// when Enabled is set.
+	SizeMiB uint
+}
+
+// swapFileName is the name of the host file backing the guest swap
+// device, relative to the pod run path.
+const swapFileName = "swap"
+
+// createSwapFile creates the sparse host file backing the guest swap
+// device and returns its path.
+func (p *Pod) createSwapFile() (string, error) {
+	if p.config.GuestSwap.SizeMiB == 0 {
+		return "", fmt.Errorf("Guest swap requires a non-zero size")
+	}
+
+	if err := os.MkdirAll(p.runPath, dirMode); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(p.runPath, swapFileName)
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(int64(p.config.GuestSwap.SizeMiB) << 20); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// attachSwapDevice creates the swap backing file and attaches it to the
+// VM as a block device. The predicted guest device path is stored in
+// the pod state so the agent can turn the device into swap space.
+func (p *Pod) attachSwapDevice() error {
+	if !p.config.GuestSwap.Enabled {
+		return nil
+	}
+
+	path, err := p.createSwapFile()
+	if err != nil {
+		return err
+	}
+
+	index, err := p.getAndSetPodBlockIndex()
+	if err != nil {
+		return err
+	}
+
+	driveName, err := getVirtDriveName(index)
+	if err != nil {
+		return err
+	}
+
+	p.state.GuestSwapDevice = filepath.Join(devPath, driveName)
+	if err := p.storage.storePodResource(p.id, stateFileType, p.state); err != nil {
+		return err
+	}
+
+	drive := Drive{
+		File:   path,
+		Format: "raw",
+		ID:     makeNameID("swap", p.id),
+	}
+
+	return p.hypervisor.addDevice(drive, blockDev)
+}
@@ -0,0 +1,82 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSwapPod(t *testing.T, config *PodConfig) *Pod {
+	runPath, err := ioutil.TempDir(testDir, "swap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Pod{
+		id:      "swap-test-pod",
+		config:  config,
+		runPath: runPath,
+	}
+}
+
+func TestCreateSwapFile(t *testing.T) {
+	config := &PodConfig{
+		GuestSwap: GuestSwapConfig{
+			Enabled: true,
+			SizeMiB: 64,
+		},
+	}
+
+	pod := newTestSwapPod(t, config)
+	defer os.RemoveAll(pod.runPath)
+
+	path, err := pod.createSwapFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != filepath.Join(pod.runPath, swapFileName) {
+		t.Fatalf("Got unexpected swap path %q", path)
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fileInfo.Size() != 64<<20 {
+		t.Fatalf("Got swap file size %d\nExpecting %d", fileInfo.Size(), 64<<20)
+	}
+}
+
+func TestCreateSwapFileZeroSize(t *testing.T) {
+	config := &PodConfig{
+		GuestSwap: GuestSwapConfig{
+			Enabled: true,
+		},
+	}
+
+	pod := newTestSwapPod(t, config)
+	defer os.RemoveAll(pod.runPath)
+
+	if _, err := pod.createSwapFile(); err == nil {
+		t.Fatal("Zero sized swap file should fail")
+	}
+}
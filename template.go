@@ -0,0 +1,202 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// vmTemplateVersion is the current template manifest format version.
+// Templates with a different version are rejected at load time.
+const vmTemplateVersion = 1
+
+// Template file names inside a template directory.
+const (
+	vmTemplateManifestFile = "template.json"
+	vmTemplateMemoryFile   = "memory.bin"
+)
+
+// VMTemplate is the manifest of a golden VM snapshot built from a
+// kernel, guest image and agent combination. The memory file holds the
+// guest memory and device state of a booted VM whose agent is ready,
+// so that a pod factory can resume clones of it instead of cold
+// booting every pod.
+type VMTemplate struct {
+	// Version is the manifest format version the template was
+	// created with.
+	Version int `json:"version"`
+
+	// Kernel and Image are the boot assets the template was built
+	// from, with their digests recorded so that a stale template
+	// cannot be consumed after its assets changed.
+	Kernel       string `json:"kernel"`
+	Image        string `json:"image"`
+	KernelDigest string `json:"kernelDigest"`
+	ImageDigest  string `json:"imageDigest"`
+
+	// AgentType is the agent the template was booted with.
+	AgentType AgentType `json:"agentType"`
+
+	// MemoryFile is the host path of the guest memory and device
+	// state snapshot.
+	MemoryFile string `json:"memoryFile"`
+
+	// CreatedAt is the template creation time.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// fileDigest returns the hex encoded SHA-256 digest of a file.
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CreateVMTemplate is the virtcontainers template creation entry
+// point. It boots a throwaway pod from the given configuration, waits
+// for the agent to be ready, snapshots the guest memory and device
+// state into templateDir and tears the pod down again. The returned
+// manifest is also stored in the template directory.
+func CreateVMTemplate(templateDir string, podConfig PodConfig) (template *VMTemplate, err error) {
+	defer auditTrail.record("CreateVMTemplate", podConfig.ID, "", time.Now(), &err)
+
+	if templateDir == "" {
+		return nil, fmt.Errorf("Missing template directory")
+	}
+
+	if err := os.MkdirAll(templateDir, dirMode); err != nil {
+		return nil, err
+	}
+
+	// The template pod must not run a workload, its only purpose is
+	// to reach the agent ready state.
+	podConfig.Containers = nil
+
+	p, err := createPodFromConfig(podConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pod is torn down whatever happens, a template pod never
+	// outlives the template creation.
+	defer func() {
+		if delErr := p.stopVM(); delErr != nil {
+			p.Logger().WithError(delErr).Warn("Could not stop the template pod VM")
+		}
+
+		if delErr := p.delete(); delErr != nil {
+			p.Logger().WithError(delErr).Warn("Could not delete the template pod")
+		}
+	}()
+
+	memoryFile := filepath.Join(templateDir, vmTemplateMemoryFile)
+
+	if err := p.hypervisor.saveState(memoryFile); err != nil {
+		return nil, err
+	}
+
+	kernelDigest, err := fileDigest(p.config.HypervisorConfig.KernelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	imageDigest, err := fileDigest(p.config.HypervisorConfig.ImagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	template = &VMTemplate{
+		Version:      vmTemplateVersion,
+		Kernel:       p.config.HypervisorConfig.KernelPath,
+		Image:        p.config.HypervisorConfig.ImagePath,
+		KernelDigest: kernelDigest,
+		ImageDigest:  imageDigest,
+		AgentType:    p.config.AgentType,
+		MemoryFile:   memoryFile,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	data, err := json.MarshalIndent(template, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(templateDir, vmTemplateManifestFile), data, 0640); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// LoadVMTemplate is the virtcontainers template loading entry point.
+// It reads a template manifest and validates it: the format version
+// must match and the boot assets must not have changed since the
+// template was created.
+func LoadVMTemplate(templateDir string) (template *VMTemplate, err error) {
+	defer auditTrail.record("LoadVMTemplate", "", "", time.Now(), &err)
+
+	data, err := ioutil.ReadFile(filepath.Join(templateDir, vmTemplateManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	template = &VMTemplate{}
+	if err := json.Unmarshal(data, template); err != nil {
+		return nil, err
+	}
+
+	if template.Version != vmTemplateVersion {
+		return nil, fmt.Errorf("Unsupported template version %d, expecting %d", template.Version, vmTemplateVersion)
+	}
+
+	for _, check := range []struct {
+		kind   string
+		path   string
+		digest string
+	}{
+		{"kernel", template.Kernel, template.KernelDigest},
+		{"image", template.Image, template.ImageDigest},
+	} {
+		digest, err := fileDigest(check.path)
+		if err != nil {
+			return nil, fmt.Errorf("Could not validate the template %s: %v", check.kind, err)
+		}
+
+		if digest != check.digest {
+			return nil, fmt.Errorf("The template %s %s changed since the template was created", check.kind, check.path)
+		}
+	}
+
+	return template, nil
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateVMTemplateMissingDir(t *testing.T) {
+	if _, err := CreateVMTemplate("", PodConfig{}); err == nil {
+		t.Fatal("Creating a template without a directory should fail")
+	}
+}
+
+func TestCreateAndLoadVMTemplate(t *testing.T) {
+	cleanUp()
+
+	templateDir, err := ioutil.TempDir("", "vc-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(templateDir)
+
+	config := newTestPodConfigNoop()
+
+	template, err := CreateVMTemplate(templateDir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if template.Version != vmTemplateVersion {
+		t.Fatalf("Got template version %d\nExpecting %d", template.Version, vmTemplateVersion)
+	}
+
+	if template.KernelDigest == "" || template.ImageDigest == "" {
+		t.Fatal("The template must record the boot asset digests")
+	}
+
+	loaded, err := LoadVMTemplate(templateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.KernelDigest != template.KernelDigest {
+		t.Fatalf("Got kernel digest %s\nExpecting %s", loaded.KernelDigest, template.KernelDigest)
+	}
+
+	// A modified asset invalidates the template.
+	if err := ioutil.WriteFile(config.HypervisorConfig.ImagePath, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadVMTemplate(templateDir); err == nil {
+		t.Fatal("Loading a template with a changed image should fail")
+	}
+}
+
+func TestLoadVMTemplateBadVersion(t *testing.T) {
+	templateDir, err := ioutil.TempDir("", "vc-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(templateDir)
+
+	manifest := []byte(`{"version": 99}`)
+	if err := ioutil.WriteFile(filepath.Join(templateDir, vmTemplateManifestFile), manifest, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadVMTemplate(templateDir); err == nil {
+		t.Fatal("Loading a template with an unknown version should fail")
+	}
+}
@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig holds the material needed to establish a mutually
+// authenticated TLS channel to a pod component, such as the guest
+// agent gRPC channel or the proxy control socket.
+type TLSConfig struct {
+	// Enabled turns TLS on for the channel. All other fields are
+	// ignored when it is false.
+	Enabled bool
+
+	// CACertPath is the path to the PEM encoded certificate
+	// authority used to verify the peer certificate.
+	CACertPath string
+
+	// CertPath and KeyPath are the paths to the PEM encoded client
+	// certificate and private key presented to the peer for mutual
+	// authentication.
+	CertPath string
+	KeyPath  string
+
+	// ServerName overrides the name expected in the peer
+	// certificate. It is optional.
+	ServerName string
+}
+
+// clientTLSConfig builds the tls.Config used to dial a TLS enabled
+// channel with the client certificate loaded.
+func (c TLSConfig) clientTLSConfig() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertPath, c.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load client certificate: %s", err)
+	}
+
+	caCert, err := ioutil.ReadFile(c.CACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read CA certificate: %s", err)
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("Could not parse CA certificate %s", c.CACertPath)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caCertPool,
+		ServerName:   c.ServerName,
+	}, nil
+}
@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createTestCertificate writes a self-signed certificate and its key
+// under dir and returns their paths.
+func createTestCertificate(t *testing.T, dir string) (certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "virtcontainers-test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath = filepath.Join(dir, "cert.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(certPath, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyPath = filepath.Join(dir, "key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certPath, keyPath
+}
+
+func TestClientTLSConfigDisabled(t *testing.T) {
+	tlsConfig, err := TLSConfig{}.clientTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tlsConfig != nil {
+		t.Fatalf("Got %+v\nExpecting nil", tlsConfig)
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "virtc-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath, keyPath := createTestCertificate(t, dir)
+
+	config := TLSConfig{
+		Enabled:    true,
+		CACertPath: certPath,
+		CertPath:   certPath,
+		KeyPath:    keyPath,
+		ServerName: "agent",
+	}
+
+	tlsConfig, err := config.clientTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("Got %d certificates\nExpecting 1", len(tlsConfig.Certificates))
+	}
+
+	if tlsConfig.RootCAs == nil {
+		t.Fatal("Expecting a CA certificate pool")
+	}
+
+	if tlsConfig.ServerName != "agent" {
+		t.Fatalf("Got server name %q\nExpecting %q", tlsConfig.ServerName, "agent")
+	}
+
+	config.CACertPath = filepath.Join(dir, "missing.pem")
+	if _, err := config.clientTLSConfig(); err == nil {
+		t.Fatal("Expecting an error with a missing CA certificate")
+	}
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import "fmt"
+
+// Supported guest clock sources.
+const (
+	// ClockKvm is the paravirtualized kvm-clock source, the
+	// default.
+	ClockKvm = "kvm-clock"
+
+	// ClockTSC is the raw TSC clock source.
+	ClockTSC = "tsc"
+)
+
+// TuningConfig groups the guest time and entropy tuning knobs.
+type TuningConfig struct {
+	// ClockSource selects the guest clock source, ClockKvm
+	// (default) or ClockTSC.
+	ClockSource string
+
+	// HPET enables the guest HPET timer, it is disabled by
+	// default.
+	HPET bool
+
+	// RNGBytesPerPeriod rate limits the virtio-rng device to that
+	// many bytes per period, 0 disables both the device and the
+	// rate limiting.
+	RNGBytesPerPeriod uint
+
+	// RNGPeriodMs is the rate limiting period in milliseconds, it
+	// defaults to 1000 when RNGBytesPerPeriod is set.
+	RNGPeriodMs uint
+}
+
+// validate checks the tuning values.
+func (t TuningConfig) validate() error {
+	switch t.ClockSource {
+	case "", ClockKvm, ClockTSC:
+	default:
+		return fmt.Errorf("Unknown clock source %s", t.ClockSource)
+	}
+
+	return nil
+}
+
+// clockKernelParams returns the guest kernel parameters selecting the
+// configured clock source.
+func (t TuningConfig) clockKernelParams() []Param {
+	if t.ClockSource == ClockTSC {
+		return []Param{
+			{"clocksource", "tsc"},
+			{"tsc", "reliable"},
+			{"no_timer_check", ""},
+		}
+	}
+
+	return []Param{
+		{"clocksource", "kvm-clock"},
+		{"no_timer_check", ""},
+	}
+}
+
+// rngPeriodMs returns the effective virtio-rng rate limiting period.
+func (t TuningConfig) rngPeriodMs() uint {
+	if t.RNGPeriodMs == 0 {
+		return 1000
+	}
+
+	return t.RNGPeriodMs
+}
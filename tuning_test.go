@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTuningConfigValidate(t *testing.T) {
+	for _, source := range []string{"", ClockKvm, ClockTSC} {
+		config := TuningConfig{ClockSource: source}
+		if err := config.validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := TuningConfig{ClockSource: "hpet"}
+	if err := config.validate(); err == nil {
+		t.Fatal("Unknown clock source should fail")
+	}
+}
+
+func TestTuningConfigClockKernelParams(t *testing.T) {
+	kvmParams := []Param{
+		{"clocksource", "kvm-clock"},
+		{"no_timer_check", ""},
+	}
+
+	tscParams := []Param{
+		{"clocksource", "tsc"},
+		{"tsc", "reliable"},
+		{"no_timer_check", ""},
+	}
+
+	tests := []struct {
+		config   TuningConfig
+		expected []Param
+	}{
+		{TuningConfig{}, kvmParams},
+		{TuningConfig{ClockSource: ClockKvm}, kvmParams},
+		{TuningConfig{ClockSource: ClockTSC}, tscParams},
+	}
+
+	for _, test := range tests {
+		params := test.config.clockKernelParams()
+		if !reflect.DeepEqual(params, test.expected) {
+			t.Fatalf("Got %v for %+v\nExpecting %v", params, test.config, test.expected)
+		}
+	}
+}
+
+func TestTuningConfigRNGPeriodMs(t *testing.T) {
+	config := TuningConfig{RNGBytesPerPeriod: 1024}
+	if period := config.rngPeriodMs(); period != 1000 {
+		t.Fatalf("Got default period %d\nExpecting 1000", period)
+	}
+
+	config.RNGPeriodMs = 100
+	if period := config.rngPeriodMs(); period != 100 {
+		t.Fatalf("Got period %d\nExpecting 100", period)
+	}
+}
@@ -0,0 +1,78 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// CPUDevice describes a set of dedicated vCPUs hotplugged into the VM
+// for a single container.
+type CPUDevice struct {
+	// ID identifies the container owning the vCPUs.
+	ID string
+
+	// Count is the number of vCPUs to hotplug.
+	Count uint32
+
+	// HostCPUs is the set of host CPUs the vCPU threads should be
+	// pinned to. An empty set means no host pinning.
+	HostCPUs []int
+}
+
+// cpuSetMask converts a list of CPU numbers into the bitmask expected
+// by sched_setaffinity.
+func cpuSetMask(cpus []int) ([]uint64, error) {
+	var mask []uint64
+
+	for _, cpu := range cpus {
+		if cpu < 0 {
+			return nil, fmt.Errorf("Invalid CPU number %d", cpu)
+		}
+
+		for len(mask) <= cpu/64 {
+			mask = append(mask, 0)
+		}
+
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+
+	if len(mask) == 0 {
+		return nil, fmt.Errorf("Empty CPU set")
+	}
+
+	return mask, nil
+}
+
+// pinThreadToCPUs restricts the given thread to the provided host CPU
+// set with sched_setaffinity.
+func pinThreadToCPUs(tid int, cpus []int) error {
+	mask, err := cpuSetMask(cpus)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_SCHED_SETAFFINITY,
+		uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return fmt.Errorf("Could not pin thread %d: %v", tid, errno)
+	}
+
+	return nil
+}
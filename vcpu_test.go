@@ -0,0 +1,70 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCPUSetMask(t *testing.T) {
+	tests := []struct {
+		cpus     []int
+		expected []uint64
+	}{
+		{[]int{0}, []uint64{1}},
+		{[]int{0, 1}, []uint64{3}},
+		{[]int{63}, []uint64{1 << 63}},
+		{[]int{64}, []uint64{0, 1}},
+		{[]int{1, 65}, []uint64{2, 2}},
+	}
+
+	for _, test := range tests {
+		mask, err := cpuSetMask(test.cpus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(mask, test.expected) {
+			t.Fatalf("Got mask %v for CPUs %v\nExpecting %v", mask, test.cpus, test.expected)
+		}
+	}
+}
+
+func TestCPUSetMaskInvalid(t *testing.T) {
+	if _, err := cpuSetMask(nil); err == nil {
+		t.Fatal("Empty CPU set should fail")
+	}
+
+	if _, err := cpuSetMask([]int{-1}); err == nil {
+		t.Fatal("Negative CPU number should fail")
+	}
+}
+
+func TestPinThreadToCPUs(t *testing.T) {
+	// Pinning the current thread to every online CPU does not
+	// change its affinity.
+	var cpus []int
+	for cpu := 0; cpu < runtime.NumCPU(); cpu++ {
+		cpus = append(cpus, cpu)
+	}
+
+	if err := pinThreadToCPUs(0, cpus); err != nil {
+		t.Fatal(err)
+	}
+}
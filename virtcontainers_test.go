@@ -60,10 +60,12 @@ func cleanUp() {
 
 	os.Mkdir(filepath.Join(testDir, testBundle), dirMode)
 
-	_, err := os.Create(filepath.Join(testDir, testImage))
-	if err != nil {
-		fmt.Println("Could not recreate test image:", err)
-		os.Exit(1)
+	for _, filename := range []string{testKernel, testImage, testHypervisor} {
+		_, err := os.Create(filepath.Join(testDir, filename))
+		if err != nil {
+			fmt.Printf("Could not recreate test %s: %v\n", filename, err)
+			os.Exit(1)
+		}
 	}
 }
 
@@ -0,0 +1,239 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/mdlayher/vsock"
+	"github.com/sirupsen/logrus"
+)
+
+// nfsServerBinary is the user space NFS server binary serving NFS
+// backed volumes. It is looked up in the regular binary search path.
+const nfsServerBinary = "unfsd"
+
+// nfsVsockServer serves one host directory over NFS through a vsock
+// port. The NFS server itself is a user space server bound to the host
+// loopback, the vsock port is bridged to it so that only the guest can
+// reach the export.
+type nfsVsockServer struct {
+	podID  string
+	volume Volume
+
+	// port is the vsock port the guest mounts the export from. It
+	// is allocated by the kernel when the server starts, the host
+	// AF_VSOCK port space is global so a fixed port would limit the
+	// backend to a single pod per host.
+	port uint32
+
+	exportsPath string
+	cmd         *exec.Cmd
+	listener    net.Listener
+}
+
+func newNFSVsockServer(podID string, volume Volume) *nfsVsockServer {
+	return &nfsVsockServer{
+		podID:       podID,
+		volume:      volume,
+		exportsPath: filepath.Join(runStoragePath, podID, fmt.Sprintf("nfs-%s.exports", volume.MountTag)),
+	}
+}
+
+// Logger returns a logrus logger appropriate for logging nfsVsockServer messages
+func (s *nfsVsockServer) Logger() *logrus.Entry {
+	return virtLog.WithFields(logrus.Fields{
+		"subsystem": "nfs-vsock",
+		"pod-id":    s.podID,
+		"mount-tag": s.volume.MountTag,
+	})
+}
+
+// freeLocalPort asks the kernel for an unused loopback TCP port.
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// vsockListen opens a listening vsock socket on a kernel allocated
+// port and returns the port together with the listener.
+func vsockListen() (net.Listener, uint32, error) {
+	listener, err := vsock.Listen(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	addr, ok := listener.Addr().(*vsock.Addr)
+	if !ok {
+		listener.Close()
+		return nil, 0, fmt.Errorf("Unexpected vsock listener address %v", listener.Addr())
+	}
+
+	return listener, addr.Port, nil
+}
+
+// start launches the NFS server for the volume and starts bridging the
+// vsock port to it.
+func (s *nfsVsockServer) start() error {
+	if _, err := os.Stat(s.volume.HostPath); err != nil {
+		return err
+	}
+
+	binPath, err := exec.LookPath(nfsServerBinary)
+	if err != nil {
+		return fmt.Errorf("Could not find the NFS server %s: %v", nfsServerBinary, err)
+	}
+
+	// The export is restricted to the loopback, the only way in is
+	// the vsock bridge below.
+	exports := fmt.Sprintf("%s 127.0.0.1(rw,no_root_squash,insecure)\n", s.volume.HostPath)
+	if err := ioutil.WriteFile(s.exportsPath, []byte(exports), 0640); err != nil {
+		return err
+	}
+
+	nfsPort, err := freeLocalPort()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(binPath,
+		"-d", "-s", "-t",
+		"-e", s.exportsPath,
+		"-l", "127.0.0.1",
+		"-n", fmt.Sprintf("%d", nfsPort),
+		"-m", fmt.Sprintf("%d", nfsPort),
+	)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	listener, port, err := vsockListen()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	s.cmd = cmd
+	s.listener = listener
+	s.port = port
+
+	go s.acceptLoop(nfsPort)
+
+	s.Logger().WithField("vsock-port", s.port).Info("NFS volume export started")
+
+	return nil
+}
+
+// acceptLoop bridges every guest vsock connection to the NFS server.
+func (s *nfsVsockServer) acceptLoop(nfsPort int) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.bridgeConn(conn, nfsPort)
+	}
+}
+
+func (s *nfsVsockServer) bridgeConn(conn net.Conn, nfsPort int) {
+	defer conn.Close()
+
+	nfsConn, err := net.Dial("tcp4", fmt.Sprintf("127.0.0.1:%d", nfsPort))
+	if err != nil {
+		s.Logger().WithError(err).Warn("Could not reach the NFS server")
+		return
+	}
+	defer nfsConn.Close()
+
+	go io.Copy(nfsConn, conn)
+	io.Copy(conn, nfsConn)
+}
+
+// stop terminates the vsock bridge and the NFS server, and removes the
+// exports file.
+func (s *nfsVsockServer) stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+
+	if s.cmd != nil {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+		s.cmd = nil
+	}
+
+	os.Remove(s.exportsPath)
+}
+
+// startNFSVolumes starts one NFS server per NFS backed volume of the
+// pod. The allocated vsock ports are recorded in the pod state so the
+// guest side knows where to mount each export from.
+func (p *Pod) startNFSVolumes() error {
+	for _, volume := range p.config.Volumes {
+		if volume.Backend != VolumeNFS {
+			continue
+		}
+
+		server := newNFSVsockServer(p.id, volume)
+		if err := server.start(); err != nil {
+			p.stopNFSVolumes()
+			return err
+		}
+
+		p.nfsServers = append(p.nfsServers, server)
+
+		if p.state.NFSVsockPorts == nil {
+			p.state.NFSVsockPorts = make(map[string]uint32)
+		}
+		p.state.NFSVsockPorts[volume.MountTag] = server.port
+	}
+
+	if len(p.nfsServers) == 0 {
+		return nil
+	}
+
+	if err := p.storage.storePodResource(p.id, stateFileType, p.state); err != nil {
+		p.stopNFSVolumes()
+		return err
+	}
+
+	return nil
+}
+
+// stopNFSVolumes stops every NFS server the pod started.
+func (p *Pod) stopNFSVolumes() {
+	for _, server := range p.nfsServers {
+		server.stop()
+	}
+
+	p.nfsServers = nil
+}
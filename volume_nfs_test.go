@@ -0,0 +1,161 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	govmmQemu "github.com/intel/govmm/qemu"
+	"github.com/mdlayher/vsock"
+)
+
+func TestNFSVsockServerStartMissingHostPath(t *testing.T) {
+	volume := Volume{
+		MountTag: "shared",
+		HostPath: "/nonexistent/nfs/volume",
+		Backend:  VolumeNFS,
+	}
+
+	server := newNFSVsockServer(testPodID, volume)
+
+	if err := server.start(); err == nil {
+		server.stop()
+		t.Fatal("Starting an NFS export for a missing host path should fail")
+	}
+}
+
+func TestNFSVsockServerBridge(t *testing.T) {
+	// A loopback TCP echo server stands in for the NFS server.
+	echo, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}(conn)
+		}
+	}()
+
+	listener, port, err := vsockListen()
+	if err != nil {
+		t.Skipf("AF_VSOCK is not available: %v", err)
+	}
+	defer listener.Close()
+
+	if port == 0 {
+		t.Fatal("Expecting a kernel allocated vsock port")
+	}
+
+	server := &nfsVsockServer{
+		podID: testPodID,
+		volume: Volume{
+			MountTag: "shared",
+			Backend:  VolumeNFS,
+		},
+		port:     port,
+		listener: listener,
+	}
+
+	nfsPort := echo.Addr().(*net.TCPAddr).Port
+	go server.acceptLoop(nfsPort)
+
+	// The local context ID requires vsock loopback support.
+	conn, err := vsock.Dial(vsock.ContextIDReserved, port)
+	if err != nil {
+		t.Skipf("Vsock loopback is not available: %v", err)
+	}
+	defer conn.Close()
+
+	sent := []byte("nfs bridge probe")
+	if _, err := conn.Write(sent); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make([]byte, len(sent))
+	if _, err := io.ReadFull(conn, received); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(received, sent) {
+		t.Fatalf("Got %s\nExpecting %s", received, sent)
+	}
+}
+
+func TestStartNFSVolumesNoNFSVolume(t *testing.T) {
+	pod := &Pod{
+		id: testPodID,
+		config: &PodConfig{
+			Volumes: []Volume{
+				{
+					MountTag: "shared",
+					HostPath: "/tmp",
+				},
+			},
+		},
+	}
+
+	if err := pod.startNFSVolumes(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pod.nfsServers) != 0 {
+		t.Fatalf("Got %d NFS servers\nExpecting none", len(pod.nfsServers))
+	}
+}
+
+func TestQemuAppendFSDevicesSkipsNFSVolumes(t *testing.T) {
+	podConfig := PodConfig{
+		Volumes: []Volume{
+			{
+				MountTag: "shared9p",
+				HostPath: "/tmp",
+			},
+			{
+				MountTag: "sharednfs",
+				HostPath: "/tmp",
+				Backend:  VolumeNFS,
+			},
+		},
+	}
+
+	q := &qemu{}
+
+	var devices []govmmQemu.Device
+	devices = q.appendFSDevices(devices, podConfig)
+
+	if len(devices) != 1 {
+		t.Fatalf("Got %d devices\nExpecting 1", len(devices))
+	}
+
+	fsDevice, ok := devices[0].(govmmQemu.FSDevice)
+	if !ok || fsDevice.MountTag != "shared9p" {
+		t.Fatalf("Got %v\nExpecting the 9p volume only", devices[0])
+	}
+}
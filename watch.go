@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"sync"
+	"time"
+)
+
+// stateEventBufferSize is the number of undelivered state events kept
+// per subscription before events start being dropped.
+const stateEventBufferSize = 16
+
+// StateEvent describes one state transition of a pod or one of its
+// containers.
+type StateEvent struct {
+	// Version increases by one for every transition of the pod.
+	// A gap between the versions of two consecutive events tells the
+	// subscriber it missed transitions and should resynchronize with
+	// StatusPod.
+	Version uint64
+
+	// PodID is the pod the transition belongs to.
+	PodID string
+
+	// ContainerID is the container that changed state, empty for pod
+	// level transitions.
+	ContainerID string
+
+	// State is the state that was entered.
+	State stateString
+
+	// Timestamp is the time the transition was recorded.
+	Timestamp time.Time
+}
+
+// Subscription delivers the state transitions of one pod, or of one of
+// its containers, to a subscriber.
+type Subscription struct {
+	podID       string
+	containerID string
+	events      chan StateEvent
+}
+
+// Events returns the channel the state events are delivered on. The
+// channel is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan StateEvent {
+	return s.events
+}
+
+// Close terminates the subscription.
+func (s *Subscription) Close() {
+	watchers.unsubscribe(s)
+}
+
+// stateWatchers tracks the state subscriptions and the per pod event
+// version. It is process wide: subscriptions do not survive the
+// process embedding virtcontainers.
+type stateWatchers struct {
+	sync.Mutex
+	versions      map[string]uint64
+	subscriptions map[string][]*Subscription
+}
+
+var watchers = &stateWatchers{
+	versions:      map[string]uint64{},
+	subscriptions: map[string][]*Subscription{},
+}
+
+func (w *stateWatchers) subscribe(podID, containerID string) *Subscription {
+	sub := &Subscription{
+		podID:       podID,
+		containerID: containerID,
+		events:      make(chan StateEvent, stateEventBufferSize),
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	w.subscriptions[podID] = append(w.subscriptions[podID], sub)
+
+	return sub
+}
+
+func (w *stateWatchers) unsubscribe(sub *Subscription) {
+	w.Lock()
+	defer w.Unlock()
+
+	subs := w.subscriptions[sub.podID]
+	for idx, s := range subs {
+		if s == sub {
+			w.subscriptions[sub.podID] = append(subs[:idx], subs[idx+1:]...)
+			close(sub.events)
+			break
+		}
+	}
+
+	if len(w.subscriptions[sub.podID]) == 0 {
+		delete(w.subscriptions, sub.podID)
+	}
+}
+
+// publish delivers a state transition to every matching subscription.
+// Slow subscribers have events dropped rather than blocking the pod
+// lifecycle, the version gap tells them to resynchronize.
+func (w *stateWatchers) publish(podID, containerID string, state stateString) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.versions[podID]++
+
+	event := StateEvent{
+		Version:     w.versions[podID],
+		PodID:       podID,
+		ContainerID: containerID,
+		State:       state,
+		Timestamp:   time.Now(),
+	}
+
+	for _, sub := range w.subscriptions[podID] {
+		if sub.containerID != "" && sub.containerID != containerID {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe delivers every state transition of the pod and of its
+// containers, so that controllers can reconcile on changes instead of
+// polling StatusPod.
+func (p *Pod) Subscribe() *Subscription {
+	return watchers.subscribe(p.id, "")
+}
+
+// SubscribeContainer delivers the state transitions of a single
+// container of the pod.
+func (p *Pod) SubscribeContainer(containerID string) *Subscription {
+	return watchers.subscribe(p.id, containerID)
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"testing"
+)
+
+func TestSubscribePodStateEvents(t *testing.T) {
+	pod := &Pod{id: "watch-test-pod"}
+
+	sub := pod.Subscribe()
+	defer sub.Close()
+
+	watchers.publish(pod.id, "", StateRunning)
+	watchers.publish(pod.id, "watched-container", StateRunning)
+
+	event := <-sub.Events()
+	if event.PodID != pod.id || event.ContainerID != "" || event.State != StateRunning {
+		t.Fatalf("Wrong pod event: %+v", event)
+	}
+
+	containerEvent := <-sub.Events()
+	if containerEvent.ContainerID != "watched-container" {
+		t.Fatalf("Wrong container event: %+v", containerEvent)
+	}
+
+	if containerEvent.Version != event.Version+1 {
+		t.Fatalf("Expecting consecutive versions, got %d and %d", event.Version, containerEvent.Version)
+	}
+}
+
+func TestSubscribeContainerFiltersEvents(t *testing.T) {
+	pod := &Pod{id: "watch-filter-pod"}
+
+	sub := pod.SubscribeContainer("wanted")
+	defer sub.Close()
+
+	watchers.publish(pod.id, "other", StateRunning)
+	watchers.publish(pod.id, "wanted", StateStopped)
+
+	event := <-sub.Events()
+	if event.ContainerID != "wanted" || event.State != StateStopped {
+		t.Fatalf("Wrong filtered event: %+v", event)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	pod := &Pod{id: "watch-close-pod"}
+
+	sub := pod.Subscribe()
+	sub.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("Expecting the event channel to be closed")
+	}
+
+	// Publishing after close must not panic.
+	watchers.publish(pod.id, "", StateRunning)
+}
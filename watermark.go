@@ -0,0 +1,206 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// watermarkFile persists the pod resource watermarks in the pod
+// runtime directory, so the high-water marks survive across calls.
+const watermarkFile = "watermarks.json"
+
+// clockTicksPerSecond is the kernel USER_HZ value used for the CPU
+// time accounting in procfs.
+const clockTicksPerSecond = 100
+
+// PodWatermarks holds the high-water marks of the guest resource
+// utilization, enabling right-sizing recommendations by higher
+// layers.
+type PodWatermarks struct {
+	// GuestMemoryMaxBytes is the largest guest memory footprint
+	// observed, as reported by the balloon device.
+	GuestMemoryMaxBytes uint64 `json:"guest-memory-max-bytes"`
+
+	// VCPUUtilizationMaxPercent is the highest vCPU utilization
+	// observed between two samples, as a percentage of the vCPUs
+	// allocated to the pod.
+	VCPUUtilizationMaxPercent uint64 `json:"vcpu-utilization-max-percent"`
+}
+
+// WatermarkThresholds defines the utilization levels above which a
+// resource alert is emitted. A zero threshold disables the alert.
+type WatermarkThresholds struct {
+	// GuestMemoryBytes is the guest memory footprint threshold.
+	GuestMemoryBytes uint64
+
+	// VCPUUtilizationPercent is the vCPU utilization threshold.
+	VCPUUtilizationPercent uint64
+}
+
+// ResourceAlert describes a pod resource sample that crossed its
+// configured threshold.
+type ResourceAlert struct {
+	// PodID is the pod the sample belongs to.
+	PodID string
+
+	// Resource names the resource that crossed its threshold,
+	// "guest-memory" or "vcpu-utilization".
+	Resource string
+
+	// Value is the sampled value.
+	Value uint64
+
+	// Threshold is the configured threshold that was crossed.
+	Threshold uint64
+}
+
+// ResourceAlertHandler receives every resource alert, so higher
+// layers can turn them into right-sizing events.
+type ResourceAlertHandler func(alert ResourceAlert)
+
+var resourceAlertLock sync.Mutex
+var resourceAlertHandler ResourceAlertHandler
+
+// SetResourceAlertHandler registers the handler called whenever a pod
+// resource sample crosses its threshold. A nil handler disables the
+// alerts.
+func SetResourceAlertHandler(handler ResourceAlertHandler) {
+	resourceAlertLock.Lock()
+	defer resourceAlertLock.Unlock()
+
+	resourceAlertHandler = handler
+}
+
+// emitResourceAlert logs the threshold crossing and hands it to the
+// registered handler.
+func emitResourceAlert(alert ResourceAlert) {
+	resourceAlertLock.Lock()
+	handler := resourceAlertHandler
+	resourceAlertLock.Unlock()
+
+	virtLog.WithField("pod-id", alert.PodID).
+		WithField("resource", alert.Resource).
+		WithField("value", alert.Value).
+		WithField("threshold", alert.Threshold).
+		Warn("Pod resource threshold crossed")
+
+	if handler != nil {
+		handler(alert)
+	}
+}
+
+// checkResourceThreshold emits an alert when the sampled value
+// crosses a non-zero threshold.
+func checkResourceThreshold(podID, resource string, value, threshold uint64) {
+	if threshold == 0 || value < threshold {
+		return
+	}
+
+	emitResourceAlert(ResourceAlert{
+		PodID:     podID,
+		Resource:  resource,
+		Value:     value,
+		Threshold: threshold,
+	})
+}
+
+// watermarkState is the persisted watermark bookkeeping. The last CPU
+// sample is kept so the vCPU utilization can be computed as a delta
+// between two calls.
+type watermarkState struct {
+	Watermarks         PodWatermarks `json:"watermarks"`
+	LastCPUTimeTicks   uint64        `json:"last-cpu-time-ticks"`
+	LastSampleUnixNano int64         `json:"last-sample-unix-nano"`
+}
+
+func watermarkPath(podID string) string {
+	return filepath.Join(runStoragePath, podID, watermarkFile)
+}
+
+// sampleWatermarks takes a guest memory and vCPU utilization sample,
+// updates the persisted high-water marks and emits alerts for any
+// threshold crossed.
+func (p *Pod) sampleWatermarks() (PodWatermarks, error) {
+	var state watermarkState
+	if data, err := ioutil.ReadFile(watermarkPath(p.id)); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return PodWatermarks{}, err
+		}
+	}
+
+	now := time.Now()
+
+	// The guest memory footprint comes from the balloon device. A
+	// hypervisor without one simply does not contribute a sample.
+	if response, err := p.hypervisor.sendCommand("query-balloon", nil); err == nil {
+		if balloon, ok := response.(map[string]interface{}); ok {
+			if actual, ok := balloon["actual"].(float64); ok {
+				memory := uint64(actual)
+				if memory > state.Watermarks.GuestMemoryMaxBytes {
+					state.Watermarks.GuestMemoryMaxBytes = memory
+				}
+
+				checkResourceThreshold(p.id, "guest-memory", memory,
+					p.config.WatermarkThresholds.GuestMemoryBytes)
+			}
+		}
+	}
+
+	// The vCPU utilization is computed from the CPU time the
+	// hypervisor process accumulated since the previous sample.
+	if qemuState, ok := p.hypervisor.getState().(QemuState); ok {
+		if pid, err := findHypervisorPid(qemuState.UUID); err == nil {
+			if usage, err := processResourceUsage(pid); err == nil {
+				vcpus := uint64(p.config.VMConfig.VCPUs)
+				elapsed := now.UnixNano() - state.LastSampleUnixNano
+
+				if state.LastSampleUnixNano > 0 && elapsed > 0 &&
+					usage.CPUTimeTicks >= state.LastCPUTimeTicks && vcpus > 0 {
+					ticks := usage.CPUTimeTicks - state.LastCPUTimeTicks
+					cpuTimeNano := ticks * (1e9 / clockTicksPerSecond)
+					percent := cpuTimeNano * 100 / (uint64(elapsed) * vcpus)
+
+					if percent > state.Watermarks.VCPUUtilizationMaxPercent {
+						state.Watermarks.VCPUUtilizationMaxPercent = percent
+					}
+
+					checkResourceThreshold(p.id, "vcpu-utilization", percent,
+						p.config.WatermarkThresholds.VCPUUtilizationPercent)
+				}
+
+				state.LastCPUTimeTicks = usage.CPUTimeTicks
+				state.LastSampleUnixNano = now.UnixNano()
+			}
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return PodWatermarks{}, err
+	}
+
+	if err := ioutil.WriteFile(watermarkPath(p.id), data, 0640); err != nil {
+		return PodWatermarks{}, err
+	}
+
+	return state.Watermarks, nil
+}
@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package virtcontainers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckResourceThreshold(t *testing.T) {
+	var alerts []ResourceAlert
+
+	SetResourceAlertHandler(func(alert ResourceAlert) {
+		alerts = append(alerts, alert)
+	})
+	defer SetResourceAlertHandler(nil)
+
+	// A zero threshold disables the alert.
+	checkResourceThreshold(testPodID, "guest-memory", 100, 0)
+	if len(alerts) != 0 {
+		t.Fatalf("Got %v\nExpecting no alerts", alerts)
+	}
+
+	// A sample below the threshold should not alert.
+	checkResourceThreshold(testPodID, "guest-memory", 100, 200)
+	if len(alerts) != 0 {
+		t.Fatalf("Got %v\nExpecting no alerts", alerts)
+	}
+
+	checkResourceThreshold(testPodID, "guest-memory", 300, 200)
+	if len(alerts) != 1 {
+		t.Fatalf("Got %v\nExpecting one alert", alerts)
+	}
+
+	expected := ResourceAlert{
+		PodID:     testPodID,
+		Resource:  "guest-memory",
+		Value:     300,
+		Threshold: 200,
+	}
+
+	if alerts[0] != expected {
+		t.Fatalf("Got %+v\nExpecting %+v", alerts[0], expected)
+	}
+}
+
+func TestSampleWatermarks(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "vc-watermark-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(runDir)
+
+	savedRunPath := runStoragePath
+	runStoragePath = runDir
+	defer func() {
+		runStoragePath = savedRunPath
+	}()
+
+	if err := os.MkdirAll(filepath.Join(runDir, testPodID), dirMode); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &Pod{
+		id:         testPodID,
+		config:     &PodConfig{},
+		hypervisor: &mockHypervisor{},
+	}
+
+	watermarks, err := pod.sampleWatermarks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if watermarks.GuestMemoryMaxBytes != 0 || watermarks.VCPUUtilizationMaxPercent != 0 {
+		t.Fatalf("Got %+v\nExpecting zero watermarks", watermarks)
+	}
+
+	if _, err := os.Stat(watermarkPath(testPodID)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second sample reuses the persisted state.
+	if _, err := pod.sampleWatermarks(); err != nil {
+		t.Fatal(err)
+	}
+}